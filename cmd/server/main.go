@@ -13,6 +13,17 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/joho/godotenv"
+	"github.com/zhuiye8/Lyss-chat-server/internal/ai/components"
+	"github.com/zhuiye8/Lyss-chat-server/internal/ai/graphs"
+	"github.com/zhuiye8/Lyss-chat-server/internal/ai/providers"
+	"github.com/zhuiye8/Lyss-chat-server/internal/ai/providers/openai"
+	"github.com/zhuiye8/Lyss-chat-server/internal/api"
+	"github.com/zhuiye8/Lyss-chat-server/internal/repository/postgres"
+	chatService "github.com/zhuiye8/Lyss-chat-server/internal/service/chat"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/config"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/db"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/logger"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/sandbox"
 )
 
 // 健康检查响应
@@ -28,17 +39,58 @@ func healthHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func main() {
+	// 以沙箱初始化模式启动时建立隔离并执行代码，不再返回
+	sandbox.Init()
+
 	// 加载环境变量
 	err := godotenv.Load()
 	if err != nil {
 		log.Println("警告: 未找到 .env 文件，使用环境变量")
 	}
 
-	// 获取端口
-	port := os.Getenv("APP_PORT")
-	if port == "" {
-		port = "8000"
+	// 加载配置
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("加载配置失败: %v", err)
+	}
+	appLogger := logger.New(cfg.LogLevel)
+
+	// 连接数据库、Redis 和 MinIO
+	database, err := db.NewPostgres(cfg.Database)
+	if err != nil {
+		log.Fatalf("连接数据库失败: %v", err)
 	}
+	defer database.Close()
+
+	redis, err := db.NewRedis(cfg.Redis)
+	if err != nil {
+		log.Fatalf("连接 Redis 失败: %v", err)
+	}
+	defer redis.Close()
+
+	minio, err := db.NewMinIO(cfg.MinIO)
+	if err != nil {
+		log.Fatalf("连接 MinIO 失败: %v", err)
+	}
+
+	// 创建聊天图形，按模型所属提供商的代码选用提供商和配置中的 API 密钥
+	registry := providers.NewProviderRegistry(appLogger)
+	registry.RegisterFactory(openai.ProviderID, &openai.Factory{})
+	runtime := components.NewModelRuntime(
+		postgres.NewModelRepository(database),
+		postgres.NewProviderRepository(database),
+		registry,
+		cfg.Models.APIKeys,
+		appLogger,
+	)
+	aiGraphs, err := graphs.NewChatGraphs(context.Background(), runtime, appLogger)
+	if err != nil {
+		log.Fatalf("创建聊天图形失败: %v", err)
+	}
+
+	// 聊天服务在所有处理器之间共享
+	chat := chatService.NewService(database, redis, aiGraphs, cfg, appLogger)
+	purger := chatService.NewTrashPurger(database, minio, cfg.Chat, appLogger)
 
 	// 创建路由器
 	r := mux.NewRouter()
@@ -46,18 +98,27 @@ func main() {
 	// 注册健康检查路由
 	r.HandleFunc("/v1/health", healthHandler).Methods("GET")
 
+	// 注册 API 路由
+	api.RegisterRoutes(r, database, redis, minio, chat, purger, cfg, appLogger)
+
+	// 后台任务随服务运行，关闭服务时取消
+	jobs, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	go purger.Run(jobs)
+	go chat.Embeddings().Sweep(jobs)
+
 	// 创建 HTTP 服务器
 	server := &http.Server{
-		Addr:         fmt.Sprintf(":%s", port),
+		Addr:         fmt.Sprintf(":%d", cfg.Server.Port),
 		Handler:      r,
-		ReadTimeout:  60 * time.Second,
-		WriteTimeout: 60 * time.Second,
-		IdleTimeout:  120 * time.Second,
+		ReadTimeout:  time.Duration(cfg.Server.ReadTimeout) * time.Second,
+		WriteTimeout: time.Duration(cfg.Server.WriteTimeout) * time.Second,
+		IdleTimeout:  time.Duration(cfg.Server.IdleTimeout) * time.Second,
 	}
 
 	// 启动服务器
 	go func() {
-		log.Printf("服务器启动在 :%s", port)
+		log.Printf("服务器启动在 :%d", cfg.Server.Port)
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			log.Fatalf("服务器启动失败: %v", err)
 		}
@@ -69,6 +130,7 @@ func main() {
 	<-quit
 
 	log.Println("服务器关闭中...")
+	stopJobs()
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
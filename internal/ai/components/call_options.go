@@ -0,0 +1,24 @@
+package components
+
+import "context"
+
+// callOptionsKey 是调用选项在上下文中的键
+type callOptionsKey struct{}
+
+// CallOptions 表示单次模型调用的选项
+// 图形节点的输入只能是消息列表，因此模型与生成参数通过上下文传递给适配器
+type CallOptions struct {
+	ModelID string
+	Params  map[string]interface{}
+}
+
+// WithCallOptions 返回携带调用选项的上下文
+func WithCallOptions(ctx context.Context, opts CallOptions) context.Context {
+	return context.WithValue(ctx, callOptionsKey{}, opts)
+}
+
+// CallOptionsFromContext 从上下文中获取调用选项
+func CallOptionsFromContext(ctx context.Context) (CallOptions, bool) {
+	opts, ok := ctx.Value(callOptionsKey{}).(CallOptions)
+	return opts, ok
+}
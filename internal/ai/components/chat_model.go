@@ -2,11 +2,10 @@ package components
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
 
-	"github.com/cloudwego/eino/components"
+	einomodel "github.com/cloudwego/eino/components/model"
 	"github.com/cloudwego/eino/schema"
+	"github.com/zhuiye8/Lyss-chat-server/internal/ai/providers"
 	"github.com/zhuiye8/Lyss-chat-server/internal/domain/model"
 	"github.com/zhuiye8/Lyss-chat-server/pkg/logger"
 )
@@ -15,14 +14,15 @@ import (
 type ModelProvider interface {
 	GetModel(ctx context.Context, modelID string) (*model.Model, error)
 	GetAPIKey(ctx context.Context, providerID string) (*model.APIKey, error)
-	CallModel(ctx context.Context, modelID string, messages []*schema.Message) (*schema.Message, error)
-	StreamModel(ctx context.Context, modelID string, messages []*schema.Message) (<-chan *schema.Message, error)
+	CallModel(ctx context.Context, modelID string, messages []*schema.Message, params map[string]interface{}) (*schema.Message, error)
+	StreamModel(ctx context.Context, modelID string, messages []*schema.Message, params map[string]interface{}) (<-chan *schema.Message, error)
 }
 
 // ChatModelAdapter �?Eino ChatModel 组件的适配�?
 type ChatModelAdapter struct {
 	provider ModelProvider
 	modelID  string
+	tools    []providers.ToolDefinition
 	logger   *logger.Logger
 }
 
@@ -35,18 +35,70 @@ func NewChatModelAdapter(provider ModelProvider, modelID string, logger *logger.
 	}
 }
 
-// Call 实现 ChatModel 接口�?Call 方法
-func (a *ChatModelAdapter) Call(ctx context.Context, messages []*schema.Message) (*schema.Message, error) {
-	a.logger.Debug("调用模型", a.modelID)
-	return a.provider.CallModel(ctx, a.modelID, messages)
+// Generate 实现 Eino BaseChatModel 接口的 Generate 方法
+func (a *ChatModelAdapter) Generate(ctx context.Context, messages []*schema.Message, _ ...einomodel.Option) (*schema.Message, error) {
+	modelID, params := a.callOptions(ctx)
+	a.logger.Debug("调用模型", modelID)
+	return a.provider.CallModel(ctx, modelID, messages, params)
 }
 
-// Stream 实现 ChatModel 接口�?Stream 方法
-func (a *ChatModelAdapter) Stream(ctx context.Context, messages []*schema.Message) (<-chan *schema.Message, error) {
-	a.logger.Debug("流式调用模型", a.modelID)
-	return a.provider.StreamModel(ctx, a.modelID, messages)
+// Stream 实现 Eino BaseChatModel 接口的 Stream 方法，将提供商的分片通道转换为 Eino 流
+// 提供商以 ErrorChunk 报告的中途错误作为普通分片原样转发，由调用方通过 providers.ChunkError 识别
+func (a *ChatModelAdapter) Stream(ctx context.Context, messages []*schema.Message, _ ...einomodel.Option) (*schema.StreamReader[*schema.Message], error) {
+	modelID, params := a.callOptions(ctx)
+	a.logger.Debug("流式调用模型", modelID)
+	chunks, err := a.provider.StreamModel(ctx, modelID, messages, params)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, writer := schema.Pipe[*schema.Message](0)
+	go func() {
+		defer writer.Close()
+		for chunk := range chunks {
+			if closed := writer.Send(chunk, nil); closed {
+				// 读取方已关闭，继续读完通道使提供商的 goroutine 能够退出
+				for range chunks {
+				}
+				return
+			}
+		}
+	}()
+	return reader, nil
+}
+
+// callOptions 返回本次调用的模型与生成参数，上下文中指定的模型优先
+func (a *ChatModelAdapter) callOptions(ctx context.Context) (string, map[string]interface{}) {
+	opts, ok := CallOptionsFromContext(ctx)
+	if !ok {
+		return a.modelID, a.withTools(nil)
+	}
+	if opts.ModelID == "" {
+		opts.ModelID = a.modelID
+	}
+	return opts.ModelID, a.withTools(opts.Params)
+}
+
+// WithTools 返回声明了给定工具的适配器副本
+func (a *ChatModelAdapter) WithTools(tools []providers.ToolDefinition) *ChatModelAdapter {
+	adapter := *a
+	adapter.tools = tools
+	return &adapter
+}
+
+// withTools 将适配器声明的工具加入调用参数，不修改原参数
+func (a *ChatModelAdapter) withTools(params map[string]interface{}) map[string]interface{} {
+	if len(a.tools) == 0 {
+		return params
+	}
+	merged := make(map[string]interface{}, len(params)+1)
+	for key, value := range params {
+		merged[key] = value
+	}
+	merged[providers.ParamTools] = a.tools
+	return merged
 }
 
-// Ensure ChatModelAdapter implements components.ChatModel
-var _ components.ChatModel = (*ChatModelAdapter)(nil)
+// Ensure ChatModelAdapter implements einomodel.BaseChatModel
+var _ einomodel.BaseChatModel = (*ChatModelAdapter)(nil)
 
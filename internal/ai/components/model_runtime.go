@@ -0,0 +1,92 @@
+package components
+
+import (
+	"context"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/zhuiye8/Lyss-chat-server/internal/ai/providers"
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/model"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/logger"
+)
+
+// ModelRuntime 按模型记录调用对应提供商的模型，实现 ModelService 接口
+// 模型与提供商从数据库读取，API 密钥按提供商代码从配置中读取
+type ModelRuntime struct {
+	modelRepo    model.ModelRepository
+	providerRepo model.ProviderRepository
+	registry     *providers.ProviderRegistry
+	apiKeys      map[string]string
+	logger       *logger.Logger
+}
+
+// NewModelRuntime 创建一个新的模型运行时，registry 中的工厂以提供商代码注册
+func NewModelRuntime(modelRepo model.ModelRepository, providerRepo model.ProviderRepository, registry *providers.ProviderRegistry, apiKeys map[string]string, logger *logger.Logger) *ModelRuntime {
+	return &ModelRuntime{
+		modelRepo:    modelRepo,
+		providerRepo: providerRepo,
+		registry:     registry,
+		apiKeys:      apiKeys,
+		logger:       logger,
+	}
+}
+
+// GetModel 获取模型记录
+func (r *ModelRuntime) GetModel(ctx context.Context, id string) (*model.Model, error) {
+	return r.modelRepo.GetByID(ctx, id)
+}
+
+// GetAPIKey 返回提供商在配置中的 API 密钥，未配置时返回 providers.ErrInvalidAPIKey
+func (r *ModelRuntime) GetAPIKey(ctx context.Context, providerID string) (*model.APIKey, error) {
+	provider, err := r.providerRepo.GetByID(ctx, providerID)
+	if err != nil {
+		return nil, err
+	}
+	key := r.apiKeys[provider.Code]
+	if key == "" {
+		return nil, providers.ErrInvalidAPIKey
+	}
+	return &model.APIKey{
+		ProviderID: provider.ID,
+		Key:        key,
+		Status:     model.APIKeyStatusActive,
+	}, nil
+}
+
+// CallModel 调用模型，modelID 为模型记录的 ID
+func (r *ModelRuntime) CallModel(ctx context.Context, modelID string, messages []*schema.Message, params map[string]interface{}) (*schema.Message, error) {
+	m, provider, err := r.resolve(ctx, modelID)
+	if err != nil {
+		return nil, err
+	}
+	return provider.Call(ctx, m.ModelID, messages, params)
+}
+
+// StreamModel 流式调用模型，modelID 为模型记录的 ID
+func (r *ModelRuntime) StreamModel(ctx context.Context, modelID string, messages []*schema.Message, params map[string]interface{}) (<-chan *schema.Message, error) {
+	m, provider, err := r.resolve(ctx, modelID)
+	if err != nil {
+		return nil, err
+	}
+	return provider.Stream(ctx, m.ModelID, messages, params)
+}
+
+// resolve 获取模型记录及其所属提供商的实例
+func (r *ModelRuntime) resolve(ctx context.Context, modelID string) (*model.Model, providers.Provider, error) {
+	m, err := r.modelRepo.GetByID(ctx, modelID)
+	if err != nil {
+		return nil, nil, err
+	}
+	record, err := r.providerRepo.GetByID(ctx, m.ProviderID)
+	if err != nil {
+		return nil, nil, err
+	}
+	apiKey := r.apiKeys[record.Code]
+	if apiKey == "" {
+		return nil, nil, providers.ErrInvalidAPIKey
+	}
+	provider, err := r.registry.GetProvider(record.Code, apiKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return m, provider, nil
+}
@@ -11,8 +11,8 @@ import (
 type ModelService interface {
 	GetModel(ctx context.Context, id string) (*model.Model, error)
 	GetAPIKey(ctx context.Context, providerID string) (*model.APIKey, error)
-	CallModel(ctx context.Context, modelID string, messages []*schema.Message) (*schema.Message, error)
-	StreamModel(ctx context.Context, modelID string, messages []*schema.Message) (<-chan *schema.Message, error)
+	CallModel(ctx context.Context, modelID string, messages []*schema.Message, params map[string]interface{}) (*schema.Message, error)
+	StreamModel(ctx context.Context, modelID string, messages []*schema.Message, params map[string]interface{}) (<-chan *schema.Message, error)
 }
 
 // ModelServiceAdapter 是模型服务的适配�?
@@ -31,12 +31,12 @@ func (a *ModelServiceAdapter) GetAPIKey(ctx context.Context, providerID string)
 }
 
 // CallModel 实现 ModelProvider 接口�?CallModel 方法
-func (a *ModelServiceAdapter) CallModel(ctx context.Context, modelID string, messages []*schema.Message) (*schema.Message, error) {
-	return a.ModelService.CallModel(ctx, modelID, messages)
+func (a *ModelServiceAdapter) CallModel(ctx context.Context, modelID string, messages []*schema.Message, params map[string]interface{}) (*schema.Message, error) {
+	return a.ModelService.CallModel(ctx, modelID, messages, params)
 }
 
 // StreamModel 实现 ModelProvider 接口�?StreamModel 方法
-func (a *ModelServiceAdapter) StreamModel(ctx context.Context, modelID string, messages []*schema.Message) (<-chan *schema.Message, error) {
-	return a.ModelService.StreamModel(ctx, modelID, messages)
+func (a *ModelServiceAdapter) StreamModel(ctx context.Context, modelID string, messages []*schema.Message, params map[string]interface{}) (<-chan *schema.Message, error) {
+	return a.ModelService.StreamModel(ctx, modelID, messages, params)
 }
 
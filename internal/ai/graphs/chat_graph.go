@@ -2,25 +2,44 @@ package graphs
 
 import (
 	"context"
+	"errors"
+	"io"
+	"strings"
 
+	"github.com/cloudwego/eino/components/prompt"
 	"github.com/cloudwego/eino/compose"
 	"github.com/cloudwego/eino/schema"
 	"github.com/zhuiye8/Lyss-chat-server/internal/ai/components"
+	"github.com/zhuiye8/Lyss-chat-server/internal/ai/providers"
 	"github.com/zhuiye8/Lyss-chat-server/pkg/logger"
 )
 
-// 聊天模板
-const chatTemplate = `你是一个有用的AI助手。请根据用户的问题提供准确、有帮助的回答�?
-如果你不知道答案，请诚实地说你不知道，不要编造信息�?
-`
+// DefaultSystemPrompt 是租户未配置系统提示词时使用的默认值
+const DefaultSystemPrompt = `你是一个有用的AI助手。请根据用户的问题提供准确、有帮助的回答。
+如果你不知道答案，请诚实地说你不知道，不要编造信息。`
+
+// SystemMessageTemplate 是系统消息模板，运行时从输入中读取系统提示词和回复语言
+const SystemMessageTemplate = "{system_prompt}\n\n请默认使用 {language} 回复用户，除非用户明确要求使用其他语言。"
+
+// chatTemplate 是聊天图形使用的提示词模板
+var chatTemplate = prompt.FromMessages(
+	schema.FString,
+	schema.SystemMessage(SystemMessageTemplate),
+	schema.MessagesPlaceholder("messages", false),
+)
+
+// RenderSystemMessage 渲染系统消息文本，用于在调用前估算其 token 数
+func RenderSystemMessage(systemPrompt, language string) string {
+	return strings.NewReplacer("{system_prompt}", systemPrompt, "{language}", language).Replace(SystemMessageTemplate)
+}
 
 // ChatGraph 表示聊天图形
 type ChatGraph struct {
-	graph *compose.CompiledGraph[map[string]any, *schema.Message]
+	graph compose.Runnable[map[string]any, *schema.Message]
 }
 
 // NewChatGraph 创建一个新的聊天图�?
-func NewChatGraph(ctx context.Context, model components.ChatModelAdapter, logger *logger.Logger) (*ChatGraph, error) {
+func NewChatGraph(ctx context.Context, model *components.ChatModelAdapter, logger *logger.Logger) (*ChatGraph, error) {
 	// 创建图形
 	graph := compose.NewGraph[map[string]any, *schema.Message]()
 
@@ -31,7 +50,7 @@ func NewChatGraph(ctx context.Context, model components.ChatModelAdapter, logger
 	}
 
 	// 添加聊天模型节点
-	err = graph.AddChatModelNode("node_model", &model)
+	err = graph.AddChatModelNode("node_model", model)
 	if err != nil {
 		return nil, err
 	}
@@ -47,6 +66,11 @@ func NewChatGraph(ctx context.Context, model components.ChatModelAdapter, logger
 		return nil, err
 	}
 
+	err = graph.AddEdge("node_model", compose.END)
+	if err != nil {
+		return nil, err
+	}
+
 	// 编译图形
 	compiled, err := graph.Compile(ctx)
 	if err != nil {
@@ -60,11 +84,46 @@ func NewChatGraph(ctx context.Context, model components.ChatModelAdapter, logger
 
 // Invoke 调用聊天图形
 func (g *ChatGraph) Invoke(ctx context.Context, input map[string]any) (*schema.Message, error) {
-	return g.graph.Invoke(ctx, input)
+	return g.graph.Invoke(withCallOptions(ctx, input), input)
 }
 
-// Stream 流式调用聊天图形
+// Stream 流式调用聊天图形，返回的通道在流结束后关闭，中途出错时以 providers.ErrorChunk 结束
 func (g *ChatGraph) Stream(ctx context.Context, input map[string]any) (<-chan *schema.Message, error) {
-	return g.graph.Stream(ctx, input)
+	reader, err := g.graph.Stream(withCallOptions(ctx, input), input)
+	if err != nil {
+		return nil, err
+	}
+
+	chunks := make(chan *schema.Message)
+	go func() {
+		defer close(chunks)
+		defer reader.Close()
+		for {
+			chunk, err := reader.Recv()
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if err != nil {
+				chunk = providers.ErrorChunk(err)
+			}
+			select {
+			case chunks <- chunk:
+			case <-ctx.Done():
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return chunks, nil
+}
+
+// withCallOptions 将输入中的 model_id 与 params 放入上下文，供聊天模型节点读取
+func withCallOptions(ctx context.Context, input map[string]any) context.Context {
+	var opts components.CallOptions
+	opts.ModelID, _ = input["model_id"].(string)
+	opts.Params, _ = input["params"].(map[string]interface{})
+	return components.WithCallOptions(ctx, opts)
 }
 
@@ -4,17 +4,28 @@ import (
 	"context"
 
 	"github.com/zhuiye8/Lyss-chat-server/internal/ai/components"
-	"github.com/zhuiye8/Lyss-chat-server/internal/service/model"
+	"github.com/zhuiye8/Lyss-chat-server/internal/ai/providers"
 	"github.com/zhuiye8/Lyss-chat-server/pkg/logger"
 )
 
 // ChatGraphs 包含所有聊天图�?
 type ChatGraphs struct {
 	Chat *ChatGraph
+
+	// modelProvider 用于按助手配置构建聊天图形
+	modelProvider components.ModelProvider
+	logger        *logger.Logger
+}
+
+// AssistantConfig 表示构建助手聊天图形所需的配置
+// 模型与生成参数仍由每次调用的输入指定，ModelID 仅在输入未指定时使用
+type AssistantConfig struct {
+	ModelID string
+	Tools   []providers.ToolDefinition
 }
 
 // NewChatGraphs 创建一个新的聊天图形集�?
-func NewChatGraphs(ctx context.Context, modelService *model.Service, logger *logger.Logger) (*ChatGraphs, error) {
+func NewChatGraphs(ctx context.Context, modelService components.ModelService, logger *logger.Logger) (*ChatGraphs, error) {
 	// 创建模型提供商适配�?
 	modelProvider := &components.ModelServiceAdapter{
 		ModelService: modelService,
@@ -24,13 +35,21 @@ func NewChatGraphs(ctx context.Context, modelService *model.Service, logger *log
 	chatModel := components.NewChatModelAdapter(modelProvider, "", logger)
 
 	// 创建聊天图形
-	chatGraph, err := NewChatGraph(ctx, *chatModel, logger)
+	chatGraph, err := NewChatGraph(ctx, chatModel, logger)
 	if err != nil {
 		return nil, err
 	}
 
 	return &ChatGraphs{
-		Chat: chatGraph,
+		Chat:          chatGraph,
+		modelProvider: modelProvider,
+		logger:        logger,
 	}, nil
 }
 
+// ForAssistant 按助手的当前配置构建聊天图形，聊天模型默认使用助手的模型并声明助手启用的工具
+// 助手配置随时可能修改，因此每次请求时构建，不做缓存
+func (g *ChatGraphs) ForAssistant(ctx context.Context, cfg AssistantConfig) (*ChatGraph, error) {
+	chatModel := components.NewChatModelAdapter(g.modelProvider, cfg.ModelID, g.logger).WithTools(cfg.Tools)
+	return NewChatGraph(ctx, chatModel, g.logger)
+}
@@ -0,0 +1,13 @@
+package providers
+
+import "context"
+
+// Embedder 表示支持文本向量化的提供商能力
+// 提供商按需实现该接口，调用方通过类型断言判断是否支持
+type Embedder interface {
+	// Embed 将文本批量转换为向量，返回的向量与输入一一对应
+	Embed(ctx context.Context, modelID string, texts []string) ([][]float32, error)
+}
+
+// ErrEmbeddingNotSupported 表示提供商不支持向量化
+var ErrEmbeddingNotSupported = NewError("embedding not supported")
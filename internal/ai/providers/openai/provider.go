@@ -5,7 +5,6 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"strings"
 
 	"github.com/cloudwego/eino/schema"
 	"github.com/sashabaranov/go-openai"
@@ -92,23 +91,7 @@ func (p *Provider) Call(ctx context.Context, modelID string, messages []*schema.
 	}
 
 	// 应用可选参�?
-	if params != nil {
-		if temp, ok := params["temperature"].(float64); ok {
-			req.Temperature = float32(temp)
-		}
-		if topP, ok := params["top_p"].(float64); ok {
-			req.TopP = float32(topP)
-		}
-		if maxTokens, ok := params["max_tokens"].(float64); ok {
-			req.MaxTokens = int(maxTokens)
-		}
-		if presencePenalty, ok := params["presence_penalty"].(float64); ok {
-			req.PresencePenalty = float32(presencePenalty)
-		}
-		if frequencyPenalty, ok := params["frequency_penalty"].(float64); ok {
-			req.FrequencyPenalty = float32(frequencyPenalty)
-		}
-	}
+	applyParams(&req, params)
 
 	// 调用 API
 	resp, err := p.client.CreateChatCompletion(ctx, req)
@@ -124,7 +107,7 @@ func (p *Provider) Call(ctx context.Context, modelID string, messages []*schema.
 
 	// 转换响应
 	return &schema.Message{
-		Role:    resp.Choices[0].Message.Role,
+		Role:    schema.RoleType(resp.Choices[0].Message.Role),
 		Content: resp.Choices[0].Message.Content,
 	}, nil
 }
@@ -144,26 +127,12 @@ func (p *Provider) Stream(ctx context.Context, modelID string, messages []*schem
 		Model:    modelID,
 		Messages: openaiMessages,
 		Stream:   true,
+		// 在最后一个分片中返回 token 用量
+		StreamOptions: &openai.StreamOptions{IncludeUsage: true},
 	}
 
 	// 应用可选参�?
-	if params != nil {
-		if temp, ok := params["temperature"].(float64); ok {
-			req.Temperature = float32(temp)
-		}
-		if topP, ok := params["top_p"].(float64); ok {
-			req.TopP = float32(topP)
-		}
-		if maxTokens, ok := params["max_tokens"].(float64); ok {
-			req.MaxTokens = int(maxTokens)
-		}
-		if presencePenalty, ok := params["presence_penalty"].(float64); ok {
-			req.PresencePenalty = float32(presencePenalty)
-		}
-		if frequencyPenalty, ok := params["frequency_penalty"].(float64); ok {
-			req.FrequencyPenalty = float32(frequencyPenalty)
-		}
-	}
+	applyParams(&req, params)
 
 	// 创建流式响应通道
 	stream, err := p.client.CreateChatCompletionStream(ctx, req)
@@ -180,34 +149,66 @@ func (p *Provider) Stream(ctx context.Context, modelID string, messages []*schem
 		defer close(outputChan)
 		defer stream.Close()
 
-		var fullContent strings.Builder
+		// send 发送分片，调用方已取消时返回 false
+		send := func(chunk *schema.Message) bool {
+			select {
+			case outputChan <- chunk:
+				return true
+			case <-ctx.Done():
+				// 调用方已取消，停止接收以免阻塞
+				return false
+			}
+		}
 
+		meta := &schema.ResponseMeta{}
 		for {
 			response, err := stream.Recv()
 			if errors.Is(err, io.EOF) {
-				// 流结�?
+				// 流结束
 				break
 			}
 
 			if err != nil {
 				p.logger.Error("接收流式响应失败", err)
+				send(providers.ErrorChunk(fmt.Errorf("%w: %v", providers.ErrAPICallFailed, err)))
 				return
 			}
 
-			// 检查是否有内容
-			if len(response.Choices) == 0 || response.Choices[0].Delta.Content == "" {
+			// 用量在最后一个不含 choices 的分片中返回
+			if response.Usage != nil {
+				meta.Usage = &schema.TokenUsage{
+					PromptTokens:     response.Usage.PromptTokens,
+					CompletionTokens: response.Usage.CompletionTokens,
+					TotalTokens:      response.Usage.TotalTokens,
+				}
+			}
+			if len(response.Choices) == 0 {
 				continue
 			}
 
-			// 累积内容
-			fullContent.WriteString(response.Choices[0].Delta.Content)
+			choice := response.Choices[0]
+			if choice.FinishReason != "" {
+				meta.FinishReason = string(choice.FinishReason)
+			}
 
-			// 发送消�?
-			outputChan <- &schema.Message{
-				Role:    "assistant",
-				Content: fullContent.String(),
+			// 只发送新增的内容和工具调用增量
+			if choice.Delta.Content == "" && len(choice.Delta.ToolCalls) == 0 {
+				continue
+			}
+			if !send(&schema.Message{
+				Role:      schema.Assistant,
+				Content:   choice.Delta.Content,
+				ToolCalls: convertToolCallDeltas(choice.Delta.ToolCalls),
+			}) {
+				return
 			}
 		}
+
+		// 发送携带结束原因和用量的最后一个分片
+		send(&schema.Message{
+			Role:         schema.Assistant,
+			ResponseMeta: meta,
+		})
 	}()
 
 	return outputChan, nil
@@ -228,10 +229,114 @@ func convertToOpenAIMessages(messages []*schema.Message) []openai.ChatCompletion
 	openaiMessages := make([]openai.ChatCompletionMessage, 0, len(messages))
 	for _, msg := range messages {
 		openaiMessages = append(openaiMessages, openai.ChatCompletionMessage{
-			Role:    msg.Role,
+			Role:    string(msg.Role),
 			Content: msg.Content,
 		})
 	}
 	return openaiMessages
 }
 
+// convertToolCallDeltas 将 OpenAI 工具调用增量转换为 schema.ToolCall
+func convertToolCallDeltas(toolCalls []openai.ToolCall) []schema.ToolCall {
+	if len(toolCalls) == 0 {
+		return nil
+	}
+	deltas := make([]schema.ToolCall, 0, len(toolCalls))
+	for _, tc := range toolCalls {
+		deltas = append(deltas, schema.ToolCall{
+			Index: tc.Index,
+			ID:    tc.ID,
+			Type:  string(tc.Type),
+			Function: schema.FunctionCall{
+				Name:      tc.Function.Name,
+				Arguments: tc.Function.Arguments,
+			},
+		})
+	}
+	return deltas
+}
+
+// applyParams 将可选参数应用到请求
+func applyParams(req *openai.ChatCompletionRequest, params map[string]interface{}) {
+	if params == nil {
+		return
+	}
+	if temp, ok := params["temperature"].(float64); ok {
+		req.Temperature = float32(temp)
+	}
+	if topP, ok := params["top_p"].(float64); ok {
+		req.TopP = float32(topP)
+	}
+	if maxTokens, ok := params["max_tokens"].(float64); ok {
+		req.MaxTokens = int(maxTokens)
+	}
+	if presencePenalty, ok := params["presence_penalty"].(float64); ok {
+		req.PresencePenalty = float32(presencePenalty)
+	}
+	if frequencyPenalty, ok := params["frequency_penalty"].(float64); ok {
+		req.FrequencyPenalty = float32(frequencyPenalty)
+	}
+	if stop, ok := params["stop"].([]string); ok {
+		req.Stop = stop
+	}
+	if seed, ok := params["seed"].(float64); ok {
+		s := int(seed)
+		req.Seed = &s
+	}
+	if tools, ok := params[providers.ParamTools].([]providers.ToolDefinition); ok {
+		req.Tools = convertTools(tools)
+	}
+}
+
+// convertTools 将工具定义转换为 OpenAI 函数工具
+func convertTools(tools []providers.ToolDefinition) []openai.Tool {
+	if len(tools) == 0 {
+		return nil
+	}
+	openaiTools := make([]openai.Tool, 0, len(tools))
+	for _, tool := range tools {
+		function := &openai.FunctionDefinition{
+			Name:        tool.Name,
+			Description: tool.Description,
+		}
+		if len(tool.Parameters) > 0 {
+			function.Parameters = tool.Parameters
+		}
+		openaiTools = append(openaiTools, openai.Tool{
+			Type:     openai.ToolTypeFunction,
+			Function: function,
+		})
+	}
+	return openaiTools
+}
+
+// Embed 将文本批量转换为向量
+func (p *Provider) Embed(ctx context.Context, modelID string, texts []string) ([][]float32, error) {
+	resp, err := p.client.CreateEmbeddings(ctx, openai.EmbeddingRequest{
+		Input: texts,
+		Model: openai.EmbeddingModel(modelID),
+	})
+	if err != nil {
+		p.logger.Error("OpenAI 向量化 API 调用失败", err)
+		return nil, fmt.Errorf("%w: %v", providers.ErrAPICallFailed, err)
+	}
+
+	// 按输入顺序返回向量
+	vectors := make([][]float32, len(texts))
+	for _, item := range resp.Data {
+		if item.Index < 0 || item.Index >= len(vectors) {
+			continue
+		}
+		vectors[item.Index] = item.Embedding
+	}
+	for _, vector := range vectors {
+		if vector == nil {
+			return nil, errors.New("OpenAI 返回的向量数量与输入不一致")
+		}
+	}
+
+	return vectors, nil
+}
+
+// 确保 Provider 支持向量化
+var _ providers.Embedder = (*Provider)(nil)
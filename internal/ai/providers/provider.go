@@ -2,6 +2,7 @@ package providers
 
 import (
 	"context"
+	"sync"
 
 	"github.com/cloudwego/eino/schema"
 	"github.com/zhuiye8/Lyss-chat-server/internal/domain/model"
@@ -19,7 +20,8 @@ type Provider interface {
 	// Call 调用模型
 	Call(ctx context.Context, modelID string, messages []*schema.Message, params map[string]interface{}) (*schema.Message, error)
 
-	// Stream 流式调用模型
+	// Stream 流式调用模型，每个分片只包含新增的内容和工具调用增量
+	// 最后一个分片的 ResponseMeta 携带结束原因和 token 用量，中途出错时以 ErrorChunk 结束
 	Stream(ctx context.Context, modelID string, messages []*schema.Message, params map[string]interface{}) (<-chan *schema.Message, error)
 }
 
@@ -30,7 +32,9 @@ type ProviderFactory interface {
 }
 
 // ProviderRegistry 管理所有模型提供商
+// 注册表在并发的请求之间共享，工厂和已创建的提供商实例均由读写锁保护
 type ProviderRegistry struct {
+	mu        sync.RWMutex
 	factories map[string]ProviderFactory
 	providers map[string]Provider
 	logger    *logger.Logger
@@ -47,29 +51,40 @@ func NewProviderRegistry(logger *logger.Logger) *ProviderRegistry {
 
 // RegisterFactory 注册提供商工�?
 func (r *ProviderRegistry) RegisterFactory(providerID string, factory ProviderFactory) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	r.factories[providerID] = factory
 }
 
 // GetProvider 获取提供商实�?
 func (r *ProviderRegistry) GetProvider(providerID string, apiKey string) (Provider, error) {
-	// 检查是否已经创建了提供商实�?
+	// 已经创建过提供商实例时直接返回
+	r.mu.RLock()
+	provider, ok := r.providers[providerID]
+	r.mu.RUnlock()
+	if ok {
+		return provider, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// 获取写锁后再次检查，并发的首次调用只创建一个实例
 	if provider, ok := r.providers[providerID]; ok {
 		return provider, nil
 	}
 
-	// 获取提供商工�?
+	// 获取提供商工厂
 	factory, ok := r.factories[providerID]
 	if !ok {
 		return nil, ErrProviderNotFound
 	}
 
-	// 创建提供商实�?
+	// 创建并缓存提供商实例
 	provider, err := factory.Create(apiKey, r.logger)
 	if err != nil {
 		return nil, err
 	}
-
-	// 缓存提供商实�?
 	r.providers[providerID] = provider
 
 	return provider, nil
@@ -77,6 +92,9 @@ func (r *ProviderRegistry) GetProvider(providerID string, apiKey string) (Provid
 
 // GetSupportedProviders 获取支持的提供商列表
 func (r *ProviderRegistry) GetSupportedProviders() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	providers := make([]string, 0, len(r.factories))
 	for providerID := range r.factories {
 		providers = append(providers, providerID)
@@ -0,0 +1,66 @@
+package providers
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/model"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/logger"
+)
+
+type fakeProvider struct{}
+
+func (fakeProvider) GetName() string { return "fake" }
+
+func (fakeProvider) GetModels() []*model.Model { return nil }
+
+func (fakeProvider) Call(ctx context.Context, modelID string, messages []*schema.Message, params map[string]interface{}) (*schema.Message, error) {
+	return nil, nil
+}
+
+func (fakeProvider) Stream(ctx context.Context, modelID string, messages []*schema.Message, params map[string]interface{}) (<-chan *schema.Message, error) {
+	return nil, nil
+}
+
+type countingFactory struct {
+	created atomic.Int32
+}
+
+func (f *countingFactory) Create(apiKey string, logger *logger.Logger) (Provider, error) {
+	f.created.Add(1)
+	return fakeProvider{}, nil
+}
+
+func TestGetProviderConcurrent(t *testing.T) {
+	registry := NewProviderRegistry(logger.New("error"))
+	factory := &countingFactory{}
+	registry.RegisterFactory("fake", factory)
+
+	const workers = 32
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			if _, err := registry.GetProvider("fake", "key"); err != nil {
+				t.Errorf("GetProvider: %v", err)
+			}
+			registry.GetSupportedProviders()
+		}()
+	}
+	wg.Wait()
+
+	if got := factory.created.Load(); got != 1 {
+		t.Fatalf("factory created %d providers, want 1", got)
+	}
+}
+
+func TestGetProviderUnknown(t *testing.T) {
+	registry := NewProviderRegistry(logger.New("error"))
+	if _, err := registry.GetProvider("missing", "key"); err != ErrProviderNotFound {
+		t.Fatalf("GetProvider error = %v, want %v", err, ErrProviderNotFound)
+	}
+}
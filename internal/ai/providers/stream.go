@@ -0,0 +1,29 @@
+package providers
+
+import (
+	"errors"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// streamErrorKey 是流式分片中携带错误信息的扩展字段
+const streamErrorKey = "stream_error"
+
+// ErrorChunk 创建携带错误的流式分片，提供商在流中途出错时将其作为最后一个分片发送
+func ErrorChunk(err error) *schema.Message {
+	return &schema.Message{
+		Role:  schema.Assistant,
+		Extra: map[string]any{streamErrorKey: err.Error()},
+	}
+}
+
+// ChunkError 返回流式分片携带的错误，没有错误时返回 nil
+func ChunkError(chunk *schema.Message) error {
+	if chunk == nil || chunk.Extra == nil {
+		return nil
+	}
+	if message, ok := chunk.Extra[streamErrorKey].(string); ok {
+		return errors.New(message)
+	}
+	return nil
+}
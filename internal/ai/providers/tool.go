@@ -0,0 +1,14 @@
+package providers
+
+import "encoding/json"
+
+// ParamTools 是调用参数中工具列表的键，取值为 []ToolDefinition
+const ParamTools = "tools"
+
+// ToolDefinition 表示向模型声明的函数工具，Parameters 为 JSON Schema 描述的参数
+// 不支持工具的提供商忽略该参数
+type ToolDefinition struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"`
+}
@@ -0,0 +1,136 @@
+package tokenizer
+
+import (
+	"math"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/pkoukk/tiktoken-go"
+	tiktokenloader "github.com/pkoukk/tiktoken-go-loader"
+)
+
+// estimateMargin 是估算结果的安全系数，估算偏低时会超出上下文窗口，因此宁可多算
+const estimateMargin = 1.15
+
+func init() {
+	// 使用随依赖打包的词表文件，不在运行时下载
+	tiktoken.SetBpeLoader(tiktokenloader.NewOfflineLoader())
+}
+
+// Tokenizer 表示 token 计数器
+type Tokenizer interface {
+	// Count 返回文本的 token 数
+	Count(text string) int
+}
+
+// estimator 按字符类别估算 token 数，仅用于没有可用词表的模型以及词表加载失败时
+// 不同模型族的词表对中日韩文字和拉丁文字的切分粒度差异较大，因此分别设置系数，结果再乘以 estimateMargin
+type estimator struct {
+	charsPerToken float64 // 每个 token 平均对应的非中日韩字符数
+	tokensPerCJK  float64 // 每个中日韩字符平均对应的 token 数
+}
+
+// Count 返回文本的估算 token 数
+func (e estimator) Count(text string) int {
+	if text == "" {
+		return 0
+	}
+
+	var cjk, other int
+	for _, r := range text {
+		if isCJK(r) {
+			cjk++
+		} else {
+			other++
+		}
+	}
+
+	return int(math.Ceil((float64(other)/e.charsPerToken + float64(cjk)*e.tokensPerCJK) * estimateMargin))
+}
+
+// encoding 使用 BPE 词表精确计数，词表在首次计数时加载，加载失败时改用 fallback 估算
+type encoding struct {
+	name     string
+	fallback Tokenizer
+
+	once sync.Once
+	bpe  *tiktoken.Tiktoken
+}
+
+// Count 返回文本的 token 数，不把文本中的特殊 token 标记当作特殊 token
+func (e *encoding) Count(text string) int {
+	if text == "" {
+		return 0
+	}
+	e.once.Do(func() {
+		e.bpe, _ = tiktoken.GetEncoding(e.name)
+	})
+	if e.bpe == nil {
+		return e.fallback.Count(text)
+	}
+	return len(e.bpe.EncodeOrdinary(text))
+}
+
+// family 表示一组共用词表的模型
+type family struct {
+	prefixes  []string
+	tokenizer Tokenizer
+}
+
+// families 按模型 ID 前缀匹配，靠前的优先
+// OpenAI 模型使用其公开的 BPE 词表，其他模型的词表未公开或未打包，使用估算
+var families = []family{
+	{prefixes: []string{"gpt-4o", "gpt-4.1", "gpt-5", "o1", "o3", "o4"}, tokenizer: &encoding{name: "o200k_base", fallback: estimator{charsPerToken: 4.2, tokensPerCJK: 0.7}}},
+	{prefixes: []string{"gpt-4", "gpt-3.5", "text-embedding-"}, tokenizer: &encoding{name: "cl100k_base", fallback: estimator{charsPerToken: 4.0, tokensPerCJK: 1.0}}},
+	{prefixes: []string{"claude"}, tokenizer: estimator{charsPerToken: 3.5, tokensPerCJK: 1.1}},
+	{prefixes: []string{"qwen", "deepseek", "glm", "yi-", "baichuan", "moonshot"}, tokenizer: estimator{charsPerToken: 3.8, tokensPerCJK: 0.6}},
+}
+
+// defaultTokenizer 用于无法识别的模型，取偏保守的系数避免超出上下文
+var defaultTokenizer Tokenizer = estimator{charsPerToken: 3.5, tokensPerCJK: 1.0}
+
+// ForModel 根据模型 ID 返回对应的 token 计数器
+func ForModel(modelID string) Tokenizer {
+	id := strings.ToLower(modelID)
+	for _, f := range families {
+		for _, prefix := range f.prefixes {
+			if strings.HasPrefix(id, prefix) {
+				return f.tokenizer
+			}
+		}
+	}
+	return defaultTokenizer
+}
+
+// TruncateTail 保留文本末尾，使其 token 数不超过 maxTokens
+func TruncateTail(t Tokenizer, text string, maxTokens int) string {
+	if maxTokens <= 0 {
+		return ""
+	}
+	if t.Count(text) <= maxTokens {
+		return text
+	}
+
+	// 二分查找可保留的最长后缀
+	runes := []rune(text)
+	lo, hi := 0, len(runes)
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if t.Count(string(runes[len(runes)-mid:])) <= maxTokens {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+
+	return string(runes[len(runes)-lo:])
+}
+
+// isCJK 判断字符是否为中日韩文字
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) ||
+		unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) ||
+		unicode.Is(unicode.Hangul, r)
+}
@@ -0,0 +1,112 @@
+package tokenizer
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestForModelCount(t *testing.T) {
+	tests := []struct {
+		model string
+		text  string
+		want  int
+	}{
+		{"gpt-4o-mini", "", 0},
+		{"gpt-4o-mini", "hello world", 2},
+		{"gpt-4-turbo", "hello world", 2},
+		{"gpt-3.5-turbo", "tiktoken is great!", 6},
+		// 文本中的特殊 token 标记按普通文本计数
+		{"gpt-4", "<|endoftext|>", 7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.model+"/"+tt.text, func(t *testing.T) {
+			if got := ForModel(tt.model).Count(tt.text); got != tt.want {
+				t.Fatalf("Count(%q) = %d, want %d", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestForModelFamilies(t *testing.T) {
+	tests := []struct {
+		model    string
+		encoding string
+	}{
+		{"gpt-4o", "o200k_base"},
+		{"GPT-4.1-mini", "o200k_base"},
+		{"o3-mini", "o200k_base"},
+		{"gpt-4", "cl100k_base"},
+		{"gpt-3.5-turbo", "cl100k_base"},
+		{"claude-3-5-sonnet", ""},
+		{"unknown-model", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			name := ""
+			if e, ok := ForModel(tt.model).(*encoding); ok {
+				name = e.name
+			}
+			if name != tt.encoding {
+				t.Fatalf("ForModel(%q) encoding = %q, want %q", tt.model, name, tt.encoding)
+			}
+		})
+	}
+}
+
+func TestEstimatorMargin(t *testing.T) {
+	e := estimator{charsPerToken: 4, tokensPerCJK: 1}
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{strings.Repeat("a", 40), 12},
+		{strings.Repeat("中", 20), 23},
+		{strings.Repeat("a", 40) + strings.Repeat("中", 20), 35},
+	}
+	for _, tt := range tests {
+		if got := e.Count(tt.text); got != tt.want {
+			t.Fatalf("Count(%d runes) = %d, want %d", utf8.RuneCountInString(tt.text), got, tt.want)
+		}
+	}
+}
+
+func TestEncodingFallback(t *testing.T) {
+	e := &encoding{name: "missing_base", fallback: estimator{charsPerToken: 4, tokensPerCJK: 1}}
+	if got := e.Count(strings.Repeat("a", 40)); got != 12 {
+		t.Fatalf("Count = %d, want fallback estimate 12", got)
+	}
+}
+
+func TestTruncateTail(t *testing.T) {
+	tok := ForModel("gpt-4o")
+	text := strings.Repeat("hello world ", 50) + "the end"
+
+	tests := []struct {
+		name      string
+		maxTokens int
+	}{
+		{"zero", 0},
+		{"small", 5},
+		{"medium", 40},
+		{"fits", 1000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := TruncateTail(tok, text, tt.maxTokens)
+			if n := tok.Count(got); n > tt.maxTokens {
+				t.Fatalf("Count(result) = %d, want <= %d", n, tt.maxTokens)
+			}
+			if !strings.HasSuffix(text, got) {
+				t.Fatalf("result %q is not a suffix of the input", got)
+			}
+			if tt.maxTokens > 0 && !strings.HasSuffix(got, "the end") {
+				t.Fatalf("result %q lost the end of the text", got)
+			}
+			if tt.maxTokens >= tok.Count(text) && got != text {
+				t.Fatalf("text within budget was truncated")
+			}
+		})
+	}
+}
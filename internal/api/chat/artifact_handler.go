@@ -0,0 +1,225 @@
+package chat
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/chat"
+	"github.com/zhuiye8/Lyss-chat-server/internal/middleware"
+	chatService "github.com/zhuiye8/Lyss-chat-server/internal/service/chat"
+	"github.com/zhuiye8/Lyss-chat-server/internal/util"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/logger"
+)
+
+// ArtifactHandler 表示代码画布产物处理器
+type ArtifactHandler struct {
+	service *chatService.Service
+	logger  *logger.Logger
+}
+
+// NewArtifactHandler 创建一个新的产物处理器
+func NewArtifactHandler(service *chatService.Service, logger *logger.Logger) *ArtifactHandler {
+	return &ArtifactHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// ListArtifacts 处理获取画布产物列表请求
+func (h *ArtifactHandler) ListArtifacts(w http.ResponseWriter, r *http.Request) {
+	canvasID, ok := pathID(w, r, "画布ID不能为空")
+	if !ok {
+		return
+	}
+
+	artifacts, err := h.service.ListArtifacts(r.Context(), canvasID)
+	if err != nil {
+		h.logger.Error("获取产物列表失败", err)
+		if writeArtifactError(w, err) {
+			return
+		}
+		util.NotFoundError(w, "画布不存在")
+		return
+	}
+
+	util.SuccessResponse(w, artifacts, http.StatusOK)
+}
+
+// GetArtifact 处理获取产物最新内容请求
+func (h *ArtifactHandler) GetArtifact(w http.ResponseWriter, r *http.Request) {
+	canvasID, artifactID, ok := artifactParams(w, r)
+	if !ok {
+		return
+	}
+
+	artifact, err := h.service.GetArtifact(r.Context(), canvasID, artifactID)
+	if err != nil {
+		h.logger.Error("获取产物失败", err)
+		if writeArtifactError(w, err) {
+			return
+		}
+		util.InternalServerError(w, "获取产物失败")
+		return
+	}
+
+	util.SuccessResponse(w, artifact, http.StatusOK)
+}
+
+// ListArtifactVersions 处理获取产物版本历史请求
+func (h *ArtifactHandler) ListArtifactVersions(w http.ResponseWriter, r *http.Request) {
+	canvasID, artifactID, ok := artifactParams(w, r)
+	if !ok {
+		return
+	}
+
+	versions, err := h.service.ListArtifactVersions(r.Context(), canvasID, artifactID)
+	if err != nil {
+		h.logger.Error("获取产物版本失败", err)
+		if writeArtifactError(w, err) {
+			return
+		}
+		util.InternalServerError(w, "获取产物版本失败")
+		return
+	}
+
+	util.SuccessResponse(w, versions, http.StatusOK)
+}
+
+// GetArtifactVersion 处理获取产物指定版本请求
+func (h *ArtifactHandler) GetArtifactVersion(w http.ResponseWriter, r *http.Request) {
+	canvasID, artifactID, ok := artifactParams(w, r)
+	if !ok {
+		return
+	}
+
+	version, err := strconv.Atoi(mux.Vars(r)["version"])
+	if err != nil || version < 1 {
+		util.BadRequestError(w, "无效的版本号", nil)
+		return
+	}
+
+	artifactVersion, err := h.service.GetArtifactVersion(r.Context(), canvasID, artifactID, version)
+	if err != nil {
+		h.logger.Error("获取产物版本失败", err)
+		if writeArtifactError(w, err) {
+			return
+		}
+		util.InternalServerError(w, "获取产物版本失败")
+		return
+	}
+
+	util.SuccessResponse(w, artifactVersion, http.StatusOK)
+}
+
+// DiffArtifact 处理比较产物版本请求，from 和 to 为可选的版本号
+func (h *ArtifactHandler) DiffArtifact(w http.ResponseWriter, r *http.Request) {
+	canvasID, artifactID, ok := artifactParams(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	from, ok := optionalVersionParam(w, query.Get("from"))
+	if !ok {
+		return
+	}
+	to, ok := optionalVersionParam(w, query.Get("to"))
+	if !ok {
+		return
+	}
+
+	diff, err := h.service.DiffArtifact(r.Context(), canvasID, artifactID, from, to)
+	if err != nil {
+		h.logger.Error("比较产物版本失败", err)
+		if writeArtifactError(w, err) {
+			return
+		}
+		util.InternalServerError(w, "比较产物版本失败")
+		return
+	}
+
+	util.SuccessResponse(w, diff, http.StatusOK)
+}
+
+// RevertArtifact 处理将产物回退到指定版本请求
+func (h *ArtifactHandler) RevertArtifact(w http.ResponseWriter, r *http.Request) {
+	canvasID, artifactID, ok := artifactParams(w, r)
+	if !ok {
+		return
+	}
+
+	var req chat.RevertArtifactRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		util.BadRequestError(w, "无效的请求体", nil)
+		return
+	}
+	if req.Version < 1 {
+		util.BadRequestError(w, "无效的版本号", nil)
+		return
+	}
+
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		util.UnauthorizedError(w, "未认证")
+		return
+	}
+
+	artifact, err := h.service.RevertArtifact(r.Context(), userID, canvasID, artifactID, &req)
+	if err != nil {
+		h.logger.Error("回退产物失败", err)
+		if writeArtifactError(w, err) {
+			return
+		}
+		util.NotFoundError(w, "画布不存在")
+		return
+	}
+
+	util.SuccessResponse(w, artifact, http.StatusOK)
+}
+
+// artifactParams 获取路径中的画布 ID 和产物 ID
+func artifactParams(w http.ResponseWriter, r *http.Request) (string, string, bool) {
+	canvasID, ok := pathID(w, r, "画布ID不能为空")
+	if !ok {
+		return "", "", false
+	}
+
+	artifactID := mux.Vars(r)["artifact_id"]
+	if artifactID == "" {
+		util.BadRequestError(w, "产物ID不能为空", nil)
+		return "", "", false
+	}
+
+	return canvasID, artifactID, true
+}
+
+// optionalVersionParam 解析可选的版本号查询参数，版本 0 表示产物创建前的空内容
+func optionalVersionParam(w http.ResponseWriter, value string) (*int, bool) {
+	if value == "" {
+		return nil, true
+	}
+	version, err := strconv.Atoi(value)
+	if err != nil || version < 0 {
+		util.BadRequestError(w, "无效的版本号", nil)
+		return nil, false
+	}
+	return &version, true
+}
+
+// writeArtifactError 将产物的业务错误映射为对应的 HTTP 响应，其余错误按聊天错误处理
+func writeArtifactError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, chatService.ErrArtifactNotFound):
+		util.NotFoundError(w, "产物不存在")
+	case errors.Is(err, chatService.ErrArtifactVersionNotFound):
+		util.NotFoundError(w, "产物版本不存在")
+	case errors.Is(err, chatService.ErrInvalidArtifactVersion):
+		util.BadRequestError(w, "不能回退到当前版本", nil)
+	default:
+		return writeChatError(w, err)
+	}
+	return true
+}
@@ -0,0 +1,248 @@
+package chat
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/chat"
+	"github.com/zhuiye8/Lyss-chat-server/internal/middleware"
+	chatService "github.com/zhuiye8/Lyss-chat-server/internal/service/chat"
+	"github.com/zhuiye8/Lyss-chat-server/internal/util"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/logger"
+)
+
+// AssistantHandler 表示助手处理器
+type AssistantHandler struct {
+	service *chatService.Service
+	logger  *logger.Logger
+}
+
+// NewAssistantHandler 创建一个新的助手处理器
+func NewAssistantHandler(service *chatService.Service, logger *logger.Logger) *AssistantHandler {
+	return &AssistantHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// ListAssistants 处理获取助手列表请求
+func (h *AssistantHandler) ListAssistants(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		util.UnauthorizedError(w, "未认证")
+		return
+	}
+
+	// 获取过滤参数
+	query := r.URL.Query()
+	req := chat.AssistantListRequest{
+		UserID:      userID,
+		Scope:       optionalParam(query.Get("scope")),
+		WorkspaceID: optionalParam(query.Get("workspace_id")),
+	}
+
+	// 获取分页参数
+	page := 1
+	pageSize := 20
+	if pageStr := query.Get("page"); pageStr != "" {
+		if p, err := strconv.Atoi(pageStr); err == nil && p > 0 {
+			page = p
+		}
+	}
+	if pageSizeStr := query.Get("page_size"); pageSizeStr != "" {
+		if ps, err := strconv.Atoi(pageSizeStr); err == nil && ps > 0 {
+			pageSize = ps
+		}
+	}
+
+	assistants, total, err := h.service.ListAssistants(r.Context(), &req, page, pageSize)
+	if err != nil {
+		h.logger.Error("获取助手列表失败", err)
+		if writeAssistantError(w, err) {
+			return
+		}
+		util.InternalServerError(w, "获取助手列表失败")
+		return
+	}
+
+	// 构建响应
+	response := map[string]interface{}{
+		"items":     assistants,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	}
+
+	util.SuccessResponse(w, response, http.StatusOK)
+}
+
+// GetAssistant 处理获取助手详情请求
+func (h *AssistantHandler) GetAssistant(w http.ResponseWriter, r *http.Request) {
+	userID, id, ok := assistantParams(w, r)
+	if !ok {
+		return
+	}
+
+	assistant, err := h.service.GetAssistant(r.Context(), userID, id)
+	if err != nil {
+		h.logger.Error("获取助手失败", err)
+		if writeAssistantError(w, err) {
+			return
+		}
+		util.InternalServerError(w, "获取助手失败")
+		return
+	}
+
+	util.SuccessResponse(w, assistant, http.StatusOK)
+}
+
+// CreateAssistant 处理创建助手请求
+func (h *AssistantHandler) CreateAssistant(w http.ResponseWriter, r *http.Request) {
+	var req chat.CreateAssistantRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		util.BadRequestError(w, "无效的请求体", nil)
+		return
+	}
+
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		util.UnauthorizedError(w, "未认证")
+		return
+	}
+
+	assistant, err := h.service.CreateAssistant(r.Context(), userID, &req)
+	if err != nil {
+		h.logger.Error("创建助手失败", err)
+		if writeAssistantError(w, err) {
+			return
+		}
+		util.InternalServerError(w, "创建助手失败")
+		return
+	}
+
+	util.SuccessResponse(w, assistant, http.StatusCreated)
+}
+
+// UpdateAssistant 处理修改助手请求
+func (h *AssistantHandler) UpdateAssistant(w http.ResponseWriter, r *http.Request) {
+	userID, id, ok := assistantParams(w, r)
+	if !ok {
+		return
+	}
+
+	var req chat.UpdateAssistantRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		util.BadRequestError(w, "无效的请求体", nil)
+		return
+	}
+
+	assistant, err := h.service.UpdateAssistant(r.Context(), userID, id, &req)
+	if err != nil {
+		h.logger.Error("修改助手失败", err)
+		if writeAssistantError(w, err) {
+			return
+		}
+		util.InternalServerError(w, "修改助手失败")
+		return
+	}
+
+	util.SuccessResponse(w, assistant, http.StatusOK)
+}
+
+// DeleteAssistant 处理删除助手请求
+func (h *AssistantHandler) DeleteAssistant(w http.ResponseWriter, r *http.Request) {
+	userID, id, ok := assistantParams(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteAssistant(r.Context(), userID, id); err != nil {
+		h.logger.Error("删除助手失败", err)
+		if writeAssistantError(w, err) {
+			return
+		}
+		util.InternalServerError(w, "删除助手失败")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PublishAssistant 处理发布助手到工作区或租户请求
+func (h *AssistantHandler) PublishAssistant(w http.ResponseWriter, r *http.Request) {
+	userID, id, ok := assistantParams(w, r)
+	if !ok {
+		return
+	}
+
+	var req chat.PublishAssistantRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		util.BadRequestError(w, "无效的请求体", nil)
+		return
+	}
+
+	assistant, err := h.service.PublishAssistant(r.Context(), userID, id, &req)
+	if err != nil {
+		h.logger.Error("发布助手失败", err)
+		if writeAssistantError(w, err) {
+			return
+		}
+		util.InternalServerError(w, "发布助手失败")
+		return
+	}
+
+	util.SuccessResponse(w, assistant, http.StatusOK)
+}
+
+// UnpublishAssistant 处理取消发布助手请求，助手恢复为创建者私有
+func (h *AssistantHandler) UnpublishAssistant(w http.ResponseWriter, r *http.Request) {
+	userID, id, ok := assistantParams(w, r)
+	if !ok {
+		return
+	}
+
+	assistant, err := h.service.UnpublishAssistant(r.Context(), userID, id)
+	if err != nil {
+		h.logger.Error("取消发布助手失败", err)
+		if writeAssistantError(w, err) {
+			return
+		}
+		util.InternalServerError(w, "取消发布助手失败")
+		return
+	}
+
+	util.SuccessResponse(w, assistant, http.StatusOK)
+}
+
+// assistantParams 获取当前用户和路径中的助手 ID
+func assistantParams(w http.ResponseWriter, r *http.Request) (string, string, bool) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		util.UnauthorizedError(w, "未认证")
+		return "", "", false
+	}
+
+	id, ok := pathID(w, r, "助手ID不能为空")
+	if !ok {
+		return "", "", false
+	}
+
+	return userID, id, true
+}
+
+// writeAssistantError 将助手的业务错误映射为对应的 HTTP 响应，其余错误按聊天错误处理
+func writeAssistantError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, chatService.ErrAssistantNotFound):
+		util.NotFoundError(w, "助手不存在")
+	case errors.Is(err, chatService.ErrAssistantForbidden):
+		util.ForbiddenError(w, "只有助手创建者可以修改助手")
+	case errors.Is(err, chatService.ErrInvalidAssistant):
+		util.BadRequestError(w, err.Error(), nil)
+	default:
+		return writeChatError(w, err)
+	}
+	return true
+}
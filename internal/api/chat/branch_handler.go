@@ -0,0 +1,192 @@
+package chat
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/chat"
+	"github.com/zhuiye8/Lyss-chat-server/internal/middleware"
+	chatService "github.com/zhuiye8/Lyss-chat-server/internal/service/chat"
+	"github.com/zhuiye8/Lyss-chat-server/internal/util"
+)
+
+// GetMessageTree 处理获取消息树请求
+func (h *MessageHandler) GetMessageTree(w http.ResponseWriter, r *http.Request) {
+	// 获取路径参数
+	vars := mux.Vars(r)
+	canvasID := vars["id"]
+	if canvasID == "" {
+		util.BadRequestError(w, "画布ID不能为空", nil)
+		return
+	}
+
+	// 调用服务
+	tree, err := h.service.GetMessageTree(r.Context(), canvasID)
+	if err != nil {
+		h.logger.Error("获取消息树失败", err)
+		util.NotFoundError(w, "画布不存在")
+		return
+	}
+
+	util.SuccessResponse(w, tree, http.StatusOK)
+}
+
+// RegenerateMessage 处理重新生成助手消息请求
+func (h *MessageHandler) RegenerateMessage(w http.ResponseWriter, r *http.Request) {
+	canvasID, messageID, userID, ok := h.branchParams(w, r)
+	if !ok {
+		return
+	}
+
+	// 调用服务
+	message, err := h.service.RegenerateMessage(r.Context(), userID, canvasID, messageID)
+	if err != nil {
+		h.logger.Error("重新生成消息失败", err)
+		if writeBranchError(w, err) {
+			return
+		}
+		util.InternalServerError(w, "重新生成消息失败")
+		return
+	}
+
+	util.SuccessResponse(w, message, http.StatusCreated)
+}
+
+// StreamRegenerateMessage 处理流式重新生成助手消息请求
+func (h *MessageHandler) StreamRegenerateMessage(w http.ResponseWriter, r *http.Request) {
+	canvasID, messageID, userID, ok := h.branchParams(w, r)
+	if !ok {
+		return
+	}
+
+	// 调用服务
+	events, err := h.service.StreamRegenerateMessage(r.Context(), userID, canvasID, messageID)
+	if err != nil {
+		h.logger.Error("流式重新生成消息失败", err)
+		if writeBranchError(w, err) {
+			return
+		}
+		util.InternalServerError(w, "重新生成消息失败")
+		return
+	}
+
+	setStreamHeaders(w)
+	h.writeStream(w, events)
+}
+
+// EditMessage 处理编辑用户消息请求
+func (h *MessageHandler) EditMessage(w http.ResponseWriter, r *http.Request) {
+	canvasID, messageID, userID, ok := h.branchParams(w, r)
+	if !ok {
+		return
+	}
+
+	req, ok := decodeEditRequest(w, r)
+	if !ok {
+		return
+	}
+
+	// 调用服务
+	message, err := h.service.EditMessage(r.Context(), userID, canvasID, messageID, req)
+	if err != nil {
+		h.logger.Error("编辑消息失败", err)
+		if writeBranchError(w, err) {
+			return
+		}
+		util.InternalServerError(w, "编辑消息失败")
+		return
+	}
+
+	util.SuccessResponse(w, message, http.StatusCreated)
+}
+
+// StreamEditMessage 处理流式编辑用户消息请求
+func (h *MessageHandler) StreamEditMessage(w http.ResponseWriter, r *http.Request) {
+	canvasID, messageID, userID, ok := h.branchParams(w, r)
+	if !ok {
+		return
+	}
+
+	req, ok := decodeEditRequest(w, r)
+	if !ok {
+		return
+	}
+
+	// 调用服务
+	events, err := h.service.StreamEditMessage(r.Context(), userID, canvasID, messageID, req)
+	if err != nil {
+		h.logger.Error("流式编辑消息失败", err)
+		if writeBranchError(w, err) {
+			return
+		}
+		util.InternalServerError(w, "编辑消息失败")
+		return
+	}
+
+	setStreamHeaders(w)
+	h.writeStream(w, events)
+}
+
+// branchParams 解析分支操作的路径参数和当前用户
+func (h *MessageHandler) branchParams(w http.ResponseWriter, r *http.Request) (canvasID, messageID, userID string, ok bool) {
+	// 获取路径参数
+	vars := mux.Vars(r)
+	canvasID = vars["id"]
+	messageID = vars["message_id"]
+	if canvasID == "" || messageID == "" {
+		util.BadRequestError(w, "画布ID和消息ID不能为空", nil)
+		return "", "", "", false
+	}
+
+	// 获取用户ID
+	userID, ok = middleware.GetUserID(r.Context())
+	if !ok {
+		util.UnauthorizedError(w, "未认证")
+		return "", "", "", false
+	}
+
+	return canvasID, messageID, userID, true
+}
+
+// decodeEditRequest 解析并校验编辑消息请求体
+func decodeEditRequest(w http.ResponseWriter, r *http.Request) (*chat.EditMessageRequest, bool) {
+	var req chat.EditMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		util.BadRequestError(w, "无效的请求体", nil)
+		return nil, false
+	}
+
+	if req.Content == "" {
+		util.BadRequestError(w, "消息内容不能为空", nil)
+		return nil, false
+	}
+
+	return &req, true
+}
+
+// setStreamHeaders 设置 SSE 响应头，并取消写超时
+func setStreamHeaders(w http.ResponseWriter) {
+	clearWriteDeadline(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("Transfer-Encoding", "chunked")
+}
+
+// clearWriteDeadline 取消服务器设置的写超时，使持续写出的响应不会在 WriteTimeout 后被中断
+func clearWriteDeadline(w http.ResponseWriter) {
+	// 响应不支持设置超时时保留原有超时
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+}
+
+// writeBranchError 将重新生成和编辑消息的业务错误映射为对应的 HTTP 响应，其余错误按发送消息处理
+func writeBranchError(w http.ResponseWriter, err error) bool {
+	if errors.Is(err, chatService.ErrInvalidBranchTarget) {
+		util.BadRequestError(w, "只能重新生成跟在用户消息之后的助手消息，只能编辑用户消息", nil)
+		return true
+	}
+	return writeChatError(w, err)
+}
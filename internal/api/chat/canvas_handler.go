@@ -2,29 +2,27 @@ package chat
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gorilla/mux"
 	"github.com/zhuiye8/Lyss-chat-server/internal/domain/chat"
 	"github.com/zhuiye8/Lyss-chat-server/internal/middleware"
-	"github.com/zhuiye8/Lyss-chat-server/internal/service/chat"
+	chatService "github.com/zhuiye8/Lyss-chat-server/internal/service/chat"
 	"github.com/zhuiye8/Lyss-chat-server/internal/util"
-	"github.com/zhuiye8/Lyss-chat-server/pkg/config"
-	"github.com/zhuiye8/Lyss-chat-server/pkg/db"
 	"github.com/zhuiye8/Lyss-chat-server/pkg/logger"
 )
 
 // CanvasHandler 表示画布处理�?
 type CanvasHandler struct {
-	service *chat.Service
+	service *chatService.Service
 	logger  *logger.Logger
 }
 
 // NewCanvasHandler 创建一个新的画布处理器
-func NewCanvasHandler(db *db.Postgres, cfg *config.Config, logger *logger.Logger) *CanvasHandler {
-	// 注意：这里需要传�?aiGraphs，但我们暂时传入 nil，后续会修复
-	service := chat.NewService(db, nil, cfg, logger)
+func NewCanvasHandler(service *chatService.Service, logger *logger.Logger) *CanvasHandler {
 	return &CanvasHandler{
 		service: service,
 		logger:  logger,
@@ -32,6 +30,9 @@ func NewCanvasHandler(db *db.Postgres, cfg *config.Config, logger *logger.Logger
 }
 
 // ListCanvases 处理获取画布列表请求
+// 查询参数：workspace_id 必填；type、status、folder_id（root 表示顶层）、tag_id（可重复或逗号分隔，须全部匹配）、
+// pinned、favorite 为可选过滤条件；sort 为 created_at（默认）、updated_at、last_message_at、title、pinned 或 favorite，
+// order 为 desc（默认）或 asc
 func (h *CanvasHandler) ListCanvases(w http.ResponseWriter, r *http.Request) {
 	// 获取查询参数
 	workspaceID := r.URL.Query().Get("workspace_id")
@@ -40,12 +41,37 @@ func (h *CanvasHandler) ListCanvases(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	canvasType := r.URL.Query().Get("type")
-	var canvasTypePtr *string
-	if canvasType != "" {
-		canvasTypePtr = &canvasType
+	// 获取过滤和排序参数
+	query := r.URL.Query()
+	req := chat.CanvasListRequest{
+		WorkspaceID: workspaceID,
+		Type:        optionalParam(query.Get("type")),
+		Status:      optionalParam(query.Get("status")),
+		FolderID:    optionalParam(query.Get("folder_id")),
+		Sort:        query.Get("sort"),
+		Order:       query.Get("order"),
+	}
+	for _, tagID := range query["tag_id"] {
+		req.TagIDs = append(req.TagIDs, strings.Split(tagID, ",")...)
+	}
+	var err error
+	if req.Pinned, err = optionalBoolParam(query.Get("pinned")); err != nil {
+		util.BadRequestError(w, "pinned 只能为 true 或 false", nil)
+		return
+	}
+	if req.Favorite, err = optionalBoolParam(query.Get("favorite")); err != nil {
+		util.BadRequestError(w, "favorite 只能为 true 或 false", nil)
+		return
 	}
 
+	// 获取用户ID，用于收藏标记
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		util.UnauthorizedError(w, "未认证")
+		return
+	}
+	req.UserID = userID
+
 	// 获取分页参数
 	page := 1
 	pageSize := 20
@@ -61,9 +87,12 @@ func (h *CanvasHandler) ListCanvases(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 调用服务
-	canvases, total, err := h.service.ListCanvases(workspaceID, canvasTypePtr, page, pageSize)
+	canvases, total, err := h.service.ListCanvases(r.Context(), &req, page, pageSize)
 	if err != nil {
 		h.logger.Error("获取画布列表失败", err)
+		if writeCanvasError(w, err) {
+			return
+		}
 		util.InternalServerError(w, "获取画布列表失败")
 		return
 	}
@@ -89,8 +118,15 @@ func (h *CanvasHandler) GetCanvas(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// 获取用户ID
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		util.UnauthorizedError(w, "未认证")
+		return
+	}
+
 	// 调用服务
-	canvas, err := h.service.GetCanvas(id)
+	canvas, err := h.service.GetCanvas(r.Context(), userID, id)
 	if err != nil {
 		h.logger.Error("获取画布详情失败", err)
 		util.NotFoundError(w, "画布不存�?)
@@ -123,9 +159,12 @@ func (h *CanvasHandler) CreateCanvas(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 调用服务
-	canvas, err := h.service.CreateCanvas(userID, &req)
+	canvas, err := h.service.CreateCanvas(r.Context(), userID, &req)
 	if err != nil {
 		h.logger.Error("创建画布失败", err)
+		if writeCanvasError(w, err) {
+			return
+		}
 		util.InternalServerError(w, "创建画布失败")
 		return
 	}
@@ -150,10 +189,19 @@ func (h *CanvasHandler) UpdateCanvas(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		util.UnauthorizedError(w, "未认证")
+		return
+	}
+
 	// 调用服务
-	canvas, err := h.service.UpdateCanvas(id, &req)
+	canvas, err := h.service.UpdateCanvas(r.Context(), userID, id, &req)
 	if err != nil {
 		h.logger.Error("更新画布失败", err)
+		if writeCanvasError(w, err) {
+			return
+		}
 		util.NotFoundError(w, "画布不存�?)
 		return
 	}
@@ -171,8 +219,15 @@ func (h *CanvasHandler) DeleteCanvas(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 调用服务
-	err := h.service.DeleteCanvas(id)
+	// 获取用户ID
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		util.UnauthorizedError(w, "未认证")
+		return
+	}
+
+	// 调用服务，画布移入回收站
+	err := h.service.DeleteCanvas(r.Context(), userID, id)
 	if err != nil {
 		h.logger.Error("删除画布失败", err)
 		util.NotFoundError(w, "画布不存�?)
@@ -182,3 +237,68 @@ func (h *CanvasHandler) DeleteCanvas(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+
+// SetActiveLeaf 处理切换画布当前分支请求
+func (h *CanvasHandler) SetActiveLeaf(w http.ResponseWriter, r *http.Request) {
+	// 获取路径参数
+	vars := mux.Vars(r)
+	id := vars["id"]
+	if id == "" {
+		util.BadRequestError(w, "画布ID不能为空", nil)
+		return
+	}
+
+	// 解析请求体
+	var req chat.SetActiveLeafRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		util.BadRequestError(w, "无效的请求体", nil)
+		return
+	}
+	if req.MessageID == "" {
+		util.BadRequestError(w, "消息ID不能为空", nil)
+		return
+	}
+
+	// 调用服务
+	canvas, err := h.service.SetActiveLeaf(r.Context(), id, req.MessageID)
+	if err != nil {
+		h.logger.Error("切换画布分支失败", err)
+		if writeChatError(w, err) {
+			return
+		}
+		util.NotFoundError(w, "画布不存在")
+		return
+	}
+
+	util.SuccessResponse(w, canvas, http.StatusOK)
+}
+
+// writeCanvasError 将画布操作的业务错误映射为对应的 HTTP 响应
+// 创建和修改画布时会校验所在文件夹和使用的助手，相应错误按文件夹和助手错误处理
+func writeCanvasError(w http.ResponseWriter, err error) bool {
+	if errors.Is(err, chatService.ErrInvalidCanvasList) {
+		util.BadRequestError(w, "排序字段只能为 created_at、updated_at、last_message_at、title、pinned 或 favorite，排序方向只能为 asc 或 desc，状态只能为 active 或 archived", nil)
+		return true
+	}
+	return writeOrganizationError(w, err) || writeAssistantError(w, err)
+}
+
+// optionalParam 将空的查询参数转换为 nil
+func optionalParam(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+// optionalBoolParam 解析可选的布尔查询参数，为空时返回 nil
+func optionalBoolParam(value string) (*bool, error) {
+	if value == "" {
+		return nil, nil
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, err
+	}
+	return &parsed, nil
+}
@@ -0,0 +1,90 @@
+package chat
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/chat"
+	"github.com/zhuiye8/Lyss-chat-server/internal/middleware"
+	chatService "github.com/zhuiye8/Lyss-chat-server/internal/service/chat"
+	"github.com/zhuiye8/Lyss-chat-server/internal/util"
+)
+
+// StreamCompare 处理对比模式发送消息请求，以 SSE 返回所有模型的事件
+// 每条事件的数据为 CompareEvent，断线后可按其中的生成 ID 和事件序号分别重新订阅各模型的生成
+func (h *MessageHandler) StreamCompare(w http.ResponseWriter, r *http.Request) {
+	canvasID, ok := pathID(w, r, "画布ID不能为空")
+	if !ok {
+		return
+	}
+
+	// 解析请求体
+	var req chat.CompareMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		util.BadRequestError(w, "无效的请求体", nil)
+		return
+	}
+
+	// 验证请求
+	if req.Content == "" {
+		util.BadRequestError(w, "消息内容不能为空", nil)
+		return
+	}
+
+	// 获取用户ID
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		util.UnauthorizedError(w, "未认证")
+		return
+	}
+
+	// 调用服务
+	events, err := h.service.StreamCompare(r.Context(), userID, canvasID, &req)
+	if err != nil {
+		h.logger.Error("对比模式发送消息失败", err)
+		if writeCompareError(w, err) {
+			return
+		}
+		util.InternalServerError(w, "对比模式发送消息失败")
+		return
+	}
+
+	setStreamHeaders(w)
+	h.writeStream(w, events)
+}
+
+// SelectCompareReply 处理选定对比回复继续对话请求，返回切换分支后的画布
+func (h *MessageHandler) SelectCompareReply(w http.ResponseWriter, r *http.Request) {
+	canvasID, messageID, userID, ok := h.branchParams(w, r)
+	if !ok {
+		return
+	}
+
+	// 调用服务
+	canvas, err := h.service.SelectCompareReply(r.Context(), userID, canvasID, messageID)
+	if err != nil {
+		h.logger.Error("选定对比回复失败", err)
+		if writeCompareError(w, err) {
+			return
+		}
+		util.NotFoundError(w, "消息不存在")
+		return
+	}
+
+	util.SuccessResponse(w, canvas, http.StatusOK)
+}
+
+// writeCompareError 将对比模式的业务错误映射为对应的 HTTP 响应
+// 各模型的生成与普通发送共用同一流程，其余错误与发送消息一致
+func writeCompareError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, chatService.ErrInvalidCompare):
+		util.BadRequestError(w, "对比模式需要 2 到 4 个不重复的模型", nil)
+	case errors.Is(err, chatService.ErrNotCompareReply):
+		util.BadRequestError(w, "该消息不是对比模式的回复", nil)
+	default:
+		return writeChatError(w, err)
+	}
+	return true
+}
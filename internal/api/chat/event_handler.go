@@ -0,0 +1,102 @@
+package chat
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/chat"
+	"github.com/zhuiye8/Lyss-chat-server/internal/middleware"
+	"github.com/zhuiye8/Lyss-chat-server/internal/util"
+)
+
+// eventKeepAlive 是 SSE 心跳注释的发送间隔，避免代理断开空闲连接
+const eventKeepAlive = 15 * time.Second
+
+// StreamEvents 处理订阅画布协作事件请求，以 SSE 推送画布上的消息、生成增量、在线和输入状态
+func (h *CanvasHandler) StreamEvents(w http.ResponseWriter, r *http.Request) {
+	// 获取路径参数
+	vars := mux.Vars(r)
+	canvasID := vars["id"]
+	if canvasID == "" {
+		util.BadRequestError(w, "画布ID不能为空", nil)
+		return
+	}
+
+	// 获取用户ID
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		util.UnauthorizedError(w, "未认证")
+		return
+	}
+
+	// 调用服务
+	events, err := h.service.SubscribeCanvas(r.Context(), canvasID, userID)
+	if err != nil {
+		h.logger.Error("订阅画布事件失败", err)
+		util.NotFoundError(w, "画布不存在")
+		return
+	}
+
+	setStreamHeaders(w)
+	w.(http.Flusher).Flush()
+
+	keepAlive := time.NewTicker(eventKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			eventJSON, err := json.Marshal(event)
+			if err != nil {
+				h.logger.Error("序列化画布事件失败", err)
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, eventJSON)
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case <-r.Context().Done():
+			return
+		}
+		w.(http.Flusher).Flush()
+	}
+}
+
+// SetTyping 处理更新输入状态请求
+func (h *CanvasHandler) SetTyping(w http.ResponseWriter, r *http.Request) {
+	// 获取路径参数
+	vars := mux.Vars(r)
+	canvasID := vars["id"]
+	if canvasID == "" {
+		util.BadRequestError(w, "画布ID不能为空", nil)
+		return
+	}
+
+	// 解析请求体
+	var req chat.TypingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		util.BadRequestError(w, "无效的请求体", nil)
+		return
+	}
+
+	// 获取用户ID
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		util.UnauthorizedError(w, "未认证")
+		return
+	}
+
+	// 调用服务
+	if err := h.service.SetTyping(r.Context(), canvasID, userID, req.Typing); err != nil {
+		h.logger.Error("更新输入状态失败", err)
+		util.NotFoundError(w, "画布不存在")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
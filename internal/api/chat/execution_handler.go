@@ -0,0 +1,55 @@
+package chat
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/chat"
+	chatService "github.com/zhuiye8/Lyss-chat-server/internal/service/chat"
+	"github.com/zhuiye8/Lyss-chat-server/internal/util"
+)
+
+// ExecuteCode 处理执行助手消息中代码块的请求，以 SSE 返回执行输出
+func (h *MessageHandler) ExecuteCode(w http.ResponseWriter, r *http.Request) {
+	canvasID, messageID, userID, ok := h.branchParams(w, r)
+	if !ok {
+		return
+	}
+
+	// 解析请求体
+	var req chat.ExecuteCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		util.BadRequestError(w, "无效的请求体", nil)
+		return
+	}
+
+	// 调用服务
+	events, err := h.service.ExecuteCode(r.Context(), userID, canvasID, messageID, &req)
+	if err != nil {
+		h.logger.Error("执行代码失败", err)
+		if writeExecutionError(w, err) {
+			return
+		}
+		util.NotFoundError(w, "消息不存在")
+		return
+	}
+
+	setStreamHeaders(w)
+	h.writeStream(w, events)
+}
+
+// writeExecutionError 将代码执行的业务错误映射为对应的 HTTP 响应，其余错误按聊天错误处理
+func writeExecutionError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, chatService.ErrExecutionDisabled):
+		util.ForbiddenError(w, "代码执行未启用")
+	case errors.Is(err, chatService.ErrExecutionUnsupported):
+		util.BadRequestError(w, err.Error(), nil)
+	case errors.Is(err, chatService.ErrCodeBlockNotFound):
+		util.NotFoundError(w, "代码块不存在")
+	default:
+		return writeChatError(w, err)
+	}
+	return true
+}
@@ -0,0 +1,143 @@
+package chat
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/gorilla/mux"
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/chat"
+	"github.com/zhuiye8/Lyss-chat-server/internal/middleware"
+	chatService "github.com/zhuiye8/Lyss-chat-server/internal/service/chat"
+	"github.com/zhuiye8/Lyss-chat-server/internal/util"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/db"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/logger"
+)
+
+// exportContentTypes 是各导出格式的响应类型
+var exportContentTypes = map[string]string{
+	chat.ExportFormatMarkdown: "text/markdown; charset=utf-8",
+	chat.ExportFormatJSON:     "application/json; charset=utf-8",
+	chat.ExportFormatHTML:     "text/html; charset=utf-8",
+}
+
+// ExportHandler 表示导出处理器
+type ExportHandler struct {
+	service *chatService.ExportService
+	logger  *logger.Logger
+}
+
+// NewExportHandler 创建一个新的导出处理器
+func NewExportHandler(db *db.Postgres, redis *db.Redis, minio *db.MinIO, logger *logger.Logger) *ExportHandler {
+	return &ExportHandler{
+		service: chatService.NewExportService(db, redis, minio, logger),
+		logger:  logger,
+	}
+}
+
+// ExportCanvas 处理导出画布请求
+// 查询参数：format 为 md（默认）、json 或 html，attachments 为 link（默认）或 inline
+func (h *ExportHandler) ExportCanvas(w http.ResponseWriter, r *http.Request) {
+	// 获取路径参数
+	vars := mux.Vars(r)
+	id := vars["id"]
+	if id == "" {
+		util.BadRequestError(w, "画布ID不能为空", nil)
+		return
+	}
+
+	opts := chat.ExportOptions{
+		Format:      r.URL.Query().Get("format"),
+		Attachments: r.URL.Query().Get("attachments"),
+	}
+	if err := chatService.NormalizeExportOptions(&opts); err != nil {
+		writeExportError(w, err)
+		return
+	}
+
+	canvas, err := h.service.GetCanvas(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			util.NotFoundError(w, "画布不存在")
+			return
+		}
+		h.logger.Error("获取画布详情失败", err)
+		util.InternalServerError(w, "获取画布详情失败")
+		return
+	}
+
+	// 内容边读取边写出，开始写出后出错只能中断响应；大画布导出耗时较长，不受写超时限制
+	clearWriteDeadline(w)
+	fileName := chatService.ExportFileName(canvas, opts.Format)
+	w.Header().Set("Content-Type", exportContentTypes[opts.Format])
+	w.Header().Set("Content-Disposition", "attachment; filename*=UTF-8''"+url.PathEscape(fileName))
+	w.WriteHeader(http.StatusOK)
+
+	if err := h.service.Export(r.Context(), canvas, opts, flushWriter{w}); err != nil {
+		h.logger.Error("导出画布失败", err)
+	}
+}
+
+// ExportWorkspace 处理导出工作区请求
+// 导出在后台执行，响应中返回任务，完成后通过任务结果获取下载链接
+func (h *ExportHandler) ExportWorkspace(w http.ResponseWriter, r *http.Request) {
+	// 获取路径参数
+	vars := mux.Vars(r)
+	workspaceID := vars["id"]
+	if workspaceID == "" {
+		util.BadRequestError(w, "工作区ID不能为空", nil)
+		return
+	}
+
+	// 解析请求体，请求体可以为空
+	var req chat.WorkspaceExportRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			util.BadRequestError(w, "无效的请求体", nil)
+			return
+		}
+	}
+
+	// 获取用户ID
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		util.UnauthorizedError(w, "未认证")
+		return
+	}
+
+	// 调用服务
+	job, err := h.service.StartWorkspaceExport(r.Context(), userID, workspaceID, &req)
+	if err != nil {
+		if writeExportError(w, err) {
+			return
+		}
+		h.logger.Error("创建工作区导出任务失败", err)
+		util.InternalServerError(w, "创建工作区导出任务失败")
+		return
+	}
+
+	util.SuccessResponse(w, job, http.StatusAccepted)
+}
+
+// writeExportError 将导出的业务错误映射为对应的 HTTP 响应
+func writeExportError(w http.ResponseWriter, err error) bool {
+	if errors.Is(err, chatService.ErrInvalidExportOptions) {
+		util.BadRequestError(w, "导出格式只能为 md、json 或 html，附件导出方式只能为 link 或 inline", nil)
+		return true
+	}
+	return false
+}
+
+// flushWriter 在响应支持时将每批导出内容立即发送给客户端
+type flushWriter struct {
+	http.ResponseWriter
+}
+
+// Flush 刷新响应缓冲区
+func (w flushWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
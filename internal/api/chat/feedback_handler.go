@@ -0,0 +1,161 @@
+package chat
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/chat"
+	chatService "github.com/zhuiye8/Lyss-chat-server/internal/service/chat"
+	"github.com/zhuiye8/Lyss-chat-server/internal/util"
+)
+
+// SubmitFeedback 处理提交或修改消息反馈请求
+func (h *MessageHandler) SubmitFeedback(w http.ResponseWriter, r *http.Request) {
+	canvasID, messageID, userID, ok := h.branchParams(w, r)
+	if !ok {
+		return
+	}
+
+	// 解析请求体
+	var req chat.FeedbackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		util.BadRequestError(w, "无效的请求体", nil)
+		return
+	}
+
+	// 调用服务
+	feedback, err := h.service.SubmitFeedback(r.Context(), userID, canvasID, messageID, &req)
+	if err != nil {
+		if writeFeedbackError(w, err) {
+			return
+		}
+		h.logger.Error("提交反馈失败", err)
+		util.InternalServerError(w, "提交反馈失败")
+		return
+	}
+
+	util.SuccessResponse(w, feedback, http.StatusOK)
+}
+
+// GetFeedback 处理获取当前用户对消息的反馈请求
+func (h *MessageHandler) GetFeedback(w http.ResponseWriter, r *http.Request) {
+	canvasID, messageID, userID, ok := h.branchParams(w, r)
+	if !ok {
+		return
+	}
+
+	// 调用服务
+	feedback, err := h.service.GetFeedback(r.Context(), userID, canvasID, messageID)
+	if err != nil {
+		if writeFeedbackError(w, err) {
+			return
+		}
+		h.logger.Error("获取反馈失败", err)
+		util.NotFoundError(w, "消息不存在")
+		return
+	}
+
+	util.SuccessResponse(w, feedback, http.StatusOK)
+}
+
+// DeleteFeedback 处理撤销消息反馈请求
+func (h *MessageHandler) DeleteFeedback(w http.ResponseWriter, r *http.Request) {
+	canvasID, messageID, userID, ok := h.branchParams(w, r)
+	if !ok {
+		return
+	}
+
+	// 调用服务
+	if err := h.service.DeleteFeedback(r.Context(), userID, canvasID, messageID); err != nil {
+		if writeFeedbackError(w, err) {
+			return
+		}
+		h.logger.Error("撤销反馈失败", err)
+		util.InternalServerError(w, "撤销反馈失败")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetFeedbackStats 处理反馈统计请求
+// 查询参数：group_by 为 model（默认）、provider、canvas、day、week 或 month，
+// workspace_id、canvas_id、model_id 为可选过滤条件，from、to 为时间范围（RFC 3339 或 YYYY-MM-DD，左闭右开），
+// limit 为最大分组数
+func (h *MessageHandler) GetFeedbackStats(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	req := &chat.FeedbackStatsRequest{
+		GroupBy: query.Get("group_by"),
+	}
+	if workspaceID := query.Get("workspace_id"); workspaceID != "" {
+		req.WorkspaceID = &workspaceID
+	}
+	if canvasID := query.Get("canvas_id"); canvasID != "" {
+		req.CanvasID = &canvasID
+	}
+	if modelID := query.Get("model_id"); modelID != "" {
+		req.ModelID = &modelID
+	}
+	for param, target := range map[string]**time.Time{"from": &req.From, "to": &req.To} {
+		value := query.Get(param)
+		if value == "" {
+			continue
+		}
+		t, err := parseTimeParam(value)
+		if err != nil {
+			util.BadRequestError(w, "时间格式无效，应为 RFC 3339 时间或 YYYY-MM-DD 日期", nil)
+			return
+		}
+		*target = &t
+	}
+	if limitStr := query.Get("limit"); limitStr != "" {
+		if limit, err := strconv.Atoi(limitStr); err == nil && limit > 0 {
+			req.Limit = limit
+		}
+	}
+
+	// 调用服务
+	stats, err := h.service.FeedbackStats(r.Context(), req)
+	if err != nil {
+		if writeFeedbackError(w, err) {
+			return
+		}
+		h.logger.Error("获取反馈统计失败", err)
+		util.InternalServerError(w, "获取反馈统计失败")
+		return
+	}
+
+	// 构建响应
+	response := map[string]interface{}{
+		"group_by": req.GroupBy,
+		"items":    stats,
+	}
+
+	util.SuccessResponse(w, response, http.StatusOK)
+}
+
+// writeFeedbackError 将消息反馈的业务错误映射为对应的 HTTP 响应，其余错误按聊天错误处理
+func writeFeedbackError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, chatService.ErrInvalidFeedback):
+		util.BadRequestError(w, "评价只能为 up 或 down，原因代码须为预定义值，评论不能超过 2000 个字符，统计分组和时间范围须有效", nil)
+	case errors.Is(err, chatService.ErrFeedbackNotFound):
+		util.NotFoundError(w, "反馈不存在")
+	case errors.Is(err, chatService.ErrInvalidBranchTarget):
+		util.BadRequestError(w, "只能评价助手消息", nil)
+	default:
+		return writeChatError(w, err)
+	}
+	return true
+}
+
+// parseTimeParam 解析 RFC 3339 时间或 YYYY-MM-DD 日期
+func parseTimeParam(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02", value, time.Local)
+}
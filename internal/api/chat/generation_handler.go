@@ -0,0 +1,74 @@
+package chat
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	chatService "github.com/zhuiye8/Lyss-chat-server/internal/service/chat"
+	"github.com/zhuiye8/Lyss-chat-server/internal/util"
+)
+
+// StopGeneration 处理停止生成请求，生成可能运行在任意实例上
+func (h *MessageHandler) StopGeneration(w http.ResponseWriter, r *http.Request) {
+	// 获取路径参数
+	vars := mux.Vars(r)
+	canvasID := vars["id"]
+	generationID := vars["gen_id"]
+	if canvasID == "" || generationID == "" {
+		util.BadRequestError(w, "画布ID和生成ID不能为空", nil)
+		return
+	}
+
+	// 调用服务
+	if err := h.service.StopGeneration(r.Context(), canvasID, generationID); err != nil {
+		h.logger.Error("停止生成失败", err)
+		util.NotFoundError(w, "画布不存在")
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// AttachGeneration 处理重新订阅生成事件请求，从 Last-Event-ID 之后的事件开始重放
+func (h *MessageHandler) AttachGeneration(w http.ResponseWriter, r *http.Request) {
+	// 获取路径参数
+	vars := mux.Vars(r)
+	canvasID := vars["id"]
+	generationID := vars["gen_id"]
+	if canvasID == "" || generationID == "" {
+		util.BadRequestError(w, "画布ID和生成ID不能为空", nil)
+		return
+	}
+
+	// 浏览器 EventSource 重连时携带 Last-Event-ID 头，也允许通过查询参数指定
+	lastEventIDStr := r.Header.Get("Last-Event-ID")
+	if lastEventIDStr == "" {
+		lastEventIDStr = r.URL.Query().Get("last_event_id")
+	}
+	var lastEventID int64
+	if lastEventIDStr != "" {
+		var err error
+		lastEventID, err = strconv.ParseInt(lastEventIDStr, 10, 64)
+		if err != nil || lastEventID < 0 {
+			util.BadRequestError(w, "无效的事件ID", nil)
+			return
+		}
+	}
+
+	// 调用服务
+	events, err := h.service.AttachGeneration(r.Context(), canvasID, generationID, lastEventID)
+	if err != nil {
+		h.logger.Error("重新订阅生成失败", err)
+		if errors.Is(err, chatService.ErrGenerationNotFound) {
+			util.NotFoundError(w, "生成不存在或已过期")
+			return
+		}
+		util.NotFoundError(w, "画布不存在")
+		return
+	}
+
+	setStreamHeaders(w)
+	h.writeStream(w, events)
+}
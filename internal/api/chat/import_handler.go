@@ -0,0 +1,125 @@
+package chat
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/chat"
+	"github.com/zhuiye8/Lyss-chat-server/internal/middleware"
+	chatService "github.com/zhuiye8/Lyss-chat-server/internal/service/chat"
+	"github.com/zhuiye8/Lyss-chat-server/internal/util"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/db"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/logger"
+)
+
+// errImportTooLarge 表示导入文件超过大小限制
+var errImportTooLarge = errors.New("导入文件过大")
+
+// ImportHandler 表示导入处理器
+type ImportHandler struct {
+	service *chatService.ImportService
+	logger  *logger.Logger
+}
+
+// NewImportHandler 创建一个新的导入处理器
+func NewImportHandler(db *db.Postgres, redis *db.Redis, logger *logger.Logger) *ImportHandler {
+	return &ImportHandler{
+		service: chatService.NewImportService(db, redis, logger),
+		logger:  logger,
+	}
+}
+
+// ImportConversations 处理导入对话请求
+// 文件以 multipart 表单的 file 字段上传，或直接作为 JSON 请求体；
+// 查询参数 format 为 chatgpt 或 openai，为空时根据内容识别
+// 导入在后台执行，响应中返回任务，通过任务查询进度和结果
+func (h *ImportHandler) ImportConversations(w http.ResponseWriter, r *http.Request) {
+	// 获取路径参数
+	vars := mux.Vars(r)
+	workspaceID := vars["id"]
+	if workspaceID == "" {
+		util.BadRequestError(w, "工作区ID不能为空", nil)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format != "" && format != chat.ImportSourceChatGPT && format != chat.ImportSourceOpenAI {
+		util.BadRequestError(w, "导入格式只能为 chatgpt 或 openai", nil)
+		return
+	}
+
+	// 获取用户ID
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		util.UnauthorizedError(w, "未认证")
+		return
+	}
+
+	// 读取导入文件
+	data, err := readImportFile(w, r)
+	if err != nil {
+		if errors.Is(err, errImportTooLarge) {
+			util.BadRequestError(w, "导入文件不能超过 64MB", nil)
+			return
+		}
+		util.BadRequestError(w, "无法读取导入文件", nil)
+		return
+	}
+
+	// 调用服务
+	job, err := h.service.StartImport(r.Context(), userID, workspaceID, &chat.ImportRequest{
+		Format: format,
+		Data:   data,
+	})
+	if err != nil {
+		if errors.Is(err, chatService.ErrInvalidImport) {
+			util.BadRequestError(w, "无法识别的导入文件，支持 ChatGPT 导出的 conversations.json 和 OpenAI 风格的消息 JSON", nil)
+			return
+		}
+		h.logger.Error("创建导入任务失败", err)
+		util.InternalServerError(w, "创建导入任务失败")
+		return
+	}
+
+	util.SuccessResponse(w, job, http.StatusAccepted)
+}
+
+// readImportFile 读取 multipart 表单中的 file 字段或整个请求体
+func readImportFile(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	// 为 multipart 表单的其他字段和边界预留空间
+	r.Body = http.MaxBytesReader(w, r.Body, chatService.MaxImportBytes+1<<20)
+
+	var file io.Reader = r.Body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		reader, err := r.MultipartReader()
+		if err != nil {
+			return nil, err
+		}
+		for {
+			part, err := reader.NextPart()
+			if err != nil {
+				return nil, err
+			}
+			if part.FormName() == "file" {
+				file = part
+				break
+			}
+		}
+	}
+
+	data, err := io.ReadAll(io.LimitReader(file, chatService.MaxImportBytes+1))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return nil, errImportTooLarge
+		}
+		return nil, err
+	}
+	if len(data) > chatService.MaxImportBytes {
+		return nil, errImportTooLarge
+	}
+	return data, nil
+}
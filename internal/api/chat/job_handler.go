@@ -0,0 +1,59 @@
+package chat
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/zhuiye8/Lyss-chat-server/internal/middleware"
+	chatService "github.com/zhuiye8/Lyss-chat-server/internal/service/chat"
+	"github.com/zhuiye8/Lyss-chat-server/internal/util"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/db"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/logger"
+)
+
+// JobHandler 表示后台任务处理器
+type JobHandler struct {
+	jobs   *chatService.JobTracker
+	logger *logger.Logger
+}
+
+// NewJobHandler 创建一个新的后台任务处理器
+func NewJobHandler(redis *db.Redis, logger *logger.Logger) *JobHandler {
+	return &JobHandler{
+		jobs:   chatService.NewJobTracker(redis, logger),
+		logger: logger,
+	}
+}
+
+// GetJob 处理获取后台任务状态请求
+func (h *JobHandler) GetJob(w http.ResponseWriter, r *http.Request) {
+	// 获取路径参数
+	vars := mux.Vars(r)
+	id := vars["id"]
+	if id == "" {
+		util.BadRequestError(w, "任务ID不能为空", nil)
+		return
+	}
+
+	// 获取用户ID
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		util.UnauthorizedError(w, "未认证")
+		return
+	}
+
+	// 调用服务
+	job, err := h.jobs.Get(r.Context(), userID, id)
+	if err != nil {
+		if errors.Is(err, chatService.ErrJobNotFound) {
+			util.NotFoundError(w, "任务不存在或已过期")
+			return
+		}
+		h.logger.Error("获取任务状态失败", err)
+		util.InternalServerError(w, "获取任务状态失败")
+		return
+	}
+
+	util.SuccessResponse(w, job, http.StatusOK)
+}
@@ -1,7 +1,9 @@
 package chat
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -11,8 +13,6 @@ import (
 	"github.com/zhuiye8/Lyss-chat-server/internal/middleware"
 	chatService "github.com/zhuiye8/Lyss-chat-server/internal/service/chat"
 	"github.com/zhuiye8/Lyss-chat-server/internal/util"
-	"github.com/zhuiye8/Lyss-chat-server/pkg/config"
-	"github.com/zhuiye8/Lyss-chat-server/pkg/db"
 	"github.com/zhuiye8/Lyss-chat-server/pkg/logger"
 )
 
@@ -23,9 +23,7 @@ type MessageHandler struct {
 }
 
 // NewMessageHandler 创建一个新的消息处理器
-func NewMessageHandler(db *db.Postgres, cfg *config.Config, logger *logger.Logger) *MessageHandler {
-	// 注意：这里需要传�?aiGraphs，但我们暂时传入 nil，后续会修复
-	service := chatService.NewService(db, nil, cfg, logger)
+func NewMessageHandler(service *chatService.Service, logger *logger.Logger) *MessageHandler {
 	return &MessageHandler{
 		service: service,
 		logger:  logger,
@@ -56,8 +54,26 @@ func (h *MessageHandler) ListMessages(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// 仅返回当前分支路径
+	if r.URL.Query().Get("path") == "active" {
+		nodes, total, err := h.service.GetActivePath(r.Context(), canvasID, page, pageSize)
+		if err != nil {
+			h.logger.Error("获取当前分支消息失败", err)
+			util.InternalServerError(w, "获取消息列表失败")
+			return
+		}
+
+		util.SuccessResponse(w, map[string]interface{}{
+			"items":     nodes,
+			"total":     total,
+			"page":      page,
+			"page_size": pageSize,
+		}, http.StatusOK)
+		return
+	}
+
 	// 调用服务
-	messages, total, err := h.service.GetMessages(canvasID, page, pageSize)
+	messages, total, err := h.service.GetMessages(r.Context(), canvasID, page, pageSize)
 	if err != nil {
 		h.logger.Error("获取消息列表失败", err)
 		util.InternalServerError(w, "获取消息列表失败")
@@ -106,9 +122,12 @@ func (h *MessageHandler) SendMessage(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 调用服务
-	message, err := h.service.SendMessage(userID, canvasID, &req)
+	message, err := h.service.SendMessage(r.Context(), userID, canvasID, &req)
 	if err != nil {
 		h.logger.Error("发送消息失�?, err)
+		if writeChatError(w, err) {
+			return
+		}
 		util.InternalServerError(w, "发送消息失�?)
 		return
 	}
@@ -146,56 +165,50 @@ func (h *MessageHandler) StreamMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 设置响应�?
-	w.Header().Set("Content-Type", "text/event-stream")
-	w.Header().Set("Cache-Control", "no-cache")
-	w.Header().Set("Connection", "keep-alive")
-	w.Header().Set("Transfer-Encoding", "chunked")
-
 	// 调用服务
-	messageChan, err := h.service.StreamMessage(userID, canvasID, &req)
+	events, err := h.service.StreamMessage(r.Context(), userID, canvasID, &req)
 	if err != nil {
 		h.logger.Error("流式发送消息失�?, err)
+		if writeChatError(w, err) {
+			return
+		}
 		util.InternalServerError(w, "流式发送消息失�?)
 		return
 	}
 
-	// 发送用户消息确�?
-	userMessage := map[string]interface{}{
-		"type":    "user",
-		"content": req.Content,
-	}
-	userMessageJSON, _ := json.Marshal(userMessage)
-	fmt.Fprintf(w, "data: %s\n\n", userMessageJSON)
-	w.(http.Flusher).Flush()
+	setStreamHeaders(w)
+	h.writeStream(w, events)
+}
 
-	// 流式发�?AI 响应
-	for message := range messageChan {
-		// 构建事件数据
-		event := map[string]interface{}{
-			"type":    "assistant",
-			"id":      message.ID,
-			"content": message.Content,
-		}
-		
-		// 序列化为 JSON
-		eventJSON, err := json.Marshal(event)
-		if err != nil {
-			h.logger.Error("序列化事件失�?, err)
-			continue
-		}
-		
-		// 发送事�?
-		fmt.Fprintf(w, "data: %s\n\n", eventJSON)
+// writeStream 以 SSE 形式写出生成事件，每条事件携带序号作为事件 ID
+func (h *MessageHandler) writeStream(w http.ResponseWriter, events <-chan chatService.GenerationEvent) {
+	for event := range events {
+		// 事件 ID 用于断线后通过 Last-Event-ID 续传
+		fmt.Fprintf(w, "id: %d\ndata: %s\n\n", event.ID, event.Data)
 		w.(http.Flusher).Flush()
 	}
-
-	// 发送结束事�?
-	endEvent := map[string]interface{}{
-		"type": "done",
-	}
-	endEventJSON, _ := json.Marshal(endEvent)
-	fmt.Fprintf(w, "data: %s\n\n", endEventJSON)
-	w.(http.Flusher).Flush()
 }
 
+// writeChatError 将发送消息和生成回复共用的业务错误映射为对应的 HTTP 响应
+// 各功能的错误由所在处理器先行映射，未匹配时再交由该函数处理
+func writeChatError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, chatService.ErrProviderNotAllowed):
+		util.ForbiddenError(w, "租户不允许使用该模型提供商")
+	case errors.Is(err, chatService.ErrNoModelConfigured):
+		util.BadRequestError(w, "未配置可用的模型", nil)
+	case errors.Is(err, chatService.ErrChatUnavailable):
+		util.ErrorResponse(w, "SERVICE_UNAVAILABLE", "聊天服务暂不可用", http.StatusServiceUnavailable, nil)
+	case errors.Is(err, chatService.ErrMessageNotInCanvas):
+		util.NotFoundError(w, "消息不存在")
+	case errors.Is(err, sql.ErrNoRows):
+		util.NotFoundError(w, "画布不存在")
+	case errors.Is(err, chatService.ErrInvalidSettings):
+		util.BadRequestError(w, err.Error(), nil)
+	case errors.Is(err, chatService.ErrCanvasArchived):
+		util.ForbiddenError(w, "画布已归档，只读")
+	default:
+		return false
+	}
+	return true
+}
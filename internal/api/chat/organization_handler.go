@@ -0,0 +1,356 @@
+package chat
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/chat"
+	"github.com/zhuiye8/Lyss-chat-server/internal/middleware"
+	chatService "github.com/zhuiye8/Lyss-chat-server/internal/service/chat"
+	"github.com/zhuiye8/Lyss-chat-server/internal/util"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/logger"
+)
+
+// OrganizationHandler 表示画布整理处理器，处理文件夹、标签、置顶和收藏
+type OrganizationHandler struct {
+	service *chatService.Service
+	logger  *logger.Logger
+}
+
+// NewOrganizationHandler 创建一个新的画布整理处理器
+func NewOrganizationHandler(service *chatService.Service, logger *logger.Logger) *OrganizationHandler {
+	return &OrganizationHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// ListFolders 处理获取工作区文件夹列表请求
+func (h *OrganizationHandler) ListFolders(w http.ResponseWriter, r *http.Request) {
+	workspaceID, ok := pathID(w, r, "工作区ID不能为空")
+	if !ok {
+		return
+	}
+
+	folders, err := h.service.ListFolders(r.Context(), workspaceID)
+	if err != nil {
+		h.logger.Error("获取文件夹列表失败", err)
+		util.InternalServerError(w, "获取文件夹列表失败")
+		return
+	}
+
+	util.SuccessResponse(w, folders, http.StatusOK)
+}
+
+// CreateFolder 处理创建文件夹请求
+func (h *OrganizationHandler) CreateFolder(w http.ResponseWriter, r *http.Request) {
+	workspaceID, ok := pathID(w, r, "工作区ID不能为空")
+	if !ok {
+		return
+	}
+
+	var req chat.CreateFolderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		util.BadRequestError(w, "无效的请求体", nil)
+		return
+	}
+
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		util.UnauthorizedError(w, "未认证")
+		return
+	}
+
+	folder, err := h.service.CreateFolder(r.Context(), userID, workspaceID, &req)
+	if err != nil {
+		h.logger.Error("创建文件夹失败", err)
+		if writeOrganizationError(w, err) {
+			return
+		}
+		util.InternalServerError(w, "创建文件夹失败")
+		return
+	}
+
+	util.SuccessResponse(w, folder, http.StatusCreated)
+}
+
+// UpdateFolder 处理重命名或移动文件夹请求
+func (h *OrganizationHandler) UpdateFolder(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "文件夹ID不能为空")
+	if !ok {
+		return
+	}
+
+	var req chat.UpdateFolderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		util.BadRequestError(w, "无效的请求体", nil)
+		return
+	}
+
+	folder, err := h.service.UpdateFolder(r.Context(), id, &req)
+	if err != nil {
+		h.logger.Error("修改文件夹失败", err)
+		if writeOrganizationError(w, err) {
+			return
+		}
+		util.InternalServerError(w, "修改文件夹失败")
+		return
+	}
+
+	util.SuccessResponse(w, folder, http.StatusOK)
+}
+
+// DeleteFolder 处理删除文件夹请求
+func (h *OrganizationHandler) DeleteFolder(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "文件夹ID不能为空")
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteFolder(r.Context(), id); err != nil {
+		h.logger.Error("删除文件夹失败", err)
+		if writeOrganizationError(w, err) {
+			return
+		}
+		util.InternalServerError(w, "删除文件夹失败")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListTags 处理获取工作区标签列表请求
+func (h *OrganizationHandler) ListTags(w http.ResponseWriter, r *http.Request) {
+	workspaceID, ok := pathID(w, r, "工作区ID不能为空")
+	if !ok {
+		return
+	}
+
+	tags, err := h.service.ListTags(r.Context(), workspaceID)
+	if err != nil {
+		h.logger.Error("获取标签列表失败", err)
+		util.InternalServerError(w, "获取标签列表失败")
+		return
+	}
+
+	util.SuccessResponse(w, tags, http.StatusOK)
+}
+
+// CreateTag 处理创建标签请求
+func (h *OrganizationHandler) CreateTag(w http.ResponseWriter, r *http.Request) {
+	workspaceID, ok := pathID(w, r, "工作区ID不能为空")
+	if !ok {
+		return
+	}
+
+	var req chat.CreateTagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		util.BadRequestError(w, "无效的请求体", nil)
+		return
+	}
+
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		util.UnauthorizedError(w, "未认证")
+		return
+	}
+
+	tag, err := h.service.CreateTag(r.Context(), userID, workspaceID, &req)
+	if err != nil {
+		h.logger.Error("创建标签失败", err)
+		if writeOrganizationError(w, err) {
+			return
+		}
+		util.InternalServerError(w, "创建标签失败")
+		return
+	}
+
+	util.SuccessResponse(w, tag, http.StatusCreated)
+}
+
+// UpdateTag 处理修改标签请求
+func (h *OrganizationHandler) UpdateTag(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "标签ID不能为空")
+	if !ok {
+		return
+	}
+
+	var req chat.UpdateTagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		util.BadRequestError(w, "无效的请求体", nil)
+		return
+	}
+
+	tag, err := h.service.UpdateTag(r.Context(), id, &req)
+	if err != nil {
+		h.logger.Error("修改标签失败", err)
+		if writeOrganizationError(w, err) {
+			return
+		}
+		util.InternalServerError(w, "修改标签失败")
+		return
+	}
+
+	util.SuccessResponse(w, tag, http.StatusOK)
+}
+
+// DeleteTag 处理删除标签请求
+func (h *OrganizationHandler) DeleteTag(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "标签ID不能为空")
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteTag(r.Context(), id); err != nil {
+		h.logger.Error("删除标签失败", err)
+		if writeOrganizationError(w, err) {
+			return
+		}
+		util.InternalServerError(w, "删除标签失败")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// MoveCanvas 处理将画布移动到文件夹请求
+func (h *OrganizationHandler) MoveCanvas(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "画布ID不能为空")
+	if !ok {
+		return
+	}
+
+	var req chat.MoveCanvasRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		util.BadRequestError(w, "无效的请求体", nil)
+		return
+	}
+
+	canvas, err := h.service.MoveCanvas(r.Context(), id, &req)
+	if err != nil {
+		h.logger.Error("移动画布失败", err)
+		if writeOrganizationError(w, err) {
+			return
+		}
+		util.NotFoundError(w, "画布不存在")
+		return
+	}
+
+	util.SuccessResponse(w, canvas, http.StatusOK)
+}
+
+// SetCanvasTags 处理设置画布标签请求
+func (h *OrganizationHandler) SetCanvasTags(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "画布ID不能为空")
+	if !ok {
+		return
+	}
+
+	var req chat.SetCanvasTagsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		util.BadRequestError(w, "无效的请求体", nil)
+		return
+	}
+
+	canvas, err := h.service.SetCanvasTags(r.Context(), id, &req)
+	if err != nil {
+		h.logger.Error("设置画布标签失败", err)
+		if writeOrganizationError(w, err) {
+			return
+		}
+		util.NotFoundError(w, "画布不存在")
+		return
+	}
+
+	util.SuccessResponse(w, canvas, http.StatusOK)
+}
+
+// PinCanvas 处理置顶画布请求
+func (h *OrganizationHandler) PinCanvas(w http.ResponseWriter, r *http.Request) {
+	h.setPinned(w, r, true)
+}
+
+// UnpinCanvas 处理取消置顶画布请求
+func (h *OrganizationHandler) UnpinCanvas(w http.ResponseWriter, r *http.Request) {
+	h.setPinned(w, r, false)
+}
+
+// FavoriteCanvas 处理收藏画布请求
+func (h *OrganizationHandler) FavoriteCanvas(w http.ResponseWriter, r *http.Request) {
+	h.setFavorite(w, r, true)
+}
+
+// UnfavoriteCanvas 处理取消收藏画布请求
+func (h *OrganizationHandler) UnfavoriteCanvas(w http.ResponseWriter, r *http.Request) {
+	h.setFavorite(w, r, false)
+}
+
+// setPinned 置顶或取消置顶画布
+func (h *OrganizationHandler) setPinned(w http.ResponseWriter, r *http.Request, pinned bool) {
+	id, ok := pathID(w, r, "画布ID不能为空")
+	if !ok {
+		return
+	}
+
+	canvas, err := h.service.SetCanvasPinned(r.Context(), id, pinned)
+	if err != nil {
+		h.logger.Error("设置画布置顶失败", err)
+		util.NotFoundError(w, "画布不存在")
+		return
+	}
+
+	util.SuccessResponse(w, canvas, http.StatusOK)
+}
+
+// setFavorite 收藏或取消收藏画布
+func (h *OrganizationHandler) setFavorite(w http.ResponseWriter, r *http.Request, favorite bool) {
+	id, ok := pathID(w, r, "画布ID不能为空")
+	if !ok {
+		return
+	}
+
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		util.UnauthorizedError(w, "未认证")
+		return
+	}
+
+	if err := h.service.SetCanvasFavorite(r.Context(), userID, id, favorite); err != nil {
+		h.logger.Error("设置画布收藏失败", err)
+		util.NotFoundError(w, "画布不存在")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeOrganizationError 将文件夹和标签的业务错误映射为对应的 HTTP 响应，其余错误按聊天错误处理
+func writeOrganizationError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, chatService.ErrFolderNotFound):
+		util.NotFoundError(w, "文件夹不存在")
+	case errors.Is(err, chatService.ErrInvalidFolder):
+		util.BadRequestError(w, "文件夹名称不能为空且不能超过 255 个字符，不能移动到自身或子文件夹下", nil)
+	case errors.Is(err, chatService.ErrTagNotFound):
+		util.NotFoundError(w, "标签不存在")
+	case errors.Is(err, chatService.ErrInvalidTag):
+		util.BadRequestError(w, "标签名称不能为空且不能超过 50 个字符，颜色不能超过 20 个字符，每个画布最多 20 个标签", nil)
+	case errors.Is(err, chatService.ErrTagExists):
+		util.ErrorResponse(w, "CONFLICT", "同名标签已存在", http.StatusConflict, nil)
+	default:
+		return writeChatError(w, err)
+	}
+	return true
+}
+
+// pathID 获取路径参数中的 ID，为空时返回 400
+func pathID(w http.ResponseWriter, r *http.Request, message string) (string, bool) {
+	id := mux.Vars(r)["id"]
+	if id == "" {
+		util.BadRequestError(w, message, nil)
+		return "", false
+	}
+	return id, true
+}
@@ -0,0 +1,307 @@
+package chat
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/chat"
+	"github.com/zhuiye8/Lyss-chat-server/internal/middleware"
+	chatService "github.com/zhuiye8/Lyss-chat-server/internal/service/chat"
+	"github.com/zhuiye8/Lyss-chat-server/internal/util"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/logger"
+)
+
+// PromptHandler 表示提示词库处理器
+type PromptHandler struct {
+	service *chatService.Service
+	logger  *logger.Logger
+}
+
+// NewPromptHandler 创建一个新的提示词库处理器
+func NewPromptHandler(service *chatService.Service, logger *logger.Logger) *PromptHandler {
+	return &PromptHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// ListPrompts 处理获取提示词模板列表请求
+func (h *PromptHandler) ListPrompts(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		util.UnauthorizedError(w, "未认证")
+		return
+	}
+
+	// 获取过滤和排序参数
+	query := r.URL.Query()
+	req := chat.PromptListRequest{
+		UserID:      userID,
+		Scope:       optionalParam(query.Get("scope")),
+		WorkspaceID: optionalParam(query.Get("workspace_id")),
+		Query:       query.Get("q"),
+		Sort:        query.Get("sort"),
+	}
+
+	// 获取分页参数
+	page := 1
+	pageSize := 20
+	if pageStr := query.Get("page"); pageStr != "" {
+		if p, err := strconv.Atoi(pageStr); err == nil && p > 0 {
+			page = p
+		}
+	}
+	if pageSizeStr := query.Get("page_size"); pageSizeStr != "" {
+		if ps, err := strconv.Atoi(pageSizeStr); err == nil && ps > 0 {
+			pageSize = ps
+		}
+	}
+
+	templates, total, err := h.service.ListPrompts(r.Context(), &req, page, pageSize)
+	if err != nil {
+		h.logger.Error("获取提示词模板列表失败", err)
+		if writePromptError(w, err) {
+			return
+		}
+		util.InternalServerError(w, "获取提示词模板列表失败")
+		return
+	}
+
+	// 构建响应
+	response := map[string]interface{}{
+		"items":     templates,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	}
+
+	util.SuccessResponse(w, response, http.StatusOK)
+}
+
+// GetPrompt 处理获取提示词模板详情请求
+func (h *PromptHandler) GetPrompt(w http.ResponseWriter, r *http.Request) {
+	userID, id, ok := promptParams(w, r)
+	if !ok {
+		return
+	}
+
+	template, err := h.service.GetPrompt(r.Context(), userID, id)
+	if err != nil {
+		h.logger.Error("获取提示词模板失败", err)
+		if writePromptError(w, err) {
+			return
+		}
+		util.InternalServerError(w, "获取提示词模板失败")
+		return
+	}
+
+	util.SuccessResponse(w, template, http.StatusOK)
+}
+
+// CreatePrompt 处理创建提示词模板请求
+func (h *PromptHandler) CreatePrompt(w http.ResponseWriter, r *http.Request) {
+	var req chat.CreatePromptRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		util.BadRequestError(w, "无效的请求体", nil)
+		return
+	}
+
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		util.UnauthorizedError(w, "未认证")
+		return
+	}
+
+	template, err := h.service.CreatePrompt(r.Context(), userID, &req)
+	if err != nil {
+		h.logger.Error("创建提示词模板失败", err)
+		if writePromptError(w, err) {
+			return
+		}
+		util.InternalServerError(w, "创建提示词模板失败")
+		return
+	}
+
+	util.SuccessResponse(w, template, http.StatusCreated)
+}
+
+// UpdatePrompt 处理修改提示词模板请求
+func (h *PromptHandler) UpdatePrompt(w http.ResponseWriter, r *http.Request) {
+	userID, id, ok := promptParams(w, r)
+	if !ok {
+		return
+	}
+
+	var req chat.UpdatePromptRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		util.BadRequestError(w, "无效的请求体", nil)
+		return
+	}
+
+	template, err := h.service.UpdatePrompt(r.Context(), userID, id, &req)
+	if err != nil {
+		h.logger.Error("修改提示词模板失败", err)
+		if writePromptError(w, err) {
+			return
+		}
+		util.InternalServerError(w, "修改提示词模板失败")
+		return
+	}
+
+	util.SuccessResponse(w, template, http.StatusOK)
+}
+
+// DeletePrompt 处理删除提示词模板请求
+func (h *PromptHandler) DeletePrompt(w http.ResponseWriter, r *http.Request) {
+	userID, id, ok := promptParams(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeletePrompt(r.Context(), userID, id); err != nil {
+		h.logger.Error("删除提示词模板失败", err)
+		if writePromptError(w, err) {
+			return
+		}
+		util.InternalServerError(w, "删除提示词模板失败")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListPromptVersions 处理获取提示词模板版本历史请求
+func (h *PromptHandler) ListPromptVersions(w http.ResponseWriter, r *http.Request) {
+	userID, id, ok := promptParams(w, r)
+	if !ok {
+		return
+	}
+
+	versions, err := h.service.ListPromptVersions(r.Context(), userID, id)
+	if err != nil {
+		h.logger.Error("获取提示词模板版本失败", err)
+		if writePromptError(w, err) {
+			return
+		}
+		util.InternalServerError(w, "获取提示词模板版本失败")
+		return
+	}
+
+	util.SuccessResponse(w, versions, http.StatusOK)
+}
+
+// SharePrompt 处理设置提示词模板分享对象请求
+func (h *PromptHandler) SharePrompt(w http.ResponseWriter, r *http.Request) {
+	userID, id, ok := promptParams(w, r)
+	if !ok {
+		return
+	}
+
+	var req chat.SharePromptRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		util.BadRequestError(w, "无效的请求体", nil)
+		return
+	}
+
+	userIDs, err := h.service.SharePrompt(r.Context(), userID, id, &req)
+	if err != nil {
+		h.logger.Error("分享提示词模板失败", err)
+		if writePromptError(w, err) {
+			return
+		}
+		util.InternalServerError(w, "分享提示词模板失败")
+		return
+	}
+
+	util.SuccessResponse(w, map[string]interface{}{"user_ids": userIDs}, http.StatusOK)
+}
+
+// RenderPrompt 处理预览提示词模板渲染结果请求
+func (h *PromptHandler) RenderPrompt(w http.ResponseWriter, r *http.Request) {
+	userID, id, ok := promptParams(w, r)
+	if !ok {
+		return
+	}
+
+	var req chat.RenderPromptRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		util.BadRequestError(w, "无效的请求体", nil)
+		return
+	}
+
+	rendered, err := h.service.RenderPrompt(r.Context(), userID, id, &req)
+	if err != nil {
+		h.logger.Error("渲染提示词模板失败", err)
+		if writePromptError(w, err) {
+			return
+		}
+		util.InternalServerError(w, "渲染提示词模板失败")
+		return
+	}
+
+	util.SuccessResponse(w, rendered, http.StatusOK)
+}
+
+// StartCanvas 处理从提示词模板创建画布请求
+func (h *PromptHandler) StartCanvas(w http.ResponseWriter, r *http.Request) {
+	userID, id, ok := promptParams(w, r)
+	if !ok {
+		return
+	}
+
+	var req chat.StartCanvasFromPromptRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		util.BadRequestError(w, "无效的请求体", nil)
+		return
+	}
+	if req.WorkspaceID == "" {
+		util.BadRequestError(w, "工作区ID不能为空", nil)
+		return
+	}
+
+	response, err := h.service.StartCanvasFromPrompt(r.Context(), userID, id, &req)
+	if err != nil {
+		h.logger.Error("从提示词模板创建画布失败", err)
+		if writePromptError(w, err) {
+			return
+		}
+		util.InternalServerError(w, "从提示词模板创建画布失败")
+		return
+	}
+
+	util.SuccessResponse(w, response, http.StatusCreated)
+}
+
+// promptParams 获取当前用户和路径中的模板 ID
+func promptParams(w http.ResponseWriter, r *http.Request) (string, string, bool) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		util.UnauthorizedError(w, "未认证")
+		return "", "", false
+	}
+
+	id, ok := pathID(w, r, "模板ID不能为空")
+	if !ok {
+		return "", "", false
+	}
+
+	return userID, id, true
+}
+
+// writePromptError 将提示词模板的业务错误映射为对应的 HTTP 响应
+// 从模板创建画布时的其余错误按画布错误处理
+func writePromptError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, chatService.ErrPromptNotFound):
+		util.NotFoundError(w, "提示词模板不存在")
+	case errors.Is(err, chatService.ErrPromptForbidden):
+		util.ForbiddenError(w, "只有模板创建者可以修改模板")
+	case errors.Is(err, chatService.ErrInvalidPrompt), errors.Is(err, chatService.ErrPromptVariableMissing):
+		util.BadRequestError(w, err.Error(), nil)
+	default:
+		return writeCanvasError(w, err)
+	}
+	return true
+}
@@ -0,0 +1,110 @@
+package chat
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/chat"
+	chatService "github.com/zhuiye8/Lyss-chat-server/internal/service/chat"
+	"github.com/zhuiye8/Lyss-chat-server/internal/util"
+)
+
+// UpdateMessage 处理原地编辑消息请求
+func (h *MessageHandler) UpdateMessage(w http.ResponseWriter, r *http.Request) {
+	canvasID, messageID, userID, ok := h.branchParams(w, r)
+	if !ok {
+		return
+	}
+
+	// 解析请求体
+	var req chat.UpdateMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		util.BadRequestError(w, "无效的请求体", nil)
+		return
+	}
+	if req.Content == "" {
+		util.BadRequestError(w, "消息内容不能为空", nil)
+		return
+	}
+
+	// 调用服务
+	message, err := h.service.UpdateMessage(r.Context(), userID, canvasID, messageID, &req)
+	if err != nil {
+		h.logger.Error("编辑消息失败", err)
+		if writeRevisionError(w, err) {
+			return
+		}
+		util.InternalServerError(w, "编辑消息失败")
+		return
+	}
+
+	util.SuccessResponse(w, message, http.StatusOK)
+}
+
+// DeleteMessage 处理删除消息请求
+// 查询参数 prune 为 true 时一并删除该消息的所有后代消息
+func (h *MessageHandler) DeleteMessage(w http.ResponseWriter, r *http.Request) {
+	canvasID, messageID, userID, ok := h.branchParams(w, r)
+	if !ok {
+		return
+	}
+
+	prune := false
+	if pruneStr := r.URL.Query().Get("prune"); pruneStr != "" {
+		p, err := strconv.ParseBool(pruneStr)
+		if err != nil {
+			util.BadRequestError(w, "prune 只能为 true 或 false", nil)
+			return
+		}
+		prune = p
+	}
+
+	// 调用服务
+	deleted, err := h.service.DeleteMessage(r.Context(), userID, canvasID, messageID, prune)
+	if err != nil {
+		h.logger.Error("删除消息失败", err)
+		if writeRevisionError(w, err) {
+			return
+		}
+		util.NotFoundError(w, "消息不存在")
+		return
+	}
+
+	util.SuccessResponse(w, map[string]interface{}{"deleted_ids": deleted}, http.StatusOK)
+}
+
+// ListMessageRevisions 处理获取消息修订历史请求
+func (h *MessageHandler) ListMessageRevisions(w http.ResponseWriter, r *http.Request) {
+	canvasID, messageID, _, ok := h.branchParams(w, r)
+	if !ok {
+		return
+	}
+
+	// 调用服务
+	revisions, err := h.service.ListMessageRevisions(r.Context(), canvasID, messageID)
+	if err != nil {
+		h.logger.Error("获取消息修订历史失败", err)
+		if writeRevisionError(w, err) {
+			return
+		}
+		util.NotFoundError(w, "消息不存在")
+		return
+	}
+
+	util.SuccessResponse(w, revisions, http.StatusOK)
+}
+
+// writeRevisionError 将原地编辑和删除消息的业务错误映射为对应的 HTTP 响应，其余错误按聊天错误处理
+func writeRevisionError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, chatService.ErrMessageUnchanged):
+		util.BadRequestError(w, "消息内容未改变", nil)
+	case errors.Is(err, chatService.ErrInvalidBranchTarget):
+		util.BadRequestError(w, "只能原地编辑用户消息和系统消息", nil)
+	default:
+		return writeChatError(w, err)
+	}
+	return true
+}
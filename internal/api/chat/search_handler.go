@@ -0,0 +1,86 @@
+package chat
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/chat"
+	chatService "github.com/zhuiye8/Lyss-chat-server/internal/service/chat"
+	"github.com/zhuiye8/Lyss-chat-server/internal/util"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/logger"
+)
+
+// SearchHandler 表示搜索处理器
+type SearchHandler struct {
+	service *chatService.Service
+	logger  *logger.Logger
+}
+
+// NewSearchHandler 创建一个新的搜索处理器
+func NewSearchHandler(service *chatService.Service, logger *logger.Logger) *SearchHandler {
+	return &SearchHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// Search 处理搜索请求
+// 查询参数：q 搜索词，mode 为 keyword（默认）或 semantic，
+// workspace_id、canvas_id、type（canvas 或 message）为可选过滤条件
+func (h *SearchHandler) Search(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	req := &chat.SearchRequest{
+		Query: query.Get("q"),
+		Mode:  query.Get("mode"),
+	}
+	if workspaceID := query.Get("workspace_id"); workspaceID != "" {
+		req.WorkspaceID = &workspaceID
+	}
+	if canvasID := query.Get("canvas_id"); canvasID != "" {
+		req.CanvasID = &canvasID
+	}
+	if kind := query.Get("type"); kind != "" {
+		req.Kind = &kind
+	}
+
+	// 获取分页参数
+	page := 1
+	pageSize := 20
+	if pageStr := query.Get("page"); pageStr != "" {
+		if p, err := strconv.Atoi(pageStr); err == nil && p > 0 {
+			page = p
+		}
+	}
+	if pageSizeStr := query.Get("page_size"); pageSizeStr != "" {
+		if ps, err := strconv.Atoi(pageSizeStr); err == nil && ps > 0 && ps <= 100 {
+			pageSize = ps
+		}
+	}
+
+	// 调用服务
+	results, total, err := h.service.Search(r.Context(), req, page, pageSize)
+	if err != nil {
+		switch {
+		case errors.Is(err, chatService.ErrInvalidSearch):
+			util.BadRequestError(w, "搜索词不能为空且不能超过 200 个字符，类型只能为 canvas 或 message，方式只能为 keyword 或 semantic", nil)
+			return
+		case errors.Is(err, chatService.ErrSemanticSearchDisabled):
+			util.BadRequestError(w, "语义搜索未启用", nil)
+			return
+		}
+		h.logger.Error("搜索失败", err)
+		util.InternalServerError(w, "搜索失败")
+		return
+	}
+
+	// 构建响应
+	response := map[string]interface{}{
+		"items":     results,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	}
+
+	util.SuccessResponse(w, response, http.StatusOK)
+}
@@ -0,0 +1,118 @@
+package chat
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	chatService "github.com/zhuiye8/Lyss-chat-server/internal/service/chat"
+	"github.com/zhuiye8/Lyss-chat-server/internal/util"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/logger"
+)
+
+// TrashHandler 表示回收站处理器
+type TrashHandler struct {
+	service *chatService.Service
+	purger  *chatService.TrashPurger
+	logger  *logger.Logger
+}
+
+// NewTrashHandler 创建一个新的回收站处理器
+func NewTrashHandler(service *chatService.Service, purger *chatService.TrashPurger, logger *logger.Logger) *TrashHandler {
+	return &TrashHandler{
+		service: service,
+		purger:  purger,
+		logger:  logger,
+	}
+}
+
+// ListTrash 处理获取工作区回收站画布列表请求
+func (h *TrashHandler) ListTrash(w http.ResponseWriter, r *http.Request) {
+	// 获取路径参数
+	vars := mux.Vars(r)
+	workspaceID := vars["id"]
+	if workspaceID == "" {
+		util.BadRequestError(w, "工作区ID不能为空", nil)
+		return
+	}
+
+	// 获取分页参数
+	page := 1
+	pageSize := 20
+	if pageStr := r.URL.Query().Get("page"); pageStr != "" {
+		if p, err := strconv.Atoi(pageStr); err == nil && p > 0 {
+			page = p
+		}
+	}
+	if pageSizeStr := r.URL.Query().Get("page_size"); pageSizeStr != "" {
+		if ps, err := strconv.Atoi(pageSizeStr); err == nil && ps > 0 {
+			pageSize = ps
+		}
+	}
+
+	// 调用服务
+	canvases, total, err := h.service.ListTrash(r.Context(), workspaceID, page, pageSize)
+	if err != nil {
+		h.logger.Error("获取回收站画布列表失败", err)
+		util.InternalServerError(w, "获取回收站画布列表失败")
+		return
+	}
+
+	// 构建响应
+	response := map[string]interface{}{
+		"items":     canvases,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	}
+
+	util.SuccessResponse(w, response, http.StatusOK)
+}
+
+// RestoreCanvas 处理从回收站恢复画布请求
+func (h *TrashHandler) RestoreCanvas(w http.ResponseWriter, r *http.Request) {
+	// 获取路径参数
+	vars := mux.Vars(r)
+	id := vars["id"]
+	if id == "" {
+		util.BadRequestError(w, "画布ID不能为空", nil)
+		return
+	}
+
+	// 调用服务
+	canvas, err := h.service.RestoreCanvas(r.Context(), id)
+	if err != nil {
+		h.logger.Error("恢复画布失败", err)
+		util.NotFoundError(w, "回收站中不存在该画布")
+		return
+	}
+
+	util.SuccessResponse(w, canvas, http.StatusOK)
+}
+
+// PurgeCanvas 处理彻底删除回收站画布请求，画布的消息和附件一并删除且不可恢复
+func (h *TrashHandler) PurgeCanvas(w http.ResponseWriter, r *http.Request) {
+	// 获取路径参数
+	vars := mux.Vars(r)
+	id := vars["id"]
+	if id == "" {
+		util.BadRequestError(w, "画布ID不能为空", nil)
+		return
+	}
+
+	// 调用服务
+	err := h.purger.PurgeCanvas(r.Context(), id)
+	if err != nil {
+		h.logger.Error("彻底删除画布失败", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			util.NotFoundError(w, "回收站中不存在该画布")
+			return
+		}
+		util.InternalServerError(w, "彻底删除画布失败")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -5,19 +5,25 @@ import (
 	"github.com/zhuiye8/Lyss-chat-server/internal/api/auth"
 	"github.com/zhuiye8/Lyss-chat-server/internal/api/chat"
 	"github.com/zhuiye8/Lyss-chat-server/internal/api/model"
+	"github.com/zhuiye8/Lyss-chat-server/internal/api/tenant"
 	"github.com/zhuiye8/Lyss-chat-server/internal/api/user"
+	"github.com/zhuiye8/Lyss-chat-server/internal/api/ws"
 	"github.com/zhuiye8/Lyss-chat-server/internal/middleware"
+	chatService "github.com/zhuiye8/Lyss-chat-server/internal/service/chat"
 	"github.com/zhuiye8/Lyss-chat-server/pkg/config"
 	"github.com/zhuiye8/Lyss-chat-server/pkg/db"
 	"github.com/zhuiye8/Lyss-chat-server/pkg/logger"
 )
 
 // RegisterRoutes 注册所有 API 路由
+// 聊天相关的处理器共享同一个聊天服务和回收站清理服务，其后台任务由 main 随服务生命周期启动
 func RegisterRoutes(
 	r *mux.Router,
 	db *db.Postgres,
 	redis *db.Redis,
 	minio *db.MinIO,
+	chatSvc *chatService.Service,
+	purger *chatService.TrashPurger,
 	cfg *config.Config,
 	logger *logger.Logger,
 ) {
@@ -31,6 +37,10 @@ func RegisterRoutes(
 	authRoutes.HandleFunc("/register", authHandler.Register).Methods("POST")
 	authRoutes.HandleFunc("/refresh", authHandler.RefreshToken).Methods("POST")
 
+	// WebSocket 路由，在建立连接时自行认证
+	wsHandler := ws.NewHandler(chatSvc, cfg, logger)
+	api.HandleFunc("/ws", wsHandler.ServeWS).Methods("GET")
+
 	// 需要认证的路由
 	authenticated := api.NewRoute().Subrouter()
 	authenticated.Use(middleware.Auth(cfg))
@@ -53,21 +63,125 @@ func RegisterRoutes(
 	tenantRoutes.HandleFunc("/{id}", userHandler.UpdateTenant).Methods("PUT")
 	tenantRoutes.HandleFunc("/{id}", userHandler.DeleteTenant).Methods("DELETE")
 
+	// 租户设置路由
+	settingsHandler := tenant.NewSettingsHandler(db, cfg, logger)
+	tenantRoutes.HandleFunc("/{id}/settings", settingsHandler.GetSettings).Methods("GET")
+	tenantRoutes.HandleFunc("/{id}/settings", settingsHandler.UpdateSettings).Methods("PUT")
+
 	// 画布路由
-	canvasHandler := chat.NewCanvasHandler(db, cfg, logger)
+	canvasHandler := chat.NewCanvasHandler(chatSvc, logger)
 	canvasRoutes := authenticated.PathPrefix("/canvases").Subrouter()
 	canvasRoutes.HandleFunc("", canvasHandler.ListCanvases).Methods("GET")
 	canvasRoutes.HandleFunc("/{id}", canvasHandler.GetCanvas).Methods("GET")
 	canvasRoutes.HandleFunc("", canvasHandler.CreateCanvas).Methods("POST")
 	canvasRoutes.HandleFunc("/{id}", canvasHandler.UpdateCanvas).Methods("PUT")
 	canvasRoutes.HandleFunc("/{id}", canvasHandler.DeleteCanvas).Methods("DELETE")
+	canvasRoutes.HandleFunc("/{id}/active-leaf", canvasHandler.SetActiveLeaf).Methods("PUT")
+	canvasRoutes.HandleFunc("/{id}/events", canvasHandler.StreamEvents).Methods("GET")
+	canvasRoutes.HandleFunc("/{id}/typing", canvasHandler.SetTyping).Methods("POST")
+
+	// 回收站路由
+	trashHandler := chat.NewTrashHandler(chatSvc, purger, logger)
+	canvasRoutes.HandleFunc("/{id}/restore", trashHandler.RestoreCanvas).Methods("POST")
+	canvasRoutes.HandleFunc("/{id}/permanent", trashHandler.PurgeCanvas).Methods("DELETE")
+	authenticated.HandleFunc("/workspaces/{id}/trash", trashHandler.ListTrash).Methods("GET")
+
+	// 画布整理路由
+	organizationHandler := chat.NewOrganizationHandler(chatSvc, logger)
+	canvasRoutes.HandleFunc("/{id}/folder", organizationHandler.MoveCanvas).Methods("PUT")
+	canvasRoutes.HandleFunc("/{id}/tags", organizationHandler.SetCanvasTags).Methods("PUT")
+	canvasRoutes.HandleFunc("/{id}/pin", organizationHandler.PinCanvas).Methods("PUT")
+	canvasRoutes.HandleFunc("/{id}/pin", organizationHandler.UnpinCanvas).Methods("DELETE")
+	canvasRoutes.HandleFunc("/{id}/favorite", organizationHandler.FavoriteCanvas).Methods("PUT")
+	canvasRoutes.HandleFunc("/{id}/favorite", organizationHandler.UnfavoriteCanvas).Methods("DELETE")
+	authenticated.HandleFunc("/workspaces/{id}/folders", organizationHandler.ListFolders).Methods("GET")
+	authenticated.HandleFunc("/workspaces/{id}/folders", organizationHandler.CreateFolder).Methods("POST")
+	authenticated.HandleFunc("/folders/{id}", organizationHandler.UpdateFolder).Methods("PUT")
+	authenticated.HandleFunc("/folders/{id}", organizationHandler.DeleteFolder).Methods("DELETE")
+	authenticated.HandleFunc("/workspaces/{id}/tags", organizationHandler.ListTags).Methods("GET")
+	authenticated.HandleFunc("/workspaces/{id}/tags", organizationHandler.CreateTag).Methods("POST")
+	authenticated.HandleFunc("/tags/{id}", organizationHandler.UpdateTag).Methods("PUT")
+	authenticated.HandleFunc("/tags/{id}", organizationHandler.DeleteTag).Methods("DELETE")
+
+	// 代码画布产物路由
+	artifactHandler := chat.NewArtifactHandler(chatSvc, logger)
+	artifactRoutes := canvasRoutes.PathPrefix("/{id}/artifacts").Subrouter()
+	artifactRoutes.HandleFunc("", artifactHandler.ListArtifacts).Methods("GET")
+	artifactRoutes.HandleFunc("/{artifact_id}", artifactHandler.GetArtifact).Methods("GET")
+	artifactRoutes.HandleFunc("/{artifact_id}/versions", artifactHandler.ListArtifactVersions).Methods("GET")
+	artifactRoutes.HandleFunc("/{artifact_id}/versions/{version}", artifactHandler.GetArtifactVersion).Methods("GET")
+	artifactRoutes.HandleFunc("/{artifact_id}/diff", artifactHandler.DiffArtifact).Methods("GET")
+	artifactRoutes.HandleFunc("/{artifact_id}/revert", artifactHandler.RevertArtifact).Methods("POST")
+
+	// 导出路由
+	exportHandler := chat.NewExportHandler(db, redis, minio, logger)
+	canvasRoutes.HandleFunc("/{id}/export", exportHandler.ExportCanvas).Methods("GET")
+	authenticated.HandleFunc("/workspaces/{id}/export", exportHandler.ExportWorkspace).Methods("POST")
+
+	// 导入路由
+	importHandler := chat.NewImportHandler(db, redis, logger)
+	authenticated.HandleFunc("/workspaces/{id}/import", importHandler.ImportConversations).Methods("POST")
+
+	// 后台任务路由
+	jobHandler := chat.NewJobHandler(redis, logger)
+	authenticated.HandleFunc("/jobs/{id}", jobHandler.GetJob).Methods("GET")
+
+	// 提示词库路由
+	promptHandler := chat.NewPromptHandler(chatSvc, logger)
+	promptRoutes := authenticated.PathPrefix("/prompts").Subrouter()
+	promptRoutes.HandleFunc("", promptHandler.ListPrompts).Methods("GET")
+	promptRoutes.HandleFunc("/{id}", promptHandler.GetPrompt).Methods("GET")
+	promptRoutes.HandleFunc("", promptHandler.CreatePrompt).Methods("POST")
+	promptRoutes.HandleFunc("/{id}", promptHandler.UpdatePrompt).Methods("PUT")
+	promptRoutes.HandleFunc("/{id}", promptHandler.DeletePrompt).Methods("DELETE")
+	promptRoutes.HandleFunc("/{id}/versions", promptHandler.ListPromptVersions).Methods("GET")
+	promptRoutes.HandleFunc("/{id}/shares", promptHandler.SharePrompt).Methods("PUT")
+	promptRoutes.HandleFunc("/{id}/render", promptHandler.RenderPrompt).Methods("POST")
+	promptRoutes.HandleFunc("/{id}/canvases", promptHandler.StartCanvas).Methods("POST")
+
+	// 助手路由
+	assistantHandler := chat.NewAssistantHandler(chatSvc, logger)
+	assistantRoutes := authenticated.PathPrefix("/assistants").Subrouter()
+	assistantRoutes.HandleFunc("", assistantHandler.ListAssistants).Methods("GET")
+	assistantRoutes.HandleFunc("/{id}", assistantHandler.GetAssistant).Methods("GET")
+	assistantRoutes.HandleFunc("", assistantHandler.CreateAssistant).Methods("POST")
+	assistantRoutes.HandleFunc("/{id}", assistantHandler.UpdateAssistant).Methods("PUT")
+	assistantRoutes.HandleFunc("/{id}", assistantHandler.DeleteAssistant).Methods("DELETE")
+	assistantRoutes.HandleFunc("/{id}/publish", assistantHandler.PublishAssistant).Methods("PUT")
+	assistantRoutes.HandleFunc("/{id}/publish", assistantHandler.UnpublishAssistant).Methods("DELETE")
 
 	// 消息路由
-	messageHandler := chat.NewMessageHandler(db, cfg, logger)
+	messageHandler := chat.NewMessageHandler(chatSvc, logger)
 	messageRoutes := authenticated.PathPrefix("/canvases/{id}/messages").Subrouter()
 	messageRoutes.HandleFunc("", messageHandler.ListMessages).Methods("GET")
 	messageRoutes.HandleFunc("", messageHandler.SendMessage).Methods("POST")
 	messageRoutes.HandleFunc("/stream", messageHandler.StreamMessage).Methods("POST")
+	messageRoutes.HandleFunc("/compare", messageHandler.StreamCompare).Methods("POST")
+	messageRoutes.HandleFunc("/tree", messageHandler.GetMessageTree).Methods("GET")
+	messageRoutes.HandleFunc("/{message_id}/regenerate", messageHandler.RegenerateMessage).Methods("POST")
+	messageRoutes.HandleFunc("/{message_id}/regenerate/stream", messageHandler.StreamRegenerateMessage).Methods("POST")
+	messageRoutes.HandleFunc("/{message_id}/edit", messageHandler.EditMessage).Methods("POST")
+	messageRoutes.HandleFunc("/{message_id}/edit/stream", messageHandler.StreamEditMessage).Methods("POST")
+	messageRoutes.HandleFunc("/{message_id}", messageHandler.UpdateMessage).Methods("PATCH")
+	messageRoutes.HandleFunc("/{message_id}", messageHandler.DeleteMessage).Methods("DELETE")
+	messageRoutes.HandleFunc("/{message_id}/revisions", messageHandler.ListMessageRevisions).Methods("GET")
+	messageRoutes.HandleFunc("/{message_id}/feedback", messageHandler.GetFeedback).Methods("GET")
+	messageRoutes.HandleFunc("/{message_id}/feedback", messageHandler.SubmitFeedback).Methods("PUT")
+	messageRoutes.HandleFunc("/{message_id}/feedback", messageHandler.DeleteFeedback).Methods("DELETE")
+	messageRoutes.HandleFunc("/{message_id}/executions", messageHandler.ExecuteCode).Methods("POST")
+	messageRoutes.HandleFunc("/{message_id}/select", messageHandler.SelectCompareReply).Methods("POST")
+
+	// 反馈统计路由
+	authenticated.HandleFunc("/feedback/stats", messageHandler.GetFeedbackStats).Methods("GET")
+
+	// 生成路由
+	generationRoutes := authenticated.PathPrefix("/canvases/{id}/generations").Subrouter()
+	generationRoutes.HandleFunc("/{gen_id}/stop", messageHandler.StopGeneration).Methods("POST")
+	generationRoutes.HandleFunc("/{gen_id}/events", messageHandler.AttachGeneration).Methods("GET")
+
+	// 搜索路由
+	searchHandler := chat.NewSearchHandler(chatSvc, logger)
+	authenticated.HandleFunc("/search", searchHandler.Search).Methods("GET")
 
 	// 模型路由
 	modelHandler := model.NewModelHandler(db, cfg, logger)
@@ -0,0 +1,109 @@
+package tenant
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/user"
+	"github.com/zhuiye8/Lyss-chat-server/internal/middleware"
+	"github.com/zhuiye8/Lyss-chat-server/internal/service/tenant"
+	"github.com/zhuiye8/Lyss-chat-server/internal/util"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/config"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/db"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/logger"
+)
+
+// SettingsHandler 表示租户设置处理器
+type SettingsHandler struct {
+	service *tenant.SettingsService
+	logger  *logger.Logger
+}
+
+// NewSettingsHandler 创建一个新的租户设置处理器
+func NewSettingsHandler(db *db.Postgres, cfg *config.Config, logger *logger.Logger) *SettingsHandler {
+	return &SettingsHandler{
+		service: tenant.NewSettingsService(db, logger),
+		logger:  logger,
+	}
+}
+
+// GetSettings 处理获取租户设置请求
+func (h *SettingsHandler) GetSettings(w http.ResponseWriter, r *http.Request) {
+	if !h.authorizeTenant(w, r) {
+		return
+	}
+
+	// 调用服务
+	settings, err := h.service.GetSettings(r.Context())
+	if err != nil {
+		h.logger.Error("获取租户设置失败", err)
+		util.InternalServerError(w, "获取租户设置失败")
+		return
+	}
+
+	util.SuccessResponse(w, settings, http.StatusOK)
+}
+
+// UpdateSettings 处理更新租户设置请求，只有租户管理员可以修改
+func (h *SettingsHandler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	if !h.authorizeTenant(w, r) {
+		return
+	}
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		util.UnauthorizedError(w, "未认证")
+		return
+	}
+
+	// 解析请求体
+	var req user.UpdateTenantSettingsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		util.BadRequestError(w, "无效的请求体", nil)
+		return
+	}
+
+	// 调用服务
+	settings, err := h.service.UpdateSettings(r.Context(), userID, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, tenant.ErrSettingsForbidden):
+			util.ForbiddenError(w, err.Error())
+		case errors.Is(err, tenant.ErrInvalidTrashRetention),
+			errors.Is(err, tenant.ErrInvalidDefaultModel),
+			errors.Is(err, tenant.ErrDefaultModelForbidden):
+			util.BadRequestError(w, err.Error(), nil)
+		default:
+			h.logger.Error("更新租户设置失败", err)
+			util.InternalServerError(w, "更新租户设置失败")
+		}
+		return
+	}
+
+	util.SuccessResponse(w, settings, http.StatusOK)
+}
+
+// authorizeTenant 校验路径中的租户是否为当前用户所属租户
+func (h *SettingsHandler) authorizeTenant(w http.ResponseWriter, r *http.Request) bool {
+	// 获取路径参数
+	vars := mux.Vars(r)
+	tenantID := vars["id"]
+	if tenantID == "" {
+		util.BadRequestError(w, "租户ID不能为空", nil)
+		return false
+	}
+
+	// 获取当前租户ID
+	currentTenantID, ok := middleware.GetTenantID(r.Context())
+	if !ok {
+		util.UnauthorizedError(w, "未认证")
+		return false
+	}
+	if currentTenantID != tenantID {
+		util.ForbiddenError(w, "无权访问该租户设置")
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,393 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/chat"
+	chatService "github.com/zhuiye8/Lyss-chat-server/internal/service/chat"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/config"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/logger"
+)
+
+const (
+	// writeWait 是单次写入的超时时间
+	writeWait = 10 * time.Second
+	// outboundBuffer 是待发送帧的缓冲大小
+	outboundBuffer = 64
+)
+
+// connection 表示一个已认证的 WebSocket 连接
+// 一个连接可以同时订阅多个画布并进行多个生成，所有帧由单独的写协程顺序写出
+type connection struct {
+	conn    *websocket.Conn
+	service *chatService.Service
+	cfg     config.WebSocketConfig
+	logger  *logger.Logger
+	userID  string
+	limiter *rateLimiter
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	out    chan *Frame
+
+	mu            sync.Mutex
+	subscriptions map[string]*subscription
+	forwarding    map[string]bool
+	generations   int
+}
+
+// subscription 表示连接对一个画布的订阅
+type subscription struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// newConnection 创建一个新的连接，ctx 中需包含用户和租户信息
+func newConnection(ctx context.Context, conn *websocket.Conn, service *chatService.Service, cfg config.WebSocketConfig, userID string, logger *logger.Logger) *connection {
+	ctx, cancel := context.WithCancel(ctx)
+	return &connection{
+		conn:          conn,
+		service:       service,
+		cfg:           cfg,
+		logger:        logger,
+		userID:        userID,
+		limiter:       newRateLimiter(cfg.FramesPerSecond, cfg.FrameBurst),
+		ctx:           ctx,
+		cancel:        cancel,
+		out:           make(chan *Frame, outboundBuffer),
+		subscriptions: make(map[string]*subscription),
+		forwarding:    make(map[string]bool),
+	}
+}
+
+// run 运行连接直到客户端断开
+func (c *connection) run() {
+	defer c.cancel()
+	go c.writeLoop()
+	c.readLoop()
+}
+
+// readLoop 读取并处理客户端帧
+func (c *connection) readLoop() {
+	pongWait := 2 * c.pingInterval()
+	if c.cfg.MaxFrameBytes > 0 {
+		c.conn.SetReadLimit(c.cfg.MaxFrameBytes)
+	}
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				c.logger.Warn("WebSocket 连接异常关闭", err)
+			}
+			return
+		}
+		c.conn.SetReadDeadline(time.Now().Add(pongWait))
+
+		var frame Frame
+		if err := json.Unmarshal(data, &frame); err != nil {
+			c.push(errorFrame("", ErrorCodeBadRequest, "无效的帧"))
+			continue
+		}
+
+		if !c.limiter.Allow() {
+			c.push(errorFrame(frame.ID, ErrorCodeRateLimited, "请求过于频繁"))
+			continue
+		}
+
+		c.handle(&frame)
+	}
+}
+
+// writeLoop 顺序写出待发送帧并定期发送心跳
+func (c *connection) writeLoop() {
+	ticker := time.NewTicker(c.pingInterval())
+	defer ticker.Stop()
+	defer c.conn.Close()
+
+	for {
+		select {
+		case frame := <-c.out:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteJSON(frame); err != nil {
+				c.cancel()
+				return
+			}
+		case <-ticker.C:
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait)); err != nil {
+				c.cancel()
+				return
+			}
+		case <-c.ctx.Done():
+			// 认证令牌过期时以策略违规关闭，客户端据此刷新令牌后重连
+			code, text := websocket.CloseNormalClosure, ""
+			if errors.Is(c.ctx.Err(), context.DeadlineExceeded) {
+				code, text = websocket.ClosePolicyViolation, "认证令牌已过期"
+			}
+			c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, text), time.Now().Add(writeWait))
+			return
+		}
+	}
+}
+
+// handle 分发客户端帧
+func (c *connection) handle(frame *Frame) {
+	switch frame.Type {
+	case FramePing:
+		c.push(&Frame{Type: FramePong, ID: frame.ID})
+	case FrameSubscribe:
+		c.subscribe(frame)
+	case FrameUnsubscribe:
+		c.unsubscribe(frame)
+	case FrameSend:
+		c.send(frame)
+	case FrameStop:
+		c.stop(frame)
+	case FrameTyping:
+		c.typing(frame)
+	default:
+		c.push(errorFrame(frame.ID, ErrorCodeBadRequest, "未知的帧类型"))
+	}
+}
+
+// subscribe 订阅画布协作事件，携带 generation_id 时从 last_event_id 之后重新订阅该生成的事件
+func (c *connection) subscribe(frame *Frame) {
+	if frame.CanvasID == "" {
+		c.push(errorFrame(frame.ID, ErrorCodeBadRequest, "画布ID不能为空"))
+		return
+	}
+
+	canvasCtx, err := c.subscribeCanvas(frame.CanvasID)
+	if err != nil {
+		c.push(errorFrame(frame.ID, ErrorCodeNotFound, "画布不存在"))
+		return
+	}
+
+	if frame.GenerationID != "" {
+		events, err := c.service.AttachGeneration(canvasCtx, frame.CanvasID, frame.GenerationID, frame.LastEventID)
+		if err != nil {
+			c.push(c.serviceError(frame.ID, err))
+			return
+		}
+		go c.forward(frame.CanvasID, events)
+	}
+
+	c.push(&Frame{Type: FrameAck, ID: frame.ID, CanvasID: frame.CanvasID, GenerationID: frame.GenerationID})
+}
+
+// unsubscribe 取消订阅画布，并停止转发该画布上的协作事件和生成事件
+// 生成本身不会因此停止，需要停止时应发送 stop 帧
+func (c *connection) unsubscribe(frame *Frame) {
+	c.mu.Lock()
+	if sub, ok := c.subscriptions[frame.CanvasID]; ok {
+		sub.cancel()
+		delete(c.subscriptions, frame.CanvasID)
+	}
+	c.mu.Unlock()
+
+	c.push(&Frame{Type: FrameAck, ID: frame.ID, CanvasID: frame.CanvasID})
+}
+
+// send 在画布上发送消息并转发生成事件，画布未订阅时自动订阅
+func (c *connection) send(frame *Frame) {
+	if frame.CanvasID == "" || frame.Message == nil || frame.Message.Content == "" {
+		c.push(errorFrame(frame.ID, ErrorCodeBadRequest, "画布ID和消息内容不能为空"))
+		return
+	}
+
+	if !c.acquireGeneration() {
+		c.push(errorFrame(frame.ID, ErrorCodeTooManyStreams, "同时进行的生成过多"))
+		return
+	}
+
+	canvasCtx, err := c.subscribeCanvas(frame.CanvasID)
+	if err != nil {
+		c.releaseGeneration()
+		c.push(errorFrame(frame.ID, ErrorCodeNotFound, "画布不存在"))
+		return
+	}
+	events, err := c.service.StreamMessage(canvasCtx, c.userID, frame.CanvasID, frame.Message)
+	if err != nil {
+		c.releaseGeneration()
+		c.push(c.serviceError(frame.ID, err))
+		return
+	}
+
+	c.push(&Frame{Type: FrameAck, ID: frame.ID, CanvasID: frame.CanvasID})
+	go func() {
+		defer c.releaseGeneration()
+		c.forward(frame.CanvasID, events)
+	}()
+}
+
+// stop 停止画布上的生成
+func (c *connection) stop(frame *Frame) {
+	if frame.CanvasID == "" || frame.GenerationID == "" {
+		c.push(errorFrame(frame.ID, ErrorCodeBadRequest, "画布ID和生成ID不能为空"))
+		return
+	}
+
+	if err := c.service.StopGeneration(c.ctx, frame.CanvasID, frame.GenerationID); err != nil {
+		c.push(errorFrame(frame.ID, ErrorCodeNotFound, "画布不存在"))
+		return
+	}
+
+	c.push(&Frame{Type: FrameAck, ID: frame.ID, CanvasID: frame.CanvasID, GenerationID: frame.GenerationID})
+}
+
+// typing 广播当前用户在画布上的输入状态
+func (c *connection) typing(frame *Frame) {
+	if frame.CanvasID == "" {
+		c.push(errorFrame(frame.ID, ErrorCodeBadRequest, "画布ID不能为空"))
+		return
+	}
+
+	if err := c.service.SetTyping(c.ctx, frame.CanvasID, c.userID, frame.Typing); err != nil {
+		c.push(errorFrame(frame.ID, ErrorCodeNotFound, "画布不存在"))
+		return
+	}
+
+	c.push(&Frame{Type: FrameAck, ID: frame.ID, CanvasID: frame.CanvasID})
+}
+
+// forward 将生成事件转发为 delta 帧
+func (c *connection) forward(canvasID string, events <-chan chatService.GenerationEvent) {
+	var generationID string
+	defer func() {
+		c.mu.Lock()
+		delete(c.forwarding, generationID)
+		c.mu.Unlock()
+	}()
+
+	for event := range events {
+		// 记录正在转发的生成，画布事件中相同生成的 message.delta 不再重复推送
+		if generationID == "" {
+			generationID = event.GenerationID
+			c.mu.Lock()
+			c.forwarding[generationID] = true
+			c.mu.Unlock()
+		}
+
+		if !c.push(&Frame{
+			Type:         FrameDelta,
+			CanvasID:     canvasID,
+			GenerationID: event.GenerationID,
+			EventID:      event.ID,
+			Event:        event.Data,
+		}) {
+			return
+		}
+	}
+}
+
+// push 将帧放入发送队列，连接关闭时返回 false
+func (c *connection) push(frame *Frame) bool {
+	select {
+	case c.out <- frame:
+		return true
+	case <-c.ctx.Done():
+		return false
+	}
+}
+
+// forwardEvents 将画布协作事件转发为 event 帧
+func (c *connection) forwardEvents(canvasID string, events <-chan *chat.CanvasEvent) {
+	for event := range events {
+		if event.Type == chat.CanvasEventMessageDelta && c.isForwarding(event.GenerationID) {
+			continue
+		}
+
+		data, err := json.Marshal(event)
+		if err != nil {
+			c.logger.Error("序列化画布事件失败", err)
+			continue
+		}
+		if !c.push(&Frame{Type: FrameEvent, CanvasID: canvasID, Event: data}) {
+			return
+		}
+	}
+}
+
+// isForwarding 判断生成事件是否已通过 delta 帧转发
+func (c *connection) isForwarding(generationID string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.forwarding[generationID]
+}
+
+// subscribeCanvas 订阅画布协作事件，返回订阅的上下文，取消订阅或连接关闭时取消
+// 已订阅的画布直接返回原有上下文
+func (c *connection) subscribeCanvas(canvasID string) (context.Context, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if sub, ok := c.subscriptions[canvasID]; ok {
+		return sub.ctx, nil
+	}
+
+	ctx, cancel := context.WithCancel(c.ctx)
+	events, err := c.service.SubscribeCanvas(ctx, canvasID, c.userID)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	c.subscriptions[canvasID] = &subscription{ctx: ctx, cancel: cancel}
+	go c.forwardEvents(canvasID, events)
+
+	return ctx, nil
+}
+
+// acquireGeneration 占用一个生成名额
+func (c *connection) acquireGeneration() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cfg.MaxConcurrentGenerations > 0 && c.generations >= c.cfg.MaxConcurrentGenerations {
+		return false
+	}
+	c.generations++
+	return true
+}
+
+// releaseGeneration 释放一个生成名额
+func (c *connection) releaseGeneration() {
+	c.mu.Lock()
+	c.generations--
+	c.mu.Unlock()
+}
+
+// serviceError 将聊天服务错误转换为错误帧
+func (c *connection) serviceError(requestID string, err error) *Frame {
+	switch {
+	case errors.Is(err, chatService.ErrGenerationNotFound):
+		return errorFrame(requestID, ErrorCodeNotFound, "生成不存在或已过期")
+	case errors.Is(err, chatService.ErrProviderNotAllowed):
+		return errorFrame(requestID, ErrorCodeForbidden, "租户不允许使用该模型提供商")
+	case errors.Is(err, chatService.ErrNoModelConfigured):
+		return errorFrame(requestID, ErrorCodeBadRequest, "未配置可用的模型")
+	case errors.Is(err, chatService.ErrInvalidSettings):
+		return errorFrame(requestID, ErrorCodeBadRequest, err.Error())
+	case errors.Is(err, chatService.ErrCanvasArchived):
+		return errorFrame(requestID, ErrorCodeForbidden, "画布已归档，只读")
+	default:
+		c.logger.Error("处理 WebSocket 请求失败", err)
+		return errorFrame(requestID, ErrorCodeInternal, "处理请求失败")
+	}
+}
+
+// pingInterval 返回心跳间隔
+func (c *connection) pingInterval() time.Duration {
+	if c.cfg.PingInterval <= 0 {
+		return 30 * time.Second
+	}
+	return time.Duration(c.cfg.PingInterval) * time.Second
+}
@@ -0,0 +1,63 @@
+package ws
+
+import (
+	"encoding/json"
+
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/chat"
+)
+
+// 客户端帧类型
+const (
+	FrameSubscribe   = "subscribe"
+	FrameUnsubscribe = "unsubscribe"
+	FrameSend        = "send"
+	FrameStop        = "stop"
+	FrameTyping      = "typing"
+	FramePing        = "ping"
+)
+
+// 服务端帧类型
+const (
+	FrameAck   = "ack"
+	FrameDelta = "delta"
+	FrameEvent = "event"
+	FrameError = "error"
+	FramePong  = "pong"
+)
+
+// 错误码
+const (
+	ErrorCodeBadRequest     = "bad_request"
+	ErrorCodeRateLimited    = "rate_limited"
+	ErrorCodeTooManyStreams = "too_many_generations"
+	ErrorCodeNotFound       = "not_found"
+	ErrorCodeForbidden      = "forbidden"
+	ErrorCodeInternal       = "internal_error"
+)
+
+// Frame 表示 WebSocket 上的一帧 JSON 消息
+// 客户端可携带 id，服务端对该帧的 ack 或 error 会原样带回，用于关联请求
+// delta 帧的 event 为生成事件，event 帧的 event 为画布协作事件
+type Frame struct {
+	Type         string                   `json:"type"`
+	ID           string                   `json:"id,omitempty"`
+	CanvasID     string                   `json:"canvas_id,omitempty"`
+	GenerationID string                   `json:"generation_id,omitempty"`
+	LastEventID  int64                    `json:"last_event_id,omitempty"`
+	Message      *chat.SendMessageRequest `json:"message,omitempty"`
+	EventID      int64                    `json:"event_id,omitempty"`
+	Event        json.RawMessage          `json:"event,omitempty"`
+	Typing       bool                     `json:"typing,omitempty"`
+	Code         string                   `json:"code,omitempty"`
+	Error        string                   `json:"error,omitempty"`
+}
+
+// errorFrame 创建错误帧
+func errorFrame(requestID, code, message string) *Frame {
+	return &Frame{
+		Type:  FrameError,
+		ID:    requestID,
+		Code:  code,
+		Error: message,
+	}
+}
@@ -0,0 +1,94 @@
+package ws
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gorilla/websocket"
+	"github.com/zhuiye8/Lyss-chat-server/internal/middleware"
+	chatService "github.com/zhuiye8/Lyss-chat-server/internal/service/chat"
+	"github.com/zhuiye8/Lyss-chat-server/internal/util"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/config"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/logger"
+)
+
+// Handler 表示 WebSocket 处理器
+type Handler struct {
+	service  *chatService.Service
+	cfg      *config.Config
+	upgrader websocket.Upgrader
+	logger   *logger.Logger
+}
+
+// NewHandler 创建一个新的 WebSocket 处理器
+func NewHandler(service *chatService.Service, cfg *config.Config, logger *logger.Logger) *Handler {
+	h := &Handler{
+		service: service,
+		cfg:     cfg,
+		logger:  logger,
+	}
+	h.upgrader = websocket.Upgrader{
+		ReadBufferSize:  4096,
+		WriteBufferSize: 4096,
+		CheckOrigin:     h.checkOrigin,
+	}
+	return h
+}
+
+// ServeWS 处理 WebSocket 连接请求，建立连接时校验 JWT
+// 浏览器无法为 WebSocket 设置请求头，因此也接受 access_token 查询参数
+func (h *Handler) ServeWS(w http.ResponseWriter, r *http.Request) {
+	tokenString := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if tokenString == "" {
+		tokenString = r.URL.Query().Get("access_token")
+	}
+	if tokenString == "" {
+		util.UnauthorizedError(w, "未提供认证令牌")
+		return
+	}
+
+	ctx, err := middleware.WithToken(r.Context(), tokenString, h.cfg.JWT.Secret)
+	if err != nil {
+		util.UnauthorizedError(w, "无效的认证令牌")
+		return
+	}
+	userID, _ := middleware.GetUserID(ctx)
+
+	// 令牌过期时连接随之关闭，客户端需使用新的令牌重新连接
+	if expiresAt, ok := middleware.GetTokenExpiry(ctx); ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, expiresAt)
+		defer cancel()
+	}
+
+	// 升级连接，失败时 upgrader 已写出错误响应
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		h.logger.Warn("WebSocket 升级失败", err)
+		return
+	}
+
+	newConnection(ctx, conn, h.service, h.cfg.WebSocket, userID, h.logger).run()
+}
+
+// checkOrigin 校验请求来源，未配置允许的来源时只允许同源请求
+// 没有 Origin 请求头的请求不是由浏览器发起，不受跨站请求影响，直接放行
+func (h *Handler) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if len(h.cfg.WebSocket.AllowedOrigins) == 0 {
+		u, err := url.Parse(origin)
+		return err == nil && strings.EqualFold(u.Host, r.Host)
+	}
+
+	for _, allowed := range h.cfg.WebSocket.AllowedOrigins {
+		if strings.EqualFold(strings.TrimSpace(allowed), origin) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,40 @@
+package ws
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	tests := []struct {
+		name    string
+		rate    float64
+		burst   int
+		elapsed time.Duration // 取完初始令牌后经过的时间
+		calls   int
+		want    int
+	}{
+		{"burst then limited", 1, 3, 0, 5, 3},
+		{"refill after wait", 1, 3, 2 * time.Second, 5, 5},
+		{"refill capped at burst", 1, 3, time.Hour, 10, 6},
+		{"burst below one", 1, 0, 0, 3, 1},
+		{"unlimited", 0, 1, 0, 100, 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newRateLimiter(tt.rate, tt.burst)
+			allowed := 0
+			for i := 0; i < tt.calls; i++ {
+				if i == int(l.burst) {
+					l.last = l.last.Add(-tt.elapsed)
+				}
+				if l.Allow() {
+					allowed++
+				}
+			}
+			if allowed != tt.want {
+				t.Fatalf("allowed %d of %d, want %d", allowed, tt.calls, tt.want)
+			}
+		})
+	}
+}
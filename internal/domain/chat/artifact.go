@@ -0,0 +1,60 @@
+package chat
+
+import (
+	"context"
+	"time"
+)
+
+// Artifact 表示代码画布中的产物，通常对应一个文件
+// 助手回复中带有文件名的围栏代码块按名称提取为产物的新版本，Content 为最新版本的内容
+type Artifact struct {
+	ID             string    `json:"id" db:"id"`
+	TenantID       string    `json:"tenant_id" db:"tenant_id"`
+	CanvasID       string    `json:"canvas_id" db:"canvas_id"`
+	Name           string    `json:"name" db:"name"`
+	Language       *string   `json:"language,omitempty" db:"language"`
+	CurrentVersion int       `json:"current_version" db:"current_version"`
+	Content        string    `json:"content" db:"content"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// ArtifactVersion 表示产物的一个版本
+// MessageID 为产生该版本的助手消息，回退产生的版本 RevertedFrom 为回退到的版本号
+type ArtifactVersion struct {
+	ID           string    `json:"id" db:"id"`
+	TenantID     string    `json:"tenant_id" db:"tenant_id"`
+	ArtifactID   string    `json:"artifact_id" db:"artifact_id"`
+	Version      int       `json:"version" db:"version"`
+	Language     *string   `json:"language,omitempty" db:"language"`
+	Content      string    `json:"content" db:"content"`
+	MessageID    *string   `json:"message_id,omitempty" db:"message_id"`
+	RevertedFrom *int      `json:"reverted_from,omitempty" db:"reverted_from"`
+	CreatedBy    string    `json:"created_by" db:"created_by"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
+
+// ArtifactDiff 表示产物两个版本之间的差异，Diff 为统一差异格式的文本
+type ArtifactDiff struct {
+	ArtifactID  string `json:"artifact_id"`
+	Name        string `json:"name"`
+	FromVersion int    `json:"from_version"`
+	ToVersion   int    `json:"to_version"`
+	Diff        string `json:"diff"`
+}
+
+// RevertArtifactRequest 表示将产物回退到指定版本的请求，回退以新版本的形式记录
+type RevertArtifactRequest struct {
+	Version int `json:"version" validate:"required,min=1"`
+}
+
+// ArtifactRepository 表示产物仓库接口
+type ArtifactRepository interface {
+	ListByCanvas(ctx context.Context, canvasID string) ([]*Artifact, error)
+	GetByID(ctx context.Context, id string) (*Artifact, error)
+	// SaveVersion 为画布中指定名称的产物追加新版本，产物不存在时创建
+	// 保存后 artifact 和 version 更新为数据库中的最新状态
+	SaveVersion(ctx context.Context, artifact *Artifact, version *ArtifactVersion) error
+	ListVersions(ctx context.Context, artifactID string) ([]*ArtifactVersion, error)
+	GetVersion(ctx context.Context, artifactID string, version int) (*ArtifactVersion, error)
+}
@@ -0,0 +1,126 @@
+package chat
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// AssistantScope 表示助手的可见范围，取值与提示词模板相同
+// user 为创建者私有，发布到 workspace 或 tenant 后对工作区或整个租户可见
+const (
+	AssistantScopeUser      = "user"
+	AssistantScopeWorkspace = "workspace"
+	AssistantScopeTenant    = "tenant"
+)
+
+// AssistantTool 表示助手启用的函数工具，Parameters 为 JSON Schema 描述的参数
+// 模型发起的工具调用以 tool_call 事件推送给客户端执行
+type AssistantTool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"`
+}
+
+// AssistantTools 表示助手的工具列表，以 JSONB 存储
+type AssistantTools []AssistantTool
+
+// Value 实现 driver.Valuer 接口，以 JSONB 存储
+func (t AssistantTools) Value() (driver.Value, error) {
+	if t == nil {
+		t = AssistantTools{}
+	}
+	return json.Marshal(t)
+}
+
+// Scan 实现 sql.Scanner 接口
+func (t *AssistantTools) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*t = AssistantTools{}
+		return nil
+	case []byte:
+		return json.Unmarshal(v, t)
+	case string:
+		return json.Unmarshal([]byte(v), t)
+	default:
+		return fmt.Errorf("无法扫描助手工具: %T", src)
+	}
+}
+
+// Assistant 表示可复用的助手，打包系统提示词、默认模型与参数和工具
+// 使用助手的画布在每次生成时按助手的当前配置构建聊天图形
+// 知识库检索尚未接入生成流程，KnowledgeBaseIDs 保留为空，创建或修改时传入知识库会被拒绝
+type Assistant struct {
+	ID               string             `json:"id" db:"id"`
+	TenantID         string             `json:"tenant_id" db:"tenant_id"`
+	Scope            string             `json:"scope" db:"scope"`
+	WorkspaceID      *string            `json:"workspace_id,omitempty" db:"workspace_id"`
+	Name             string             `json:"name" db:"name"`
+	Description      *string            `json:"description,omitempty" db:"description"`
+	Avatar           *string            `json:"avatar,omitempty" db:"avatar"`
+	SystemPrompt     *string            `json:"system_prompt,omitempty" db:"system_prompt"`
+	ModelID          *string            `json:"model_id,omitempty" db:"model_id"`
+	Settings         GenerationSettings `json:"settings" db:"settings"`
+	Tools            AssistantTools     `json:"tools" db:"tools"`
+	KnowledgeBaseIDs pq.StringArray     `json:"knowledge_base_ids" db:"knowledge_base_ids"`
+	CreatedBy        string             `json:"created_by" db:"created_by"`
+	CreatedAt        time.Time          `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time          `json:"updated_at" db:"updated_at"`
+}
+
+// CreateAssistantRequest 表示创建助手的请求，新建的助手为创建者私有
+// Settings 中的 system_prompt 不生效，系统提示词使用 SystemPrompt
+type CreateAssistantRequest struct {
+	Name             string              `json:"name" validate:"required"`
+	Description      *string             `json:"description,omitempty"`
+	Avatar           *string             `json:"avatar,omitempty"`
+	SystemPrompt     *string             `json:"system_prompt,omitempty"`
+	ModelID          *string             `json:"model_id,omitempty" validate:"omitempty,uuid"`
+	Settings         *GenerationSettings `json:"settings,omitempty"`
+	Tools            AssistantTools      `json:"tools,omitempty"`
+	KnowledgeBaseIDs []string            `json:"knowledge_base_ids,omitempty"`
+}
+
+// UpdateAssistantRequest 表示修改助手的请求，字符串字段传入空字符串表示清除
+type UpdateAssistantRequest struct {
+	Name             *string             `json:"name,omitempty"`
+	Description      *string             `json:"description,omitempty"`
+	Avatar           *string             `json:"avatar,omitempty"`
+	SystemPrompt     *string             `json:"system_prompt,omitempty"`
+	ModelID          *string             `json:"model_id,omitempty"`
+	Settings         *GenerationSettings `json:"settings,omitempty"`
+	Tools            *AssistantTools     `json:"tools,omitempty"`
+	KnowledgeBaseIDs *[]string           `json:"knowledge_base_ids,omitempty"`
+}
+
+// PublishAssistantRequest 表示发布助手的请求，发布到工作区时须指定 WorkspaceID
+type PublishAssistantRequest struct {
+	Scope       string  `json:"scope" validate:"required,oneof=workspace tenant"`
+	WorkspaceID *string `json:"workspace_id,omitempty" validate:"omitempty,uuid"`
+}
+
+// AssistantListRequest 表示列出助手的过滤条件
+// 返回当前用户可见的助手：已发布的助手和自己创建的私有助手
+// 指定 WorkspaceID 时只包含该工作区的工作区助手
+type AssistantListRequest struct {
+	UserID      string  `json:"-"`
+	Scope       *string `json:"scope,omitempty"`
+	WorkspaceID *string `json:"workspace_id,omitempty"`
+	Offset      int     `json:"-"`
+	Limit       int     `json:"-"`
+}
+
+// AssistantRepository 表示助手仓库接口
+type AssistantRepository interface {
+	Create(ctx context.Context, assistant *Assistant) error
+	GetByID(ctx context.Context, id string) (*Assistant, error)
+	Update(ctx context.Context, assistant *Assistant) error
+	// Delete 删除助手，使用该助手的画布回退为普通画布
+	Delete(ctx context.Context, id string) error
+	List(ctx context.Context, req *AssistantListRequest) ([]*Assistant, int, error)
+}
@@ -0,0 +1,29 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Attachment 表示消息附件
+// URL 为外部链接（http/https）或 MinIO 存储桶中的对象键
+type Attachment struct {
+	ID        string          `json:"id" db:"id"`
+	TenantID  string          `json:"tenant_id" db:"tenant_id"`
+	MessageID string          `json:"message_id" db:"message_id"`
+	Type      string          `json:"type" db:"type"`
+	Name      string          `json:"name" db:"name"`
+	Size      int64           `json:"size" db:"size"`
+	MimeType  string          `json:"mime_type" db:"mime_type"`
+	URL       string          `json:"url" db:"url"`
+	Metadata  json.RawMessage `json:"metadata,omitempty" db:"metadata"`
+	CreatedAt time.Time       `json:"created_at" db:"created_at"`
+}
+
+// AttachmentRepository 表示附件仓库接口
+type AttachmentRepository interface {
+	ListByMessageIDs(ctx context.Context, messageIDs []string) ([]*Attachment, error)
+	// ListByCanvasID 获取画布所有消息的附件，包括已删除的消息
+	ListByCanvasID(ctx context.Context, canvasID string) ([]*Attachment, error)
+}
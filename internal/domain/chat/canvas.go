@@ -1,21 +1,40 @@
 package chat
 
 import (
+	"context"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 // Canvas 表示画布实体
+// TitleCustomized 表示用户是否手动修改过标题，TopicTags 为自动生成的主题标签
+// DeletedAt 不为空表示画布在回收站中
+// PinnedAt 不为空表示画布已置顶，Favorite 表示当前用户是否收藏了画布，Tags 为用户定义的标签
 type Canvas struct {
-	ID          string     `json:"id" db:"id"`
-	WorkspaceID string     `json:"workspace_id" db:"workspace_id"`
-	Title       string     `json:"title" db:"title"`
-	Description *string    `json:"description,omitempty" db:"description"`
-	Type        string     `json:"type" db:"type"`
-	Status      string     `json:"status" db:"status"`
-	ModelID     *string    `json:"model_id,omitempty" db:"model_id"`
-	CreatedBy   string     `json:"created_by" db:"created_by"`
-	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+	ID              string             `json:"id" db:"id"`
+	TenantID        string             `json:"tenant_id" db:"tenant_id"`
+	WorkspaceID     string             `json:"workspace_id" db:"workspace_id"`
+	Title           string             `json:"title" db:"title"`
+	TitleCustomized bool               `json:"title_customized" db:"title_customized"`
+	TopicTags       pq.StringArray     `json:"topic_tags" db:"topic_tags"`
+	Description     *string            `json:"description,omitempty" db:"description"`
+	Type            string             `json:"type" db:"type"`
+	Status          string             `json:"status" db:"status"`
+	ModelID         *string            `json:"model_id,omitempty" db:"model_id"`
+	AssistantID     *string            `json:"assistant_id,omitempty" db:"assistant_id"`
+	Settings        GenerationSettings `json:"settings" db:"settings"`
+	ActiveLeafID    *string            `json:"active_leaf_id,omitempty" db:"active_leaf_id"`
+	FolderID        *string            `json:"folder_id,omitempty" db:"folder_id"`
+	PinnedAt        *time.Time         `json:"pinned_at,omitempty" db:"pinned_at"`
+	Favorite        bool               `json:"favorite" db:"favorite"`
+	Tags            []*Tag             `json:"tags,omitempty" db:"-"`
+	LastMessageAt   *time.Time         `json:"last_message_at,omitempty" db:"last_message_at"`
+	AutoTitledAt    *time.Time         `json:"auto_titled_at,omitempty" db:"auto_titled_at"`
+	DeletedAt       *time.Time         `json:"deleted_at,omitempty" db:"deleted_at"`
+	CreatedBy       string             `json:"created_by" db:"created_by"`
+	CreatedAt       time.Time          `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time          `json:"updated_at" db:"updated_at"`
 }
 
 // CanvasType 表示画布类型
@@ -24,7 +43,7 @@ const (
 	CanvasTypeCode = "code"
 )
 
-// CanvasStatus 表示画布状态
+// CanvasStatus 表示画布状态，归档的画布只读
 const (
 	CanvasStatusActive   = "active"
 	CanvasStatusArchived = "archived"
@@ -32,28 +51,58 @@ const (
 
 // CreateCanvasRequest 表示创建画布的请求
 type CreateCanvasRequest struct {
-	Title       string  `json:"title" validate:"required"`
-	Description *string `json:"description,omitempty"`
-	WorkspaceID string  `json:"workspace_id" validate:"required,uuid"`
-	Type        string  `json:"type" validate:"required,oneof=chat code"`
-	ModelID     *string `json:"model_id,omitempty" validate:"omitempty,uuid"`
+	Title       string              `json:"title" validate:"required"`
+	Description *string             `json:"description,omitempty"`
+	WorkspaceID string              `json:"workspace_id" validate:"required,uuid"`
+	FolderID    *string             `json:"folder_id,omitempty" validate:"omitempty,uuid"`
+	Type        string              `json:"type" validate:"required,oneof=chat code"`
+	ModelID     *string             `json:"model_id,omitempty" validate:"omitempty,uuid"`
+	AssistantID *string             `json:"assistant_id,omitempty" validate:"omitempty,uuid"`
+	Settings    *GenerationSettings `json:"settings,omitempty"`
 }
 
-// UpdateCanvasRequest 表示更新画布的请求
+// UpdateCanvasRequest 表示更新画布的请求，AssistantID 传入空字符串表示不再使用助手
 type UpdateCanvasRequest struct {
-	Title       *string `json:"title,omitempty"`
-	Description *string `json:"description,omitempty"`
-	Status      *string `json:"status,omitempty" validate:"omitempty,oneof=active archived"`
-	ModelID     *string `json:"model_id,omitempty" validate:"omitempty,uuid"`
+	Title       *string             `json:"title,omitempty"`
+	Description *string             `json:"description,omitempty"`
+	Status      *string             `json:"status,omitempty" validate:"omitempty,oneof=active archived"`
+	ModelID     *string             `json:"model_id,omitempty" validate:"omitempty,uuid"`
+	AssistantID *string             `json:"assistant_id,omitempty"`
+	Settings    *GenerationSettings `json:"settings,omitempty"`
+}
+
+// SetActiveLeafRequest 表示切换画布当前分支的请求
+type SetActiveLeafRequest struct {
+	MessageID string `json:"message_id" validate:"required,uuid"`
 }
 
 // CanvasRepository 表示画布仓库接口
 type CanvasRepository interface {
-	Create(canvas *Canvas) error
-	GetByID(id string) (*Canvas, error)
-	Update(canvas *Canvas) error
-	Delete(id string) error
-	List(workspaceID string, canvasType *string, offset, limit int) ([]*Canvas, int, error)
+	Create(ctx context.Context, canvas *Canvas) error
+	GetByID(ctx context.Context, id string) (*Canvas, error)
+	Update(ctx context.Context, canvas *Canvas) error
+	// Delete 彻底删除画布及其消息
+	Delete(ctx context.Context, id string) error
+	// Trash 将画布移入回收站，画布不存在或已在回收站中时返回 false
+	Trash(ctx context.Context, id, deletedBy string) (bool, error)
+	// Restore 将画布移出回收站
+	Restore(ctx context.Context, id string) (*Canvas, error)
+	// GetTrashedByID 获取回收站中的画布
+	GetTrashedByID(ctx context.Context, id string) (*Canvas, error)
+	ListTrash(ctx context.Context, workspaceID string, offset, limit int) ([]*Canvas, int, error)
+	// ListExpiredTrash 跨租户列出超过租户保留天数的回收站画布，租户未配置时使用 defaultDays
+	ListExpiredTrash(ctx context.Context, defaultDays, limit int) ([]*Canvas, error)
+	// ApplyAutoTitle 写入自动生成的标题和标签，标题已被用户修改时只写入标签
+	// 画布已完成过自动标题时不做修改并返回 false
+	ApplyAutoTitle(ctx context.Context, id, title string, tags []string) (bool, error)
+	SetActiveLeaf(ctx context.Context, id, messageID string) error
+	// List 按条件列出画布，req.UserID 不为空时填充该用户的收藏标记
+	List(ctx context.Context, req *CanvasListRequest) ([]*Canvas, int, error)
+	// SetFolder 将画布移动到文件夹，folderID 为空表示移动到顶层
+	SetFolder(ctx context.Context, id string, folderID *string) error
+	SetPinned(ctx context.Context, id string, pinned bool) error
+	SetFavorite(ctx context.Context, id, userID string, favorite bool) error
+	IsFavorite(ctx context.Context, id, userID string) (bool, error)
 }
 
 // CanvasService 表示画布服务接口
@@ -0,0 +1,24 @@
+package chat
+
+import "encoding/json"
+
+// CompareMessageRequest 表示对比模式发送消息的请求
+// 同一条用户消息同时发给多个模型，各模型的回复作为该用户消息下的兄弟助手消息保存
+type CompareMessageRequest struct {
+	Content  string          `json:"content" validate:"required"`
+	ParentID *string         `json:"parent_id,omitempty" validate:"omitempty,uuid"`
+	Metadata json.RawMessage `json:"metadata,omitempty"`
+	// ModelIDs 为参与对比的模型，不能重复
+	ModelIDs []string `json:"model_ids" validate:"required,min=2,dive,uuid"`
+	// Settings 对所有参与对比的模型生效，覆盖画布的生成设置
+	Settings *GenerationSettings `json:"settings,omitempty"`
+}
+
+// CompareEvent 表示对比模式流式响应中的一条事件
+// Event 为对应模型生成的流式事件，EventID 为该事件在其生成内的序号，可用于重新订阅单个生成
+type CompareEvent struct {
+	GenerationID string          `json:"generation_id"`
+	ModelID      string          `json:"model_id"`
+	EventID      int64           `json:"event_id"`
+	Event        json.RawMessage `json:"event"`
+}
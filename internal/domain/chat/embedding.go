@@ -0,0 +1,38 @@
+package chat
+
+import (
+	"context"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// MessageEmbedding 表示消息的向量，每条消息只保留当前嵌入模型生成的向量
+type MessageEmbedding struct {
+	MessageID  string          `json:"message_id" db:"message_id"`
+	TenantID   string          `json:"tenant_id" db:"tenant_id"`
+	CanvasID   string          `json:"canvas_id" db:"canvas_id"`
+	Model      string          `json:"model" db:"model"`
+	Dimensions int             `json:"dimensions" db:"dimensions"`
+	Embedding  pq.Float32Array `json:"embedding" db:"embedding"`
+	CreatedAt  time.Time       `json:"created_at" db:"created_at"`
+}
+
+// EmbeddingCandidate 表示进程内计算相似度时的候选消息
+type EmbeddingCandidate struct {
+	SearchResult
+	Embedding pq.Float32Array `db:"embedding"`
+}
+
+// EmbeddingRepository 表示消息向量仓库接口
+type EmbeddingRepository interface {
+	Upsert(ctx context.Context, embeddings []*MessageEmbedding) error
+	// ListPending 列出没有 model 生成的向量的消息，用于补全和更换模型后重新向量化
+	ListPending(ctx context.Context, model string, limit int) ([]*Message, error)
+	// VectorSupported 判断数据库是否启用了 pgvector
+	VectorSupported(ctx context.Context) (bool, error)
+	// SearchSimilar 使用 pgvector 按余弦相似度检索消息和画布
+	SearchSimilar(ctx context.Context, req *SearchRequest, model string, vector []float32, minScore float64) ([]*SearchResult, int, error)
+	// ListCandidates 列出满足过滤条件的最近 limit 条消息及其向量，用于进程内计算相似度
+	ListCandidates(ctx context.Context, req *SearchRequest, model string, limit int) ([]*EmbeddingCandidate, error)
+}
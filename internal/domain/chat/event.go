@@ -0,0 +1,38 @@
+package chat
+
+import "time"
+
+// CanvasEventType 表示画布协作事件类型
+const (
+	CanvasEventMessageCreated   = "message.created"
+	CanvasEventMessageDelta     = "message.delta"
+	CanvasEventMessageUpdated   = "message.updated"
+	CanvasEventMessageDeleted   = "message.deleted"
+	CanvasEventCanvasUpdated    = "canvas.updated"
+	CanvasEventCanvasDeleted    = "canvas.deleted"
+	CanvasEventPresence         = "presence"
+	CanvasEventTyping           = "typing"
+	CanvasEventArtifactsUpdated = "artifacts.updated"
+)
+
+// CanvasEvent 表示画布上的协作事件，通过事件总线广播给订阅该画布的所有客户端
+type CanvasEvent struct {
+	Type         string       `json:"type"`
+	CanvasID     string       `json:"canvas_id"`
+	UserID       string       `json:"user_id,omitempty"`
+	Message      *Message     `json:"message,omitempty"`
+	MessageIDs   []string     `json:"message_ids,omitempty"`
+	GenerationID string       `json:"generation_id,omitempty"`
+	EventID      int64        `json:"event_id,omitempty"`
+	Delta        *StreamEvent `json:"delta,omitempty"`
+	Canvas       *Canvas      `json:"canvas,omitempty"`
+	Users        []string     `json:"users,omitempty"`
+	Typing       bool         `json:"typing,omitempty"`
+	Artifacts    []*Artifact  `json:"artifacts,omitempty"`
+	CreatedAt    time.Time    `json:"created_at"`
+}
+
+// TypingRequest 表示更新输入状态的请求
+type TypingRequest struct {
+	Typing bool `json:"typing"`
+}
@@ -0,0 +1,50 @@
+package chat
+
+import "time"
+
+// ExecutionEventType 表示代码执行流式事件类型
+// 事件依次为 start、若干 stdout/stderr、done，出错时发送 error
+const (
+	ExecutionEventStart  = "start"
+	ExecutionEventStdout = "stdout"
+	ExecutionEventStderr = "stderr"
+	ExecutionEventDone   = "done"
+	ExecutionEventError  = "error"
+)
+
+// ExecuteCodeRequest 表示执行助手消息中代码块的请求
+// Block 为代码块在消息中的序号（从 0 开始，按所有围栏代码块计数）
+type ExecuteCodeRequest struct {
+	Block int `json:"block" validate:"min=0"`
+}
+
+// ExecutionEvent 表示代码执行的流式事件
+type ExecutionEvent struct {
+	Type        string           `json:"type"`
+	ExecutionID string           `json:"execution_id,omitempty"`
+	Language    string           `json:"language,omitempty"`
+	Data        string           `json:"data,omitempty"`
+	Result      *ExecutionResult `json:"result,omitempty"`
+	Error       string           `json:"error,omitempty"`
+}
+
+// ExecutionResult 表示一次代码执行的结果，保存在消息元数据的 executions 中
+// 被信号终止（超时、超出输出上限等）时 ExitCode 为 -1
+type ExecutionResult struct {
+	ID         string    `json:"id"`
+	Block      int       `json:"block"`
+	Language   string    `json:"language"`
+	ExitCode   int       `json:"exit_code"`
+	Stdout     string    `json:"stdout"`
+	Stderr     string    `json:"stderr"`
+	TimedOut   bool      `json:"timed_out"`
+	Truncated  bool      `json:"truncated"`
+	DurationMs int64     `json:"duration_ms"`
+	CreatedBy  string    `json:"created_by"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// ExecutionMetadata 表示消息元数据中的执行记录，只保留最近的若干次
+type ExecutionMetadata struct {
+	Executions []ExecutionResult `json:"executions,omitempty"`
+}
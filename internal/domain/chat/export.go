@@ -0,0 +1,58 @@
+package chat
+
+import "time"
+
+// ExportFormat 表示导出格式
+const (
+	ExportFormatMarkdown = "md"
+	ExportFormatJSON     = "json"
+	ExportFormatHTML     = "html"
+)
+
+// ExportAttachments 表示附件的导出方式
+const (
+	// ExportAttachmentsLink 以链接导出，存储在 MinIO 中的附件使用预签名链接
+	ExportAttachmentsLink = "link"
+	// ExportAttachmentsInline 将存储在 MinIO 中的附件内嵌到导出文件中
+	ExportAttachmentsInline = "inline"
+)
+
+// ExportOptions 表示导出选项
+type ExportOptions struct {
+	Format      string
+	Attachments string
+}
+
+// ExportedMessage 表示 JSON 导出中的一条消息
+type ExportedMessage struct {
+	ID          string                `json:"id"`
+	ParentID    *string               `json:"parent_id,omitempty"`
+	Role        string                `json:"role"`
+	Content     string                `json:"content"`
+	ModelID     string                `json:"model_id,omitempty"`
+	ModelName   string                `json:"model_name,omitempty"`
+	Attachments []*ExportedAttachment `json:"attachments,omitempty"`
+	CreatedAt   time.Time             `json:"created_at"`
+}
+
+// ExportedAttachment 表示导出的附件，内嵌时 Data 为 base64 编码的内容
+type ExportedAttachment struct {
+	Name     string `json:"name"`
+	MimeType string `json:"mime_type"`
+	Size     int64  `json:"size"`
+	URL      string `json:"url,omitempty"`
+	Data     string `json:"data,omitempty"`
+}
+
+// WorkspaceExportRequest 表示导出整个工作区的请求
+type WorkspaceExportRequest struct {
+	Format      string `json:"format" validate:"omitempty,oneof=md json html"`
+	Attachments string `json:"attachments" validate:"omitempty,oneof=link inline"`
+}
+
+// WorkspaceExportResult 表示工作区导出任务的结果
+type WorkspaceExportResult struct {
+	ObjectKey string    `json:"object_key"`
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
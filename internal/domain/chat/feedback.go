@@ -0,0 +1,92 @@
+package chat
+
+import (
+	"context"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// FeedbackRating 表示反馈评价
+const (
+	FeedbackRatingUp   = "up"
+	FeedbackRatingDown = "down"
+)
+
+// FeedbackReason 表示反馈原因代码
+const (
+	FeedbackReasonAccurate      = "accurate"
+	FeedbackReasonHelpful       = "helpful"
+	FeedbackReasonWellWritten   = "well_written"
+	FeedbackReasonInaccurate    = "inaccurate"
+	FeedbackReasonUnhelpful     = "unhelpful"
+	FeedbackReasonIncomplete    = "incomplete"
+	FeedbackReasonOffTopic      = "off_topic"
+	FeedbackReasonTooVerbose    = "too_verbose"
+	FeedbackReasonUnsafe        = "unsafe"
+	FeedbackReasonBadFormatting = "bad_formatting"
+	FeedbackReasonOther         = "other"
+)
+
+// FeedbackGroupBy 表示反馈统计的分组方式
+const (
+	FeedbackGroupByModel    = "model"
+	FeedbackGroupByProvider = "provider"
+	FeedbackGroupByCanvas   = "canvas"
+	FeedbackGroupByDay      = "day"
+	FeedbackGroupByWeek     = "week"
+	FeedbackGroupByMonth    = "month"
+)
+
+// Feedback 表示用户对助手消息的反馈
+type Feedback struct {
+	ID        string         `json:"id" db:"id"`
+	TenantID  string         `json:"tenant_id" db:"tenant_id"`
+	MessageID string         `json:"message_id" db:"message_id"`
+	CanvasID  string         `json:"canvas_id" db:"canvas_id"`
+	UserID    string         `json:"user_id" db:"user_id"`
+	ModelID   *string        `json:"model_id,omitempty" db:"model_id"`
+	Rating    string         `json:"rating" db:"rating"`
+	Reasons   pq.StringArray `json:"reasons" db:"reasons"`
+	Comment   *string        `json:"comment,omitempty" db:"comment"`
+	CreatedAt time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at" db:"updated_at"`
+}
+
+// FeedbackRequest 表示提交或修改反馈的请求
+type FeedbackRequest struct {
+	Rating  string   `json:"rating" validate:"required,oneof=up down"`
+	Reasons []string `json:"reasons,omitempty"`
+	Comment *string  `json:"comment,omitempty"`
+}
+
+// FeedbackStatsRequest 表示反馈统计的请求，时间范围为左闭右开
+type FeedbackStatsRequest struct {
+	GroupBy     string
+	WorkspaceID *string
+	CanvasID    *string
+	ModelID     *string
+	From        *time.Time
+	To          *time.Time
+	Limit       int
+}
+
+// FeedbackStat 表示一个分组的反馈统计
+// Key 为模型、提供商或画布的 ID，按时间分组时为时间段起始日期；Label 为对应的名称
+type FeedbackStat struct {
+	Key          string         `json:"key" db:"key"`
+	Label        string         `json:"label" db:"label"`
+	Up           int            `json:"up" db:"up"`
+	Down         int            `json:"down" db:"down"`
+	Total        int            `json:"total" db:"total"`
+	Satisfaction float64        `json:"satisfaction" db:"-"`
+	Reasons      map[string]int `json:"reasons" db:"-"`
+}
+
+// FeedbackRepository 表示反馈仓库接口
+type FeedbackRepository interface {
+	Upsert(ctx context.Context, feedback *Feedback) error
+	Get(ctx context.Context, messageID, userID string) (*Feedback, error)
+	Delete(ctx context.Context, messageID, userID string) (bool, error)
+	Stats(ctx context.Context, req *FeedbackStatsRequest) ([]*FeedbackStat, error)
+}
@@ -0,0 +1,54 @@
+package chat
+
+import (
+	"context"
+	"time"
+)
+
+// ImportSource 表示导入来源的格式
+const (
+	// ImportSourceChatGPT 是 ChatGPT 导出的 conversations.json
+	ImportSourceChatGPT = "chatgpt"
+	// ImportSourceOpenAI 是 OpenAI 风格的消息 JSON
+	ImportSourceOpenAI = "openai"
+)
+
+// CanvasImport 表示导入对话的来源记录，用于重复检测
+type CanvasImport struct {
+	ID          string    `json:"id" db:"id"`
+	TenantID    string    `json:"tenant_id" db:"tenant_id"`
+	WorkspaceID string    `json:"workspace_id" db:"workspace_id"`
+	CanvasID    string    `json:"canvas_id" db:"canvas_id"`
+	Source      string    `json:"source" db:"source"`
+	ExternalID  string    `json:"external_id" db:"external_id"`
+	CreatedBy   string    `json:"created_by" db:"created_by"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+// ImportMetadata 表示导入消息的元数据
+type ImportMetadata struct {
+	Source      string `json:"import_source"`
+	SourceID    string `json:"import_source_id,omitempty"`
+	SourceModel string `json:"import_source_model,omitempty"`
+}
+
+// ImportRequest 表示导入对话的请求，Format 为空时根据内容识别
+type ImportRequest struct {
+	Format string
+	Data   []byte
+}
+
+// ImportResult 表示导入任务的结果
+type ImportResult struct {
+	Imported   int      `json:"imported"`
+	Duplicates int      `json:"duplicates"`
+	Failed     int      `json:"failed"`
+	CanvasIDs  []string `json:"canvas_ids"`
+	Errors     []string `json:"errors,omitempty"`
+}
+
+// ImportRepository 表示导入记录仓库接口
+type ImportRepository interface {
+	Exists(ctx context.Context, workspaceID, source, externalID string) (bool, error)
+	Create(ctx context.Context, record *CanvasImport) error
+}
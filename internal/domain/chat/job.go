@@ -0,0 +1,35 @@
+package chat
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// JobType 表示后台任务类型
+const (
+	JobTypeWorkspaceExport = "workspace_export"
+	JobTypeImport          = "import"
+)
+
+// JobStatus 表示后台任务状态
+const (
+	JobStatusPending   = "pending"
+	JobStatusRunning   = "running"
+	JobStatusCompleted = "completed"
+	JobStatusFailed    = "failed"
+)
+
+// Job 表示后台任务，Progress/Total 为已处理数和总数，Result 为任务类型相关的结果
+type Job struct {
+	ID        string          `json:"id"`
+	TenantID  string          `json:"tenant_id"`
+	Type      string          `json:"type"`
+	Status    string          `json:"status"`
+	Progress  int             `json:"progress"`
+	Total     int             `json:"total"`
+	Error     string          `json:"error,omitempty"`
+	Result    json.RawMessage `json:"result,omitempty"`
+	CreatedBy string          `json:"created_by"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
@@ -1,6 +1,7 @@
 package chat
 
 import (
+	"context"
 	"encoding/json"
 	"time"
 )
@@ -8,12 +9,15 @@ import (
 // Message 表示消息实体
 type Message struct {
 	ID         string          `json:"id" db:"id"`
+	TenantID   string          `json:"tenant_id" db:"tenant_id"`
 	CanvasID   string          `json:"canvas_id" db:"canvas_id"`
 	ParentID   *string         `json:"parent_id,omitempty" db:"parent_id"`
 	Role       string          `json:"role" db:"role"`
 	Content    string          `json:"content" db:"content"`
 	Metadata   json.RawMessage `json:"metadata,omitempty" db:"metadata"`
 	TokenCount *int            `json:"token_count,omitempty" db:"token_count"`
+	EditedAt   *time.Time      `json:"edited_at,omitempty" db:"edited_at"`
+	DeletedAt  *time.Time      `json:"deleted_at,omitempty" db:"deleted_at"`
 	CreatedBy  string          `json:"created_by" db:"created_by"`
 	CreatedAt  time.Time       `json:"created_at" db:"created_at"`
 }
@@ -25,19 +29,95 @@ const (
 	MessageRoleSystem    = "system"
 )
 
+// FinishReason 表示助手消息的结束原因
+const (
+	FinishReasonStop      = "stop"
+	FinishReasonLength    = "length"
+	FinishReasonToolCalls = "tool_calls"
+	FinishReasonCancelled = "cancelled"
+	FinishReasonError     = "error"
+)
+
+// AssistantMetadata 表示助手消息的元数据
+// 对比模式生成的回复带有 CompareID，同一次对比的回复相同；被选中继续对话的回复 Selected 为 true
+type AssistantMetadata struct {
+	ModelID      string      `json:"model_id,omitempty"`
+	FinishReason string      `json:"finish_reason"`
+	Usage        *TokenUsage `json:"usage,omitempty"`
+	CompareID    string      `json:"compare_id,omitempty"`
+	Selected     bool        `json:"selected,omitempty"`
+}
+
 // SendMessageRequest 表示发送消息的请求
 type SendMessageRequest struct {
 	Content  string          `json:"content" validate:"required"`
 	ParentID *string         `json:"parent_id,omitempty" validate:"omitempty,uuid"`
 	Metadata json.RawMessage `json:"metadata,omitempty"`
+	// Settings 仅对本条消息生效，覆盖画布的生成设置
+	Settings *GenerationSettings `json:"settings,omitempty"`
+}
+
+// EditMessageRequest 表示编辑消息的请求，编辑会创建一个新的兄弟消息
+type EditMessageRequest struct {
+	Content  string          `json:"content" validate:"required"`
+	Metadata json.RawMessage `json:"metadata,omitempty"`
+}
+
+// UpdateMessageRequest 表示原地编辑消息的请求，编辑前的内容保存为修订版本
+type UpdateMessageRequest struct {
+	Content  string          `json:"content" validate:"required"`
+	Metadata json.RawMessage `json:"metadata,omitempty"`
+}
+
+// MessageRevision 表示消息被编辑前的一个版本
+// Revision 从 1 开始递增，1 为消息的原始内容；CreatedBy 与 CreatedAt 为该版本的作者和写入时间
+type MessageRevision struct {
+	ID        string          `json:"id" db:"id"`
+	TenantID  string          `json:"tenant_id" db:"tenant_id"`
+	MessageID string          `json:"message_id" db:"message_id"`
+	Revision  int             `json:"revision" db:"revision"`
+	Content   string          `json:"content" db:"content"`
+	Metadata  json.RawMessage `json:"metadata,omitempty" db:"metadata"`
+	CreatedBy string          `json:"created_by" db:"created_by"`
+	CreatedAt time.Time       `json:"created_at" db:"created_at"`
+}
+
+// MessageRevisions 表示消息的当前内容及历史版本
+type MessageRevisions struct {
+	Message   *Message           `json:"message"`
+	Revisions []*MessageRevision `json:"revisions"`
+}
+
+// MessageNode 表示消息树中的节点
+type MessageNode struct {
+	*Message
+	SiblingIndex int            `json:"sibling_index"`
+	SiblingCount int            `json:"sibling_count"`
+	Active       bool           `json:"active"`
+	Children     []*MessageNode `json:"children,omitempty"`
+}
+
+// MessageTree 表示画布的消息树
+type MessageTree struct {
+	CanvasID     string         `json:"canvas_id"`
+	ActiveLeafID *string        `json:"active_leaf_id,omitempty"`
+	Roots        []*MessageNode `json:"roots"`
 }
 
 // MessageRepository 表示消息仓库接口
 type MessageRepository interface {
-	Create(message *Message) error
-	GetByID(id string) (*Message, error)
-	GetByCanvasID(canvasID string, offset, limit int) ([]*Message, int, error)
-	GetConversation(messageID string, limit int) ([]*Message, error)
+	Create(ctx context.Context, message *Message) error
+	GetByID(ctx context.Context, id string) (*Message, error)
+	GetByCanvasID(ctx context.Context, canvasID string, offset, limit int) ([]*Message, int, error)
+	ListByCanvasID(ctx context.Context, canvasID string) ([]*Message, error)
+	GetConversation(ctx context.Context, messageID string, limit int) ([]*Message, error)
+	GetBranch(ctx context.Context, messageID string) ([]*Message, error)
+	CreateBatch(ctx context.Context, messages []*Message) error
+	UpdateContent(ctx context.Context, id, content string, metadata json.RawMessage, editedBy string) (*Message, error)
+	SoftDelete(ctx context.Context, id, deletedBy string, prune bool) ([]string, error)
+	ListRevisions(ctx context.Context, messageID string) ([]*MessageRevision, error)
+	AppendExecution(ctx context.Context, id string, execution json.RawMessage, keep int) (*Message, error)
+	SelectCompareReply(ctx context.Context, canvasID, compareID, id string) ([]*Message, error)
 }
 
 // MessageService 表示消息服务接口
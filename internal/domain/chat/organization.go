@@ -0,0 +1,132 @@
+package chat
+
+import (
+	"context"
+	"time"
+)
+
+// Folder 表示工作区内的画布文件夹，ParentID 为空表示顶层文件夹
+type Folder struct {
+	ID          string    `json:"id" db:"id"`
+	TenantID    string    `json:"tenant_id" db:"tenant_id"`
+	WorkspaceID string    `json:"workspace_id" db:"workspace_id"`
+	ParentID    *string   `json:"parent_id,omitempty" db:"parent_id"`
+	Name        string    `json:"name" db:"name"`
+	CreatedBy   string    `json:"created_by" db:"created_by"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Tag 表示用户定义的画布标签，与自动生成的 TopicTags 相互独立
+type Tag struct {
+	ID          string    `json:"id" db:"id"`
+	TenantID    string    `json:"tenant_id" db:"tenant_id"`
+	WorkspaceID string    `json:"workspace_id" db:"workspace_id"`
+	Name        string    `json:"name" db:"name"`
+	Color       *string   `json:"color,omitempty" db:"color"`
+	CreatedBy   string    `json:"created_by" db:"created_by"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+// CanvasTag 表示画布与标签的关联
+type CanvasTag struct {
+	CanvasID string `db:"canvas_id"`
+	Tag
+}
+
+// CreateFolderRequest 表示创建文件夹的请求
+type CreateFolderRequest struct {
+	Name     string  `json:"name" validate:"required"`
+	ParentID *string `json:"parent_id,omitempty" validate:"omitempty,uuid"`
+}
+
+// UpdateFolderRequest 表示修改文件夹的请求
+// ParentID 传入空字符串表示移动到顶层
+type UpdateFolderRequest struct {
+	Name     *string `json:"name,omitempty"`
+	ParentID *string `json:"parent_id,omitempty"`
+}
+
+// CreateTagRequest 表示创建标签的请求
+type CreateTagRequest struct {
+	Name  string  `json:"name" validate:"required"`
+	Color *string `json:"color,omitempty"`
+}
+
+// UpdateTagRequest 表示修改标签的请求，Color 传入空字符串表示清除颜色
+type UpdateTagRequest struct {
+	Name  *string `json:"name,omitempty"`
+	Color *string `json:"color,omitempty"`
+}
+
+// MoveCanvasRequest 表示将画布移动到文件夹的请求，FolderID 为空表示移动到顶层
+type MoveCanvasRequest struct {
+	FolderID *string `json:"folder_id" validate:"omitempty,uuid"`
+}
+
+// SetCanvasTagsRequest 表示设置画布标签的请求，传入的标签替换画布现有的标签
+type SetCanvasTagsRequest struct {
+	TagIDs []string `json:"tag_ids"`
+}
+
+// CanvasSort 表示画布列表的排序字段
+const (
+	CanvasSortCreatedAt     = "created_at"
+	CanvasSortUpdatedAt     = "updated_at"
+	CanvasSortLastMessageAt = "last_message_at"
+	CanvasSortTitle         = "title"
+	CanvasSortPinned        = "pinned"
+	CanvasSortFavorite      = "favorite"
+)
+
+// SortOrder 表示排序方向
+const (
+	SortOrderAsc  = "asc"
+	SortOrderDesc = "desc"
+)
+
+// CanvasFolderRoot 是文件夹过滤条件中表示顶层（不在任何文件夹中）的取值
+const CanvasFolderRoot = "root"
+
+// CanvasListRequest 表示列出画布的过滤和排序条件
+// TagIDs 要求画布同时带有全部标签，Favorite 按 UserID 的收藏过滤
+type CanvasListRequest struct {
+	WorkspaceID string   `json:"workspace_id"`
+	UserID      string   `json:"-"`
+	Type        *string  `json:"type,omitempty"`
+	Status      *string  `json:"status,omitempty"`
+	FolderID    *string  `json:"folder_id,omitempty"`
+	TagIDs      []string `json:"tag_ids,omitempty"`
+	Pinned      *bool    `json:"pinned,omitempty"`
+	Favorite    *bool    `json:"favorite,omitempty"`
+	Sort        string   `json:"sort,omitempty"`
+	Order       string   `json:"order,omitempty"`
+	Offset      int      `json:"-"`
+	Limit       int      `json:"-"`
+}
+
+// FolderRepository 表示文件夹仓库接口
+type FolderRepository interface {
+	Create(ctx context.Context, folder *Folder) error
+	GetByID(ctx context.Context, id string) (*Folder, error)
+	Update(ctx context.Context, folder *Folder) error
+	// Delete 删除文件夹，其中的子文件夹和画布移动到被删除文件夹的父文件夹下
+	Delete(ctx context.Context, id string) error
+	ListByWorkspace(ctx context.Context, workspaceID string) ([]*Folder, error)
+	// IsDescendant 判断 folderID 是否为 ancestorID 本身或其子孙文件夹
+	IsDescendant(ctx context.Context, folderID, ancestorID string) (bool, error)
+}
+
+// TagRepository 表示标签仓库接口
+type TagRepository interface {
+	Create(ctx context.Context, tag *Tag) error
+	GetByID(ctx context.Context, id string) (*Tag, error)
+	Update(ctx context.Context, tag *Tag) error
+	Delete(ctx context.Context, id string) error
+	ListByWorkspace(ctx context.Context, workspaceID string) ([]*Tag, error)
+	// CountInWorkspace 统计给定标签中属于工作区的数量
+	CountInWorkspace(ctx context.Context, workspaceID string, ids []string) (int, error)
+	// SetCanvasTags 替换画布的标签
+	SetCanvasTags(ctx context.Context, canvasID string, tagIDs []string) error
+	ListByCanvasIDs(ctx context.Context, canvasIDs []string) ([]*CanvasTag, error)
+}
@@ -0,0 +1,187 @@
+package chat
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// PromptScope 表示提示词模板的可见范围
+const (
+	PromptScopeUser      = "user"
+	PromptScopeWorkspace = "workspace"
+	PromptScopeTenant    = "tenant"
+)
+
+// PromptRole 表示模板渲染后的用途：system 作为画布系统提示词，user 作为第一条用户消息
+const (
+	PromptRoleSystem = "system"
+	PromptRoleUser   = "user"
+)
+
+// PromptSort 表示提示词模板列表的排序字段
+const (
+	PromptSortUpdatedAt = "updated_at"
+	PromptSortUsage     = "usage"
+	PromptSortTitle     = "title"
+)
+
+// PromptVariable 表示模板中的变量，如 {{language}}
+// 未声明但出现在模板内容中的变量自动补充为必填变量
+type PromptVariable struct {
+	Name        string  `json:"name"`
+	Description *string `json:"description,omitempty"`
+	Default     *string `json:"default,omitempty"`
+	Required    bool    `json:"required"`
+}
+
+// PromptVariables 表示模板的变量列表，以 JSONB 存储
+type PromptVariables []PromptVariable
+
+// Value 实现 driver.Valuer 接口，以 JSONB 存储
+func (v PromptVariables) Value() (driver.Value, error) {
+	if v == nil {
+		v = PromptVariables{}
+	}
+	return json.Marshal(v)
+}
+
+// Scan 实现 sql.Scanner 接口
+func (v *PromptVariables) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case nil:
+		*v = PromptVariables{}
+		return nil
+	case []byte:
+		return json.Unmarshal(s, v)
+	case string:
+		return json.Unmarshal([]byte(s), v)
+	default:
+		return fmt.Errorf("无法扫描模板变量: %T", src)
+	}
+}
+
+// PromptTemplate 表示提示词库中的模板，Version 为当前版本号
+type PromptTemplate struct {
+	ID          string          `json:"id" db:"id"`
+	TenantID    string          `json:"tenant_id" db:"tenant_id"`
+	Scope       string          `json:"scope" db:"scope"`
+	WorkspaceID *string         `json:"workspace_id,omitempty" db:"workspace_id"`
+	Title       string          `json:"title" db:"title"`
+	Description *string         `json:"description,omitempty" db:"description"`
+	Content     string          `json:"content" db:"content"`
+	Role        string          `json:"role" db:"role"`
+	Variables   PromptVariables `json:"variables" db:"variables"`
+	Version     int             `json:"version" db:"version"`
+	UsageCount  int             `json:"usage_count" db:"usage_count"`
+	SharedWith  []string        `json:"shared_with,omitempty" db:"-"`
+	CreatedBy   string          `json:"created_by" db:"created_by"`
+	CreatedAt   time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at" db:"updated_at"`
+}
+
+// PromptTemplateVersion 表示模板的一个历史版本
+type PromptTemplateVersion struct {
+	ID         string          `json:"id" db:"id"`
+	TenantID   string          `json:"tenant_id" db:"tenant_id"`
+	TemplateID string          `json:"template_id" db:"template_id"`
+	Version    int             `json:"version" db:"version"`
+	Content    string          `json:"content" db:"content"`
+	Variables  PromptVariables `json:"variables" db:"variables"`
+	CreatedBy  string          `json:"created_by" db:"created_by"`
+	CreatedAt  time.Time       `json:"created_at" db:"created_at"`
+}
+
+// CreatePromptRequest 表示创建提示词模板的请求
+type CreatePromptRequest struct {
+	Scope       string          `json:"scope" validate:"required,oneof=user workspace tenant"`
+	WorkspaceID *string         `json:"workspace_id,omitempty" validate:"omitempty,uuid"`
+	Title       string          `json:"title" validate:"required"`
+	Description *string         `json:"description,omitempty"`
+	Content     string          `json:"content" validate:"required"`
+	Role        string          `json:"role,omitempty" validate:"omitempty,oneof=system user"`
+	Variables   PromptVariables `json:"variables,omitempty"`
+}
+
+// UpdatePromptRequest 表示修改提示词模板的请求，修改内容或变量会生成新版本
+type UpdatePromptRequest struct {
+	Scope       *string          `json:"scope,omitempty" validate:"omitempty,oneof=user workspace tenant"`
+	WorkspaceID *string          `json:"workspace_id,omitempty" validate:"omitempty,uuid"`
+	Title       *string          `json:"title,omitempty"`
+	Description *string          `json:"description,omitempty"`
+	Content     *string          `json:"content,omitempty"`
+	Role        *string          `json:"role,omitempty" validate:"omitempty,oneof=system user"`
+	Variables   *PromptVariables `json:"variables,omitempty"`
+}
+
+// RenderPromptRequest 表示渲染提示词模板的请求，Version 为空时使用当前版本
+type RenderPromptRequest struct {
+	Version   *int           `json:"version,omitempty"`
+	Variables map[string]any `json:"variables,omitempty"`
+}
+
+// RenderPromptResponse 表示模板渲染结果
+type RenderPromptResponse struct {
+	Version int    `json:"version"`
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// SharePromptRequest 表示设置模板分享对象的请求，传入的用户替换现有的分享对象
+type SharePromptRequest struct {
+	UserIDs []string `json:"user_ids"`
+}
+
+// StartCanvasFromPromptRequest 表示从提示词模板创建画布的请求
+// Title 为空时使用模板标题
+type StartCanvasFromPromptRequest struct {
+	RenderPromptRequest
+	WorkspaceID string              `json:"workspace_id" validate:"required,uuid"`
+	FolderID    *string             `json:"folder_id,omitempty" validate:"omitempty,uuid"`
+	Title       *string             `json:"title,omitempty"`
+	Type        string              `json:"type,omitempty" validate:"omitempty,oneof=chat code"`
+	ModelID     *string             `json:"model_id,omitempty" validate:"omitempty,uuid"`
+	Settings    *GenerationSettings `json:"settings,omitempty"`
+}
+
+// StartCanvasFromPromptResponse 表示从模板创建画布的结果
+// 用户消息类模板会立即发送第一条消息，Message 为 AI 的回复
+type StartCanvasFromPromptResponse struct {
+	Canvas  *Canvas  `json:"canvas"`
+	Message *Message `json:"message,omitempty"`
+}
+
+// PromptListRequest 表示列出提示词模板的过滤和排序条件
+// 返回当前用户可见的模板：租户和工作区模板、自己创建的和分享给自己的个人模板
+// 指定 WorkspaceID 时只包含该工作区的工作区模板
+type PromptListRequest struct {
+	UserID      string  `json:"-"`
+	Scope       *string `json:"scope,omitempty"`
+	WorkspaceID *string `json:"workspace_id,omitempty"`
+	Query       string  `json:"q,omitempty"`
+	Sort        string  `json:"sort,omitempty"`
+	Offset      int     `json:"-"`
+	Limit       int     `json:"-"`
+}
+
+// PromptRepository 表示提示词模板仓库接口
+type PromptRepository interface {
+	// Create 创建模板并保存其第一个版本
+	Create(ctx context.Context, template *PromptTemplate) error
+	GetByID(ctx context.Context, id string) (*PromptTemplate, error)
+	// Update 保存模板，newVersion 为真时同时保存一个新版本
+	Update(ctx context.Context, template *PromptTemplate, newVersion bool) error
+	Delete(ctx context.Context, id string) error
+	List(ctx context.Context, req *PromptListRequest) ([]*PromptTemplate, int, error)
+	ListVersions(ctx context.Context, templateID string) ([]*PromptTemplateVersion, error)
+	GetVersion(ctx context.Context, templateID string, version int) (*PromptTemplateVersion, error)
+	// IncrementUsage 累加模板的使用次数
+	IncrementUsage(ctx context.Context, id string) error
+	// IsSharedWith 判断模板是否分享给了用户
+	IsSharedWith(ctx context.Context, id, userID string) (bool, error)
+	ListShares(ctx context.Context, id string) ([]string, error)
+	// SetShares 替换模板的分享对象
+	SetShares(ctx context.Context, id, createdBy string, userIDs []string) error
+}
@@ -0,0 +1,50 @@
+package chat
+
+import (
+	"context"
+	"time"
+)
+
+// SearchKind 表示搜索结果类型
+const (
+	SearchKindCanvas  = "canvas"
+	SearchKindMessage = "message"
+)
+
+// SearchMode 表示搜索方式
+const (
+	// SearchModeKeyword 按关键词全文检索
+	SearchModeKeyword = "keyword"
+	// SearchModeSemantic 按向量相似度检索，可以匹配措辞不同但含义相近的内容
+	SearchModeSemantic = "semantic"
+)
+
+// SearchRequest 表示搜索请求，WorkspaceID、CanvasID 和 Kind 为可选的过滤条件
+type SearchRequest struct {
+	Query       string
+	Mode        string
+	WorkspaceID *string
+	CanvasID    *string
+	Kind        *string
+	Offset      int
+	Limit       int
+}
+
+// SearchResult 表示一条搜索结果
+// 消息结果携带 MessageID，客户端可据此跳转到对话中的该消息
+type SearchResult struct {
+	Kind        string    `json:"kind" db:"kind"`
+	CanvasID    string    `json:"canvas_id" db:"canvas_id"`
+	CanvasTitle string    `json:"canvas_title" db:"canvas_title"`
+	MessageID   *string   `json:"message_id,omitempty" db:"message_id"`
+	Role        *string   `json:"role,omitempty" db:"role"`
+	Content     string    `json:"-" db:"content"`
+	Snippet     string    `json:"snippet" db:"-"`
+	Rank        float64   `json:"rank" db:"rank"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+// SearchRepository 表示搜索仓库接口
+type SearchRepository interface {
+	Search(ctx context.Context, req *SearchRequest) ([]*SearchResult, int, error)
+}
@@ -0,0 +1,84 @@
+package chat
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// GenerationSettings 表示生成设置，未设置的字段使用模型或租户的默认值
+type GenerationSettings struct {
+	SystemPrompt    *string  `json:"system_prompt,omitempty"`
+	Temperature     *float64 `json:"temperature,omitempty"`
+	TopP            *float64 `json:"top_p,omitempty"`
+	MaxOutputTokens *int     `json:"max_output_tokens,omitempty"`
+	Stop            []string `json:"stop,omitempty"`
+	Seed            *int     `json:"seed,omitempty"`
+}
+
+// Merge 返回以 override 中已设置的字段覆盖后的设置，不修改原设置
+func (s GenerationSettings) Merge(override *GenerationSettings) GenerationSettings {
+	if override == nil {
+		return s
+	}
+	if override.SystemPrompt != nil {
+		s.SystemPrompt = override.SystemPrompt
+	}
+	if override.Temperature != nil {
+		s.Temperature = override.Temperature
+	}
+	if override.TopP != nil {
+		s.TopP = override.TopP
+	}
+	if override.MaxOutputTokens != nil {
+		s.MaxOutputTokens = override.MaxOutputTokens
+	}
+	if override.Stop != nil {
+		s.Stop = override.Stop
+	}
+	if override.Seed != nil {
+		s.Seed = override.Seed
+	}
+	return s
+}
+
+// Params 转换为提供商调用参数，数值统一使用 float64，与 JSON 解码结果一致
+func (s GenerationSettings) Params() map[string]interface{} {
+	params := make(map[string]interface{})
+	if s.Temperature != nil {
+		params["temperature"] = *s.Temperature
+	}
+	if s.TopP != nil {
+		params["top_p"] = *s.TopP
+	}
+	if s.MaxOutputTokens != nil {
+		params["max_tokens"] = float64(*s.MaxOutputTokens)
+	}
+	if len(s.Stop) > 0 {
+		params["stop"] = s.Stop
+	}
+	if s.Seed != nil {
+		params["seed"] = float64(*s.Seed)
+	}
+	return params
+}
+
+// Value 实现 driver.Valuer 接口，以 JSONB 存储
+func (s GenerationSettings) Value() (driver.Value, error) {
+	return json.Marshal(s)
+}
+
+// Scan 实现 sql.Scanner 接口
+func (s *GenerationSettings) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*s = GenerationSettings{}
+		return nil
+	case []byte:
+		return json.Unmarshal(v, s)
+	case string:
+		return json.Unmarshal([]byte(v), s)
+	default:
+		return fmt.Errorf("无法扫描生成设置: %T", src)
+	}
+}
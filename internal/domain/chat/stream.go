@@ -0,0 +1,40 @@
+package chat
+
+// StreamEventType 表示流式事件类型
+const (
+	StreamEventStart    = "start"
+	StreamEventDelta    = "delta"
+	StreamEventUsage    = "usage"
+	StreamEventToolCall = "tool_call"
+	StreamEventError    = "error"
+	StreamEventDone     = "done"
+)
+
+// StreamEvent 表示流式响应中的一条事件
+// start 携带消息标识，delta 只携带新增的文本，done 携带结束原因，error 携带错误信息
+type StreamEvent struct {
+	Type         string         `json:"type"`
+	MessageID    string         `json:"message_id,omitempty"`
+	ParentID     *string        `json:"parent_id,omitempty"`
+	ModelID      string         `json:"model_id,omitempty"`
+	Delta        string         `json:"delta,omitempty"`
+	Usage        *TokenUsage    `json:"usage,omitempty"`
+	ToolCall     *ToolCallDelta `json:"tool_call,omitempty"`
+	FinishReason string         `json:"finish_reason,omitempty"`
+	Error        string         `json:"error,omitempty"`
+}
+
+// TokenUsage 表示一次生成的 token 用量
+type TokenUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// ToolCallDelta 表示工具调用的增量，同一 Index 的增量按顺序拼接即为完整调用
+type ToolCallDelta struct {
+	Index     int    `json:"index"`
+	ID        string `json:"id,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments,omitempty"`
+}
@@ -0,0 +1,26 @@
+package chat
+
+import (
+	"context"
+	"time"
+)
+
+// ConversationSummary 表示对话摘要，覆盖从根消息到 CoveredMessageID 的分支前缀
+type ConversationSummary struct {
+	ID               string    `json:"id" db:"id"`
+	TenantID         string    `json:"tenant_id" db:"tenant_id"`
+	CanvasID         string    `json:"canvas_id" db:"canvas_id"`
+	CoveredMessageID string    `json:"covered_message_id" db:"covered_message_id"`
+	MessageCount     int       `json:"message_count" db:"message_count"`
+	Content          string    `json:"content" db:"content"`
+	Checksum         string    `json:"checksum" db:"checksum"`
+	ModelID          *string   `json:"model_id,omitempty" db:"model_id"`
+	CreatedAt        time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// SummaryRepository 表示对话摘要仓库接口
+type SummaryRepository interface {
+	Upsert(ctx context.Context, summary *ConversationSummary) error
+	ListByCoveredMessageIDs(ctx context.Context, canvasID string, messageIDs []string) ([]*ConversationSummary, error)
+}
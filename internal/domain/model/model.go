@@ -1,6 +1,7 @@
 package model
 
 import (
+	"context"
 	"encoding/json"
 	"time"
 )
@@ -14,12 +15,32 @@ type Model struct {
 	Description *string         `json:"description,omitempty" db:"description"`
 	Capabilities json.RawMessage `json:"capabilities" db:"capabilities"`
 	Parameters  json.RawMessage `json:"parameters" db:"parameters"`
+	MaxTokens   *int            `json:"max_tokens,omitempty" db:"max_tokens"`
 	Status      string          `json:"status" db:"status"`
 	IsPublic    bool            `json:"is_public" db:"is_public"`
 	CreatedAt   time.Time       `json:"created_at" db:"created_at"`
 	UpdatedAt   time.Time       `json:"updated_at" db:"updated_at"`
 }
 
+// ContextWindow 返回模型的上下文窗口大小，未配置时返回 fallback
+func (m *Model) ContextWindow(fallback int) int {
+	if m.MaxTokens != nil && *m.MaxTokens > 0 {
+		return *m.MaxTokens
+	}
+	return fallback
+}
+
+// MaxOutputTokens 返回模型参数中配置的最大输出 token 数，未配置时返回 fallback
+func (m *Model) MaxOutputTokens(fallback int) int {
+	var params struct {
+		MaxTokens int `json:"max_tokens"`
+	}
+	if len(m.Parameters) > 0 && json.Unmarshal(m.Parameters, &params) == nil && params.MaxTokens > 0 {
+		return params.MaxTokens
+	}
+	return fallback
+}
+
 // ModelStatus 表示模型状态
 const (
 	ModelStatusActive   = "active"
@@ -34,6 +55,7 @@ type CreateModelRequest struct {
 	Description  *string         `json:"description,omitempty"`
 	Capabilities json.RawMessage `json:"capabilities" validate:"required"`
 	Parameters   json.RawMessage `json:"parameters" validate:"required"`
+	MaxTokens    *int            `json:"max_tokens,omitempty" validate:"omitempty,min=1"`
 	IsPublic     bool            `json:"is_public"`
 }
 
@@ -43,17 +65,18 @@ type UpdateModelRequest struct {
 	Description  *string         `json:"description,omitempty"`
 	Capabilities *json.RawMessage `json:"capabilities,omitempty"`
 	Parameters   *json.RawMessage `json:"parameters,omitempty"`
+	MaxTokens    *int            `json:"max_tokens,omitempty" validate:"omitempty,min=1"`
 	Status       *string         `json:"status,omitempty" validate:"omitempty,oneof=active inactive"`
 	IsPublic     *bool           `json:"is_public,omitempty"`
 }
 
 // ModelRepository 表示模型仓库接口
 type ModelRepository interface {
-	Create(model *Model) error
-	GetByID(id string) (*Model, error)
-	Update(model *Model) error
-	Delete(id string) error
-	List(providerID *string, status *string, offset, limit int) ([]*Model, int, error)
+	Create(ctx context.Context, model *Model) error
+	GetByID(ctx context.Context, id string) (*Model, error)
+	Update(ctx context.Context, model *Model) error
+	Delete(ctx context.Context, id string) error
+	List(ctx context.Context, providerID *string, status *string, offset, limit int) ([]*Model, int, error)
 }
 
 // ModelService 表示模型服务接口
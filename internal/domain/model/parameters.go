@@ -0,0 +1,41 @@
+package model
+
+import "encoding/json"
+
+// ParameterRange 表示数值参数的取值范围
+type ParameterRange struct {
+	Min *float64 `json:"min,omitempty"`
+	Max *float64 `json:"max,omitempty"`
+}
+
+// Contains 判断取值是否在范围内
+func (r *ParameterRange) Contains(v float64) bool {
+	if r.Min != nil && v < *r.Min {
+		return false
+	}
+	if r.Max != nil && v > *r.Max {
+		return false
+	}
+	return true
+}
+
+// ModelParameters 表示模型在 Parameters 中声明的可调参数，未声明的参数视为不支持
+// 例如：{"max_tokens": 4096, "temperature": {"min": 0, "max": 2}, "top_p": {"min": 0, "max": 1}, "max_stop": 4, "seed": true}
+type ModelParameters struct {
+	MaxTokens   int             `json:"max_tokens"`
+	Temperature *ParameterRange `json:"temperature"`
+	TopP        *ParameterRange `json:"top_p"`
+	MaxStop     int             `json:"max_stop"`
+	Seed        bool            `json:"seed"`
+}
+
+// SupportedParameters 解析模型声明的可调参数，解析失败时视为未声明任何参数
+func (m *Model) SupportedParameters() ModelParameters {
+	var params ModelParameters
+	if len(m.Parameters) > 0 {
+		if err := json.Unmarshal(m.Parameters, &params); err != nil {
+			return ModelParameters{}
+		}
+	}
+	return params
+}
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"context"
 	"time"
 )
 
@@ -42,12 +43,12 @@ type UpdateProviderRequest struct {
 
 // ProviderRepository 表示提供商仓库接口
 type ProviderRepository interface {
-	Create(provider *Provider) error
-	GetByID(id string) (*Provider, error)
-	GetByCode(code, tenantID string) (*Provider, error)
-	Update(provider *Provider) error
-	Delete(id string) error
-	List(tenantID string, offset, limit int) ([]*Provider, int, error)
+	Create(ctx context.Context, provider *Provider) error
+	GetByID(ctx context.Context, id string) (*Provider, error)
+	GetByCode(ctx context.Context, code, tenantID string) (*Provider, error)
+	Update(ctx context.Context, provider *Provider) error
+	Delete(ctx context.Context, id string) error
+	List(ctx context.Context, tenantID string, offset, limit int) ([]*Provider, int, error)
 }
 
 // ProviderService 表示提供商服务接口
@@ -0,0 +1,78 @@
+package user
+
+import (
+	"context"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// 租户设置的内置默认值
+const (
+	DefaultCanvasGreeting = "欢迎使用 Lyss Chat！我是您的 AI 助手，有什么可以帮您的吗？"
+	DefaultLanguage       = "zh-CN"
+	// DefaultTrashRetentionDays 是回收站中画布的默认保留天数
+	DefaultTrashRetentionDays = 30
+	// MaxTrashRetentionDays 是回收站中画布的最大保留天数
+	MaxTrashRetentionDays = 3650
+)
+
+// TenantSettings 表示租户级别的聊天设置
+type TenantSettings struct {
+	TenantID             string         `json:"tenant_id" db:"tenant_id"`
+	DefaultModelID       *string        `json:"default_model_id,omitempty" db:"default_model_id"`
+	DefaultSystemPrompt  *string        `json:"default_system_prompt,omitempty" db:"default_system_prompt"`
+	CanvasGreeting       *string        `json:"canvas_greeting,omitempty" db:"canvas_greeting"`
+	AllowedProviders     pq.StringArray `json:"allowed_providers" db:"allowed_providers"`
+	DefaultLanguage      string         `json:"default_language" db:"default_language"`
+	TrashRetentionDays   int            `json:"trash_retention_days" db:"trash_retention_days"`
+	CodeExecutionEnabled bool           `json:"code_execution_enabled" db:"code_execution_enabled"`
+	CreatedAt            time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt            time.Time      `json:"updated_at" db:"updated_at"`
+}
+
+// DefaultTenantSettings 返回租户尚未配置时使用的默认设置
+func DefaultTenantSettings(tenantID string) *TenantSettings {
+	greeting := DefaultCanvasGreeting
+	return &TenantSettings{
+		TenantID:           tenantID,
+		CanvasGreeting:     &greeting,
+		AllowedProviders:   pq.StringArray{},
+		DefaultLanguage:    DefaultLanguage,
+		TrashRetentionDays: DefaultTrashRetentionDays,
+	}
+}
+
+// IsProviderAllowed 判断提供商是否允许使用，列表为空表示不限制
+func (s *TenantSettings) IsProviderAllowed(code string) bool {
+	if len(s.AllowedProviders) == 0 {
+		return true
+	}
+	for _, allowed := range s.AllowedProviders {
+		if allowed == code {
+			return true
+		}
+	}
+	return false
+}
+
+// UpdateTenantSettingsRequest 表示更新租户设置的请求
+// CanvasGreeting 传入空字符串表示新画布不再插入欢迎语
+type UpdateTenantSettingsRequest struct {
+	DefaultModelID       *string   `json:"default_model_id,omitempty" validate:"omitempty,uuid"`
+	DefaultSystemPrompt  *string   `json:"default_system_prompt,omitempty"`
+	CanvasGreeting       *string   `json:"canvas_greeting,omitempty"`
+	AllowedProviders     *[]string `json:"allowed_providers,omitempty"`
+	DefaultLanguage      *string   `json:"default_language,omitempty"`
+	TrashRetentionDays   *int      `json:"trash_retention_days,omitempty"`
+	CodeExecutionEnabled *bool     `json:"code_execution_enabled,omitempty"`
+}
+
+// PermissionUpdateTenantSettings 是修改租户设置所需的权限，默认授予租户的系统管理员角色
+const PermissionUpdateTenantSettings = "tenant_settings:update"
+
+// TenantSettingsRepository 表示租户设置仓库接口
+type TenantSettingsRepository interface {
+	GetByTenantID(ctx context.Context, tenantID string) (*TenantSettings, error)
+	Upsert(ctx context.Context, settings *TenantSettings) error
+}
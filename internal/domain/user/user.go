@@ -1,6 +1,7 @@
 package user
 
 import (
+	"context"
 	"time"
 )
 
@@ -71,12 +72,13 @@ type LoginResponse struct {
 
 // Repository 表示用户仓库接口
 type Repository interface {
-	Create(user *User) error
-	GetByID(id string) (*User, error)
-	GetByEmail(email, tenantID string) (*User, error)
-	Update(user *User) error
-	Delete(id string) error
-	List(tenantID string, offset, limit int) ([]*User, int, error)
+	Create(ctx context.Context, user *User) error
+	GetByID(ctx context.Context, id string) (*User, error)
+	GetByEmail(ctx context.Context, email, tenantID string) (*User, error)
+	Update(ctx context.Context, user *User) error
+	Delete(ctx context.Context, id string) error
+	List(ctx context.Context, tenantID string, offset, limit int) ([]*User, int, error)
+	HasPermission(ctx context.Context, userID, code string) (bool, error)
 }
 
 // Service 表示用户服务接口
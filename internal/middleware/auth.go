@@ -5,9 +5,11 @@ import (
 	"errors"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/zhuiye8/Lyss-chat-server/pkg/config"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/db"
 )
 
 // contextKey 是用于上下文的键类型
@@ -19,6 +21,9 @@ const UserIDKey contextKey = "user_id"
 // TenantIDKey 是租�?ID 的上下文�?
 const TenantIDKey contextKey = "tenant_id"
 
+// TokenExpiryKey 是认证令牌过期时间的上下文键
+const TokenExpiryKey contextKey = "token_expiry"
+
 // Auth 创建一个认证中间件
 func Auth(cfg *config.Config) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -32,34 +37,49 @@ func Auth(cfg *config.Config) func(http.Handler) http.Handler {
 
 			// 解析令牌
 			tokenString := strings.Replace(authHeader, "Bearer ", "", 1)
-			claims, err := validateToken(tokenString, cfg.JWT.Secret)
+			ctx, err := WithToken(r.Context(), tokenString, cfg.JWT.Secret)
 			if err != nil {
 				http.Error(w, "无效的认证令�?, http.StatusUnauthorized)
 				return
 			}
 
-			// 将用�?ID 和租�?ID 添加到上下文
-			userID, ok := claims["user_id"].(string)
-			if !ok {
-				http.Error(w, "无效的认证令�?, http.StatusUnauthorized)
-				return
-			}
-
-			tenantID, ok := claims["tenant_id"].(string)
-			if !ok {
-				http.Error(w, "无效的认证令�?, http.StatusUnauthorized)
-				return
-			}
-
-			ctx := context.WithValue(r.Context(), UserIDKey, userID)
-			ctx = context.WithValue(ctx, TenantIDKey, tenantID)
-
 			// 处理请求
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
+// ErrInvalidToken 表示认证令牌无效
+var ErrInvalidToken = errors.New("无效的认证令牌")
+
+// WithToken 校验 JWT 令牌，并将其中的用户 ID、租户 ID 和过期时间写入上下文
+// 除 HTTP 中间件外，WebSocket 等无法使用中间件的连接也通过它认证
+func WithToken(ctx context.Context, tokenString, secret string) (context.Context, error) {
+	claims, err := validateToken(tokenString, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, ok := claims["user_id"].(string)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	tenantID, ok := claims["tenant_id"].(string)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	ctx = context.WithValue(ctx, UserIDKey, userID)
+	ctx = context.WithValue(ctx, TenantIDKey, tenantID)
+	if expiresAt, err := claims.GetExpirationTime(); err == nil && expiresAt != nil {
+		ctx = context.WithValue(ctx, TokenExpiryKey, expiresAt.Time)
+	}
+	// 设置数据库行级安全所需的租户
+	ctx = db.WithTenant(ctx, tenantID)
+
+	return ctx, nil
+}
+
 // validateToken 验证 JWT 令牌
 func validateToken(tokenString, secret string) (jwt.MapClaims, error) {
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
@@ -98,3 +118,9 @@ func GetTenantID(ctx context.Context) (string, bool) {
 	return tenantID, ok
 }
 
+// GetTokenExpiry 从上下文获取认证令牌的过期时间，令牌未设置过期时间时返回 false
+func GetTokenExpiry(ctx context.Context) (time.Time, bool) {
+	expiresAt, ok := ctx.Value(TokenExpiryKey).(time.Time)
+	return expiresAt, ok
+}
+
@@ -0,0 +1,193 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/chat"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/db"
+)
+
+// ArtifactRepository 表示代码画布产物仓库
+type ArtifactRepository struct {
+	db *db.Postgres
+}
+
+// NewArtifactRepository 创建一个新的产物仓库
+func NewArtifactRepository(db *db.Postgres) *ArtifactRepository {
+	return &ArtifactRepository{
+		db: db,
+	}
+}
+
+// artifactColumns 为产物及其最新版本内容的查询列
+const artifactColumns = `a.id, a.tenant_id, a.canvas_id, a.name, a.language, a.current_version, COALESCE(v.content, '') AS content, a.created_at, a.updated_at`
+
+// ListByCanvas 列出画布的所有产物及其最新内容，按名称排序
+func (r *ArtifactRepository) ListByCanvas(ctx context.Context, canvasID string) ([]*chat.Artifact, error) {
+	query := `
+		SELECT ` + artifactColumns + `
+		FROM canvas_artifacts a
+		LEFT JOIN canvas_artifact_versions v ON v.artifact_id = a.id AND v.version = a.current_version
+		WHERE a.canvas_id = $1
+		ORDER BY a.name
+	`
+
+	var artifacts []*chat.Artifact
+	err := r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		return tx.SelectContext(ctx, &artifacts, query, canvasID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return artifacts, nil
+}
+
+// GetByID 通过 ID 获取产物及其最新内容
+func (r *ArtifactRepository) GetByID(ctx context.Context, id string) (*chat.Artifact, error) {
+	query := `
+		SELECT ` + artifactColumns + `
+		FROM canvas_artifacts a
+		LEFT JOIN canvas_artifact_versions v ON v.artifact_id = a.id AND v.version = a.current_version
+		WHERE a.id = $1
+	`
+
+	var artifact chat.Artifact
+	err := r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		return tx.GetContext(ctx, &artifact, query, id)
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("产物不存在: %w", err)
+		}
+		return nil, err
+	}
+
+	return &artifact, nil
+}
+
+// SaveVersion 为画布中指定名称的产物追加新版本，产物不存在时创建
+// 创建或锁定产物行后递增版本号，保证并发写入时版本号连续
+func (r *ArtifactRepository) SaveVersion(ctx context.Context, artifact *chat.Artifact, version *chat.ArtifactVersion) error {
+	// 生成 UUID
+	if artifact.ID == "" {
+		artifact.ID = uuid.New().String()
+	}
+	if version.ID == "" {
+		version.ID = uuid.New().String()
+	}
+
+	// 默认归属当前租户
+	if artifact.TenantID == "" {
+		artifact.TenantID, _ = db.TenantFromContext(ctx)
+	}
+	version.TenantID = artifact.TenantID
+
+	// 设置时间戳
+	now := time.Now()
+	version.CreatedAt = now
+
+	// 同名产物已存在时沿用原记录，ON CONFLICT 更新同时锁定该行
+	upsertQuery := `
+		INSERT INTO canvas_artifacts (id, tenant_id, canvas_id, name, language, current_version, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, 0, $6, $6)
+		ON CONFLICT (canvas_id, name) DO UPDATE SET updated_at = EXCLUDED.updated_at
+		RETURNING id, created_at
+	`
+
+	bumpQuery := `
+		UPDATE canvas_artifacts
+		SET current_version = current_version + 1, language = COALESCE($1, language), updated_at = $2
+		WHERE id = $3
+		RETURNING current_version, language
+	`
+
+	versionQuery := `
+		INSERT INTO canvas_artifact_versions (id, tenant_id, artifact_id, version, language, content, message_id, reverted_from, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`
+
+	return r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		row := tx.QueryRowxContext(ctx, upsertQuery, artifact.ID, artifact.TenantID, artifact.CanvasID, artifact.Name, version.Language, now)
+		if err := row.Scan(&artifact.ID, &artifact.CreatedAt); err != nil {
+			return err
+		}
+
+		row = tx.QueryRowxContext(ctx, bumpQuery, version.Language, now, artifact.ID)
+		if err := row.Scan(&artifact.CurrentVersion, &artifact.Language); err != nil {
+			return err
+		}
+
+		version.ArtifactID = artifact.ID
+		version.Version = artifact.CurrentVersion
+		_, err := tx.ExecContext(
+			ctx,
+			versionQuery,
+			version.ID,
+			version.TenantID,
+			version.ArtifactID,
+			version.Version,
+			version.Language,
+			version.Content,
+			version.MessageID,
+			version.RevertedFrom,
+			version.CreatedBy,
+			version.CreatedAt,
+		)
+		if err != nil {
+			return err
+		}
+
+		artifact.Content = version.Content
+		artifact.UpdatedAt = now
+		return nil
+	})
+}
+
+// ListVersions 列出产物的所有版本，按版本号正序排列
+func (r *ArtifactRepository) ListVersions(ctx context.Context, artifactID string) ([]*chat.ArtifactVersion, error) {
+	query := `
+		SELECT id, tenant_id, artifact_id, version, language, content, message_id, reverted_from, created_by, created_at
+		FROM canvas_artifact_versions
+		WHERE artifact_id = $1
+		ORDER BY version
+	`
+
+	var versions []*chat.ArtifactVersion
+	err := r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		return tx.SelectContext(ctx, &versions, query, artifactID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return versions, nil
+}
+
+// GetVersion 获取产物的指定版本
+func (r *ArtifactRepository) GetVersion(ctx context.Context, artifactID string, version int) (*chat.ArtifactVersion, error) {
+	query := `
+		SELECT id, tenant_id, artifact_id, version, language, content, message_id, reverted_from, created_by, created_at
+		FROM canvas_artifact_versions
+		WHERE artifact_id = $1 AND version = $2
+	`
+
+	var artifactVersion chat.ArtifactVersion
+	err := r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		return tx.GetContext(ctx, &artifactVersion, query, artifactID, version)
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("产物版本不存在: %w", err)
+		}
+		return nil, err
+	}
+
+	return &artifactVersion, nil
+}
@@ -0,0 +1,187 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/chat"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/db"
+)
+
+// AssistantRepository 表示助手仓库
+type AssistantRepository struct {
+	db *db.Postgres
+}
+
+// NewAssistantRepository 创建一个新的助手仓库
+func NewAssistantRepository(db *db.Postgres) *AssistantRepository {
+	return &AssistantRepository{
+		db: db,
+	}
+}
+
+// Create 创建一个新助手
+func (r *AssistantRepository) Create(ctx context.Context, assistant *chat.Assistant) error {
+	// 生成 UUID
+	if assistant.ID == "" {
+		assistant.ID = uuid.New().String()
+	}
+
+	// 默认归属当前租户
+	if assistant.TenantID == "" {
+		assistant.TenantID, _ = db.TenantFromContext(ctx)
+	}
+
+	// 设置时间戳
+	now := time.Now()
+	assistant.CreatedAt = now
+	assistant.UpdatedAt = now
+
+	query := `
+		INSERT INTO assistants (id, tenant_id, scope, workspace_id, name, description, avatar, system_prompt, model_id, settings, tools, knowledge_base_ids, created_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+	`
+
+	return r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(
+			ctx,
+			query,
+			assistant.ID,
+			assistant.TenantID,
+			assistant.Scope,
+			assistant.WorkspaceID,
+			assistant.Name,
+			assistant.Description,
+			assistant.Avatar,
+			assistant.SystemPrompt,
+			assistant.ModelID,
+			assistant.Settings,
+			assistant.Tools,
+			assistant.KnowledgeBaseIDs,
+			assistant.CreatedBy,
+			assistant.CreatedAt,
+			assistant.UpdatedAt,
+		)
+		return err
+	})
+}
+
+// GetByID 通过 ID 获取助手
+func (r *AssistantRepository) GetByID(ctx context.Context, id string) (*chat.Assistant, error) {
+	query := `
+		SELECT id, tenant_id, scope, workspace_id, name, description, avatar, system_prompt, model_id, settings, tools, knowledge_base_ids, created_by, created_at, updated_at
+		FROM assistants
+		WHERE id = $1
+	`
+
+	var assistant chat.Assistant
+	err := r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		return tx.GetContext(ctx, &assistant, query, id)
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("助手不存在: %w", err)
+		}
+		return nil, err
+	}
+
+	return &assistant, nil
+}
+
+// Update 更新助手
+func (r *AssistantRepository) Update(ctx context.Context, assistant *chat.Assistant) error {
+	// 更新时间戳
+	assistant.UpdatedAt = time.Now()
+
+	query := `
+		UPDATE assistants
+		SET scope = $1, workspace_id = $2, name = $3, description = $4, avatar = $5, system_prompt = $6, model_id = $7, settings = $8, tools = $9, knowledge_base_ids = $10, updated_at = $11
+		WHERE id = $12
+	`
+
+	return r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(
+			ctx,
+			query,
+			assistant.Scope,
+			assistant.WorkspaceID,
+			assistant.Name,
+			assistant.Description,
+			assistant.Avatar,
+			assistant.SystemPrompt,
+			assistant.ModelID,
+			assistant.Settings,
+			assistant.Tools,
+			assistant.KnowledgeBaseIDs,
+			assistant.UpdatedAt,
+			assistant.ID,
+		)
+		return err
+	})
+}
+
+// Delete 删除助手，使用该助手的画布回退为普通画布
+func (r *AssistantRepository) Delete(ctx context.Context, id string) error {
+	query := `
+		DELETE FROM assistants
+		WHERE id = $1
+	`
+
+	return r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(ctx, query, id)
+		return err
+	})
+}
+
+// List 列出用户可见的助手，按名称排序
+// 私有助手仅创建者可见；指定工作区时只返回该工作区的工作区助手
+func (r *AssistantRepository) List(ctx context.Context, req *chat.AssistantListRequest) ([]*chat.Assistant, int, error) {
+	// 构建查询条件，$1 为当前用户
+	conditions := []string{"(scope <> 'user' OR created_by = $1)"}
+	args := []interface{}{req.UserID}
+	addCondition := func(condition string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if req.Scope != nil {
+		addCondition("scope = $%d", *req.Scope)
+	}
+	if req.WorkspaceID != nil {
+		addCondition("(scope <> 'workspace' OR workspace_id = $%d)", *req.WorkspaceID)
+	}
+
+	whereClause := strings.Join(conditions, " AND ")
+
+	// 获取总数
+	countQuery := "SELECT COUNT(*) FROM assistants WHERE " + whereClause
+
+	// 获取助手列表
+	query := fmt.Sprintf(`
+		SELECT id, tenant_id, scope, workspace_id, name, description, avatar, system_prompt, model_id, settings, tools, knowledge_base_ids, created_by, created_at, updated_at
+		FROM assistants
+		WHERE %s
+		ORDER BY LOWER(name) ASC, created_at DESC, id
+		LIMIT $%d OFFSET $%d
+	`, whereClause, len(args)+1, len(args)+2)
+
+	var total int
+	var assistants []*chat.Assistant
+	err := r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		if err := tx.GetContext(ctx, &total, countQuery, args...); err != nil {
+			return err
+		}
+		return tx.SelectContext(ctx, &assistants, query, append(args, req.Limit, req.Offset)...)
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return assistants, total, nil
+}
@@ -0,0 +1,63 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/chat"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/db"
+)
+
+// AttachmentRepository 表示附件仓库
+type AttachmentRepository struct {
+	db *db.Postgres
+}
+
+// NewAttachmentRepository 创建一个新的附件仓库
+func NewAttachmentRepository(db *db.Postgres) *AttachmentRepository {
+	return &AttachmentRepository{
+		db: db,
+	}
+}
+
+// ListByMessageIDs 获取多条消息的附件，按创建时间正序排列
+func (r *AttachmentRepository) ListByMessageIDs(ctx context.Context, messageIDs []string) ([]*chat.Attachment, error) {
+	query := `
+		SELECT id, tenant_id, message_id, type, name, size, mime_type, url, metadata, created_at
+		FROM attachments
+		WHERE message_id = ANY($1)
+		ORDER BY created_at ASC
+	`
+
+	var attachments []*chat.Attachment
+	err := r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		return tx.SelectContext(ctx, &attachments, query, pq.Array(messageIDs))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return attachments, nil
+}
+
+// ListByCanvasID 获取画布所有消息的附件，包括已删除的消息
+func (r *AttachmentRepository) ListByCanvasID(ctx context.Context, canvasID string) ([]*chat.Attachment, error) {
+	query := `
+		SELECT a.id, a.tenant_id, a.message_id, a.type, a.name, a.size, a.mime_type, a.url, a.metadata, a.created_at
+		FROM attachments a
+		JOIN messages m ON m.id = a.message_id
+		WHERE m.canvas_id = $1
+		ORDER BY a.created_at ASC
+	`
+
+	var attachments []*chat.Attachment
+	err := r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		return tx.SelectContext(ctx, &attachments, query, canvasID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return attachments, nil
+}
@@ -1,12 +1,16 @@
 package postgres
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/zhuiye8/Lyss-chat-server/internal/domain/chat"
 	"github.com/zhuiye8/Lyss-chat-server/pkg/db"
 )
@@ -16,7 +20,7 @@ type CanvasRepository struct {
 	db *db.Postgres
 }
 
-// NewCanvasRepository 创建一个新的画布仓�?
+// NewCanvasRepository 创建一个新的画布仓库
 func NewCanvasRepository(db *db.Postgres) *CanvasRepository {
 	return &CanvasRepository{
 		db: db,
@@ -24,52 +28,70 @@ func NewCanvasRepository(db *db.Postgres) *CanvasRepository {
 }
 
 // Create 创建一个新画布
-func (r *CanvasRepository) Create(canvas *chat.Canvas) error {
+func (r *CanvasRepository) Create(ctx context.Context, canvas *chat.Canvas) error {
 	// 生成 UUID
 	if canvas.ID == "" {
 		canvas.ID = uuid.New().String()
 	}
 
-	// 设置时间�?
+	// 默认归属当前租户
+	if canvas.TenantID == "" {
+		canvas.TenantID, _ = db.TenantFromContext(ctx)
+	}
+
+	// 设置时间戳，导入的画布保留原始时间
 	now := time.Now()
-	canvas.CreatedAt = now
-	canvas.UpdatedAt = now
+	if canvas.CreatedAt.IsZero() {
+		canvas.CreatedAt = now
+	}
+	if canvas.UpdatedAt.IsZero() {
+		canvas.UpdatedAt = now
+	}
 
 	query := `
-		INSERT INTO canvases (id, workspace_id, title, description, type, status, model_id, created_by, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		INSERT INTO canvases (id, tenant_id, workspace_id, folder_id, title, title_customized, description, type, status, model_id, assistant_id, settings, created_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
 	`
 
-	_, err := r.db.DB.Exec(
-		query,
-		canvas.ID,
-		canvas.WorkspaceID,
-		canvas.Title,
-		canvas.Description,
-		canvas.Type,
-		canvas.Status,
-		canvas.ModelID,
-		canvas.CreatedBy,
-		canvas.CreatedAt,
-		canvas.UpdatedAt,
-	)
-
-	return err
+	return r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(
+			ctx,
+			query,
+			canvas.ID,
+			canvas.TenantID,
+			canvas.WorkspaceID,
+			canvas.FolderID,
+			canvas.Title,
+			canvas.TitleCustomized,
+			canvas.Description,
+			canvas.Type,
+			canvas.Status,
+			canvas.ModelID,
+			canvas.AssistantID,
+			canvas.Settings,
+			canvas.CreatedBy,
+			canvas.CreatedAt,
+			canvas.UpdatedAt,
+		)
+		return err
+	})
 }
 
-// GetByID 通过 ID 获取画布
-func (r *CanvasRepository) GetByID(id string) (*chat.Canvas, error) {
+// GetByID 通过 ID 获取画布，回收站中的画布视为不存在
+func (r *CanvasRepository) GetByID(ctx context.Context, id string) (*chat.Canvas, error) {
 	query := `
-		SELECT id, workspace_id, title, description, type, status, model_id, created_by, created_at, updated_at
+		SELECT id, tenant_id, workspace_id, title, description, type, status, model_id, assistant_id, settings, active_leaf_id, folder_id, pinned_at, last_message_at, title_customized, topic_tags, auto_titled_at, deleted_at, created_by, created_at, updated_at
 		FROM canvases
-		WHERE id = $1
+		WHERE id = $1 AND deleted_at IS NULL
 	`
 
 	var canvas chat.Canvas
-	err := r.db.DB.Get(&canvas, query, id)
+	err := r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		return tx.GetContext(ctx, &canvas, query, id)
+	})
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, fmt.Errorf("画布不存�? %w", err)
+			return nil, fmt.Errorf("画布不存在: %w", err)
 		}
 		return nil, err
 	}
@@ -78,79 +100,302 @@ func (r *CanvasRepository) GetByID(id string) (*chat.Canvas, error) {
 }
 
 // Update 更新画布
-func (r *CanvasRepository) Update(canvas *chat.Canvas) error {
-	// 更新时间�?
+func (r *CanvasRepository) Update(ctx context.Context, canvas *chat.Canvas) error {
+	// 更新时间戳
 	canvas.UpdatedAt = time.Now()
 
 	query := `
 		UPDATE canvases
-		SET title = $1, description = $2, status = $3, model_id = $4, updated_at = $5
-		WHERE id = $6
+		SET title = $1, title_customized = $2, description = $3, status = $4, model_id = $5, assistant_id = $6, settings = $7, updated_at = $8
+		WHERE id = $9 AND deleted_at IS NULL
 	`
 
-	_, err := r.db.DB.Exec(
-		query,
-		canvas.Title,
-		canvas.Description,
-		canvas.Status,
-		canvas.ModelID,
-		canvas.UpdatedAt,
-		canvas.ID,
-	)
-
-	return err
+	return r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(
+			ctx,
+			query,
+			canvas.Title,
+			canvas.TitleCustomized,
+			canvas.Description,
+			canvas.Status,
+			canvas.ModelID,
+			canvas.AssistantID,
+			canvas.Settings,
+			canvas.UpdatedAt,
+			canvas.ID,
+		)
+		return err
+	})
 }
 
-// Delete 删除画布
-func (r *CanvasRepository) Delete(id string) error {
+// Delete 彻底删除画布，消息等关联数据随画布级联删除
+func (r *CanvasRepository) Delete(ctx context.Context, id string) error {
 	query := `
 		DELETE FROM canvases
 		WHERE id = $1
 	`
 
-	_, err := r.db.DB.Exec(query, id)
-	return err
+	return r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(ctx, query, id)
+		return err
+	})
 }
 
-// List 列出画布
-func (r *CanvasRepository) List(workspaceID string, canvasType *string, offset, limit int) ([]*chat.Canvas, int, error) {
-	// 构建查询条件
-	whereClause := "WHERE workspace_id = $1"
-	args := []interface{}{workspaceID}
-	argIndex := 2
+// Trash 将画布移入回收站
+func (r *CanvasRepository) Trash(ctx context.Context, id, deletedBy string) (bool, error) {
+	query := `
+		UPDATE canvases
+		SET deleted_at = $1, deleted_by = $2
+		WHERE id = $3 AND deleted_at IS NULL
+	`
+
+	var trashed bool
+	err := r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		result, err := tx.ExecContext(ctx, query, time.Now(), deletedBy, id)
+		if err != nil {
+			return err
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		trashed = rows > 0
+		return nil
+	})
+	return trashed, err
+}
 
-	if canvasType != nil {
-		whereClause += fmt.Sprintf(" AND type = $%d", argIndex)
-		args = append(args, *canvasType)
-		argIndex++
+// Restore 将画布移出回收站
+func (r *CanvasRepository) Restore(ctx context.Context, id string) (*chat.Canvas, error) {
+	query := `
+		UPDATE canvases
+		SET deleted_at = NULL, deleted_by = NULL, updated_at = $1
+		WHERE id = $2 AND deleted_at IS NOT NULL
+		RETURNING id, tenant_id, workspace_id, title, description, type, status, model_id, assistant_id, settings, active_leaf_id, folder_id, pinned_at, last_message_at, title_customized, topic_tags, auto_titled_at, deleted_at, created_by, created_at, updated_at
+	`
+
+	var canvas chat.Canvas
+	err := r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		return tx.GetContext(ctx, &canvas, query, time.Now(), id)
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("回收站中不存在该画布: %w", err)
+		}
+		return nil, err
 	}
 
-	// 获取总数
-	countQuery := fmt.Sprintf(`
+	return &canvas, nil
+}
+
+// GetTrashedByID 通过 ID 获取回收站中的画布
+func (r *CanvasRepository) GetTrashedByID(ctx context.Context, id string) (*chat.Canvas, error) {
+	query := `
+		SELECT id, tenant_id, workspace_id, title, description, type, status, model_id, assistant_id, settings, active_leaf_id, folder_id, pinned_at, last_message_at, title_customized, topic_tags, auto_titled_at, deleted_at, created_by, created_at, updated_at
+		FROM canvases
+		WHERE id = $1 AND deleted_at IS NOT NULL
+	`
+
+	var canvas chat.Canvas
+	err := r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		return tx.GetContext(ctx, &canvas, query, id)
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("回收站中不存在该画布: %w", err)
+		}
+		return nil, err
+	}
+
+	return &canvas, nil
+}
+
+// ListTrash 列出工作区回收站中的画布，最近删除的在前
+func (r *CanvasRepository) ListTrash(ctx context.Context, workspaceID string, offset, limit int) ([]*chat.Canvas, int, error) {
+	countQuery := `
 		SELECT COUNT(*)
 		FROM canvases
-		%s
-	`, whereClause)
+		WHERE workspace_id = $1 AND deleted_at IS NOT NULL
+	`
+
+	query := `
+		SELECT id, tenant_id, workspace_id, title, description, type, status, model_id, assistant_id, settings, active_leaf_id, folder_id, pinned_at, last_message_at, title_customized, topic_tags, auto_titled_at, deleted_at, created_by, created_at, updated_at
+		FROM canvases
+		WHERE workspace_id = $1 AND deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC
+		LIMIT $2 OFFSET $3
+	`
 
 	var total int
-	err := r.db.DB.Get(&total, countQuery, args...)
+	var canvases []*chat.Canvas
+	err := r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		if err := tx.GetContext(ctx, &total, countQuery, workspaceID); err != nil {
+			return err
+		}
+		return tx.SelectContext(ctx, &canvases, query, workspaceID, limit, offset)
+	})
 	if err != nil {
 		return nil, 0, err
 	}
 
+	return canvases, total, nil
+}
+
+// ListExpiredTrash 列出超过租户保留天数的回收站画布，最早删除的在前
+// 后台任务在系统上下文中调用，跨租户查询
+func (r *CanvasRepository) ListExpiredTrash(ctx context.Context, defaultDays, limit int) ([]*chat.Canvas, error) {
+	query := `
+		SELECT c.id, c.tenant_id, c.workspace_id, c.title, c.description, c.type, c.status, c.model_id, c.assistant_id, c.settings, c.active_leaf_id, c.folder_id, c.pinned_at, c.last_message_at, c.title_customized, c.topic_tags, c.auto_titled_at, c.deleted_at, c.created_by, c.created_at, c.updated_at
+		FROM canvases c
+		LEFT JOIN tenant_settings ts ON ts.tenant_id = c.tenant_id
+		WHERE c.deleted_at IS NOT NULL
+			AND c.deleted_at < $1::timestamp - make_interval(days => COALESCE(ts.trash_retention_days, $2))
+		ORDER BY c.deleted_at ASC
+		LIMIT $3
+	`
+
+	var canvases []*chat.Canvas
+	err := r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		return tx.SelectContext(ctx, &canvases, query, time.Now(), defaultDays, limit)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return canvases, nil
+}
+
+// SetActiveLeaf 设置画布当前分支的叶子消息，messageID 为空时清除
+func (r *CanvasRepository) SetActiveLeaf(ctx context.Context, id, messageID string) error {
+	query := `
+		UPDATE canvases
+		SET active_leaf_id = NULLIF($1, '')::uuid, updated_at = $2
+		WHERE id = $3
+	`
+
+	return r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(ctx, query, messageID, time.Now(), id)
+		return err
+	})
+}
+
+// ApplyAutoTitle 写入自动生成的标题和标签
+// 条件写在同一条语句中，避免覆盖任务执行期间用户修改的标题
+func (r *CanvasRepository) ApplyAutoTitle(ctx context.Context, id, title string, tags []string) (bool, error) {
+	query := `
+		UPDATE canvases
+		SET title = CASE WHEN title_customized THEN title ELSE $1 END,
+			topic_tags = $2, auto_titled_at = $3, updated_at = $3
+		WHERE id = $4 AND auto_titled_at IS NULL
+	`
+
+	var applied bool
+	err := r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		result, err := tx.ExecContext(ctx, query, title, pq.Array(tags), time.Now(), id)
+		if err != nil {
+			return err
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		applied = rows > 0
+		return nil
+	})
+	return applied, err
+}
+
+// canvasSortColumns 是画布列表排序字段对应的列，画布表的别名为 c，收藏表的别名为 f
+// 可能为空的列排在最后
+var canvasSortColumns = map[string]string{
+	chat.CanvasSortCreatedAt:     "c.created_at",
+	chat.CanvasSortUpdatedAt:     "c.updated_at",
+	chat.CanvasSortLastMessageAt: "c.last_message_at",
+	chat.CanvasSortTitle:         "c.title",
+	chat.CanvasSortPinned:        "c.pinned_at",
+	chat.CanvasSortFavorite:      "f.created_at",
+}
+
+// List 按条件列出画布，不包含回收站中的画布
+func (r *CanvasRepository) List(ctx context.Context, req *chat.CanvasListRequest) ([]*chat.Canvas, int, error) {
+	// 构建查询条件，$1 为当前用户，用于关联收藏
+	conditions := []string{"c.deleted_at IS NULL"}
+	args := []interface{}{req.UserID}
+	addCondition := func(condition string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	addCondition("c.workspace_id = $%d", req.WorkspaceID)
+	if req.Type != nil {
+		addCondition("c.type = $%d", *req.Type)
+	}
+	if req.Status != nil {
+		addCondition("c.status = $%d", *req.Status)
+	}
+	if req.FolderID != nil {
+		if *req.FolderID == chat.CanvasFolderRoot {
+			conditions = append(conditions, "c.folder_id IS NULL")
+		} else {
+			addCondition("c.folder_id = $%d", *req.FolderID)
+		}
+	}
+	if len(req.TagIDs) > 0 {
+		args = append(args, pq.Array(req.TagIDs))
+		conditions = append(conditions, fmt.Sprintf(
+			"(SELECT COUNT(*) FROM canvas_tag_links l WHERE l.canvas_id = c.id AND l.tag_id = ANY($%d)) = %d",
+			len(args), len(req.TagIDs)))
+	}
+	if req.Pinned != nil {
+		if *req.Pinned {
+			conditions = append(conditions, "c.pinned_at IS NOT NULL")
+		} else {
+			conditions = append(conditions, "c.pinned_at IS NULL")
+		}
+	}
+	if req.Favorite != nil {
+		if *req.Favorite {
+			conditions = append(conditions, "f.canvas_id IS NOT NULL")
+		} else {
+			conditions = append(conditions, "f.canvas_id IS NULL")
+		}
+	}
+
+	fromClause := fmt.Sprintf(`
+		FROM canvases c
+		LEFT JOIN canvas_favorites f ON f.canvas_id = c.id AND f.user_id = NULLIF($1, '')::uuid
+		WHERE %s`, strings.Join(conditions, " AND "))
+
+	// 排序，相同时按创建时间倒序
+	sortColumn, ok := canvasSortColumns[req.Sort]
+	if !ok {
+		sortColumn = canvasSortColumns[chat.CanvasSortCreatedAt]
+	}
+	direction := "DESC"
+	if req.Order == chat.SortOrderAsc {
+		direction = "ASC"
+	}
+
+	// 获取总数
+	countQuery := "SELECT COUNT(*)" + fromClause
+
 	// 获取画布列表
 	query := fmt.Sprintf(`
-		SELECT id, workspace_id, title, description, type, status, model_id, created_by, created_at, updated_at
-		FROM canvases
+		SELECT c.id, c.tenant_id, c.workspace_id, c.title, c.description, c.type, c.status, c.model_id, c.assistant_id, c.settings, c.active_leaf_id, c.folder_id, c.pinned_at, c.last_message_at, c.title_customized, c.topic_tags, c.auto_titled_at, c.deleted_at, c.created_by, c.created_at, c.updated_at,
+			f.canvas_id IS NOT NULL AS favorite
 		%s
-		ORDER BY created_at DESC
+		ORDER BY %s %s NULLS LAST, c.created_at DESC, c.id
 		LIMIT $%d OFFSET $%d
-	`, whereClause, argIndex, argIndex+1)
-
-	args = append(args, limit, offset)
+	`, fromClause, sortColumn, direction, len(args)+1, len(args)+2)
 
+	var total int
 	var canvases []*chat.Canvas
-	err = r.db.DB.Select(&canvases, query, args...)
+	err := r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		if err := tx.GetContext(ctx, &total, countQuery, args...); err != nil {
+			return err
+		}
+		return tx.SelectContext(ctx, &canvases, query, append(args, req.Limit, req.Offset)...)
+	})
 	if err != nil {
 		return nil, 0, err
 	}
@@ -158,3 +403,69 @@ func (r *CanvasRepository) List(workspaceID string, canvasType *string, offset,
 	return canvases, total, nil
 }
 
+// SetFolder 将画布移动到文件夹，folderID 为空表示移动到顶层
+func (r *CanvasRepository) SetFolder(ctx context.Context, id string, folderID *string) error {
+	query := `
+		UPDATE canvases
+		SET folder_id = $1
+		WHERE id = $2 AND deleted_at IS NULL
+	`
+
+	return r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(ctx, query, folderID, id)
+		return err
+	})
+}
+
+// SetPinned 置顶或取消置顶画布，已置顶的画布保留原置顶时间
+func (r *CanvasRepository) SetPinned(ctx context.Context, id string, pinned bool) error {
+	query := `
+		UPDATE canvases
+		SET pinned_at = CASE WHEN $1 THEN COALESCE(pinned_at, $2) END
+		WHERE id = $3 AND deleted_at IS NULL
+	`
+
+	return r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(ctx, query, pinned, time.Now(), id)
+		return err
+	})
+}
+
+// SetFavorite 收藏或取消收藏画布
+func (r *CanvasRepository) SetFavorite(ctx context.Context, id, userID string, favorite bool) error {
+	addQuery := `
+		INSERT INTO canvas_favorites (user_id, canvas_id, tenant_id, created_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, canvas_id) DO NOTHING
+	`
+	removeQuery := `
+		DELETE FROM canvas_favorites
+		WHERE user_id = $1 AND canvas_id = $2
+	`
+
+	tenantID, _ := db.TenantFromContext(ctx)
+	return r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		var err error
+		if favorite {
+			_, err = tx.ExecContext(ctx, addQuery, userID, id, tenantID, time.Now())
+		} else {
+			_, err = tx.ExecContext(ctx, removeQuery, userID, id)
+		}
+		return err
+	})
+}
+
+// IsFavorite 判断用户是否收藏了画布
+func (r *CanvasRepository) IsFavorite(ctx context.Context, id, userID string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM canvas_favorites WHERE user_id = $1 AND canvas_id = $2
+		)
+	`
+
+	var favorite bool
+	err := r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		return tx.GetContext(ctx, &favorite, query, userID, id)
+	})
+	return favorite, err
+}
@@ -0,0 +1,192 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/chat"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/db"
+)
+
+// EmbeddingRepository 表示消息向量仓库
+type EmbeddingRepository struct {
+	db *db.Postgres
+}
+
+// NewEmbeddingRepository 创建一个新的消息向量仓库
+func NewEmbeddingRepository(db *db.Postgres) *EmbeddingRepository {
+	return &EmbeddingRepository{
+		db: db,
+	}
+}
+
+// Upsert 保存消息向量，已有向量（包括旧模型生成的）会被替换
+func (r *EmbeddingRepository) Upsert(ctx context.Context, embeddings []*chat.MessageEmbedding) error {
+	query := `
+		INSERT INTO message_embeddings (message_id, tenant_id, canvas_id, model, dimensions, embedding, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (message_id) DO UPDATE
+		SET model = EXCLUDED.model, dimensions = EXCLUDED.dimensions,
+			embedding = EXCLUDED.embedding, created_at = EXCLUDED.created_at
+	`
+
+	return r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		for _, embedding := range embeddings {
+			// 默认归属当前租户
+			if embedding.TenantID == "" {
+				embedding.TenantID, _ = db.TenantFromContext(ctx)
+			}
+			embedding.Dimensions = len(embedding.Embedding)
+			embedding.CreatedAt = time.Now()
+
+			_, err := tx.ExecContext(
+				ctx,
+				query,
+				embedding.MessageID,
+				embedding.TenantID,
+				embedding.CanvasID,
+				embedding.Model,
+				embedding.Dimensions,
+				embedding.Embedding,
+				embedding.CreatedAt,
+			)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ListPending 列出没有 model 生成的向量的用户和助手消息，最新的消息优先
+func (r *EmbeddingRepository) ListPending(ctx context.Context, model string, limit int) ([]*chat.Message, error) {
+	query := `
+		SELECT m.id, m.tenant_id, m.canvas_id, m.parent_id, m.role, m.content, m.metadata, m.token_count, m.created_by, m.created_at
+		FROM messages m
+		LEFT JOIN message_embeddings e ON e.message_id = m.id AND e.model = $1
+		WHERE e.message_id IS NULL AND m.deleted_at IS NULL AND m.role IN ('user', 'assistant') AND m.content <> ''
+		ORDER BY m.created_at DESC
+		LIMIT $2
+	`
+
+	var messages []*chat.Message
+	err := r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		return tx.SelectContext(ctx, &messages, query, model, limit)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+// VectorSupported 判断数据库是否启用了 pgvector
+func (r *EmbeddingRepository) VectorSupported(ctx context.Context) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'vector')`
+
+	var supported bool
+	err := r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		return tx.GetContext(ctx, &supported, query)
+	})
+	return supported, err
+}
+
+// SearchSimilar 使用 pgvector 按余弦相似度检索消息
+// 画布结果取画布内最相似的消息，MessageID 指向该消息
+func (r *EmbeddingRepository) SearchSimilar(ctx context.Context, req *chat.SearchRequest, model string, vector []float32, minScore float64) ([]*chat.SearchResult, int, error) {
+	// 构建过滤条件，$1 至 $4 为查询向量、模型、维度和最低相似度
+	filterClause, args := searchFilters(req, []interface{}{vectorLiteral(vector), model, len(vector), minScore})
+
+	var parts []string
+	if req.Kind == nil || *req.Kind == chat.SearchKindCanvas {
+		parts = append(parts, `
+			SELECT 'canvas' AS kind, canvas_id, canvas_title, message_id, role, content, rank, created_at
+			FROM (
+				SELECT DISTINCT ON (canvas_id) *
+				FROM matched
+				ORDER BY canvas_id, rank DESC
+			) best`)
+	}
+	if req.Kind == nil || *req.Kind == chat.SearchKindMessage {
+		parts = append(parts, `
+			SELECT 'message' AS kind, canvas_id, canvas_title, message_id, role, content, rank, created_at
+			FROM matched`)
+	}
+	results := fmt.Sprintf(`
+		WITH matched AS (
+			SELECT * FROM (
+				SELECT c.id AS canvas_id, c.title AS canvas_title, m.id AS message_id, m.role, m.content, m.created_at,
+					1 - (e.embedding::vector <=> $1::vector) AS rank
+				FROM message_embeddings e
+				JOIN messages m ON m.id = e.message_id
+				JOIN canvases c ON c.id = e.canvas_id
+				WHERE e.model = $2 AND e.dimensions = $3 AND m.deleted_at IS NULL%s
+			) scored
+			WHERE rank >= $4
+		)
+		SELECT * FROM (%s
+		) results`, filterClause, strings.Join(parts, "\n\t\t\tUNION ALL"))
+
+	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM (%s) similar", results)
+	query := fmt.Sprintf(`%s
+		ORDER BY rank DESC, created_at DESC
+		LIMIT $%d OFFSET $%d
+	`, results, len(args)+1, len(args)+2)
+
+	var total int
+	var hits []*chat.SearchResult
+	err := r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		if err := tx.GetContext(ctx, &total, countQuery, args...); err != nil {
+			return err
+		}
+		return tx.SelectContext(ctx, &hits, query, append(args, req.Limit, req.Offset)...)
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return hits, total, nil
+}
+
+// ListCandidates 列出满足过滤条件的最近 limit 条消息及其向量
+func (r *EmbeddingRepository) ListCandidates(ctx context.Context, req *chat.SearchRequest, model string, limit int) ([]*chat.EmbeddingCandidate, error) {
+	filterClause, args := searchFilters(req, []interface{}{model})
+	query := fmt.Sprintf(`
+		SELECT 'message' AS kind, c.id AS canvas_id, c.title AS canvas_title, m.id AS message_id, m.role, m.content,
+			0::float8 AS rank, m.created_at, e.embedding
+		FROM message_embeddings e
+		JOIN messages m ON m.id = e.message_id
+		JOIN canvases c ON c.id = e.canvas_id
+		WHERE e.model = $1 AND m.deleted_at IS NULL%s
+		ORDER BY m.created_at DESC
+		LIMIT $%d
+	`, filterClause, len(args)+1)
+
+	var candidates []*chat.EmbeddingCandidate
+	err := r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		return tx.SelectContext(ctx, &candidates, query, append(args, limit)...)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return candidates, nil
+}
+
+// vectorLiteral 将向量格式化为 pgvector 的文本表示
+func vectorLiteral(vector []float32) string {
+	var b strings.Builder
+	b.WriteByte('[')
+	for i, v := range vector {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.FormatFloat(float64(v), 'f', -1, 32))
+	}
+	b.WriteByte(']')
+	return b.String()
+}
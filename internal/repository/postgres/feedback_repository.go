@@ -0,0 +1,236 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/chat"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/db"
+)
+
+// feedbackGroupColumns 是各分组方式的分组键和名称表达式
+// 表别名：f 为反馈，c 为画布，m 为模型，p 为提供商
+var feedbackGroupColumns = map[string][2]string{
+	chat.FeedbackGroupByModel:    {"COALESCE(f.model_id::text, '')", "COALESCE(m.name, '')"},
+	chat.FeedbackGroupByProvider: {"COALESCE(p.id::text, '')", "COALESCE(p.name, '')"},
+	chat.FeedbackGroupByCanvas:   {"c.id::text", "c.title"},
+	chat.FeedbackGroupByDay:      {"to_char(date_trunc('day', f.created_at), 'YYYY-MM-DD')", "to_char(date_trunc('day', f.created_at), 'YYYY-MM-DD')"},
+	chat.FeedbackGroupByWeek:     {"to_char(date_trunc('week', f.created_at), 'YYYY-MM-DD')", "to_char(date_trunc('week', f.created_at), 'IYYY-\"W\"IW')"},
+	chat.FeedbackGroupByMonth:    {"to_char(date_trunc('month', f.created_at), 'YYYY-MM-DD')", "to_char(date_trunc('month', f.created_at), 'YYYY-MM')"},
+}
+
+// FeedbackRepository 表示反馈仓库
+type FeedbackRepository struct {
+	db *db.Postgres
+}
+
+// NewFeedbackRepository 创建一个新的反馈仓库
+func NewFeedbackRepository(db *db.Postgres) *FeedbackRepository {
+	return &FeedbackRepository{
+		db: db,
+	}
+}
+
+// Upsert 创建或覆盖用户对消息的反馈，覆盖时保留原有的 ID 和创建时间
+func (r *FeedbackRepository) Upsert(ctx context.Context, feedback *chat.Feedback) error {
+	// 生成 UUID
+	if feedback.ID == "" {
+		feedback.ID = uuid.New().String()
+	}
+
+	// 默认归属当前租户
+	if feedback.TenantID == "" {
+		feedback.TenantID, _ = db.TenantFromContext(ctx)
+	}
+
+	// 设置时间戳
+	now := time.Now()
+	if feedback.CreatedAt.IsZero() {
+		feedback.CreatedAt = now
+	}
+	feedback.UpdatedAt = now
+
+	query := `
+		INSERT INTO message_feedback (id, tenant_id, message_id, canvas_id, user_id, model_id, rating, reasons, comment, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		ON CONFLICT (message_id, user_id) DO UPDATE
+		SET rating = EXCLUDED.rating,
+			reasons = EXCLUDED.reasons,
+			comment = EXCLUDED.comment,
+			updated_at = EXCLUDED.updated_at
+		RETURNING id, created_at
+	`
+
+	return r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		return tx.QueryRowxContext(
+			ctx,
+			query,
+			feedback.ID,
+			feedback.TenantID,
+			feedback.MessageID,
+			feedback.CanvasID,
+			feedback.UserID,
+			feedback.ModelID,
+			feedback.Rating,
+			feedback.Reasons,
+			feedback.Comment,
+			feedback.CreatedAt,
+			feedback.UpdatedAt,
+		).Scan(&feedback.ID, &feedback.CreatedAt)
+	})
+}
+
+// Get 获取用户对消息的反馈，不存在时返回 nil
+func (r *FeedbackRepository) Get(ctx context.Context, messageID, userID string) (*chat.Feedback, error) {
+	query := `
+		SELECT id, tenant_id, message_id, canvas_id, user_id, model_id, rating, reasons, comment, created_at, updated_at
+		FROM message_feedback
+		WHERE message_id = $1 AND user_id = $2
+	`
+
+	var feedback chat.Feedback
+	err := r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		return tx.GetContext(ctx, &feedback, query, messageID, userID)
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &feedback, nil
+}
+
+// Delete 删除用户对消息的反馈，返回是否存在并已删除
+func (r *FeedbackRepository) Delete(ctx context.Context, messageID, userID string) (bool, error) {
+	query := `
+		DELETE FROM message_feedback
+		WHERE message_id = $1 AND user_id = $2
+	`
+
+	var deleted bool
+	err := r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		result, err := tx.ExecContext(ctx, query, messageID, userID)
+		if err != nil {
+			return err
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		deleted = rows > 0
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return deleted, nil
+}
+
+// Stats 按分组统计反馈数量和各原因代码的出现次数
+// 按时间分组时结果按时间正序排列，其余分组按反馈总数倒序排列
+// 租户隔离由行级安全保证
+func (r *FeedbackRepository) Stats(ctx context.Context, req *chat.FeedbackStatsRequest) ([]*chat.FeedbackStat, error) {
+	columns, ok := feedbackGroupColumns[req.GroupBy]
+	if !ok {
+		return nil, fmt.Errorf("不支持的分组方式: %s", req.GroupBy)
+	}
+	keyExpr, labelExpr := columns[0], columns[1]
+
+	// 构建过滤条件
+	var filters []string
+	var args []interface{}
+	if req.WorkspaceID != nil {
+		args = append(args, *req.WorkspaceID)
+		filters = append(filters, fmt.Sprintf("c.workspace_id = $%d", len(args)))
+	}
+	if req.CanvasID != nil {
+		args = append(args, *req.CanvasID)
+		filters = append(filters, fmt.Sprintf("f.canvas_id = $%d", len(args)))
+	}
+	if req.ModelID != nil {
+		args = append(args, *req.ModelID)
+		filters = append(filters, fmt.Sprintf("f.model_id = $%d", len(args)))
+	}
+	if req.From != nil {
+		args = append(args, *req.From)
+		filters = append(filters, fmt.Sprintf("f.created_at >= $%d", len(args)))
+	}
+	if req.To != nil {
+		args = append(args, *req.To)
+		filters = append(filters, fmt.Sprintf("f.created_at < $%d", len(args)))
+	}
+	whereClause := ""
+	if len(filters) > 0 {
+		whereClause = "WHERE " + strings.Join(filters, " AND ")
+	}
+
+	from := `
+		FROM message_feedback f
+		JOIN canvases c ON c.id = f.canvas_id
+		LEFT JOIN models m ON m.id = f.model_id
+		LEFT JOIN providers p ON p.id = m.provider_id`
+
+	orderBy := "total DESC, key ASC"
+	if req.GroupBy == chat.FeedbackGroupByDay || req.GroupBy == chat.FeedbackGroupByWeek || req.GroupBy == chat.FeedbackGroupByMonth {
+		orderBy = "key ASC"
+	}
+
+	statsQuery := fmt.Sprintf(`
+		SELECT %s AS key, %s AS label,
+			COUNT(*) FILTER (WHERE f.rating = 'up') AS up,
+			COUNT(*) FILTER (WHERE f.rating = 'down') AS down,
+			COUNT(*) AS total
+		%s
+		%s
+		GROUP BY 1, 2
+		ORDER BY %s
+		LIMIT $%d
+	`, keyExpr, labelExpr, from, whereClause, orderBy, len(args)+1)
+
+	reasonsQuery := fmt.Sprintf(`
+		SELECT %s AS key, reason, COUNT(*) AS count
+		%s
+		CROSS JOIN LATERAL unnest(f.reasons) AS reason
+		%s
+		GROUP BY 1, 2
+	`, keyExpr, from, whereClause)
+
+	var stats []*chat.FeedbackStat
+	var reasons []struct {
+		Key    string `db:"key"`
+		Reason string `db:"reason"`
+		Count  int    `db:"count"`
+	}
+	err := r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		if err := tx.SelectContext(ctx, &stats, statsQuery, append(args, req.Limit)...); err != nil {
+			return err
+		}
+		return tx.SelectContext(ctx, &reasons, reasonsQuery, args...)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 合并原因代码统计
+	byKey := make(map[string]*chat.FeedbackStat, len(stats))
+	for _, stat := range stats {
+		stat.Reasons = make(map[string]int)
+		byKey[stat.Key] = stat
+	}
+	for _, reason := range reasons {
+		if stat, ok := byKey[reason.Key]; ok {
+			stat.Reasons[reason.Reason] = reason.Count
+		}
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,169 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/chat"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/db"
+)
+
+// FolderRepository 表示文件夹仓库
+type FolderRepository struct {
+	db *db.Postgres
+}
+
+// NewFolderRepository 创建一个新的文件夹仓库
+func NewFolderRepository(db *db.Postgres) *FolderRepository {
+	return &FolderRepository{
+		db: db,
+	}
+}
+
+// Create 创建一个新文件夹
+func (r *FolderRepository) Create(ctx context.Context, folder *chat.Folder) error {
+	// 生成 UUID
+	if folder.ID == "" {
+		folder.ID = uuid.New().String()
+	}
+
+	// 默认归属当前租户
+	if folder.TenantID == "" {
+		folder.TenantID, _ = db.TenantFromContext(ctx)
+	}
+
+	// 设置时间戳
+	now := time.Now()
+	folder.CreatedAt = now
+	folder.UpdatedAt = now
+
+	query := `
+		INSERT INTO canvas_folders (id, tenant_id, workspace_id, parent_id, name, created_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	return r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(
+			ctx,
+			query,
+			folder.ID,
+			folder.TenantID,
+			folder.WorkspaceID,
+			folder.ParentID,
+			folder.Name,
+			folder.CreatedBy,
+			folder.CreatedAt,
+			folder.UpdatedAt,
+		)
+		return err
+	})
+}
+
+// GetByID 通过 ID 获取文件夹
+func (r *FolderRepository) GetByID(ctx context.Context, id string) (*chat.Folder, error) {
+	query := `
+		SELECT id, tenant_id, workspace_id, parent_id, name, created_by, created_at, updated_at
+		FROM canvas_folders
+		WHERE id = $1
+	`
+
+	var folder chat.Folder
+	err := r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		return tx.GetContext(ctx, &folder, query, id)
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("文件夹不存在: %w", err)
+		}
+		return nil, err
+	}
+
+	return &folder, nil
+}
+
+// Update 修改文件夹的名称和父文件夹
+func (r *FolderRepository) Update(ctx context.Context, folder *chat.Folder) error {
+	// 更新时间戳
+	folder.UpdatedAt = time.Now()
+
+	query := `
+		UPDATE canvas_folders
+		SET name = $1, parent_id = $2, updated_at = $3
+		WHERE id = $4
+	`
+
+	return r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(ctx, query, folder.Name, folder.ParentID, folder.UpdatedAt, folder.ID)
+		return err
+	})
+}
+
+// Delete 删除文件夹，其中的子文件夹和画布移动到被删除文件夹的父文件夹下
+func (r *FolderRepository) Delete(ctx context.Context, id string) error {
+	moveFoldersQuery := `
+		UPDATE canvas_folders
+		SET parent_id = (SELECT parent_id FROM canvas_folders WHERE id = $1)
+		WHERE parent_id = $1
+	`
+	moveCanvasesQuery := `
+		UPDATE canvases
+		SET folder_id = (SELECT parent_id FROM canvas_folders WHERE id = $1)
+		WHERE folder_id = $1
+	`
+	deleteQuery := `
+		DELETE FROM canvas_folders
+		WHERE id = $1
+	`
+
+	return r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		for _, query := range []string{moveFoldersQuery, moveCanvasesQuery, deleteQuery} {
+			if _, err := tx.ExecContext(ctx, query, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ListByWorkspace 列出工作区的所有文件夹，按名称排序，由调用方根据 parent_id 组装层级
+func (r *FolderRepository) ListByWorkspace(ctx context.Context, workspaceID string) ([]*chat.Folder, error) {
+	query := `
+		SELECT id, tenant_id, workspace_id, parent_id, name, created_by, created_at, updated_at
+		FROM canvas_folders
+		WHERE workspace_id = $1
+		ORDER BY name ASC, created_at ASC
+	`
+
+	var folders []*chat.Folder
+	err := r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		return tx.SelectContext(ctx, &folders, query, workspaceID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return folders, nil
+}
+
+// IsDescendant 判断 folderID 是否为 ancestorID 本身或其子孙文件夹
+func (r *FolderRepository) IsDescendant(ctx context.Context, folderID, ancestorID string) (bool, error) {
+	query := `
+		WITH RECURSIVE descendants AS (
+			SELECT id FROM canvas_folders WHERE id = $1
+			UNION
+			SELECT f.id FROM canvas_folders f JOIN descendants d ON f.parent_id = d.id
+		)
+		SELECT EXISTS (SELECT 1 FROM descendants WHERE id = $2)
+	`
+
+	var descendant bool
+	err := r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		return tx.GetContext(ctx, &descendant, query, ancestorID, folderID)
+	})
+	return descendant, err
+}
@@ -0,0 +1,82 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/chat"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/db"
+)
+
+// ImportRepository 表示导入记录仓库
+type ImportRepository struct {
+	db *db.Postgres
+}
+
+// NewImportRepository 创建一个新的导入记录仓库
+func NewImportRepository(db *db.Postgres) *ImportRepository {
+	return &ImportRepository{
+		db: db,
+	}
+}
+
+// Exists 判断工作区内是否已导入来源相同的对话
+func (r *ImportRepository) Exists(ctx context.Context, workspaceID, source, externalID string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM canvas_imports
+			WHERE workspace_id = $1 AND source = $2 AND external_id = $3
+		)
+	`
+
+	var exists bool
+	err := r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		return tx.GetContext(ctx, &exists, query, workspaceID, source, externalID)
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
+
+// Create 创建导入记录
+// 并发导入同一对话时由唯一索引保证只有一条记录写入成功
+func (r *ImportRepository) Create(ctx context.Context, record *chat.CanvasImport) error {
+	// 生成 UUID
+	if record.ID == "" {
+		record.ID = uuid.New().String()
+	}
+
+	// 默认归属当前租户
+	if record.TenantID == "" {
+		record.TenantID, _ = db.TenantFromContext(ctx)
+	}
+
+	// 设置时间戳
+	record.CreatedAt = time.Now()
+
+	query := `
+		INSERT INTO canvas_imports (id, tenant_id, workspace_id, canvas_id, source, external_id, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	return r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(
+			ctx,
+			query,
+			record.ID,
+			record.TenantID,
+			record.WorkspaceID,
+			record.CanvasID,
+			record.Source,
+			record.ExternalID,
+			record.CreatedBy,
+			record.CreatedAt,
+		)
+		return err
+	})
+}
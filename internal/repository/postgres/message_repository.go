@@ -1,6 +1,7 @@
 package postgres
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
@@ -8,6 +9,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
 	"github.com/zhuiye8/Lyss-chat-server/internal/domain/chat"
 	"github.com/zhuiye8/Lyss-chat-server/pkg/db"
 )
@@ -17,66 +19,56 @@ type MessageRepository struct {
 	db *db.Postgres
 }
 
-// NewMessageRepository 创建一个新的消息仓�?
+// NewMessageRepository 创建一个新的消息仓库
 func NewMessageRepository(db *db.Postgres) *MessageRepository {
 	return &MessageRepository{
 		db: db,
 	}
 }
 
-// Create 创建一个新消息
-func (r *MessageRepository) Create(message *chat.Message) error {
-	// 生成 UUID
-	if message.ID == "" {
-		message.ID = uuid.New().String()
-	}
-
-	// 设置时间�?
+// insertMessageQuery 是插入消息的语句
+const insertMessageQuery = `
+	INSERT INTO messages (id, tenant_id, canvas_id, parent_id, role, content, metadata, token_count, created_by, created_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+`
+
+// touchCanvasQuery 在画布中添加消息后更新画布的修改时间和最后消息时间
+// 导入的历史消息早于画布当前时间时不回退
+const touchCanvasQuery = `
+	UPDATE canvases
+	SET updated_at = GREATEST(updated_at, $1), last_message_at = GREATEST(last_message_at, $1)
+	WHERE id = $2
+`
+
+// Create 创建一个新消息，并更新画布的修改时间和最后消息时间
+func (r *MessageRepository) Create(ctx context.Context, message *chat.Message) error {
+	// 设置时间戳
 	message.CreatedAt = time.Now()
 
-	// 处理元数�?
-	var metadata []byte
-	var err error
-	if message.Metadata != nil {
-		metadata = message.Metadata
-	} else {
-		metadata = []byte("{}")
-	}
-
-	query := `
-		INSERT INTO messages (id, canvas_id, parent_id, role, content, metadata, token_count, created_by, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
-	`
-
-	_, err = r.db.DB.Exec(
-		query,
-		message.ID,
-		message.CanvasID,
-		message.ParentID,
-		message.Role,
-		message.Content,
-		metadata,
-		message.TokenCount,
-		message.CreatedBy,
-		message.CreatedAt,
-	)
-
-	return err
+	return r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		if err := insertMessage(ctx, tx, message); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, touchCanvasQuery, message.CreatedAt, message.CanvasID)
+		return err
+	})
 }
 
 // GetByID 通过 ID 获取消息
-func (r *MessageRepository) GetByID(id string) (*chat.Message, error) {
+func (r *MessageRepository) GetByID(ctx context.Context, id string) (*chat.Message, error) {
 	query := `
-		SELECT id, canvas_id, parent_id, role, content, metadata, token_count, created_by, created_at
+		SELECT id, tenant_id, canvas_id, parent_id, role, content, metadata, token_count, edited_at, deleted_at, created_by, created_at
 		FROM messages
 		WHERE id = $1
 	`
 
 	var message chat.Message
-	err := r.db.DB.Get(&message, query, id)
+	err := r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		return tx.GetContext(ctx, &message, query, id)
+	})
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, fmt.Errorf("消息不存�? %w", err)
+			return nil, fmt.Errorf("消息不存在: %w", err)
 		}
 		return nil, err
 	}
@@ -84,43 +76,75 @@ func (r *MessageRepository) GetByID(id string) (*chat.Message, error) {
 	return &message, nil
 }
 
-// GetByCanvasID 获取画布的所有消�?
-func (r *MessageRepository) GetByCanvasID(canvasID string, offset, limit int) ([]*chat.Message, int, error) {
+// GetByCanvasID 获取画布的所有消息，不包括已删除的消息
+func (r *MessageRepository) GetByCanvasID(ctx context.Context, canvasID string, offset, limit int) ([]*chat.Message, int, error) {
 	// 获取总数
 	countQuery := `
 		SELECT COUNT(*)
 		FROM messages
-		WHERE canvas_id = $1
+		WHERE canvas_id = $1 AND deleted_at IS NULL
+	`
+
+	// 获取消息列表
+	query := `
+		SELECT id, tenant_id, canvas_id, parent_id, role, content, metadata, token_count, edited_at, deleted_at, created_by, created_at
+		FROM messages
+		WHERE canvas_id = $1 AND deleted_at IS NULL
+		ORDER BY created_at ASC
+		LIMIT $2 OFFSET $3
 	`
 
 	var total int
-	err := r.db.DB.Get(&total, countQuery, canvasID)
+	var messages []*chat.Message
+	err := r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		if err := tx.GetContext(ctx, &total, countQuery, canvasID); err != nil {
+			return err
+		}
+		return tx.SelectContext(ctx, &messages, query, canvasID, limit, offset)
+	})
 	if err != nil {
 		return nil, 0, err
 	}
 
-	// 获取消息列表
+	return messages, total, nil
+}
+
+// ListByCanvasID 获取画布的全部消息，用于构建消息树
+// 包括已删除的消息，构建消息树时据此将其子消息挂到最近的未删除祖先下
+func (r *MessageRepository) ListByCanvasID(ctx context.Context, canvasID string) ([]*chat.Message, error) {
 	query := `
-		SELECT id, canvas_id, parent_id, role, content, metadata, token_count, created_by, created_at
+		SELECT id, tenant_id, canvas_id, parent_id, role, content, metadata, token_count, edited_at, deleted_at, created_by, created_at
 		FROM messages
 		WHERE canvas_id = $1
 		ORDER BY created_at ASC
-		LIMIT $2 OFFSET $3
 	`
 
 	var messages []*chat.Message
-	err = r.db.DB.Select(&messages, query, canvasID, limit, offset)
+	err := r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		return tx.SelectContext(ctx, &messages, query, canvasID)
+	})
 	if err != nil {
-		return nil, 0, err
+		return nil, err
 	}
 
-	return messages, total, nil
+	return messages, nil
 }
 
 // GetConversation 获取对话历史
-func (r *MessageRepository) GetConversation(messageID string, limit int) ([]*chat.Message, error) {
+func (r *MessageRepository) GetConversation(ctx context.Context, messageID string, limit int) ([]*chat.Message, error) {
+	return r.getAncestors(ctx, messageID, &limit)
+}
+
+// GetBranch 获取从根消息到指定消息的完整分支
+func (r *MessageRepository) GetBranch(ctx context.Context, messageID string) ([]*chat.Message, error) {
+	return r.getAncestors(ctx, messageID, nil)
+}
+
+// getAncestors 沿父消息回溯，按时间正序返回距指定消息最近的 limit 条，limit 为空时返回全部
+// 回溯经过已删除的消息但不返回它们
+func (r *MessageRepository) getAncestors(ctx context.Context, messageID string, limit *int) ([]*chat.Message, error) {
 	// 首先获取当前消息
-	currentMessage, err := r.GetByID(messageID)
+	currentMessage, err := r.GetByID(ctx, messageID)
 	if err != nil {
 		return nil, err
 	}
@@ -131,27 +155,34 @@ func (r *MessageRepository) GetConversation(messageID string, limit int) ([]*cha
 	// 获取对话历史
 	query := `
 		WITH RECURSIVE conversation AS (
-			-- 基本情况：当前消�?
-			SELECT id, canvas_id, parent_id, role, content, metadata, token_count, created_by, created_at, 0 as depth
+			-- 基本情况：当前消息
+			SELECT id, tenant_id, canvas_id, parent_id, role, content, metadata, token_count, edited_at, deleted_at, created_by, created_at, 0 as depth
 			FROM messages
 			WHERE id = $1
-			
+
 			UNION ALL
-			
+
 			-- 递归情况：父消息
-			SELECT m.id, m.canvas_id, m.parent_id, m.role, m.content, m.metadata, m.token_count, m.created_by, m.created_at, c.depth + 1
+			SELECT m.id, m.tenant_id, m.canvas_id, m.parent_id, m.role, m.content, m.metadata, m.token_count, m.edited_at, m.deleted_at, m.created_by, m.created_at, c.depth + 1
 			FROM messages m
 			JOIN conversation c ON m.id = c.parent_id
 			WHERE m.canvas_id = $2
 		)
-		SELECT id, canvas_id, parent_id, role, content, metadata, token_count, created_by, created_at
-		FROM conversation
-		ORDER BY depth DESC, created_at ASC
-		LIMIT $3
+		-- 保留距当前消息最近的 limit 条未删除消息，再按时间正序返回
+		SELECT id, tenant_id, canvas_id, parent_id, role, content, metadata, token_count, edited_at, deleted_at, created_by, created_at
+		FROM (
+			SELECT * FROM conversation
+			WHERE deleted_at IS NULL
+			ORDER BY depth ASC
+			LIMIT $3 -- 为 NULL 时不限制
+		) recent
+		ORDER BY depth DESC
 	`
 
 	var messages []*chat.Message
-	err = r.db.DB.Select(&messages, query, messageID, canvasID, limit)
+	err = r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		return tx.SelectContext(ctx, &messages, query, messageID, canvasID, limit)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -159,56 +190,251 @@ func (r *MessageRepository) GetConversation(messageID string, limit int) ([]*cha
 	return messages, nil
 }
 
-// CreateBatch 批量创建消息
-func (r *MessageRepository) CreateBatch(messages []*chat.Message) error {
-	tx, err := r.db.DB.Beginx()
+// CreateBatch 批量创建消息，并更新画布的修改时间和最后消息时间
+func (r *MessageRepository) CreateBatch(ctx context.Context, messages []*chat.Message) error {
+	return r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		// 每个画布最后一条消息的时间
+		latest := make(map[string]time.Time)
+		for _, message := range messages {
+			// 设置时间戳
+			if message.CreatedAt.IsZero() {
+				message.CreatedAt = time.Now()
+			}
+
+			if err := insertMessage(ctx, tx, message); err != nil {
+				return err
+			}
+			if message.CreatedAt.After(latest[message.CanvasID]) {
+				latest[message.CanvasID] = message.CreatedAt
+			}
+		}
+
+		for canvasID, lastMessageAt := range latest {
+			if _, err := tx.ExecContext(ctx, touchCanvasQuery, lastMessageAt, canvasID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// UpdateContent 原地修改消息内容，修改前的内容保存为新的修订版本
+// metadata 为空时保留原有元数据
+func (r *MessageRepository) UpdateContent(ctx context.Context, id, content string, metadata json.RawMessage, editedBy string) (*chat.Message, error) {
+	// 锁定消息，保证并发编辑时修订版本号连续
+	lockQuery := `
+		SELECT id
+		FROM messages
+		WHERE id = $1 AND deleted_at IS NULL
+		FOR UPDATE
+	`
+
+	// 保存当前版本，作者和时间取最近一次编辑，未编辑过时取消息的创建者和创建时间
+	revisionQuery := `
+		INSERT INTO message_revisions (id, tenant_id, message_id, revision, content, metadata, created_by, created_at)
+		SELECT $1, m.tenant_id, m.id,
+			COALESCE((SELECT MAX(revision) FROM message_revisions WHERE message_id = m.id), 0) + 1,
+			m.content, m.metadata, COALESCE(m.edited_by, m.created_by), COALESCE(m.edited_at, m.created_at)
+		FROM messages m
+		WHERE m.id = $2
+	`
+
+	updateQuery := `
+		UPDATE messages
+		SET content = $1, metadata = COALESCE($2, metadata), edited_at = $3, edited_by = $4
+		WHERE id = $5
+		RETURNING id, tenant_id, canvas_id, parent_id, role, content, metadata, token_count, edited_at, deleted_at, created_by, created_at
+	`
+
+	var newMetadata interface{}
+	if metadata != nil {
+		newMetadata = []byte(metadata)
+	}
+
+	var message chat.Message
+	err := r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		var lockedID string
+		if err := tx.GetContext(ctx, &lockedID, lockQuery, id); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, revisionQuery, uuid.New().String(), id); err != nil {
+			return err
+		}
+		return tx.GetContext(ctx, &message, updateQuery, content, newMetadata, time.Now(), editedBy, id)
+	})
 	if err != nil {
-		return err
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("消息不存在: %w", err)
+		}
+		return nil, err
 	}
-	defer tx.Rollback()
 
-	query := `
-		INSERT INTO messages (id, canvas_id, parent_id, role, content, metadata, token_count, created_by, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	return &message, nil
+}
+
+// SoftDelete 软删除消息，返回被删除的消息 ID
+// prune 为 true 时一并删除其所有后代消息；否则只删除该消息，所有消息的 parent_id 保持不变，
+// 读取历史和构建消息树时跳过已删除的消息
+func (r *MessageRepository) SoftDelete(ctx context.Context, id, deletedBy string, prune bool) ([]string, error) {
+	pruneQuery := `
+		WITH RECURSIVE subtree AS (
+			SELECT id
+			FROM messages
+			WHERE id = $1 AND deleted_at IS NULL
+
+			UNION ALL
+
+			SELECT m.id
+			FROM messages m
+			JOIN subtree s ON m.parent_id = s.id
+			WHERE m.deleted_at IS NULL
+		)
+		UPDATE messages
+		SET deleted_at = $2, deleted_by = $3
+		WHERE id IN (SELECT id FROM subtree)
+		RETURNING id
 	`
 
-	for _, message := range messages {
-		// 生成 UUID
-		if message.ID == "" {
-			message.ID = uuid.New().String()
-		}
+	deleteQuery := `
+		UPDATE messages
+		SET deleted_at = $2, deleted_by = $3
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING id
+	`
 
-		// 设置时间�?
-		if message.CreatedAt.IsZero() {
-			message.CreatedAt = time.Now()
+	var deleted []string
+	err := r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		now := time.Now()
+		if prune {
+			return tx.SelectContext(ctx, &deleted, pruneQuery, id, now, deletedBy)
 		}
-
-		// 处理元数�?
-		var metadata []byte
-		if message.Metadata != nil {
-			metadata = message.Metadata
-		} else {
-			metadata = []byte("{}")
+		return tx.SelectContext(ctx, &deleted, deleteQuery, id, now, deletedBy)
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("消息不存在: %w", err)
 		}
+		return nil, err
+	}
+	if len(deleted) == 0 {
+		return nil, fmt.Errorf("消息不存在: %w", sql.ErrNoRows)
+	}
 
-		_, err = tx.Exec(
-			query,
-			message.ID,
-			message.CanvasID,
-			message.ParentID,
-			message.Role,
-			message.Content,
-			metadata,
-			message.TokenCount,
-			message.CreatedBy,
-			message.CreatedAt,
-		)
+	return deleted, nil
+}
 
-		if err != nil {
-			return err
+// ListRevisions 获取消息的历史版本，按版本号正序排列
+func (r *MessageRepository) ListRevisions(ctx context.Context, messageID string) ([]*chat.MessageRevision, error) {
+	query := `
+		SELECT id, tenant_id, message_id, revision, content, metadata, created_by, created_at
+		FROM message_revisions
+		WHERE message_id = $1
+		ORDER BY revision ASC
+	`
+
+	var revisions []*chat.MessageRevision
+	err := r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		return tx.SelectContext(ctx, &revisions, query, messageID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+// AppendExecution 将代码执行结果追加到消息元数据的 executions 中，只保留最近 keep 次
+// 不修改消息内容，也不产生修订版本
+func (r *MessageRepository) AppendExecution(ctx context.Context, id string, execution json.RawMessage, keep int) (*chat.Message, error) {
+	query := `
+		UPDATE messages
+		SET metadata = jsonb_set(COALESCE(metadata, '{}'::jsonb), '{executions}', (
+			SELECT COALESCE(jsonb_agg(recent.entry ORDER BY recent.position), '[]'::jsonb)
+			FROM (
+				SELECT entry, position
+				FROM jsonb_array_elements(COALESCE(metadata->'executions', '[]'::jsonb) || jsonb_build_array($1::jsonb))
+					WITH ORDINALITY AS e(entry, position)
+				ORDER BY position DESC
+				LIMIT $2
+			) recent
+		))
+		WHERE id = $3 AND deleted_at IS NULL
+		RETURNING id, tenant_id, canvas_id, parent_id, role, content, metadata, token_count, edited_at, deleted_at, created_by, created_at
+	`
+
+	var message chat.Message
+	err := r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		return tx.GetContext(ctx, &message, query, []byte(execution), keep, id)
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("消息不存在: %w", err)
 		}
+		return nil, err
 	}
 
-	return tx.Commit()
+	return &message, nil
 }
 
+// SelectCompareReply 将对比模式的一条回复标记为选中，并清除同一次对比中其他回复的选中标记
+// 返回标记发生变化的回复
+func (r *MessageRepository) SelectCompareReply(ctx context.Context, canvasID, compareID, id string) ([]*chat.Message, error) {
+	query := `
+		UPDATE messages
+		SET metadata = CASE
+			WHEN id = $3 THEN metadata || '{"selected": true}'::jsonb
+			ELSE metadata - 'selected'
+		END
+		WHERE canvas_id = $1 AND metadata->>'compare_id' = $2 AND deleted_at IS NULL
+			AND (id = $3) IS DISTINCT FROM COALESCE((metadata->>'selected')::boolean, false)
+		RETURNING id, tenant_id, canvas_id, parent_id, role, content, metadata, token_count, edited_at, deleted_at, created_by, created_at
+	`
+
+	var messages []*chat.Message
+	err := r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		return tx.SelectContext(ctx, &messages, query, canvasID, compareID, id)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+// insertMessage 在事务中插入一条消息
+func insertMessage(ctx context.Context, tx *sqlx.Tx, message *chat.Message) error {
+	// 生成 UUID
+	if message.ID == "" {
+		message.ID = uuid.New().String()
+	}
+
+	// 默认归属当前租户
+	if message.TenantID == "" {
+		message.TenantID, _ = db.TenantFromContext(ctx)
+	}
+
+	// 处理元数据
+	var metadata []byte
+	if message.Metadata != nil {
+		metadata = message.Metadata
+	} else {
+		metadata = []byte("{}")
+	}
+
+	_, err := tx.ExecContext(
+		ctx,
+		insertMessageQuery,
+		message.ID,
+		message.TenantID,
+		message.CanvasID,
+		message.ParentID,
+		message.Role,
+		message.Content,
+		metadata,
+		message.TokenCount,
+		message.CreatedBy,
+		message.CreatedAt,
+	)
+
+	return err
+}
@@ -0,0 +1,185 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/model"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/db"
+)
+
+// ModelRepository 表示模型仓库
+type ModelRepository struct {
+	db *db.Postgres
+}
+
+// NewModelRepository 创建一个新的模型仓库
+func NewModelRepository(db *db.Postgres) *ModelRepository {
+	return &ModelRepository{
+		db: db,
+	}
+}
+
+// Create 创建一个新模型
+func (r *ModelRepository) Create(ctx context.Context, m *model.Model) error {
+	// 生成 UUID
+	if m.ID == "" {
+		m.ID = uuid.New().String()
+	}
+
+	// 设置时间戳
+	now := time.Now()
+	m.CreatedAt = now
+	m.UpdatedAt = now
+
+	query := `
+		INSERT INTO models (id, provider_id, model_id, name, description, capabilities, parameters, max_tokens, status, is_public, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+	`
+
+	return r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(
+			ctx,
+			query,
+			m.ID,
+			m.ProviderID,
+			m.ModelID,
+			m.Name,
+			m.Description,
+			jsonOrEmpty(m.Capabilities),
+			jsonOrEmpty(m.Parameters),
+			m.MaxTokens,
+			m.Status,
+			m.IsPublic,
+			m.CreatedAt,
+			m.UpdatedAt,
+		)
+		return err
+	})
+}
+
+// GetByID 通过 ID 获取模型
+func (r *ModelRepository) GetByID(ctx context.Context, id string) (*model.Model, error) {
+	query := `
+		SELECT id, provider_id, model_id, name, description, capabilities, parameters, max_tokens, status, is_public, created_at, updated_at
+		FROM models
+		WHERE id = $1
+	`
+
+	var m model.Model
+	err := r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		return tx.GetContext(ctx, &m, query, id)
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("模型不存在: %w", err)
+		}
+		return nil, err
+	}
+
+	return &m, nil
+}
+
+// Update 更新模型
+func (r *ModelRepository) Update(ctx context.Context, m *model.Model) error {
+	// 更新时间戳
+	m.UpdatedAt = time.Now()
+
+	query := `
+		UPDATE models
+		SET name = $1, description = $2, capabilities = $3, parameters = $4, max_tokens = $5, status = $6, is_public = $7, updated_at = $8
+		WHERE id = $9
+	`
+
+	return r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(
+			ctx,
+			query,
+			m.Name,
+			m.Description,
+			jsonOrEmpty(m.Capabilities),
+			jsonOrEmpty(m.Parameters),
+			m.MaxTokens,
+			m.Status,
+			m.IsPublic,
+			m.UpdatedAt,
+			m.ID,
+		)
+		return err
+	})
+}
+
+// Delete 删除模型
+func (r *ModelRepository) Delete(ctx context.Context, id string) error {
+	query := `
+		DELETE FROM models
+		WHERE id = $1
+	`
+
+	return r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(ctx, query, id)
+		return err
+	})
+}
+
+// List 列出模型
+func (r *ModelRepository) List(ctx context.Context, providerID *string, status *string, offset, limit int) ([]*model.Model, int, error) {
+	// 构建查询条件
+	whereClause := "WHERE 1 = 1"
+	args := []interface{}{}
+	argIndex := 1
+
+	if providerID != nil {
+		whereClause += fmt.Sprintf(" AND provider_id = $%d", argIndex)
+		args = append(args, *providerID)
+		argIndex++
+	}
+	if status != nil {
+		whereClause += fmt.Sprintf(" AND status = $%d", argIndex)
+		args = append(args, *status)
+		argIndex++
+	}
+
+	// 获取总数
+	countQuery := fmt.Sprintf(`
+		SELECT COUNT(*)
+		FROM models
+		%s
+	`, whereClause)
+
+	// 获取模型列表
+	query := fmt.Sprintf(`
+		SELECT id, provider_id, model_id, name, description, capabilities, parameters, max_tokens, status, is_public, created_at, updated_at
+		FROM models
+		%s
+		ORDER BY created_at DESC
+		LIMIT $%d OFFSET $%d
+	`, whereClause, argIndex, argIndex+1)
+
+	var total int
+	var models []*model.Model
+	err := r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		if err := tx.GetContext(ctx, &total, countQuery, args...); err != nil {
+			return err
+		}
+		return tx.SelectContext(ctx, &models, query, append(args, limit, offset)...)
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return models, total, nil
+}
+
+// jsonOrEmpty 将空的 JSON 字段替换为空对象
+func jsonOrEmpty(raw []byte) []byte {
+	if len(raw) == 0 {
+		return []byte("{}")
+	}
+	return raw
+}
@@ -0,0 +1,352 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/chat"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/db"
+)
+
+// promptSortColumns 将排序字段映射到列
+var promptSortColumns = map[string]string{
+	chat.PromptSortUpdatedAt: "p.updated_at DESC",
+	chat.PromptSortUsage:     "p.usage_count DESC",
+	chat.PromptSortTitle:     "LOWER(p.title) ASC",
+}
+
+// likeEscaper 转义 LIKE 模式中的通配符
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// PromptRepository 表示提示词模板仓库
+type PromptRepository struct {
+	db *db.Postgres
+}
+
+// NewPromptRepository 创建一个新的提示词模板仓库
+func NewPromptRepository(db *db.Postgres) *PromptRepository {
+	return &PromptRepository{
+		db: db,
+	}
+}
+
+// Create 创建模板并保存其第一个版本
+func (r *PromptRepository) Create(ctx context.Context, template *chat.PromptTemplate) error {
+	// 生成 UUID
+	if template.ID == "" {
+		template.ID = uuid.New().String()
+	}
+
+	// 默认归属当前租户
+	if template.TenantID == "" {
+		template.TenantID, _ = db.TenantFromContext(ctx)
+	}
+
+	// 设置版本和时间戳
+	now := time.Now()
+	template.Version = 1
+	template.CreatedAt = now
+	template.UpdatedAt = now
+
+	query := `
+		INSERT INTO prompt_templates (id, tenant_id, scope, workspace_id, title, description, content, role, variables, version, usage_count, created_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+	`
+
+	return r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(
+			ctx,
+			query,
+			template.ID,
+			template.TenantID,
+			template.Scope,
+			template.WorkspaceID,
+			template.Title,
+			template.Description,
+			template.Content,
+			template.Role,
+			template.Variables,
+			template.Version,
+			template.UsageCount,
+			template.CreatedBy,
+			template.CreatedAt,
+			template.UpdatedAt,
+		)
+		if err != nil {
+			return err
+		}
+		return r.createVersion(ctx, tx, template)
+	})
+}
+
+// GetByID 通过 ID 获取模板
+func (r *PromptRepository) GetByID(ctx context.Context, id string) (*chat.PromptTemplate, error) {
+	query := `
+		SELECT id, tenant_id, scope, workspace_id, title, description, content, role, variables, version, usage_count, created_by, created_at, updated_at
+		FROM prompt_templates
+		WHERE id = $1
+	`
+
+	var template chat.PromptTemplate
+	err := r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		return tx.GetContext(ctx, &template, query, id)
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("提示词模板不存在: %w", err)
+		}
+		return nil, err
+	}
+
+	return &template, nil
+}
+
+// Update 保存模板，newVersion 为真时版本号加一并保存新版本
+func (r *PromptRepository) Update(ctx context.Context, template *chat.PromptTemplate, newVersion bool) error {
+	// 更新时间戳和版本号
+	template.UpdatedAt = time.Now()
+	if newVersion {
+		template.Version++
+	}
+
+	query := `
+		UPDATE prompt_templates
+		SET scope = $1, workspace_id = $2, title = $3, description = $4, content = $5, role = $6, variables = $7, version = $8, updated_at = $9
+		WHERE id = $10
+	`
+
+	return r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(
+			ctx,
+			query,
+			template.Scope,
+			template.WorkspaceID,
+			template.Title,
+			template.Description,
+			template.Content,
+			template.Role,
+			template.Variables,
+			template.Version,
+			template.UpdatedAt,
+			template.ID,
+		)
+		if err != nil || !newVersion {
+			return err
+		}
+		return r.createVersion(ctx, tx, template)
+	})
+}
+
+// createVersion 将模板当前的内容和变量保存为一个版本
+func (r *PromptRepository) createVersion(ctx context.Context, tx *sqlx.Tx, template *chat.PromptTemplate) error {
+	query := `
+		INSERT INTO prompt_template_versions (id, tenant_id, template_id, version, content, variables, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	_, err := tx.ExecContext(
+		ctx,
+		query,
+		uuid.New().String(),
+		template.TenantID,
+		template.ID,
+		template.Version,
+		template.Content,
+		template.Variables,
+		template.CreatedBy,
+		template.UpdatedAt,
+	)
+	return err
+}
+
+// Delete 删除模板，版本和分享记录随之删除
+func (r *PromptRepository) Delete(ctx context.Context, id string) error {
+	query := `
+		DELETE FROM prompt_templates
+		WHERE id = $1
+	`
+
+	return r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(ctx, query, id)
+		return err
+	})
+}
+
+// List 列出用户可见的模板
+// 个人模板仅创建者和被分享的用户可见；指定工作区时只返回该工作区的工作区模板
+func (r *PromptRepository) List(ctx context.Context, req *chat.PromptListRequest) ([]*chat.PromptTemplate, int, error) {
+	// 构建查询条件，$1 为当前用户
+	conditions := []string{`(p.scope <> 'user' OR p.created_by = $1 OR EXISTS (
+		SELECT 1 FROM prompt_template_shares s WHERE s.template_id = p.id AND s.user_id = $1))`}
+	args := []interface{}{req.UserID}
+	addCondition := func(condition string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if req.Scope != nil {
+		addCondition("p.scope = $%d", *req.Scope)
+	}
+	if req.WorkspaceID != nil {
+		addCondition("(p.scope <> 'workspace' OR p.workspace_id = $%d)", *req.WorkspaceID)
+	}
+	if req.Query != "" {
+		addCondition(`(p.title ILIKE $%[1]d OR p.description ILIKE $%[1]d)`, "%"+likeEscaper.Replace(req.Query)+"%")
+	}
+
+	whereClause := strings.Join(conditions, " AND ")
+
+	// 排序，相同时按更新时间倒序
+	sortColumn, ok := promptSortColumns[req.Sort]
+	if !ok {
+		sortColumn = promptSortColumns[chat.PromptSortUpdatedAt]
+	}
+
+	// 获取总数
+	countQuery := "SELECT COUNT(*) FROM prompt_templates p WHERE " + whereClause
+
+	// 获取模板列表
+	query := fmt.Sprintf(`
+		SELECT p.id, p.tenant_id, p.scope, p.workspace_id, p.title, p.description, p.content, p.role, p.variables, p.version, p.usage_count, p.created_by, p.created_at, p.updated_at
+		FROM prompt_templates p
+		WHERE %s
+		ORDER BY %s, p.updated_at DESC, p.id
+		LIMIT $%d OFFSET $%d
+	`, whereClause, sortColumn, len(args)+1, len(args)+2)
+
+	var total int
+	var templates []*chat.PromptTemplate
+	err := r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		if err := tx.GetContext(ctx, &total, countQuery, args...); err != nil {
+			return err
+		}
+		return tx.SelectContext(ctx, &templates, query, append(args, req.Limit, req.Offset)...)
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return templates, total, nil
+}
+
+// ListVersions 列出模板的所有版本，最新的在前
+func (r *PromptRepository) ListVersions(ctx context.Context, templateID string) ([]*chat.PromptTemplateVersion, error) {
+	query := `
+		SELECT id, tenant_id, template_id, version, content, variables, created_by, created_at
+		FROM prompt_template_versions
+		WHERE template_id = $1
+		ORDER BY version DESC
+	`
+
+	var versions []*chat.PromptTemplateVersion
+	err := r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		return tx.SelectContext(ctx, &versions, query, templateID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return versions, nil
+}
+
+// GetVersion 获取模板的指定版本
+func (r *PromptRepository) GetVersion(ctx context.Context, templateID string, version int) (*chat.PromptTemplateVersion, error) {
+	query := `
+		SELECT id, tenant_id, template_id, version, content, variables, created_by, created_at
+		FROM prompt_template_versions
+		WHERE template_id = $1 AND version = $2
+	`
+
+	var v chat.PromptTemplateVersion
+	err := r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		return tx.GetContext(ctx, &v, query, templateID, version)
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("提示词模板版本不存在: %w", err)
+		}
+		return nil, err
+	}
+
+	return &v, nil
+}
+
+// IncrementUsage 累加模板的使用次数
+func (r *PromptRepository) IncrementUsage(ctx context.Context, id string) error {
+	query := `
+		UPDATE prompt_templates
+		SET usage_count = usage_count + 1
+		WHERE id = $1
+	`
+
+	return r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(ctx, query, id)
+		return err
+	})
+}
+
+// IsSharedWith 判断模板是否分享给了用户
+func (r *PromptRepository) IsSharedWith(ctx context.Context, id, userID string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM prompt_template_shares
+			WHERE template_id = $1 AND user_id = $2
+		)
+	`
+
+	var shared bool
+	err := r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		return tx.GetContext(ctx, &shared, query, id, userID)
+	})
+	return shared, err
+}
+
+// ListShares 列出模板分享给的用户
+func (r *PromptRepository) ListShares(ctx context.Context, id string) ([]string, error) {
+	query := `
+		SELECT user_id
+		FROM prompt_template_shares
+		WHERE template_id = $1
+		ORDER BY created_at ASC
+	`
+
+	var userIDs []string
+	err := r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		return tx.SelectContext(ctx, &userIDs, query, id)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return userIDs, nil
+}
+
+// SetShares 替换模板的分享对象
+func (r *PromptRepository) SetShares(ctx context.Context, id, createdBy string, userIDs []string) error {
+	deleteQuery := `
+		DELETE FROM prompt_template_shares
+		WHERE template_id = $1 AND NOT (user_id = ANY($2))
+	`
+	insertQuery := `
+		INSERT INTO prompt_template_shares (template_id, user_id, tenant_id, created_by, created_at)
+		SELECT $1, user_id, $2, $3, $4
+		FROM UNNEST($5::uuid[]) AS user_id
+		ON CONFLICT (template_id, user_id) DO NOTHING
+	`
+
+	tenantID, _ := db.TenantFromContext(ctx)
+	return r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		if _, err := tx.ExecContext(ctx, deleteQuery, id, pq.Array(userIDs)); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, insertQuery, id, tenantID, createdBy, time.Now(), pq.Array(userIDs))
+		return err
+	})
+}
@@ -0,0 +1,177 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/model"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/db"
+)
+
+// ProviderRepository 表示提供商仓库
+type ProviderRepository struct {
+	db *db.Postgres
+}
+
+// NewProviderRepository 创建一个新的提供商仓库
+func NewProviderRepository(db *db.Postgres) *ProviderRepository {
+	return &ProviderRepository{
+		db: db,
+	}
+}
+
+// Create 创建一个新提供商
+func (r *ProviderRepository) Create(ctx context.Context, provider *model.Provider) error {
+	// 生成 UUID
+	if provider.ID == "" {
+		provider.ID = uuid.New().String()
+	}
+
+	// 设置时间戳
+	now := time.Now()
+	provider.CreatedAt = now
+	provider.UpdatedAt = now
+
+	query := `
+		INSERT INTO providers (id, tenant_id, code, name, description, base_url, status, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+
+	return r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(
+			ctx,
+			query,
+			provider.ID,
+			provider.TenantID,
+			provider.Code,
+			provider.Name,
+			provider.Description,
+			provider.BaseURL,
+			provider.Status,
+			provider.CreatedAt,
+			provider.UpdatedAt,
+		)
+		return err
+	})
+}
+
+// GetByID 通过 ID 获取提供商
+func (r *ProviderRepository) GetByID(ctx context.Context, id string) (*model.Provider, error) {
+	query := `
+		SELECT id, tenant_id, code, name, description, base_url, status, created_at, updated_at
+		FROM providers
+		WHERE id = $1
+	`
+
+	var provider model.Provider
+	err := r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		return tx.GetContext(ctx, &provider, query, id)
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("提供商不存在: %w", err)
+		}
+		return nil, err
+	}
+
+	return &provider, nil
+}
+
+// GetByCode 通过代码获取提供商
+func (r *ProviderRepository) GetByCode(ctx context.Context, code, tenantID string) (*model.Provider, error) {
+	query := `
+		SELECT id, tenant_id, code, name, description, base_url, status, created_at, updated_at
+		FROM providers
+		WHERE code = $1 AND tenant_id = $2
+	`
+
+	var provider model.Provider
+	err := r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		return tx.GetContext(ctx, &provider, query, code, tenantID)
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("提供商不存在: %w", err)
+		}
+		return nil, err
+	}
+
+	return &provider, nil
+}
+
+// Update 更新提供商
+func (r *ProviderRepository) Update(ctx context.Context, provider *model.Provider) error {
+	// 更新时间戳
+	provider.UpdatedAt = time.Now()
+
+	query := `
+		UPDATE providers
+		SET name = $1, description = $2, base_url = $3, status = $4, updated_at = $5
+		WHERE id = $6
+	`
+
+	return r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(
+			ctx,
+			query,
+			provider.Name,
+			provider.Description,
+			provider.BaseURL,
+			provider.Status,
+			provider.UpdatedAt,
+			provider.ID,
+		)
+		return err
+	})
+}
+
+// Delete 删除提供商
+func (r *ProviderRepository) Delete(ctx context.Context, id string) error {
+	query := `
+		DELETE FROM providers
+		WHERE id = $1
+	`
+
+	return r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(ctx, query, id)
+		return err
+	})
+}
+
+// List 列出租户的提供商
+func (r *ProviderRepository) List(ctx context.Context, tenantID string, offset, limit int) ([]*model.Provider, int, error) {
+	// 获取总数
+	countQuery := `
+		SELECT COUNT(*)
+		FROM providers
+		WHERE tenant_id = $1
+	`
+
+	// 获取提供商列表
+	query := `
+		SELECT id, tenant_id, code, name, description, base_url, status, created_at, updated_at
+		FROM providers
+		WHERE tenant_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	var total int
+	var providers []*model.Provider
+	err := r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		if err := tx.GetContext(ctx, &total, countQuery, tenantID); err != nil {
+			return err
+		}
+		return tx.SelectContext(ctx, &providers, query, tenantID, limit, offset)
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return providers, total, nil
+}
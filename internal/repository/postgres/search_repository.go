@@ -0,0 +1,94 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/chat"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/db"
+)
+
+// canvasTitleWeight 是画布标题命中相对于消息内容命中的排序权重
+const canvasTitleWeight = 2
+
+// SearchRepository 表示搜索仓库
+// 检索基于 search_vector 列，分词方式见 search_tokens 与 search_query 函数
+type SearchRepository struct {
+	db *db.Postgres
+}
+
+// NewSearchRepository 创建一个新的搜索仓库
+func NewSearchRepository(db *db.Postgres) *SearchRepository {
+	return &SearchRepository{
+		db: db,
+	}
+}
+
+// Search 搜索画布标题和消息内容，按相关度排序
+// 租户隔离由行级安全保证
+func (r *SearchRepository) Search(ctx context.Context, req *chat.SearchRequest) ([]*chat.SearchResult, int, error) {
+	// 构建过滤条件，$1 为搜索词
+	filterClause, args := searchFilters(req, []interface{}{req.Query})
+
+	// 画布标题与消息内容分别检索后合并
+	var parts []string
+	if req.Kind == nil || *req.Kind == chat.SearchKindCanvas {
+		parts = append(parts, fmt.Sprintf(`
+			SELECT 'canvas' AS kind, c.id AS canvas_id, c.title AS canvas_title,
+				NULL::uuid AS message_id, NULL::varchar AS role, c.title AS content,
+				ts_rank(c.search_vector, q.query) * %d AS rank, c.updated_at AS created_at
+			FROM canvases c, q
+			WHERE c.search_vector @@ q.query%s`, canvasTitleWeight, filterClause))
+	}
+	if req.Kind == nil || *req.Kind == chat.SearchKindMessage {
+		parts = append(parts, fmt.Sprintf(`
+			SELECT 'message' AS kind, c.id AS canvas_id, c.title AS canvas_title,
+				m.id AS message_id, m.role AS role, m.content AS content,
+				ts_rank(m.search_vector, q.query, 1) AS rank, m.created_at AS created_at
+			FROM messages m
+			JOIN canvases c ON c.id = m.canvas_id, q
+			WHERE m.search_vector @@ q.query AND m.deleted_at IS NULL AND m.role IN ('user', 'assistant')%s`, filterClause))
+	}
+	results := fmt.Sprintf(`
+		WITH q AS (SELECT search_query($1) AS query)
+		SELECT * FROM (%s
+		) results`, strings.Join(parts, "\n\t\t\tUNION ALL"))
+
+	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM (%s) matched", results)
+	query := fmt.Sprintf(`%s
+		ORDER BY rank DESC, created_at DESC
+		LIMIT $%d OFFSET $%d
+	`, results, len(args)+1, len(args)+2)
+
+	var total int
+	var hits []*chat.SearchResult
+	err := r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		if err := tx.GetContext(ctx, &total, countQuery, args...); err != nil {
+			return err
+		}
+		return tx.SelectContext(ctx, &hits, query, append(args, req.Limit, req.Offset)...)
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return hits, total, nil
+}
+
+// searchFilters 根据搜索请求构建画布过滤条件，画布表的别名须为 c
+// 条件追加在 args 已有参数之后，返回以 AND 开头的条件子句和完整的参数列表
+// 回收站中的画布及其消息不出现在搜索结果中
+func searchFilters(req *chat.SearchRequest, args []interface{}) (string, []interface{}) {
+	filters := []string{"c.deleted_at IS NULL"}
+	if req.WorkspaceID != nil {
+		args = append(args, *req.WorkspaceID)
+		filters = append(filters, fmt.Sprintf("c.workspace_id = $%d", len(args)))
+	}
+	if req.CanvasID != nil {
+		args = append(args, *req.CanvasID)
+		filters = append(filters, fmt.Sprintf("c.id = $%d", len(args)))
+	}
+	return " AND " + strings.Join(filters, " AND "), args
+}
@@ -0,0 +1,93 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/chat"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/db"
+)
+
+// SummaryRepository 表示对话摘要仓库
+type SummaryRepository struct {
+	db *db.Postgres
+}
+
+// NewSummaryRepository 创建一个新的对话摘要仓库
+func NewSummaryRepository(db *db.Postgres) *SummaryRepository {
+	return &SummaryRepository{
+		db: db,
+	}
+}
+
+// Upsert 创建或重建对话摘要，同一分支前缀只保留一份摘要
+func (r *SummaryRepository) Upsert(ctx context.Context, summary *chat.ConversationSummary) error {
+	// 生成 UUID
+	if summary.ID == "" {
+		summary.ID = uuid.New().String()
+	}
+
+	// 默认归属当前租户
+	if summary.TenantID == "" {
+		summary.TenantID, _ = db.TenantFromContext(ctx)
+	}
+
+	// 设置时间戳
+	now := time.Now()
+	if summary.CreatedAt.IsZero() {
+		summary.CreatedAt = now
+	}
+	summary.UpdatedAt = now
+
+	query := `
+		INSERT INTO conversation_summaries (id, tenant_id, canvas_id, covered_message_id, message_count, content, checksum, model_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (covered_message_id) DO UPDATE
+		SET message_count = EXCLUDED.message_count,
+			content = EXCLUDED.content,
+			checksum = EXCLUDED.checksum,
+			model_id = EXCLUDED.model_id,
+			updated_at = EXCLUDED.updated_at
+	`
+
+	return r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(
+			ctx,
+			query,
+			summary.ID,
+			summary.TenantID,
+			summary.CanvasID,
+			summary.CoveredMessageID,
+			summary.MessageCount,
+			summary.Content,
+			summary.Checksum,
+			summary.ModelID,
+			summary.CreatedAt,
+			summary.UpdatedAt,
+		)
+		return err
+	})
+}
+
+// ListByCoveredMessageIDs 获取覆盖到指定消息的摘要，按覆盖的消息数从多到少排列
+func (r *SummaryRepository) ListByCoveredMessageIDs(ctx context.Context, canvasID string, messageIDs []string) ([]*chat.ConversationSummary, error) {
+	query := `
+		SELECT id, tenant_id, canvas_id, covered_message_id, message_count, content, checksum, model_id, created_at, updated_at
+		FROM conversation_summaries
+		WHERE canvas_id = $1 AND covered_message_id = ANY($2)
+		ORDER BY message_count DESC
+	`
+
+	var summaries []*chat.ConversationSummary
+	err := r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		return tx.SelectContext(ctx, &summaries, query, canvasID, pq.Array(messageIDs))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return summaries, nil
+}
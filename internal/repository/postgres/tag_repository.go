@@ -0,0 +1,191 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/chat"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/db"
+)
+
+// TagRepository 表示标签仓库
+type TagRepository struct {
+	db *db.Postgres
+}
+
+// NewTagRepository 创建一个新的标签仓库
+func NewTagRepository(db *db.Postgres) *TagRepository {
+	return &TagRepository{
+		db: db,
+	}
+}
+
+// Create 创建一个新标签
+func (r *TagRepository) Create(ctx context.Context, tag *chat.Tag) error {
+	// 生成 UUID
+	if tag.ID == "" {
+		tag.ID = uuid.New().String()
+	}
+
+	// 默认归属当前租户
+	if tag.TenantID == "" {
+		tag.TenantID, _ = db.TenantFromContext(ctx)
+	}
+
+	// 设置时间戳
+	tag.CreatedAt = time.Now()
+
+	query := `
+		INSERT INTO canvas_tags (id, tenant_id, workspace_id, name, color, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	return r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(
+			ctx,
+			query,
+			tag.ID,
+			tag.TenantID,
+			tag.WorkspaceID,
+			tag.Name,
+			tag.Color,
+			tag.CreatedBy,
+			tag.CreatedAt,
+		)
+		return err
+	})
+}
+
+// GetByID 通过 ID 获取标签
+func (r *TagRepository) GetByID(ctx context.Context, id string) (*chat.Tag, error) {
+	query := `
+		SELECT id, tenant_id, workspace_id, name, color, created_by, created_at
+		FROM canvas_tags
+		WHERE id = $1
+	`
+
+	var tag chat.Tag
+	err := r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		return tx.GetContext(ctx, &tag, query, id)
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("标签不存在: %w", err)
+		}
+		return nil, err
+	}
+
+	return &tag, nil
+}
+
+// Update 修改标签的名称和颜色
+func (r *TagRepository) Update(ctx context.Context, tag *chat.Tag) error {
+	query := `
+		UPDATE canvas_tags
+		SET name = $1, color = $2
+		WHERE id = $3
+	`
+
+	return r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(ctx, query, tag.Name, tag.Color, tag.ID)
+		return err
+	})
+}
+
+// Delete 删除标签，画布上的该标签随之移除
+func (r *TagRepository) Delete(ctx context.Context, id string) error {
+	query := `
+		DELETE FROM canvas_tags
+		WHERE id = $1
+	`
+
+	return r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(ctx, query, id)
+		return err
+	})
+}
+
+// ListByWorkspace 列出工作区的所有标签，按名称排序
+func (r *TagRepository) ListByWorkspace(ctx context.Context, workspaceID string) ([]*chat.Tag, error) {
+	query := `
+		SELECT id, tenant_id, workspace_id, name, color, created_by, created_at
+		FROM canvas_tags
+		WHERE workspace_id = $1
+		ORDER BY LOWER(name) ASC
+	`
+
+	var tags []*chat.Tag
+	err := r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		return tx.SelectContext(ctx, &tags, query, workspaceID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// CountInWorkspace 统计给定标签中属于工作区的数量
+func (r *TagRepository) CountInWorkspace(ctx context.Context, workspaceID string, ids []string) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM canvas_tags
+		WHERE workspace_id = $1 AND id = ANY($2)
+	`
+
+	var count int
+	err := r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		return tx.GetContext(ctx, &count, query, workspaceID, pq.Array(ids))
+	})
+	return count, err
+}
+
+// SetCanvasTags 替换画布的标签
+func (r *TagRepository) SetCanvasTags(ctx context.Context, canvasID string, tagIDs []string) error {
+	deleteQuery := `
+		DELETE FROM canvas_tag_links
+		WHERE canvas_id = $1 AND NOT (tag_id = ANY($2))
+	`
+	insertQuery := `
+		INSERT INTO canvas_tag_links (canvas_id, tag_id, tenant_id, created_at)
+		SELECT $1, tag_id, $2, $3
+		FROM UNNEST($4::uuid[]) AS tag_id
+		ON CONFLICT (canvas_id, tag_id) DO NOTHING
+	`
+
+	tenantID, _ := db.TenantFromContext(ctx)
+	return r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		if _, err := tx.ExecContext(ctx, deleteQuery, canvasID, pq.Array(tagIDs)); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, insertQuery, canvasID, tenantID, time.Now(), pq.Array(tagIDs))
+		return err
+	})
+}
+
+// ListByCanvasIDs 获取多个画布的标签，按标签名称排序
+func (r *TagRepository) ListByCanvasIDs(ctx context.Context, canvasIDs []string) ([]*chat.CanvasTag, error) {
+	query := `
+		SELECT l.canvas_id, t.id, t.tenant_id, t.workspace_id, t.name, t.color, t.created_by, t.created_at
+		FROM canvas_tag_links l
+		JOIN canvas_tags t ON t.id = l.tag_id
+		WHERE l.canvas_id = ANY($1)
+		ORDER BY LOWER(t.name) ASC
+	`
+
+	var tags []*chat.CanvasTag
+	err := r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		return tx.SelectContext(ctx, &tags, query, pq.Array(canvasIDs))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
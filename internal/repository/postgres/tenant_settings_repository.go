@@ -0,0 +1,89 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/user"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/db"
+)
+
+// TenantSettingsRepository 表示租户设置仓库
+type TenantSettingsRepository struct {
+	db *db.Postgres
+}
+
+// NewTenantSettingsRepository 创建一个新的租户设置仓库
+func NewTenantSettingsRepository(db *db.Postgres) *TenantSettingsRepository {
+	return &TenantSettingsRepository{
+		db: db,
+	}
+}
+
+// GetByTenantID 获取租户设置
+func (r *TenantSettingsRepository) GetByTenantID(ctx context.Context, tenantID string) (*user.TenantSettings, error) {
+	query := `
+		SELECT tenant_id, default_model_id, default_system_prompt, canvas_greeting, allowed_providers, default_language, trash_retention_days, code_execution_enabled, created_at, updated_at
+		FROM tenant_settings
+		WHERE tenant_id = $1
+	`
+
+	var settings user.TenantSettings
+	err := r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		return tx.GetContext(ctx, &settings, query, tenantID)
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("租户设置不存在: %w", err)
+		}
+		return nil, err
+	}
+
+	return &settings, nil
+}
+
+// Upsert 创建或更新租户设置
+func (r *TenantSettingsRepository) Upsert(ctx context.Context, settings *user.TenantSettings) error {
+	// 设置时间戳
+	now := time.Now()
+	if settings.CreatedAt.IsZero() {
+		settings.CreatedAt = now
+	}
+	settings.UpdatedAt = now
+
+	query := `
+		INSERT INTO tenant_settings (tenant_id, default_model_id, default_system_prompt, canvas_greeting, allowed_providers, default_language, trash_retention_days, code_execution_enabled, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (tenant_id) DO UPDATE
+		SET default_model_id = EXCLUDED.default_model_id,
+			default_system_prompt = EXCLUDED.default_system_prompt,
+			canvas_greeting = EXCLUDED.canvas_greeting,
+			allowed_providers = EXCLUDED.allowed_providers,
+			default_language = EXCLUDED.default_language,
+			trash_retention_days = EXCLUDED.trash_retention_days,
+			code_execution_enabled = EXCLUDED.code_execution_enabled,
+			updated_at = EXCLUDED.updated_at
+	`
+
+	return r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(
+			ctx,
+			query,
+			settings.TenantID,
+			settings.DefaultModelID,
+			settings.DefaultSystemPrompt,
+			settings.CanvasGreeting,
+			settings.AllowedProviders,
+			settings.DefaultLanguage,
+			settings.TrashRetentionDays,
+			settings.CodeExecutionEnabled,
+			settings.CreatedAt,
+			settings.UpdatedAt,
+		)
+		return err
+	})
+}
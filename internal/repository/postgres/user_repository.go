@@ -1,11 +1,13 @@
 package postgres
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
 
 	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
 	"github.com/zhuiye8/Lyss-chat-server/internal/domain/user"
 	"github.com/zhuiye8/Lyss-chat-server/pkg/db"
 )
@@ -15,7 +17,7 @@ type UserRepository struct {
 	db *db.Postgres
 }
 
-// NewUserRepository 创建一个新的用户仓�?
+// NewUserRepository 创建一个新的用户仓库
 func NewUserRepository(db *db.Postgres) *UserRepository {
 	return &UserRepository{
 		db: db,
@@ -23,7 +25,7 @@ func NewUserRepository(db *db.Postgres) *UserRepository {
 }
 
 // Create 创建一个新用户
-func (r *UserRepository) Create(user *user.User) error {
+func (r *UserRepository) Create(ctx context.Context, user *user.User) error {
 	// 生成 UUID
 	if user.ID == "" {
 		user.ID = uuid.New().String()
@@ -34,22 +36,24 @@ func (r *UserRepository) Create(user *user.User) error {
 		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
 	`
 
-	_, err := r.db.DB.Exec(
-		query,
-		user.ID,
-		user.TenantID,
-		user.Email,
-		user.Password,
-		user.Name,
-		user.AvatarURL,
-		user.Status,
-	)
-
-	return err
+	return r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(
+			ctx,
+			query,
+			user.ID,
+			user.TenantID,
+			user.Email,
+			user.Password,
+			user.Name,
+			user.AvatarURL,
+			user.Status,
+		)
+		return err
+	})
 }
 
 // GetByID 通过 ID 获取用户
-func (r *UserRepository) GetByID(id string) (*user.User, error) {
+func (r *UserRepository) GetByID(ctx context.Context, id string) (*user.User, error) {
 	query := `
 		SELECT id, tenant_id, email, password, name, avatar_url, status, created_at, updated_at
 		FROM users
@@ -57,10 +61,12 @@ func (r *UserRepository) GetByID(id string) (*user.User, error) {
 	`
 
 	var u user.User
-	err := r.db.DB.Get(&u, query, id)
+	err := r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		return tx.GetContext(ctx, &u, query, id)
+	})
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, fmt.Errorf("用户不存�? %w", err)
+			return nil, fmt.Errorf("用户不存在: %w", err)
 		}
 		return nil, err
 	}
@@ -69,7 +75,7 @@ func (r *UserRepository) GetByID(id string) (*user.User, error) {
 }
 
 // GetByEmail 通过邮箱获取用户
-func (r *UserRepository) GetByEmail(email, tenantID string) (*user.User, error) {
+func (r *UserRepository) GetByEmail(ctx context.Context, email, tenantID string) (*user.User, error) {
 	query := `
 		SELECT id, tenant_id, email, password, name, avatar_url, status, created_at, updated_at
 		FROM users
@@ -77,10 +83,12 @@ func (r *UserRepository) GetByEmail(email, tenantID string) (*user.User, error)
 	`
 
 	var u user.User
-	err := r.db.DB.Get(&u, query, email, tenantID)
+	err := r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		return tx.GetContext(ctx, &u, query, email, tenantID)
+	})
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, fmt.Errorf("用户不存�? %w", err)
+			return nil, fmt.Errorf("用户不存在: %w", err)
 		}
 		return nil, err
 	}
@@ -89,37 +97,41 @@ func (r *UserRepository) GetByEmail(email, tenantID string) (*user.User, error)
 }
 
 // Update 更新用户
-func (r *UserRepository) Update(user *user.User) error {
+func (r *UserRepository) Update(ctx context.Context, user *user.User) error {
 	query := `
 		UPDATE users
 		SET name = $1, avatar_url = $2, status = $3, updated_at = NOW()
 		WHERE id = $4
 	`
 
-	_, err := r.db.DB.Exec(
-		query,
-		user.Name,
-		user.AvatarURL,
-		user.Status,
-		user.ID,
-	)
-
-	return err
+	return r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(
+			ctx,
+			query,
+			user.Name,
+			user.AvatarURL,
+			user.Status,
+			user.ID,
+		)
+		return err
+	})
 }
 
 // Delete 删除用户
-func (r *UserRepository) Delete(id string) error {
+func (r *UserRepository) Delete(ctx context.Context, id string) error {
 	query := `
 		DELETE FROM users
 		WHERE id = $1
 	`
 
-	_, err := r.db.DB.Exec(query, id)
-	return err
+	return r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(ctx, query, id)
+		return err
+	})
 }
 
 // List 列出用户
-func (r *UserRepository) List(tenantID string, offset, limit int) ([]*user.User, int, error) {
+func (r *UserRepository) List(ctx context.Context, tenantID string, offset, limit int) ([]*user.User, int, error) {
 	// 获取总数
 	countQuery := `
 		SELECT COUNT(*)
@@ -127,12 +139,6 @@ func (r *UserRepository) List(tenantID string, offset, limit int) ([]*user.User,
 		WHERE tenant_id = $1
 	`
 
-	var total int
-	err := r.db.DB.Get(&total, countQuery, tenantID)
-	if err != nil {
-		return nil, 0, err
-	}
-
 	// 获取用户列表
 	query := `
 		SELECT id, tenant_id, email, password, name, avatar_url, status, created_at, updated_at
@@ -142,8 +148,14 @@ func (r *UserRepository) List(tenantID string, offset, limit int) ([]*user.User,
 		LIMIT $2 OFFSET $3
 	`
 
+	var total int
 	var users []*user.User
-	err = r.db.DB.Select(&users, query, tenantID, limit, offset)
+	err := r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		if err := tx.GetContext(ctx, &total, countQuery, tenantID); err != nil {
+			return err
+		}
+		return tx.SelectContext(ctx, &users, query, tenantID, limit, offset)
+	})
 	if err != nil {
 		return nil, 0, err
 	}
@@ -151,3 +163,26 @@ func (r *UserRepository) List(tenantID string, offset, limit int) ([]*user.User,
 	return users, total, nil
 }
 
+// HasPermission 判断用户是否通过所属角色拥有指定权限，角色受行级安全约束只在当前租户内查找
+func (r *UserRepository) HasPermission(ctx context.Context, userID, code string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM user_roles ur
+			JOIN roles r ON r.id = ur.role_id
+			JOIN role_permissions rp ON rp.role_id = r.id
+			JOIN permissions p ON p.id = rp.permission_id
+			WHERE ur.user_id = $1 AND p.code = $2
+		)
+	`
+
+	var allowed bool
+	err := r.db.InTx(ctx, func(tx *sqlx.Tx) error {
+		return tx.GetContext(ctx, &allowed, query, userID, code)
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return allowed, nil
+}
@@ -67,8 +67,9 @@ func (a *redisClientAdapter) Del(ctx context.Context, keys ...string) error {
 
 // Login 处理用户登录
 func (s *Service) Login(req *user.LoginRequest) (*user.LoginResponse, error) {
-	// 获取用户
-	u, err := s.userRepo.GetByEmail(req.Email, req.TenantID)
+	// 获取用户，登录请求尚未认证，使用请求中的租户
+	ctx := db.WithTenant(context.Background(), req.TenantID)
+	u, err := s.userRepo.GetByEmail(ctx, req.Email, req.TenantID)
 	if err != nil {
 		return nil, err
 	}
@@ -94,9 +95,6 @@ func (s *Service) Login(req *user.LoginRequest) (*user.LoginResponse, error) {
 		return nil, err
 	}
 
-	// 创建上下�?
-	ctx := context.Background()
-
 	// 存储刷新令牌
 	refreshKey := fmt.Sprintf("refresh_token:%s", refreshToken)
 	err = s.redis.Client.Set(ctx, refreshKey, u.ID, time.Duration(s.cfg.JWT.RefreshExpirationHours)*time.Hour).Err()
@@ -138,8 +136,8 @@ func (s *Service) RefreshToken(refreshToken string) (*user.LoginResponse, error)
 		return nil, errors.New("无效的刷新令�?)
 	}
 
-	// 获取用户
-	u, err := s.userRepo.GetByID(userID)
+	// 获取用户，刷新令牌已通过 Redis 校验，此时租户未知，以系统身份查询
+	u, err := s.userRepo.GetByID(db.WithSystem(ctx), userID)
 	if err != nil {
 		return nil, err
 	}
@@ -211,7 +209,8 @@ func (s *Service) RefreshToken(refreshToken string) (*user.LoginResponse, error)
 // Register 处理用户注册
 func (s *Service) Register(req *user.RegisterRequest) (*user.User, error) {
 	// 检查邮箱是否已存在
-	existingUser, err := s.userRepo.GetByEmail(req.Email, req.TenantID)
+	ctx := db.WithTenant(context.Background(), req.TenantID)
+	existingUser, err := s.userRepo.GetByEmail(ctx, req.Email, req.TenantID)
 	if err == nil && existingUser != nil {
 		return nil, errors.New("邮箱已被注册")
 	}
@@ -240,7 +239,7 @@ func (s *Service) Register(req *user.RegisterRequest) (*user.User, error) {
 	}
 
 	// 保存用户
-	err = s.userRepo.Create(newUser)
+	err = s.userRepo.Create(ctx, newUser)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,488 @@
+package chat
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/chat"
+)
+
+// 产物相关错误
+var (
+	ErrArtifactNotFound        = errors.New("产物不存在")
+	ErrArtifactVersionNotFound = errors.New("产物版本不存在")
+	ErrInvalidArtifactVersion  = errors.New("无效的产物版本")
+)
+
+const (
+	// maxArtifactsPerMessage 为单条回复最多提取的产物数
+	maxArtifactsPerMessage = 20
+	// maxArtifactContentBytes 为单个产物内容的最大字节数，超出的代码块不提取
+	maxArtifactContentBytes = 256 << 10
+	// maxArtifactNameLength 为产物名称的最大长度
+	maxArtifactNameLength = 255
+	// maxArtifactContextBytes 为放入上下文的产物内容总字节数，超出部分只列出文件名
+	maxArtifactContextBytes = 48 << 10
+)
+
+// artifactInstructions 为代码画布的系统提示词补充说明，要求模型按文件输出并以差异修改已有文件
+const artifactInstructions = `这是一个代码画布，回复中带有文件名的代码块会保存为文件并记录版本。
+- 新建文件或需要整体重写时，输出完整内容，代码块信息行写明语言和文件名，例如 ` + "```go main.go" + `。
+- 修改已有文件时，不要重复输出整个文件，使用 ` + "```diff main.go" + ` 代码块输出统一差异格式的变更块（以 @@ 开头，保留少量上下文行）。
+- 不需要保存的示例代码不要写文件名。`
+
+var (
+	// fenceOpenPattern 匹配围栏代码块的开始行，捕获围栏和信息行
+	fenceOpenPattern = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})[ \t]*(.*)$")
+	// fileCommentPattern 匹配代码块首行以注释标明的文件名，如 // file: main.go
+	fileCommentPattern = regexp.MustCompile(`(?i)^\s*(?://|#|--|;|/\*|<!--)\s*(?:file|filename|path)\s*:\s*(\S+?)\s*(?:\*/|-->)?\s*$`)
+)
+
+// codeBlock 表示回复中的一个围栏代码块
+type codeBlock struct {
+	name     string
+	language string
+	content  string
+	patch    bool
+}
+
+// ListArtifacts 列出画布的所有产物及其最新内容
+func (s *Service) ListArtifacts(ctx context.Context, canvasID string) ([]*chat.Artifact, error) {
+	if _, err := s.canvasRepo.GetByID(ctx, canvasID); err != nil {
+		return nil, err
+	}
+
+	artifacts, err := s.artifactRepo.ListByCanvas(ctx, canvasID)
+	if err != nil {
+		return nil, err
+	}
+	if artifacts == nil {
+		artifacts = []*chat.Artifact{}
+	}
+	return artifacts, nil
+}
+
+// GetArtifact 获取产物及其最新内容
+func (s *Service) GetArtifact(ctx context.Context, canvasID, id string) (*chat.Artifact, error) {
+	return s.getCanvasArtifact(ctx, canvasID, id)
+}
+
+// ListArtifactVersions 列出产物的所有版本
+func (s *Service) ListArtifactVersions(ctx context.Context, canvasID, id string) ([]*chat.ArtifactVersion, error) {
+	if _, err := s.getCanvasArtifact(ctx, canvasID, id); err != nil {
+		return nil, err
+	}
+
+	versions, err := s.artifactRepo.ListVersions(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if versions == nil {
+		versions = []*chat.ArtifactVersion{}
+	}
+	return versions, nil
+}
+
+// GetArtifactVersion 获取产物的指定版本
+func (s *Service) GetArtifactVersion(ctx context.Context, canvasID, id string, version int) (*chat.ArtifactVersion, error) {
+	if _, err := s.getCanvasArtifact(ctx, canvasID, id); err != nil {
+		return nil, err
+	}
+	return s.getArtifactVersion(ctx, id, version)
+}
+
+// DiffArtifact 比较产物的两个版本
+// to 默认为最新版本，from 默认为 to 的前一版本；版本 0 表示产物创建前的空内容
+func (s *Service) DiffArtifact(ctx context.Context, canvasID, id string, from, to *int) (*chat.ArtifactDiff, error) {
+	artifact, err := s.getCanvasArtifact(ctx, canvasID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	toVersion := artifact.CurrentVersion
+	if to != nil {
+		toVersion = *to
+	}
+	fromVersion := toVersion - 1
+	if from != nil {
+		fromVersion = *from
+	}
+	if fromVersion < 0 || toVersion < 0 || fromVersion > artifact.CurrentVersion || toVersion > artifact.CurrentVersion {
+		return nil, ErrArtifactVersionNotFound
+	}
+
+	fromContent, err := s.artifactVersionContent(ctx, id, fromVersion)
+	if err != nil {
+		return nil, err
+	}
+	toContent, err := s.artifactVersionContent(ctx, id, toVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	return &chat.ArtifactDiff{
+		ArtifactID:  artifact.ID,
+		Name:        artifact.Name,
+		FromVersion: fromVersion,
+		ToVersion:   toVersion,
+		Diff: unifiedDiff(
+			fmt.Sprintf("a/%s@%d", artifact.Name, fromVersion),
+			fmt.Sprintf("b/%s@%d", artifact.Name, toVersion),
+			fromContent,
+			toContent,
+		),
+	}, nil
+}
+
+// RevertArtifact 将产物回退到指定版本，回退以新版本的形式记录，历史版本保持不变
+func (s *Service) RevertArtifact(ctx context.Context, userID, canvasID, id string, req *chat.RevertArtifactRequest) (*chat.Artifact, error) {
+	if _, err := s.getWritableCanvas(ctx, canvasID); err != nil {
+		return nil, err
+	}
+	artifact, err := s.getCanvasArtifact(ctx, canvasID, id)
+	if err != nil {
+		return nil, err
+	}
+	if req.Version == artifact.CurrentVersion {
+		return nil, ErrInvalidArtifactVersion
+	}
+
+	target, err := s.getArtifactVersion(ctx, id, req.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	revertedFrom := target.Version
+	version := &chat.ArtifactVersion{
+		Language:     target.Language,
+		Content:      target.Content,
+		RevertedFrom: &revertedFrom,
+		CreatedBy:    userID,
+	}
+	if err := s.artifactRepo.SaveVersion(ctx, artifact, version); err != nil {
+		return nil, err
+	}
+
+	s.publishArtifacts(ctx, canvasID, userID, []*chat.Artifact{artifact})
+
+	return artifact, nil
+}
+
+// getCanvasArtifact 获取属于画布的产物
+func (s *Service) getCanvasArtifact(ctx context.Context, canvasID, id string) (*chat.Artifact, error) {
+	artifact, err := s.artifactRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrArtifactNotFound
+		}
+		return nil, err
+	}
+	if artifact.CanvasID != canvasID {
+		return nil, ErrArtifactNotFound
+	}
+	return artifact, nil
+}
+
+// getArtifactVersion 获取产物的指定版本
+func (s *Service) getArtifactVersion(ctx context.Context, id string, version int) (*chat.ArtifactVersion, error) {
+	artifactVersion, err := s.artifactRepo.GetVersion(ctx, id, version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrArtifactVersionNotFound
+		}
+		return nil, err
+	}
+	return artifactVersion, nil
+}
+
+// artifactVersionContent 获取产物指定版本的内容，版本 0 为空内容
+func (s *Service) artifactVersionContent(ctx context.Context, id string, version int) (string, error) {
+	if version == 0 {
+		return "", nil
+	}
+	artifactVersion, err := s.getArtifactVersion(ctx, id, version)
+	if err != nil {
+		return "", err
+	}
+	return artifactVersion.Content, nil
+}
+
+// extractArtifacts 将代码画布中助手回复的代码块保存为产物的新版本，并广播变更
+// 同一回复中同名的代码块依次生效；差异代码块应用到产物的当前内容上，无法应用时忽略
+// 提取失败只记录日志，不影响回复本身
+func (s *Service) extractArtifacts(ctx context.Context, userID string, turn *chatTurn, message *chat.Message) {
+	if turn.canvas.Type != chat.CanvasTypeCode {
+		return
+	}
+	blocks := parseCodeBlocks(message.Content)
+	if len(blocks) == 0 {
+		return
+	}
+
+	existing, err := s.artifactRepo.ListByCanvas(ctx, turn.canvas.ID)
+	if err != nil {
+		s.logger.Error("获取画布产物失败", err)
+		return
+	}
+	current := make(map[string]string, len(existing))
+	for _, artifact := range existing {
+		current[artifact.Name] = artifact.Content
+	}
+
+	// 按首次出现的顺序合并同名代码块
+	var names []string
+	pending := make(map[string]*codeBlock)
+	for _, block := range blocks {
+		content := block.content
+		if block.patch {
+			base, ok := current[block.name]
+			if next, merged := pending[block.name]; merged {
+				base, ok = next.content, true
+			}
+			if !ok {
+				s.logger.Warn("差异代码块对应的文件不存在", block.name)
+				continue
+			}
+			patched, err := applyPatch(base, block.content)
+			if err != nil {
+				s.logger.Warn("应用差异代码块失败", fmt.Errorf("%s: %w", block.name, err))
+				continue
+			}
+			content = patched
+		}
+		if len(content) > maxArtifactContentBytes {
+			continue
+		}
+
+		next, ok := pending[block.name]
+		if !ok {
+			if len(names) == maxArtifactsPerMessage {
+				continue
+			}
+			names = append(names, block.name)
+			next = &codeBlock{name: block.name}
+			pending[block.name] = next
+		}
+		next.content = content
+		if !block.patch && block.language != "" {
+			next.language = block.language
+		}
+	}
+
+	var updated []*chat.Artifact
+	for _, name := range names {
+		block := pending[name]
+		if previous, ok := current[name]; ok && previous == block.content {
+			continue
+		}
+
+		artifact := &chat.Artifact{CanvasID: turn.canvas.ID, Name: name}
+		version := &chat.ArtifactVersion{
+			Language:  emptyToNil(&block.language),
+			Content:   block.content,
+			MessageID: &message.ID,
+			CreatedBy: userID,
+		}
+		if err := s.artifactRepo.SaveVersion(ctx, artifact, version); err != nil {
+			s.logger.Error("保存产物失败", err)
+			continue
+		}
+		updated = append(updated, artifact)
+	}
+
+	if len(updated) > 0 {
+		s.publishArtifacts(ctx, turn.canvas.ID, userID, updated)
+	}
+}
+
+// artifactContext 返回代码画布附加到系统提示词中的说明和文件当前内容
+// 文件按名称顺序放入，超出预算的文件只列出名称
+func (s *Service) artifactContext(ctx context.Context, canvas *chat.Canvas) string {
+	if canvas.Type != chat.CanvasTypeCode {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("\n\n")
+	b.WriteString(artifactInstructions)
+
+	artifacts, err := s.artifactRepo.ListByCanvas(ctx, canvas.ID)
+	if err != nil {
+		s.logger.Error("获取画布产物失败", err)
+		return b.String()
+	}
+	if len(artifacts) == 0 {
+		return b.String()
+	}
+
+	b.WriteString("\n\n当前文件的最新内容如下，修改时以此为准：")
+	budget := maxArtifactContextBytes
+	var omitted []string
+	for _, artifact := range artifacts {
+		if len(artifact.Content) > budget {
+			omitted = append(omitted, artifact.Name)
+			continue
+		}
+		budget -= len(artifact.Content)
+
+		language := ""
+		if artifact.Language != nil {
+			language = *artifact.Language
+		}
+		fence := codeFence(artifact.Content)
+		fmt.Fprintf(&b, "\n\n%s%s %s\n%s", fence, language, artifact.Name, artifact.Content)
+		if !strings.HasSuffix(artifact.Content, "\n") {
+			b.WriteByte('\n')
+		}
+		b.WriteString(fence)
+	}
+	if len(omitted) > 0 {
+		fmt.Fprintf(&b, "\n\n以下文件内容过长未列出：%s", strings.Join(omitted, "、"))
+	}
+
+	return b.String()
+}
+
+// publishArtifacts 广播产物的变更
+func (s *Service) publishArtifacts(ctx context.Context, canvasID, userID string, artifacts []*chat.Artifact) {
+	s.bus.Publish(ctx, &chat.CanvasEvent{
+		Type:      chat.CanvasEventArtifactsUpdated,
+		CanvasID:  canvasID,
+		UserID:    userID,
+		Artifacts: artifacts,
+	})
+}
+
+// parseCodeBlocks 返回回复中带有文件名的围栏代码块
+func parseCodeBlocks(content string) []*codeBlock {
+	var named []*codeBlock
+	for _, block := range fencedBlocks(content) {
+		if block.name != "" {
+			named = append(named, block)
+		}
+	}
+	return named
+}
+
+// fencedBlocks 按出现顺序解析回复中的围栏代码块，未闭合的代码块忽略
+// 文件名取自信息行（如 ```go main.go、```python title="app.py"、```main.go），
+// 或代码块首行的文件名注释（如 // file: main.go），该注释行不计入内容；没有文件名时 name 为空
+func fencedBlocks(content string) []*codeBlock {
+	var blocks []*codeBlock
+	lines := strings.Split(content, "\n")
+	for i := 0; i < len(lines); i++ {
+		match := fenceOpenPattern.FindStringSubmatch(lines[i])
+		if match == nil {
+			continue
+		}
+		fence := match[1]
+
+		// 查找闭合围栏：相同字符且不短于开始围栏
+		end := -1
+		for j := i + 1; j < len(lines); j++ {
+			line := strings.TrimSpace(lines[j])
+			if strings.HasPrefix(line, fence) && strings.Trim(line, fence[:1]) == "" {
+				end = j
+				break
+			}
+		}
+		if end < 0 {
+			break
+		}
+
+		body := lines[i+1 : end]
+		i = end
+
+		language, name := parseFenceInfo(match[2])
+		if name == "" && len(body) > 0 {
+			if comment := fileCommentPattern.FindStringSubmatch(body[0]); comment != nil {
+				name = comment[1]
+				body = body[1:]
+			}
+		}
+		name = normalizeArtifactName(name)
+
+		patch := language == "diff" || language == "patch"
+		if patch {
+			language = ""
+		}
+		blocks = append(blocks, &codeBlock{
+			name:     name,
+			language: language,
+			content:  joinLines(body),
+			patch:    patch,
+		})
+	}
+	return blocks
+}
+
+// parseFenceInfo 从信息行中解析语言和文件名
+// 支持 "go main.go"、"go:main.go"、"python title=\"app.py\"" 和只写文件名的 "main.go"
+func parseFenceInfo(info string) (string, string) {
+	fields := strings.Fields(info)
+	if len(fields) == 0 {
+		return "", ""
+	}
+
+	language := fields[0]
+	var name string
+	if before, after, ok := strings.Cut(language, ":"); ok {
+		language, name = before, after
+	} else if strings.ContainsAny(language, "./") {
+		// 只写了文件名
+		language, name = "", language
+	}
+
+	for _, field := range fields[1:] {
+		if key, value, ok := strings.Cut(field, "="); ok {
+			switch strings.ToLower(key) {
+			case "file", "filename", "path", "title", "name":
+				name = strings.Trim(value, `"'`)
+			}
+			continue
+		}
+		if name == "" {
+			name = field
+		}
+	}
+
+	return strings.ToLower(language), name
+}
+
+// normalizeArtifactName 规范化产物名称，不合法的名称返回空字符串
+// 名称为画布内的相对路径，不能包含 .. 或空白字符
+func normalizeArtifactName(name string) string {
+	name = strings.Trim(name, "`\"'")
+	if name == "" || strings.ContainsAny(name, " \t\r\n\\") {
+		return ""
+	}
+	for _, part := range strings.Split(name, "/") {
+		if part == ".." {
+			return ""
+		}
+	}
+	name = strings.TrimLeft(path.Clean("/"+name), "/")
+	if name == "" || len(name) > maxArtifactNameLength {
+		return ""
+	}
+	return name
+}
+
+// codeFence 返回能包住内容的围栏，长度大于内容中最长的连续反引号
+func codeFence(content string) string {
+	longest, run := 0, 0
+	for _, r := range content {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
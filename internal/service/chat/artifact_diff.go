@@ -0,0 +1,302 @@
+package chat
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// diffContextLines 为统一差异格式中每个变更块前后保留的上下文行数
+	diffContextLines = 3
+	// maxDiffCells 为逐行比较的最大规模（旧行数 × 新行数），超出时将差异部分整体视为替换
+	maxDiffCells = 4_000_000
+)
+
+// errPatchMismatch 表示差异与产物的当前内容不匹配
+var errPatchMismatch = errors.New("差异与当前内容不匹配")
+
+// hunkHeaderPattern 匹配统一差异格式的变更块头，如 @@ -1,3 +1,4 @@
+var hunkHeaderPattern = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// diffOp 表示逐行比较的一个操作：' ' 保留、'-' 删除、'+' 新增
+type diffOp struct {
+	kind byte
+	line string
+}
+
+// unifiedDiff 生成从 from 到 to 的统一差异格式文本，内容相同时返回空字符串
+func unifiedDiff(fromName, toName, from, to string) string {
+	ops := diffLines(splitLines(from), splitLines(to))
+
+	var b strings.Builder
+	oldLine, newLine := 1, 1
+	for start := 0; start < len(ops); {
+		// 跳到下一处变更
+		for start < len(ops) && ops[start].kind == ' ' {
+			oldLine++
+			newLine++
+			start++
+		}
+		if start == len(ops) {
+			break
+		}
+
+		// 变更块从变更前的上下文开始，相邻变更间隔不超过两倍上下文时合并为一块
+		begin := start
+		for begin > 0 && start-begin < diffContextLines && ops[begin-1].kind == ' ' {
+			begin--
+		}
+		end := start
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+			next := end
+			for next < len(ops) && ops[next].kind == ' ' {
+				next++
+			}
+			if next == len(ops) || next-end > 2*diffContextLines {
+				end += min(next-end, diffContextLines)
+				break
+			}
+			end = next
+		}
+
+		hunkOld := oldLine - (start - begin)
+		hunkNew := newLine - (start - begin)
+		var oldCount, newCount int
+		var body strings.Builder
+		for _, op := range ops[begin:end] {
+			body.WriteByte(op.kind)
+			body.WriteString(op.line)
+			body.WriteByte('\n')
+			if op.kind != '+' {
+				oldCount++
+			}
+			if op.kind != '-' {
+				newCount++
+			}
+		}
+
+		if b.Len() == 0 {
+			fmt.Fprintf(&b, "--- %s\n+++ %s\n", fromName, toName)
+		}
+		fmt.Fprintf(&b, "@@ -%s +%s @@\n", hunkRange(hunkOld, oldCount), hunkRange(hunkNew, newCount))
+		b.WriteString(body.String())
+
+		// 跳过本块中已输出的部分
+		for _, op := range ops[start:end] {
+			if op.kind != '+' {
+				oldLine++
+			}
+			if op.kind != '-' {
+				newLine++
+			}
+		}
+		start = end
+	}
+
+	return b.String()
+}
+
+// hunkRange 格式化变更块头中的起始行和行数，空范围的起始行为前一行
+func hunkRange(start, count int) string {
+	if count == 0 {
+		start--
+	}
+	if count == 1 {
+		return strconv.Itoa(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// diffLines 逐行比较，先去掉公共前后缀，再对剩余部分求最长公共子序列
+func diffLines(a, b []string) []diffOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	ops := make([]diffOp, 0, len(a)+len(b))
+	for _, line := range a[:prefix] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	ops = append(ops, diffMiddle(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	return ops
+}
+
+// diffMiddle 以最长公共子序列比较两段内容，规模过大时整体视为删除后新增
+func diffMiddle(a, b []string) []diffOp {
+	ops := make([]diffOp, 0, len(a)+len(b))
+	if len(a)*len(b) > maxDiffCells {
+		for _, line := range a {
+			ops = append(ops, diffOp{'-', line})
+		}
+		for _, line := range b {
+			ops = append(ops, diffOp{'+', line})
+		}
+		return ops
+	}
+
+	// lcs[i][j] 为 a[i:] 与 b[j:] 的最长公共子序列长度
+	lcs := make([][]int32, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+	return ops
+}
+
+// patchHunk 表示待应用的变更块，old 为变更前应匹配的行，new 为替换后的行
+type patchHunk struct {
+	start int
+	old   []string
+	new   []string
+}
+
+// applyPatch 将模型输出的统一差异应用到内容上
+// 变更块优先在头部标明的位置匹配，不匹配时在上一块之后查找，模型给出的行号常有偏差
+func applyPatch(content, patch string) (string, error) {
+	hunks, err := parsePatch(patch)
+	if err != nil {
+		return "", err
+	}
+
+	lines := splitLines(content)
+	result := make([]string, 0, len(lines))
+	cursor := 0
+	for _, hunk := range hunks {
+		at := -1
+		if hunk.start >= cursor && matchLines(lines, hunk.start, hunk.old) {
+			at = hunk.start
+		} else {
+			for i := cursor; i+len(hunk.old) <= len(lines); i++ {
+				if matchLines(lines, i, hunk.old) {
+					at = i
+					break
+				}
+			}
+		}
+		if at < 0 {
+			return "", errPatchMismatch
+		}
+
+		result = append(result, lines[cursor:at]...)
+		result = append(result, hunk.new...)
+		cursor = at + len(hunk.old)
+	}
+	result = append(result, lines[cursor:]...)
+
+	return joinLines(result), nil
+}
+
+// parsePatch 解析统一差异中的变更块，忽略文件头和 “\ No newline at end of file” 标记
+// 变更块中没有前缀的空行视为空的上下文行
+func parsePatch(patch string) ([]patchHunk, error) {
+	var hunks []patchHunk
+	var current *patchHunk
+	for _, line := range splitLines(patch) {
+		if match := hunkHeaderPattern.FindStringSubmatch(line); match != nil {
+			// 旧行数为 0 时起始行号表示插入位置的前一行，本身即为 0 起始的下标
+			start, _ := strconv.Atoi(match[1])
+			if start > 0 && match[2] != "0" {
+				start--
+			}
+			hunks = append(hunks, patchHunk{start: start})
+			current = &hunks[len(hunks)-1]
+			continue
+		}
+		if current == nil || strings.HasPrefix(line, `\`) {
+			continue
+		}
+
+		switch {
+		case line == "":
+			current.old = append(current.old, "")
+			current.new = append(current.new, "")
+		case line[0] == ' ':
+			current.old = append(current.old, line[1:])
+			current.new = append(current.new, line[1:])
+		case line[0] == '-':
+			current.old = append(current.old, line[1:])
+		case line[0] == '+':
+			current.new = append(current.new, line[1:])
+		default:
+			return nil, errPatchMismatch
+		}
+	}
+	if len(hunks) == 0 {
+		return nil, errPatchMismatch
+	}
+	return hunks, nil
+}
+
+// matchLines 判断 lines 从 at 开始是否与 expected 逐行相同
+func matchLines(lines []string, at int, expected []string) bool {
+	if at+len(expected) > len(lines) {
+		return false
+	}
+	for i, line := range expected {
+		if lines[at+i] != line {
+			return false
+		}
+	}
+	return true
+}
+
+// splitLines 按行拆分内容，末尾换行不产生空行
+func splitLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
+
+// joinLines 拼接各行，非空内容以换行结尾
+func joinLines(lines []string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
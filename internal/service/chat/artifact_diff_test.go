@@ -0,0 +1,289 @@
+package chat
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// numberedLines 返回内容为 1 到 n 的各行
+func numberedLines(n int) []string {
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = fmt.Sprint(i + 1)
+	}
+	return lines
+}
+
+// formatOps 将比较结果表示为 "前缀+行" 的列表
+func formatOps(ops []diffOp) []string {
+	items := make([]string, 0, len(ops))
+	for _, op := range ops {
+		items = append(items, string(op.kind)+op.line)
+	}
+	return items
+}
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want []string
+	}{
+		{"identical", "a b c", "a b c", []string{" a", " b", " c"}},
+		{"both empty", "", "", []string{}},
+		{"insert in middle", "a c", "a b c", []string{" a", "+b", " c"}},
+		{"delete at start", "x a b", "a b", []string{"-x", " a", " b"}},
+		{"append at end", "a b", "a b y", []string{" a", " b", "+y"}},
+		{"replace between common prefix and suffix", "p q x y s", "p q z s", []string{" p", " q", "-x", "-y", "+z", " s"}},
+		{"common line inside changed region", "a x m y b", "a u m v b", []string{" a", "-x", "+u", " m", "-y", "+v", " b"}},
+		{"all new", "", "a b", []string{"+a", "+b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := formatOps(diffLines(strings.Fields(tt.a), strings.Fields(tt.b)))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("diffLines = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffLinesMaxCellsFallback(t *testing.T) {
+	// 中间部分各有 n 行且只有一行相同，n×n 超过 maxDiffCells 时不再求最长公共子序列
+	build := func(prefix string, n int) []string {
+		lines := []string{"head"}
+		for i := 0; i < n; i++ {
+			if i == n/2 {
+				lines = append(lines, "shared")
+			} else {
+				lines = append(lines, fmt.Sprintf("%s%d", prefix, i))
+			}
+		}
+		return append(lines, "tail")
+	}
+
+	tests := []struct {
+		name       string
+		n          int
+		wantShared bool
+	}{
+		{"within limit", 100, true},
+		{"over limit", 2001, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ops := diffLines(build("old", tt.n), build("new", tt.n))
+
+			// 公共前后缀始终保留
+			if first, last := ops[0], ops[len(ops)-1]; first != (diffOp{' ', "head"}) || last != (diffOp{' ', "tail"}) {
+				t.Fatalf("first/last ops = %q/%q, want kept head and tail", string(first.kind)+first.line, string(last.kind)+last.line)
+			}
+
+			shared := false
+			for _, op := range ops {
+				if op == (diffOp{' ', "shared"}) {
+					shared = true
+				}
+			}
+			if shared != tt.wantShared {
+				t.Fatalf("shared line kept = %v, want %v", shared, tt.wantShared)
+			}
+
+			// 回退时先删除全部旧行再新增全部新行
+			if !tt.wantShared {
+				middle := ops[1 : len(ops)-1]
+				for i, op := range middle {
+					want := byte('-')
+					if i >= tt.n {
+						want = '+'
+					}
+					if op.kind != want {
+						t.Fatalf("op %d = %q, want kind %q", i, string(op.kind)+op.line, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	lines := numberedLines(20)
+	from := joinLines(lines)
+
+	changed := append([]string{}, lines...)
+	changed[9] = "ten"
+	changed = append(changed[:18], append([]string{"18.5"}, changed[18:]...)...)
+
+	tests := []struct {
+		name     string
+		from, to string
+		want     string
+	}{
+		{"identical", from, from, ""},
+		{
+			name: "separate hunks with offsets",
+			from: from,
+			to:   joinLines(changed),
+			want: "--- a\n+++ b\n" +
+				"@@ -7,7 +7,7 @@\n 7\n 8\n 9\n-10\n+ten\n 11\n 12\n 13\n" +
+				"@@ -16,5 +16,6 @@\n 16\n 17\n 18\n+18.5\n 19\n 20\n",
+		},
+		{
+			name: "nearby changes merge into one hunk",
+			from: "1\n2\n3\n4\n5\n6\n7\n8\n",
+			to:   "one\n2\n3\n4\n5\n6\n7\neight\n",
+			want: "--- a\n+++ b\n@@ -1,8 +1,8 @@\n-1\n+one\n 2\n 3\n 4\n 5\n 6\n 7\n-8\n+eight\n",
+		},
+		{"from empty", "", "x\n", "--- a\n+++ b\n@@ -0,0 +1 @@\n+x\n"},
+		{"to empty", "x\ny\n", "", "--- a\n+++ b\n@@ -1,2 +0,0 @@\n-x\n-y\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := unifiedDiff("a", "b", tt.from, tt.to)
+			if got != tt.want {
+				t.Fatalf("unifiedDiff =\n%s\nwant\n%s", got, tt.want)
+			}
+			if got == "" {
+				return
+			}
+
+			// 生成的差异可以应用回原内容
+			applied, err := applyPatch(tt.from, got)
+			if err != nil {
+				t.Fatalf("applyPatch: %v", err)
+			}
+			if applied != tt.to {
+				t.Fatalf("applyPatch = %q, want %q", applied, tt.to)
+			}
+		})
+	}
+}
+
+func TestParsePatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		patch   string
+		want    []patchHunk
+		wantErr bool
+	}{
+		{
+			name:  "headers and markers ignored",
+			patch: "--- a\n+++ b\n@@ -3,2 +3,2 @@\n c\n-d\n+D\n\\ No newline at end of file\n",
+			want:  []patchHunk{{start: 2, old: []string{"c", "d"}, new: []string{"c", "D"}}},
+		},
+		{
+			name:  "bare empty line is context",
+			patch: "@@ -1 +1,2 @@\n\n+x\n",
+			want:  []patchHunk{{start: 0, old: []string{""}, new: []string{"", "x"}}},
+		},
+		{
+			name:  "multiple hunks",
+			patch: "@@ -1 +1 @@\n-a\n+b\n@@ -0,0 +5 @@\n+e\n",
+			want: []patchHunk{
+				{start: 0, old: []string{"a"}, new: []string{"b"}},
+				{start: 0, new: []string{"e"}},
+			},
+		},
+		{
+			name:  "insertion after line",
+			patch: "@@ -3,0 +4 @@\n+x\n",
+			want: []patchHunk{
+				{start: 3, new: []string{"x"}},
+			},
+		},
+		{name: "no hunks", patch: "just text\n", wantErr: true},
+		{name: "unexpected line", patch: "@@ -1 +1 @@\n*a\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parsePatch(tt.patch)
+			if tt.wantErr {
+				if !errors.Is(err, errPatchMismatch) {
+					t.Fatalf("parsePatch error = %v, want %v", err, errPatchMismatch)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parsePatch: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("parsePatch = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyPatch(t *testing.T) {
+	content := joinLines(numberedLines(10))
+
+	tests := []struct {
+		name    string
+		content string
+		patch   string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "exact position",
+			content: content,
+			patch:   "@@ -4,3 +4,3 @@\n 4\n-5\n+five\n 6\n",
+			want:    "1\n2\n3\n4\nfive\n6\n7\n8\n9\n10\n",
+		},
+		{
+			name:    "line numbers shifted down",
+			content: "0a\n0b\n" + content,
+			patch:   "@@ -4,3 +4,3 @@\n 4\n-5\n+five\n 6\n",
+			want:    "0a\n0b\n1\n2\n3\n4\nfive\n6\n7\n8\n9\n10\n",
+		},
+		{
+			name:    "line numbers shifted up",
+			content: "1\n2\n3\n4\n5\n",
+			patch:   "@@ -40,2 +40,2 @@\n-4\n+four\n 5\n",
+			want:    "1\n2\n3\nfour\n5\n",
+		},
+		{
+			name:    "later hunk searched after earlier one",
+			content: "x\ny\nx\ny\n",
+			patch:   "@@ -1 +1 @@\n-x\n+X1\n@@ -1 +1 @@\n-x\n+X2\n",
+			want:    "X1\ny\nX2\ny\n",
+		},
+		{
+			name:    "pure insertion",
+			content: "a\nb\n",
+			patch:   "@@ -1,0 +2 @@\n+inserted\n",
+			want:    "a\ninserted\nb\n",
+		},
+		{
+			name:    "context mismatch",
+			content: content,
+			patch:   "@@ -4,2 +4,2 @@\n 4\n-missing\n+x\n",
+			wantErr: true,
+		},
+		{
+			name:    "hunks out of order",
+			content: "a\nb\n",
+			patch:   "@@ -2 +2 @@\n-b\n+B\n@@ -1 +1 @@\n-a\n+A\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := applyPatch(tt.content, tt.patch)
+			if tt.wantErr {
+				if !errors.Is(err, errPatchMismatch) {
+					t.Fatalf("applyPatch error = %v, want %v", err, errPatchMismatch)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("applyPatch: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("applyPatch = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,351 @@
+package chat
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/zhuiye8/Lyss-chat-server/internal/ai/graphs"
+	"github.com/zhuiye8/Lyss-chat-server/internal/ai/providers"
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/chat"
+)
+
+const (
+	// maxAssistantNameRunes 是助手名称的最大字符数
+	maxAssistantNameRunes = 100
+	// maxAssistantAvatarRunes 是助手头像地址的最大字符数
+	maxAssistantAvatarRunes = 500
+	// maxAssistantTools 是每个助手的最大工具数
+	maxAssistantTools = 32
+)
+
+// 助手错误
+var (
+	ErrAssistantNotFound  = errors.New("助手不存在")
+	ErrAssistantForbidden = errors.New("只有助手创建者可以修改助手")
+	ErrInvalidAssistant   = errors.New("无效的助手")
+)
+
+// assistantToolName 校验工具名称，与 OpenAI 函数名称的限制一致
+var assistantToolName = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// emptyToolParameters 是未声明参数的工具使用的 JSON Schema
+var emptyToolParameters = json.RawMessage(`{"type":"object","properties":{}}`)
+
+// ListAssistants 列出当前用户可见的助手
+func (s *Service) ListAssistants(ctx context.Context, req *chat.AssistantListRequest, page, pageSize int) ([]*chat.Assistant, int, error) {
+	if req.Scope != nil && !validPromptScope(*req.Scope) {
+		return nil, 0, fmt.Errorf("%w: 范围只能为 user、workspace 或 tenant", ErrInvalidAssistant)
+	}
+	req.Offset = (page - 1) * pageSize
+	req.Limit = pageSize
+
+	assistants, total, err := s.assistantRepo.List(ctx, req)
+	if err != nil {
+		return nil, 0, err
+	}
+	if assistants == nil {
+		assistants = []*chat.Assistant{}
+	}
+
+	return assistants, total, nil
+}
+
+// GetAssistant 获取当前用户可见的助手
+func (s *Service) GetAssistant(ctx context.Context, userID, id string) (*chat.Assistant, error) {
+	return s.getVisibleAssistant(ctx, userID, id)
+}
+
+// CreateAssistant 创建助手，新建的助手为创建者私有，发布后才对他人可见
+func (s *Service) CreateAssistant(ctx context.Context, userID string, req *chat.CreateAssistantRequest) (*chat.Assistant, error) {
+	assistant := &chat.Assistant{
+		Scope:            chat.AssistantScopeUser,
+		Name:             req.Name,
+		Description:      req.Description,
+		Avatar:           req.Avatar,
+		SystemPrompt:     req.SystemPrompt,
+		ModelID:          req.ModelID,
+		Tools:            req.Tools,
+		KnowledgeBaseIDs: pq.StringArray(req.KnowledgeBaseIDs),
+		CreatedBy:        userID,
+	}
+	if req.Settings != nil {
+		assistant.Settings = *req.Settings
+	}
+
+	if err := s.normalizeAssistant(ctx, assistant); err != nil {
+		return nil, err
+	}
+
+	if err := s.assistantRepo.Create(ctx, assistant); err != nil {
+		return nil, err
+	}
+
+	return assistant, nil
+}
+
+// UpdateAssistant 修改助手，使用该助手的画布在下一次生成时使用新配置
+func (s *Service) UpdateAssistant(ctx context.Context, userID, id string, req *chat.UpdateAssistantRequest) (*chat.Assistant, error) {
+	assistant, err := s.getOwnAssistant(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		assistant.Name = *req.Name
+	}
+	if req.Description != nil {
+		assistant.Description = emptyToNil(req.Description)
+	}
+	if req.Avatar != nil {
+		assistant.Avatar = emptyToNil(req.Avatar)
+	}
+	if req.SystemPrompt != nil {
+		assistant.SystemPrompt = emptyToNil(req.SystemPrompt)
+	}
+	if req.ModelID != nil {
+		assistant.ModelID = emptyToNil(req.ModelID)
+	}
+	if req.Settings != nil {
+		assistant.Settings = *req.Settings
+	}
+	if req.Tools != nil {
+		assistant.Tools = *req.Tools
+	}
+	if req.KnowledgeBaseIDs != nil {
+		assistant.KnowledgeBaseIDs = pq.StringArray(*req.KnowledgeBaseIDs)
+	}
+
+	if err := s.normalizeAssistant(ctx, assistant); err != nil {
+		return nil, err
+	}
+
+	if err := s.assistantRepo.Update(ctx, assistant); err != nil {
+		return nil, err
+	}
+
+	return assistant, nil
+}
+
+// PublishAssistant 将助手发布到工作区或整个租户
+func (s *Service) PublishAssistant(ctx context.Context, userID, id string, req *chat.PublishAssistantRequest) (*chat.Assistant, error) {
+	assistant, err := s.getOwnAssistant(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	switch req.Scope {
+	case chat.AssistantScopeWorkspace:
+		if req.WorkspaceID == nil || *req.WorkspaceID == "" {
+			return nil, fmt.Errorf("%w: 发布到工作区时必须指定工作区", ErrInvalidAssistant)
+		}
+		assistant.WorkspaceID = req.WorkspaceID
+	case chat.AssistantScopeTenant:
+		assistant.WorkspaceID = nil
+	default:
+		return nil, fmt.Errorf("%w: 只能发布到 workspace 或 tenant", ErrInvalidAssistant)
+	}
+	assistant.Scope = req.Scope
+
+	if err := s.assistantRepo.Update(ctx, assistant); err != nil {
+		return nil, err
+	}
+
+	return assistant, nil
+}
+
+// UnpublishAssistant 取消发布助手，已使用该助手的画布不受影响
+func (s *Service) UnpublishAssistant(ctx context.Context, userID, id string) (*chat.Assistant, error) {
+	assistant, err := s.getOwnAssistant(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	assistant.Scope = chat.AssistantScopeUser
+	assistant.WorkspaceID = nil
+	if err := s.assistantRepo.Update(ctx, assistant); err != nil {
+		return nil, err
+	}
+
+	return assistant, nil
+}
+
+// DeleteAssistant 删除助手，使用该助手的画布回退为普通画布
+func (s *Service) DeleteAssistant(ctx context.Context, userID, id string) error {
+	if _, err := s.getOwnAssistant(ctx, userID, id); err != nil {
+		return err
+	}
+	return s.assistantRepo.Delete(ctx, id)
+}
+
+// normalizeAssistant 校验助手字段，并按默认模型校验生成参数
+func (s *Service) normalizeAssistant(ctx context.Context, assistant *chat.Assistant) error {
+	name, ok := normalizeName(assistant.Name, maxAssistantNameRunes)
+	if !ok {
+		return fmt.Errorf("%w: 名称不能为空且不能超过 %d 个字符", ErrInvalidAssistant, maxAssistantNameRunes)
+	}
+	assistant.Name = name
+
+	if assistant.Avatar != nil && len([]rune(*assistant.Avatar)) > maxAssistantAvatarRunes {
+		return fmt.Errorf("%w: 头像地址不能超过 %d 个字符", ErrInvalidAssistant, maxAssistantAvatarRunes)
+	}
+	if assistant.SystemPrompt != nil && len([]rune(*assistant.SystemPrompt)) > maxPromptContentRunes {
+		return fmt.Errorf("%w: 系统提示词不能超过 %d 个字符", ErrInvalidAssistant, maxPromptContentRunes)
+	}
+
+	// 系统提示词只使用 SystemPrompt 字段
+	assistant.Settings.SystemPrompt = nil
+
+	tools, err := normalizeAssistantTools(assistant.Tools)
+	if err != nil {
+		return err
+	}
+	assistant.Tools = tools
+
+	// 知识库检索尚未接入生成流程，拒绝关联以免助手看似使用了知识库
+	if len(assistant.KnowledgeBaseIDs) > 0 {
+		return fmt.Errorf("%w: 暂不支持关联知识库", ErrInvalidAssistant)
+	}
+	assistant.KnowledgeBaseIDs = pq.StringArray{}
+
+	// 指定默认模型时校验其可用并按其校验生成参数
+	if assistant.ModelID == nil {
+		return nil
+	}
+	settings, err := s.settings.GetSettings(ctx)
+	if err != nil {
+		return err
+	}
+	if err := s.checkModelAllowed(ctx, settings, *assistant.ModelID); err != nil {
+		return err
+	}
+	m, err := s.modelRepo.GetByID(ctx, *assistant.ModelID)
+	if err != nil {
+		return err
+	}
+	return validateSettings(m, assistant.Settings)
+}
+
+// normalizeAssistantTools 校验工具定义，未声明参数的工具使用空对象参数
+func normalizeAssistantTools(tools chat.AssistantTools) (chat.AssistantTools, error) {
+	if len(tools) > maxAssistantTools {
+		return nil, fmt.Errorf("%w: 每个助手最多启用 %d 个工具", ErrInvalidAssistant, maxAssistantTools)
+	}
+
+	normalized := make(chat.AssistantTools, 0, len(tools))
+	seen := make(map[string]bool, len(tools))
+	for _, tool := range tools {
+		tool.Name = strings.TrimSpace(tool.Name)
+		if !assistantToolName.MatchString(tool.Name) {
+			return nil, fmt.Errorf("%w: 工具名称只能包含字母、数字、下划线和连字符，且不能超过 64 个字符", ErrInvalidAssistant)
+		}
+		if seen[tool.Name] {
+			return nil, fmt.Errorf("%w: 工具 %s 重复", ErrInvalidAssistant, tool.Name)
+		}
+		seen[tool.Name] = true
+
+		if len(tool.Parameters) == 0 || string(tool.Parameters) == "null" {
+			tool.Parameters = emptyToolParameters
+		} else {
+			var schema map[string]any
+			if err := json.Unmarshal(tool.Parameters, &schema); err != nil {
+				return nil, fmt.Errorf("%w: 工具 %s 的参数必须是 JSON Schema 对象", ErrInvalidAssistant, tool.Name)
+			}
+		}
+		normalized = append(normalized, tool)
+	}
+
+	return normalized, nil
+}
+
+// getVisibleAssistant 获取当前用户可见的助手，私有助手仅创建者可见
+func (s *Service) getVisibleAssistant(ctx context.Context, userID, id string) (*chat.Assistant, error) {
+	assistant, err := s.assistantRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrAssistantNotFound
+		}
+		return nil, err
+	}
+	if assistant.Scope == chat.AssistantScopeUser && assistant.CreatedBy != userID {
+		return nil, ErrAssistantNotFound
+	}
+	return assistant, nil
+}
+
+// getOwnAssistant 获取当前用户创建的助手，其他可见用户只能使用不能修改
+func (s *Service) getOwnAssistant(ctx context.Context, userID, id string) (*chat.Assistant, error) {
+	assistant, err := s.getVisibleAssistant(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if assistant.CreatedBy != userID {
+		return nil, ErrAssistantForbidden
+	}
+	return assistant, nil
+}
+
+// canvasAssistant 获取画布使用的助手，画布未使用助手时返回 nil
+// 画布创建后助手可能被取消发布，已使用该助手的画布不再校验可见性
+func (s *Service) canvasAssistant(ctx context.Context, canvas *chat.Canvas) (*chat.Assistant, error) {
+	if canvas.AssistantID == nil {
+		return nil, nil
+	}
+	assistant, err := s.assistantRepo.GetByID(ctx, *canvas.AssistantID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return assistant, nil
+}
+
+// assistantSettings 返回助手的生成设置，助手的系统提示词作为默认系统提示词
+// 画布未使用助手时返回空设置
+func assistantSettings(assistant *chat.Assistant) chat.GenerationSettings {
+	if assistant == nil {
+		return chat.GenerationSettings{}
+	}
+	settings := assistant.Settings
+	settings.SystemPrompt = assistant.SystemPrompt
+	return settings
+}
+
+// chatGraph 返回本轮使用的聊天图形，使用助手的画布按助手的当前配置构建
+// 未配置聊天图形时返回 ErrChatUnavailable
+func (s *Service) chatGraph(ctx context.Context, turn *chatTurn) (*graphs.ChatGraph, error) {
+	if s.aiGraphs == nil {
+		return nil, ErrChatUnavailable
+	}
+	if turn.assistant == nil {
+		return s.aiGraphs.Chat, nil
+	}
+
+	tools := make([]providers.ToolDefinition, 0, len(turn.assistant.Tools))
+	for _, tool := range turn.assistant.Tools {
+		tools = append(tools, providers.ToolDefinition{
+			Name:        tool.Name,
+			Description: tool.Description,
+			Parameters:  tool.Parameters,
+		})
+	}
+
+	return s.aiGraphs.ForAssistant(ctx, graphs.AssistantConfig{
+		ModelID: turn.model.ID,
+		Tools:   tools,
+	})
+}
+
+// emptyToNil 将空字符串转换为 nil
+func emptyToNil(value *string) *string {
+	if value == nil || *value == "" {
+		return nil
+	}
+	return value
+}
@@ -0,0 +1,307 @@
+package chat
+
+import (
+	"context"
+	"errors"
+
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/chat"
+)
+
+// 分支操作错误
+var (
+	ErrMessageNotInCanvas  = errors.New("消息不属于该画布")
+	ErrInvalidBranchTarget = errors.New("该消息不支持此操作")
+)
+
+// RegenerateMessage 重新生成助手消息，新回复作为原消息的兄弟节点
+func (s *Service) RegenerateMessage(ctx context.Context, userID, canvasID, messageID string) (*chat.Message, error) {
+	turn, userMessage, err := s.prepareRegenerate(ctx, canvasID, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.reply(ctx, userID, turn, userMessage)
+}
+
+// StreamRegenerateMessage 流式重新生成助手消息
+func (s *Service) StreamRegenerateMessage(ctx context.Context, userID, canvasID, messageID string) (<-chan GenerationEvent, error) {
+	turn, userMessage, err := s.prepareRegenerate(ctx, canvasID, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.streamReply(ctx, userID, turn, userMessage), nil
+}
+
+// EditMessage 编辑用户消息，创建新的兄弟用户消息并生成回复
+func (s *Service) EditMessage(ctx context.Context, userID, canvasID, messageID string, req *chat.EditMessageRequest) (*chat.Message, error) {
+	turn, userMessage, err := s.prepareEdit(ctx, userID, canvasID, messageID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.reply(ctx, userID, turn, userMessage)
+}
+
+// StreamEditMessage 流式编辑用户消息
+func (s *Service) StreamEditMessage(ctx context.Context, userID, canvasID, messageID string, req *chat.EditMessageRequest) (<-chan GenerationEvent, error) {
+	turn, userMessage, err := s.prepareEdit(ctx, userID, canvasID, messageID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.streamReply(ctx, userID, turn, userMessage), nil
+}
+
+// GetMessageTree 获取画布的完整消息树
+func (s *Service) GetMessageTree(ctx context.Context, canvasID string) (*chat.MessageTree, error) {
+	canvas, err := s.canvasRepo.GetByID(ctx, canvasID)
+	if err != nil {
+		return nil, err
+	}
+
+	messages, err := s.messageRepo.ListByCanvasID(ctx, canvasID)
+	if err != nil {
+		return nil, err
+	}
+
+	tree, _ := buildMessageTree(canvas, messages)
+	return tree, nil
+}
+
+// GetActivePath 获取画布当前分支从根到叶子的消息路径
+func (s *Service) GetActivePath(ctx context.Context, canvasID string, page, pageSize int) ([]*chat.MessageNode, int, error) {
+	canvas, err := s.canvasRepo.GetByID(ctx, canvasID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	messages, err := s.messageRepo.ListByCanvasID(ctx, canvasID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	_, path := buildMessageTree(canvas, messages)
+
+	// 路径上的节点不展开子节点
+	items := make([]*chat.MessageNode, 0, len(path))
+	for _, node := range path {
+		flat := *node
+		flat.Children = nil
+		items = append(items, &flat)
+	}
+
+	// 分页
+	total := len(items)
+	offset := (page - 1) * pageSize
+	if offset >= total {
+		return []*chat.MessageNode{}, total, nil
+	}
+	end := offset + pageSize
+	if end > total {
+		end = total
+	}
+
+	return items[offset:end], total, nil
+}
+
+// SetActiveLeaf 切换画布当前分支，指定消息不是叶子时沿最新子消息下行至叶子
+func (s *Service) SetActiveLeaf(ctx context.Context, canvasID, messageID string) (*chat.Canvas, error) {
+	canvas, err := s.getWritableCanvas(ctx, canvasID)
+	if err != nil {
+		return nil, err
+	}
+
+	messages, err := s.messageRepo.ListByCanvasID(ctx, canvasID)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[string]*chat.MessageNode, len(messages))
+	tree, _ := buildMessageTree(canvas, messages)
+	indexNodes(tree.Roots, nodes)
+
+	node, ok := nodes[messageID]
+	if !ok {
+		return nil, ErrMessageNotInCanvas
+	}
+	leaf := latestLeaf(node)
+
+	if err := s.canvasRepo.SetActiveLeaf(ctx, canvasID, leaf.ID); err != nil {
+		return nil, err
+	}
+	canvas.ActiveLeafID = &leaf.ID
+
+	s.publishCanvas(ctx, canvas)
+
+	return canvas, nil
+}
+
+// prepareRegenerate 校验待重新生成的助手消息，返回其对应的用户消息
+func (s *Service) prepareRegenerate(ctx context.Context, canvasID, messageID string) (*chatTurn, *chat.Message, error) {
+	turn, err := s.prepareTurn(ctx, canvasID, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	message, err := s.getCanvasMessage(ctx, canvasID, messageID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if message.Role != chat.MessageRoleAssistant || message.ParentID == nil {
+		return nil, nil, ErrInvalidBranchTarget
+	}
+
+	// 父消息须为用户消息，否则新回复会接在错误的位置
+	userMessage, err := s.getCanvasMessage(ctx, canvasID, *message.ParentID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if userMessage.Role != chat.MessageRoleUser {
+		return nil, nil, ErrInvalidBranchTarget
+	}
+
+	return turn, userMessage, nil
+}
+
+// prepareEdit 校验待编辑的用户消息，并以相同父消息创建新的用户消息
+func (s *Service) prepareEdit(ctx context.Context, userID, canvasID, messageID string, req *chat.EditMessageRequest) (*chatTurn, *chat.Message, error) {
+	turn, err := s.prepareTurn(ctx, canvasID, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	original, err := s.getCanvasMessage(ctx, canvasID, messageID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if original.Role != chat.MessageRoleUser {
+		return nil, nil, ErrInvalidBranchTarget
+	}
+
+	userMessage, err := s.createUserMessage(ctx, userID, turn.canvas, original.ParentID, req.Content, req.Metadata)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return turn, userMessage, nil
+}
+
+// getCanvasMessage 获取消息并校验其属于指定画布，已删除的消息视为不存在
+func (s *Service) getCanvasMessage(ctx context.Context, canvasID, messageID string) (*chat.Message, error) {
+	message, err := s.messageRepo.GetByID(ctx, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if message.CanvasID != canvasID || message.DeletedAt != nil {
+		return nil, ErrMessageNotInCanvas
+	}
+	return message, nil
+}
+
+// buildMessageTree 根据画布消息构建消息树，返回树以及当前分支从根到叶子的路径
+// messages 需按创建时间正序排列，兄弟节点的序号即创建顺序
+// 已删除的消息不出现在树中，其子消息挂到最近的未删除祖先下，没有这样的祖先时作为根节点
+func buildMessageTree(canvas *chat.Canvas, messages []*chat.Message) (*chat.MessageTree, []*chat.MessageNode) {
+	tree := &chat.MessageTree{
+		CanvasID: canvas.ID,
+		Roots:    []*chat.MessageNode{},
+	}
+
+	// 建立消息索引和未删除消息的节点索引
+	byID := make(map[string]*chat.Message, len(messages))
+	nodes := make(map[string]*chat.MessageNode, len(messages))
+	var latest *chat.MessageNode
+	for _, msg := range messages {
+		byID[msg.ID] = msg
+		if msg.DeletedAt == nil {
+			nodes[msg.ID] = &chat.MessageNode{Message: msg}
+			latest = nodes[msg.ID]
+		}
+	}
+
+	// 挂接父子关系，记录每个节点在树中的父节点
+	parents := make(map[string]*chat.MessageNode, len(nodes))
+	for _, msg := range messages {
+		node, ok := nodes[msg.ID]
+		if !ok {
+			continue
+		}
+		if parentID := liveAncestorID(byID, msg.ParentID); parentID != "" {
+			parent := nodes[parentID]
+			parent.Children = append(parent.Children, node)
+			parents[msg.ID] = parent
+			continue
+		}
+		tree.Roots = append(tree.Roots, node)
+	}
+
+	// 计算兄弟序号
+	assignSiblingIndices(tree.Roots)
+
+	// 确定当前叶子：优先使用画布指针，否则取最新消息所在分支
+	leaf := latest
+	if canvas.ActiveLeafID != nil {
+		if node, ok := nodes[*canvas.ActiveLeafID]; ok {
+			leaf = node
+		}
+	}
+	if leaf == nil {
+		return tree, nil
+	}
+	leaf = latestLeaf(leaf)
+	tree.ActiveLeafID = &leaf.ID
+
+	// 自叶子向上回溯当前路径
+	var path []*chat.MessageNode
+	for node := leaf; node != nil; node = parents[node.ID] {
+		node.Active = true
+		path = append(path, node)
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+
+	return tree, path
+}
+
+// liveAncestorID 从 id 指向的消息开始沿父消息回溯，返回第一条未删除消息的 ID，不存在时返回空字符串
+func liveAncestorID(byID map[string]*chat.Message, id *string) string {
+	// 回溯步数不超过消息数，避免异常数据中的环导致死循环
+	for steps := 0; id != nil && steps <= len(byID); steps++ {
+		msg, ok := byID[*id]
+		if !ok {
+			return ""
+		}
+		if msg.DeletedAt == nil {
+			return msg.ID
+		}
+		id = msg.ParentID
+	}
+	return ""
+}
+
+// assignSiblingIndices 递归设置节点的兄弟序号和兄弟数量
+func assignSiblingIndices(siblings []*chat.MessageNode) {
+	for i, node := range siblings {
+		node.SiblingIndex = i
+		node.SiblingCount = len(siblings)
+		assignSiblingIndices(node.Children)
+	}
+}
+
+// indexNodes 递归建立消息 ID 到节点的索引
+func indexNodes(siblings []*chat.MessageNode, nodes map[string]*chat.MessageNode) {
+	for _, node := range siblings {
+		nodes[node.ID] = node
+		indexNodes(node.Children, nodes)
+	}
+}
+
+// latestLeaf 沿最新的子消息下行，返回所在分支的叶子节点
+func latestLeaf(node *chat.MessageNode) *chat.MessageNode {
+	for len(node.Children) > 0 {
+		node = node.Children[len(node.Children)-1]
+	}
+	return node
+}
@@ -0,0 +1,175 @@
+package chat
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/chat"
+)
+
+// formatTree 将消息树表示为 "id(子节点,...)" 的形式，当前分支上的节点带 * 标记，
+// 有兄弟节点时附加 #序号/数量
+func formatTree(nodes []*chat.MessageNode) string {
+	items := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		item := node.ID
+		if node.Active {
+			item += "*"
+		}
+		if node.SiblingCount > 1 {
+			item += "#" + strconv.Itoa(node.SiblingIndex) + "/" + strconv.Itoa(node.SiblingCount)
+		}
+		if len(node.Children) > 0 {
+			item += "(" + formatTree(node.Children) + ")"
+		}
+		items = append(items, item)
+	}
+	return strings.Join(items, ",")
+}
+
+func TestBuildMessageTree(t *testing.T) {
+	deleted := time.Unix(1700000000, 0)
+	// message 创建一条消息，parent 为空表示根消息
+	message := func(id, parent string, isDeleted bool) *chat.Message {
+		m := &chat.Message{ID: id, CanvasID: "c"}
+		if parent != "" {
+			m.ParentID = &parent
+		}
+		if isDeleted {
+			m.DeletedAt = &deleted
+		}
+		return m
+	}
+
+	branches := []*chat.Message{
+		message("u1", "", false),
+		message("a1", "u1", false),
+		message("a2", "u1", false),
+		message("u2", "a1", false),
+	}
+
+	tests := []struct {
+		name     string
+		messages []*chat.Message
+		active   string
+		wantTree string
+		wantPath string
+	}{
+		{
+			name:     "empty",
+			wantTree: "",
+			wantPath: "",
+		},
+		{
+			name:     "linear",
+			messages: []*chat.Message{message("u1", "", false), message("a1", "u1", false), message("u2", "a1", false)},
+			wantTree: "u1*(a1*(u2*))",
+			wantPath: "u1,a1,u2",
+		},
+		{
+			name:     "latest message without pointer",
+			messages: branches,
+			wantTree: "u1*(a1*#0/2(u2*),a2#1/2)",
+			wantPath: "u1,a1,u2",
+		},
+		{
+			name:     "pointer to leaf",
+			messages: branches,
+			active:   "a2",
+			wantTree: "u1*(a1#0/2(u2),a2*#1/2)",
+			wantPath: "u1,a2",
+		},
+		{
+			name:     "pointer descends to latest leaf",
+			messages: branches,
+			active:   "u1",
+			wantTree: "u1*(a1#0/2(u2),a2*#1/2)",
+			wantPath: "u1,a2",
+		},
+		{
+			name:     "unknown pointer falls back to latest message",
+			messages: branches,
+			active:   "missing",
+			wantTree: "u1*(a1*#0/2(u2*),a2#1/2)",
+			wantPath: "u1,a1,u2",
+		},
+		{
+			name:     "deleted message reattaches children",
+			messages: []*chat.Message{message("u1", "", false), message("a1", "u1", true), message("u2", "a1", false)},
+			wantTree: "u1*(u2*)",
+			wantPath: "u1,u2",
+		},
+		{
+			name:     "deleted root promotes children",
+			messages: []*chat.Message{message("r", "", true), message("a", "r", false), message("b", "r", false)},
+			wantTree: "a#0/2,b*#1/2",
+			wantPath: "b",
+		},
+		{
+			name:     "deleted pointer falls back to latest message",
+			messages: []*chat.Message{message("u1", "", false), message("a1", "u1", false), message("a2", "u1", true)},
+			active:   "a2",
+			wantTree: "u1*(a1*)",
+			wantPath: "u1,a1",
+		},
+		{
+			name:     "deleted cycle",
+			messages: []*chat.Message{message("x", "y", true), message("y", "x", true), message("c", "x", false)},
+			wantTree: "c*",
+			wantPath: "c",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			canvas := &chat.Canvas{ID: "c"}
+			if tt.active != "" {
+				canvas.ActiveLeafID = &tt.active
+			}
+			// 每个用例使用新的消息副本，避免共享节点状态
+			messages := make([]*chat.Message, len(tt.messages))
+			for i, m := range tt.messages {
+				copied := *m
+				messages[i] = &copied
+			}
+
+			tree, path := buildMessageTree(canvas, messages)
+			if got := formatTree(tree.Roots); got != tt.wantTree {
+				t.Fatalf("tree = %q, want %q", got, tt.wantTree)
+			}
+			ids := make([]string, 0, len(path))
+			for _, node := range path {
+				ids = append(ids, node.ID)
+			}
+			if got := strings.Join(ids, ","); got != tt.wantPath {
+				t.Fatalf("path = %q, want %q", got, tt.wantPath)
+			}
+
+			switch {
+			case len(path) == 0 && tree.ActiveLeafID != nil:
+				t.Fatalf("ActiveLeafID = %q, want nil", *tree.ActiveLeafID)
+			case len(path) > 0 && (tree.ActiveLeafID == nil || *tree.ActiveLeafID != path[len(path)-1].ID):
+				t.Fatalf("ActiveLeafID = %v, want %q", tree.ActiveLeafID, path[len(path)-1].ID)
+			}
+		})
+	}
+}
+
+func TestLatestLeaf(t *testing.T) {
+	leaf := &chat.MessageNode{Message: &chat.Message{ID: "leaf"}}
+	root := &chat.MessageNode{
+		Message: &chat.Message{ID: "root"},
+		Children: []*chat.MessageNode{
+			{Message: &chat.Message{ID: "old"}, Children: []*chat.MessageNode{{Message: &chat.Message{ID: "old-leaf"}}}},
+			{Message: &chat.Message{ID: "new"}, Children: []*chat.MessageNode{leaf}},
+		},
+	}
+
+	if got := latestLeaf(root); got != leaf {
+		t.Fatalf("latestLeaf = %s, want leaf", got.ID)
+	}
+	if got := latestLeaf(leaf); got != leaf {
+		t.Fatalf("latestLeaf of leaf = %s, want leaf", got.ID)
+	}
+}
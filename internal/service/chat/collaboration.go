@@ -0,0 +1,86 @@
+package chat
+
+import (
+	"context"
+	"time"
+
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/chat"
+)
+
+// SubscribeCanvas 订阅画布协作事件，并在订阅期间登记用户在线
+// ctx 取消时退出订阅并广播最新的在线用户
+func (s *Service) SubscribeCanvas(ctx context.Context, canvasID, userID string) (<-chan *chat.CanvasEvent, error) {
+	// 校验画布可访问
+	if _, err := s.canvasRepo.GetByID(ctx, canvasID); err != nil {
+		return nil, err
+	}
+
+	// 先订阅再登记在线，确保能收到自己加入后的在线事件
+	events := s.bus.Subscribe(ctx, canvasID)
+	session := s.bus.Join(ctx, canvasID, userID)
+	s.publishPresence(ctx, canvasID, userID)
+
+	go func() {
+		ticker := time.NewTicker(presenceRefresh)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				s.bus.Refresh(ctx, canvasID, session)
+			case <-ctx.Done():
+				leaveCtx := context.WithoutCancel(ctx)
+				s.bus.Leave(leaveCtx, canvasID, session)
+				s.publishPresence(leaveCtx, canvasID, userID)
+				return
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// SetTyping 广播用户在画布上的输入状态
+func (s *Service) SetTyping(ctx context.Context, canvasID, userID string, typing bool) error {
+	// 校验画布可访问
+	if _, err := s.canvasRepo.GetByID(ctx, canvasID); err != nil {
+		return err
+	}
+
+	s.bus.Publish(ctx, &chat.CanvasEvent{
+		Type:     chat.CanvasEventTyping,
+		CanvasID: canvasID,
+		UserID:   userID,
+		Typing:   typing,
+	})
+	return nil
+}
+
+// publishPresence 广播画布当前的在线用户
+func (s *Service) publishPresence(ctx context.Context, canvasID, userID string) {
+	s.bus.Publish(ctx, &chat.CanvasEvent{
+		Type:     chat.CanvasEventPresence,
+		CanvasID: canvasID,
+		UserID:   userID,
+		Users:    s.bus.OnlineUsers(ctx, canvasID),
+	})
+}
+
+// publishMessage 广播新创建的消息
+func (s *Service) publishMessage(ctx context.Context, message *chat.Message) {
+	s.bus.Publish(ctx, &chat.CanvasEvent{
+		Type:     chat.CanvasEventMessageCreated,
+		CanvasID: message.CanvasID,
+		UserID:   message.CreatedBy,
+		Message:  message,
+	})
+}
+
+// publishCanvas 广播画布的变更
+func (s *Service) publishCanvas(ctx context.Context, canvas *chat.Canvas) {
+	s.bus.Publish(ctx, &chat.CanvasEvent{
+		Type:     chat.CanvasEventCanvasUpdated,
+		CanvasID: canvas.ID,
+		Canvas:   canvas,
+	})
+}
@@ -0,0 +1,186 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/chat"
+)
+
+// 对比模式错误
+var (
+	ErrInvalidCompare  = errors.New("无效的对比请求")
+	ErrNotCompareReply = errors.New("该消息不是对比模式的回复")
+)
+
+// maxCompareModels 为一次对比最多参与的模型数
+const maxCompareModels = 4
+
+// compareStream 表示对比模式中一个模型的生成事件
+type compareStream struct {
+	modelID string
+	events  <-chan GenerationEvent
+}
+
+// StreamCompare 以对比模式发送消息：同一条用户消息同时发给多个模型，各模型并发流式生成
+// 各模型的回复作为用户消息下的兄弟助手消息保存，带有模型和对比标识，当前分支停留在用户消息上，
+// 由用户选定其中一条回复后继续对话；返回的事件流合并了所有模型的事件
+func (s *Service) StreamCompare(ctx context.Context, userID, canvasID string, req *chat.CompareMessageRequest) (<-chan GenerationEvent, error) {
+	if len(req.ModelIDs) < 2 || len(req.ModelIDs) > maxCompareModels {
+		return nil, ErrInvalidCompare
+	}
+
+	base, err := s.prepareTurn(ctx, canvasID, req.Settings)
+	if err != nil {
+		return nil, err
+	}
+
+	// 每个模型各自一轮，共享画布、助手和生成设置，并按各自的模型校验
+	compareID := uuid.New().String()
+	turns := make([]*chatTurn, 0, len(req.ModelIDs))
+	seen := make(map[string]bool, len(req.ModelIDs))
+	for _, modelID := range req.ModelIDs {
+		if seen[modelID] {
+			return nil, ErrInvalidCompare
+		}
+		seen[modelID] = true
+
+		if err := s.checkModelAllowed(ctx, base.settings, modelID); err != nil {
+			return nil, err
+		}
+		m, err := s.modelRepo.GetByID(ctx, modelID)
+		if err != nil {
+			return nil, err
+		}
+		if err := validateSettings(m, base.generation); err != nil {
+			return nil, err
+		}
+
+		turn := *base
+		turn.model = m
+		turn.compareID = compareID
+		turns = append(turns, &turn)
+	}
+
+	// 创建用户消息
+	userMessage, err := s.createUserMessage(ctx, userID, base.canvas, s.defaultParentID(base.canvas, req.ParentID), req.Content, req.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	// 各模型的生成相互独立，可以分别停止和重新订阅
+	streams := make([]compareStream, 0, len(turns))
+	for _, turn := range turns {
+		streams = append(streams, compareStream{
+			modelID: turn.model.ID,
+			events:  s.streamReply(ctx, userID, turn, userMessage),
+		})
+	}
+
+	return s.mergeCompareStreams(ctx, streams), nil
+}
+
+// mergeCompareStreams 将各模型的生成事件合并为一个事件流，事件按到达顺序转发并标明所属模型
+// 合并后的事件序号在整个事件流内递增，单个生成内的序号保留在 CompareEvent 中
+func (s *Service) mergeCompareStreams(ctx context.Context, streams []compareStream) <-chan GenerationEvent {
+	out := make(chan GenerationEvent)
+
+	var mu sync.Mutex
+	var seq int64
+	var wg sync.WaitGroup
+	wg.Add(len(streams))
+	for _, stream := range streams {
+		go func() {
+			defer wg.Done()
+			for event := range stream.events {
+				data, err := json.Marshal(chat.CompareEvent{
+					GenerationID: event.GenerationID,
+					ModelID:      stream.modelID,
+					EventID:      event.ID,
+					Event:        event.Data,
+				})
+				if err != nil {
+					s.logger.Error("序列化对比事件失败", err)
+					continue
+				}
+
+				// 序号的分配和发送在同一临界区内，保证事件流中的序号递增
+				mu.Lock()
+				seq++
+				select {
+				case out <- GenerationEvent{GenerationID: event.GenerationID, ID: seq, Data: data}:
+				case <-ctx.Done():
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+
+	return out
+}
+
+// SelectCompareReply 选定对比模式中的一条回复继续对话
+// 选定的回复成为画布当前分支，并在首次选定时刷新摘要、自动命名画布和提取产物
+func (s *Service) SelectCompareReply(ctx context.Context, userID, canvasID, messageID string) (*chat.Canvas, error) {
+	message, err := s.getCanvasMessage(ctx, canvasID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if message.Role != chat.MessageRoleAssistant || message.ParentID == nil {
+		return nil, ErrNotCompareReply
+	}
+
+	var metadata chat.AssistantMetadata
+	if len(message.Metadata) == 0 || json.Unmarshal(message.Metadata, &metadata) != nil || metadata.CompareID == "" {
+		return nil, ErrNotCompareReply
+	}
+
+	// 以选定回复的模型构建本轮，用于刷新摘要和自动命名
+	turn, err := s.prepareTurn(ctx, canvasID, nil)
+	if err != nil {
+		return nil, err
+	}
+	m, err := s.modelRepo.GetByID(ctx, metadata.ModelID)
+	if err != nil {
+		return nil, err
+	}
+	turn.model = m
+
+	changed, err := s.messageRepo.SelectCompareReply(ctx, canvasID, metadata.CompareID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	for _, updated := range changed {
+		s.bus.Publish(ctx, &chat.CanvasEvent{
+			Type:     chat.CanvasEventMessageUpdated,
+			CanvasID: canvasID,
+			UserID:   userID,
+			Message:  updated,
+		})
+	}
+
+	canvas, err := s.SetActiveLeaf(ctx, canvasID, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 重复选定同一条回复时不再重复处理
+	if !metadata.Selected {
+		history, err := s.messageRepo.GetBranch(ctx, *message.ParentID)
+		if err != nil {
+			s.logger.Error("获取对话历史失败", err)
+		} else {
+			s.continueBranch(ctx, userID, turn, history, message)
+		}
+	}
+
+	return canvas, nil
+}
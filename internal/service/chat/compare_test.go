@@ -0,0 +1,162 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/chat"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/logger"
+)
+
+// receiveCompareEvent 从合并后的事件流读取一条事件并解析其中的对比事件
+func receiveCompareEvent(t *testing.T, out <-chan GenerationEvent) (GenerationEvent, chat.CompareEvent) {
+	t.Helper()
+	var event GenerationEvent
+	var ok bool
+	select {
+	case event, ok = <-out:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("读取事件超时")
+	}
+	if !ok {
+		t.Fatalf("事件流已关闭")
+	}
+
+	var compare chat.CompareEvent
+	if err := json.Unmarshal(event.Data, &compare); err != nil {
+		t.Fatalf("解析对比事件失败: %v", err)
+	}
+	return event, compare
+}
+
+// generationEvent 创建一条生成事件，内容为 "<生成ID>/<序号>"
+func generationEvent(generationID string, id int64) GenerationEvent {
+	data, _ := json.Marshal(fmt.Sprintf("%s/%d", generationID, id))
+	return GenerationEvent{GenerationID: generationID, ID: id, Data: data}
+}
+
+func TestMergeCompareStreamsArrivalOrder(t *testing.T) {
+	s := &Service{logger: logger.New("error")}
+	a := make(chan GenerationEvent)
+	b := make(chan GenerationEvent)
+	out := s.mergeCompareStreams(context.Background(), []compareStream{
+		{modelID: "model-a", events: a},
+		{modelID: "model-b", events: b},
+	})
+
+	// 输入通道无缓冲，逐条发送并读取可以精确控制事件到达的先后
+	arrivals := []struct {
+		events chan GenerationEvent
+		event  GenerationEvent
+		model  string
+	}{
+		{a, generationEvent("gen-a", 1), "model-a"},
+		{b, generationEvent("gen-b", 1), "model-b"},
+		{b, generationEvent("gen-b", 2), "model-b"},
+		{a, generationEvent("gen-a", 2), "model-a"},
+	}
+	for i, arrival := range arrivals {
+		arrival.events <- arrival.event
+		event, compare := receiveCompareEvent(t, out)
+
+		if event.ID != int64(i+1) || event.GenerationID != arrival.event.GenerationID {
+			t.Fatalf("event %d = %s/%d, want %s/%d", i, event.GenerationID, event.ID, arrival.event.GenerationID, i+1)
+		}
+		want := chat.CompareEvent{
+			GenerationID: arrival.event.GenerationID,
+			ModelID:      arrival.model,
+			EventID:      arrival.event.ID,
+			Event:        arrival.event.Data,
+		}
+		if !reflect.DeepEqual(compare, want) {
+			t.Fatalf("compare event %d = %+v, want %+v", i, compare, want)
+		}
+	}
+
+	close(a)
+	close(b)
+	select {
+	case _, ok := <-out:
+		if ok {
+			t.Fatalf("输入关闭后仍收到事件")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("输入关闭后事件流未关闭")
+	}
+}
+
+func TestMergeCompareStreamsConcurrent(t *testing.T) {
+	s := &Service{logger: logger.New("error")}
+	const models, perModel = 4, 50
+
+	streams := make([]compareStream, models)
+	var wg sync.WaitGroup
+	for i := range streams {
+		events := make(chan GenerationEvent)
+		generationID := fmt.Sprintf("gen-%d", i)
+		streams[i] = compareStream{modelID: fmt.Sprintf("model-%d", i), events: events}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer close(events)
+			for id := int64(1); id <= perModel; id++ {
+				events <- generationEvent(generationID, id)
+			}
+		}()
+	}
+
+	// 合并后的序号连续递增，每个模型的事件保持各自的先后顺序
+	out := s.mergeCompareStreams(context.Background(), streams)
+	last := make(map[string]int64)
+	var seq int64
+	for event := range out {
+		seq++
+		if event.ID != seq {
+			t.Fatalf("event ID = %d, want %d", event.ID, seq)
+		}
+		var compare chat.CompareEvent
+		if err := json.Unmarshal(event.Data, &compare); err != nil {
+			t.Fatalf("解析对比事件失败: %v", err)
+		}
+		if compare.EventID != last[compare.ModelID]+1 {
+			t.Fatalf("%s event %d after %d", compare.ModelID, compare.EventID, last[compare.ModelID])
+		}
+		last[compare.ModelID] = compare.EventID
+	}
+	wg.Wait()
+
+	if seq != models*perModel {
+		t.Fatalf("received %d events, want %d", seq, models*perModel)
+	}
+}
+
+func TestMergeCompareStreamsCanceled(t *testing.T) {
+	s := &Service{logger: logger.New("error")}
+	ctx, cancel := context.WithCancel(context.Background())
+	events := make(chan GenerationEvent, 3)
+	out := s.mergeCompareStreams(ctx, []compareStream{{modelID: "model", events: events}})
+
+	// 取消后无人读取，剩余事件被丢弃，输入关闭后事件流随之关闭
+	cancel()
+	for id := int64(1); id <= 3; id++ {
+		events <- generationEvent("gen", id)
+	}
+	close(events)
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case _, ok := <-out:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("取消后事件流未关闭")
+		}
+	}
+}
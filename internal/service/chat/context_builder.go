@@ -0,0 +1,114 @@
+package chat
+
+import (
+	"github.com/cloudwego/eino/schema"
+	"github.com/zhuiye8/Lyss-chat-server/internal/ai/tokenizer"
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/chat"
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/model"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/config"
+)
+
+// 上下文超出预算时的处理策略
+const (
+	ContextStrategyDropOldest     = "drop_oldest"
+	ContextStrategyTruncateOldest = "truncate_oldest"
+)
+
+const (
+	// messageOverheadTokens 是每条消息的角色、分隔符等格式开销
+	messageOverheadTokens = 4
+	// minTruncatedTokens 是截断后消息至少保留的 token 数，不足时直接丢弃
+	minTruncatedTokens = 32
+	// summaryPrefix 是摘要消息的前缀
+	summaryPrefix = "以下是此前对话的摘要：\n"
+)
+
+// ContextBuilder 表示对话上下文构建器，负责将分支历史裁剪到模型的上下文预算内
+type ContextBuilder struct {
+	strategy             string
+	defaultContextWindow int
+	reservedOutputTokens int
+}
+
+// NewContextBuilder 创建一个新的对话上下文构建器
+func NewContextBuilder(cfg config.ChatConfig) *ContextBuilder {
+	strategy := cfg.ContextStrategy
+	if strategy != ContextStrategyTruncateOldest {
+		strategy = ContextStrategyDropOldest
+	}
+
+	return &ContextBuilder{
+		strategy:             strategy,
+		defaultContextWindow: cfg.DefaultContextWindow,
+		reservedOutputTokens: cfg.ReservedOutputTokens,
+	}
+}
+
+// Budget 计算模型可用于输入的 token 数：上下文窗口减去预留的输出 token
+// maxOutputTokens 为画布或消息设置的最大输出 token，未设置时使用模型参数
+func (b *ContextBuilder) Budget(m *model.Model, maxOutputTokens *int) int {
+	reserved := m.MaxOutputTokens(b.reservedOutputTokens)
+	if maxOutputTokens != nil {
+		reserved = *maxOutputTokens
+	}
+	return m.ContextWindow(b.defaultContextWindow) - reserved
+}
+
+// Build 将分支历史裁剪到预算内并转换为 Eino 消息格式
+// history 按时间正序排列，最后一条为当前用户消息；系统消息、摘要和当前用户消息始终保留
+func (b *ContextBuilder) Build(tok tokenizer.Tokenizer, systemMessage, summary string, history []*chat.Message, budget int) []*schema.Message {
+	remaining := budget - tok.Count(systemMessage) - messageOverheadTokens
+
+	// 摘要作为系统消息放在历史之前
+	var summaryMessage *schema.Message
+	if summary != "" {
+		summaryMessage = schema.SystemMessage(summaryPrefix + summary)
+		remaining -= tok.Count(summaryMessage.Content) + messageOverheadTokens
+	}
+
+	// 从最新的消息向前选取
+	selected := make([]*schema.Message, 0, len(history))
+	for i := len(history) - 1; i >= 0; i-- {
+		msg := history[i]
+		cost := tok.Count(msg.Content) + messageOverheadTokens
+
+		if i == len(history)-1 || cost <= remaining {
+			selected = append(selected, toSchemaMessage(msg.Role, msg.Content))
+			remaining -= cost
+			continue
+		}
+
+		// 超出预算：按策略截断最早保留的一条，其余更早的消息全部丢弃
+		if b.strategy == ContextStrategyTruncateOldest && remaining-messageOverheadTokens >= minTruncatedTokens {
+			content := tokenizer.TruncateTail(tok, msg.Content, remaining-messageOverheadTokens)
+			selected = append(selected, toSchemaMessage(msg.Role, content))
+		}
+		break
+	}
+
+	// 恢复时间正序
+	for i, j := 0, len(selected)-1; i < j; i, j = i+1, j-1 {
+		selected[i], selected[j] = selected[j], selected[i]
+	}
+
+	// 裁剪后不以助手消息开头，避免模型看到没有提问的回答
+	if len(selected) < len(history) {
+		for len(selected) > 1 && selected[0].Role == schema.Assistant {
+			selected = selected[1:]
+		}
+	}
+
+	if summaryMessage != nil {
+		selected = append([]*schema.Message{summaryMessage}, selected...)
+	}
+
+	return selected
+}
+
+// toSchemaMessage 转换为 Eino 消息
+func toSchemaMessage(role, content string) *schema.Message {
+	return &schema.Message{
+		Role:    schema.RoleType(role),
+		Content: content,
+	}
+}
@@ -0,0 +1,91 @@
+package chat
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/chat"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/config"
+)
+
+// runeTokenizer 每个字符计为一个 token，便于计算预算
+type runeTokenizer struct{}
+
+func (runeTokenizer) Count(text string) int { return utf8.RuneCountInString(text) }
+
+func TestContextBuilderBuild(t *testing.T) {
+	history := []*chat.Message{
+		{Role: chat.MessageRoleUser, Content: "question-0"},
+		{Role: chat.MessageRoleAssistant, Content: "answer---1"},
+		{Role: chat.MessageRoleUser, Content: strings.Repeat("a", 60) + strings.Repeat("b", 40)},
+		{Role: chat.MessageRoleAssistant, Content: "answer---3"},
+		{Role: chat.MessageRoleUser, Content: "question-4"},
+	}
+	// 系统消息 3 个 token，加上格式开销共 7 个；其余每条短消息占 14 个
+	const system = "sys"
+
+	tests := []struct {
+		name     string
+		strategy string
+		summary  string
+		budget   int
+		want     []string
+	}{
+		{
+			name:     "everything fits",
+			strategy: ContextStrategyDropOldest,
+			budget:   1000,
+			want:     []string{"question-0", "answer---1", history[2].Content, "answer---3", "question-4"},
+		},
+		{
+			name:     "summary goes first",
+			strategy: ContextStrategyDropOldest,
+			summary:  "earlier",
+			budget:   1000,
+			want:     []string{summaryPrefix + "earlier", "question-0", "answer---1", history[2].Content, "answer---3", "question-4"},
+		},
+		{
+			name:     "drop oldest skips a leading assistant message",
+			strategy: ContextStrategyDropOldest,
+			budget:   7 + 28 + 40,
+			want:     []string{"question-4"},
+		},
+		{
+			name:     "truncate oldest keeps the tail",
+			strategy: ContextStrategyTruncateOldest,
+			budget:   7 + 28 + 40,
+			want:     []string{strings.Repeat("b", 36), "answer---3", "question-4"},
+		},
+		{
+			name:     "truncate oldest drops below the minimum",
+			strategy: ContextStrategyTruncateOldest,
+			budget:   7 + 28 + 30,
+			want:     []string{"question-4"},
+		},
+		{
+			name:     "current message always kept",
+			strategy: ContextStrategyTruncateOldest,
+			budget:   0,
+			want:     []string{"question-4"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := NewContextBuilder(config.ChatConfig{ContextStrategy: tt.strategy})
+			messages := builder.Build(runeTokenizer{}, system, tt.summary, history, tt.budget)
+
+			got := make([]string, 0, len(messages))
+			for _, msg := range messages {
+				got = append(got, msg.Content)
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Fatalf("Build = %q, want %q", got, tt.want)
+			}
+			if tt.summary != "" && messages[0].Role != schema.System {
+				t.Fatalf("summary role = %s, want %s", messages[0].Role, schema.System)
+			}
+		})
+	}
+}
@@ -0,0 +1,291 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/zhuiye8/Lyss-chat-server/internal/ai/providers"
+	"github.com/zhuiye8/Lyss-chat-server/internal/ai/providers/openai"
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/chat"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/config"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/db"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/logger"
+)
+
+const (
+	// maxEmbeddingInputRunes 是送入嵌入模型的单条消息最大字符数
+	maxEmbeddingInputRunes = 8000
+	// defaultMaxCandidates 是未配置时进程内检索参与计算的最近消息数
+	defaultMaxCandidates = 5000
+)
+
+// 向量检索方式
+const (
+	EmbeddingBackendAuto     = "auto"
+	EmbeddingBackendPGVector = "pgvector"
+	EmbeddingBackendMemory   = "memory"
+)
+
+// ErrSemanticSearchDisabled 表示未启用或无法使用语义搜索
+var ErrSemanticSearchDisabled = errors.New("语义搜索未启用")
+
+// EmbeddingIndexer 表示消息向量索引器
+// 新消息保存后异步向量化；Sweep 定期补全缺失的向量，
+// 更换嵌入模型后已有消息的向量不再匹配当前模型，也由该任务重新生成
+type EmbeddingIndexer struct {
+	repo        chat.EmbeddingRepository
+	embedder    providers.Embedder
+	cfg         config.EmbeddingConfig
+	logger      *logger.Logger
+	backendOnce sync.Once
+	useVector   bool
+}
+
+// NewEmbeddingIndexer 创建一个新的消息向量索引器
+// 未启用或提供商不支持向量化时，索引器不做任何处理，语义搜索返回 ErrSemanticSearchDisabled
+func NewEmbeddingIndexer(repo chat.EmbeddingRepository, cfg config.EmbeddingConfig, logger *logger.Logger) *EmbeddingIndexer {
+	idx := &EmbeddingIndexer{
+		repo:   repo,
+		cfg:    cfg,
+		logger: logger,
+	}
+	if !cfg.Enabled {
+		return idx
+	}
+
+	// 通过提供商注册表创建提供商，并检查其是否支持向量化
+	registry := providers.NewProviderRegistry(logger)
+	registry.RegisterFactory(openai.ProviderID, &openai.Factory{})
+	provider, err := registry.GetProvider(cfg.Provider, cfg.APIKey)
+	if err != nil {
+		logger.Error("创建向量化提供商失败", err)
+		return idx
+	}
+	embedder, ok := provider.(providers.Embedder)
+	if !ok {
+		logger.Error("模型提供商不支持向量化", providers.ErrEmbeddingNotSupported)
+		return idx
+	}
+	idx.embedder = embedder
+
+	return idx
+}
+
+// Index 在后台为新保存的消息生成向量
+func (idx *EmbeddingIndexer) Index(ctx context.Context, message *chat.Message) {
+	if idx.embedder == nil || !embeddable(message) {
+		return
+	}
+
+	go func() {
+		if err := idx.embed(context.WithoutCancel(ctx), []*chat.Message{message}); err != nil {
+			idx.logger.Error("消息向量化失败", err)
+		}
+	}()
+}
+
+// Search 按与搜索词的余弦相似度检索消息和画布
+func (idx *EmbeddingIndexer) Search(ctx context.Context, req *chat.SearchRequest) ([]*chat.SearchResult, int, error) {
+	if idx.embedder == nil {
+		return nil, 0, ErrSemanticSearchDisabled
+	}
+
+	vectors, err := idx.embedder.Embed(ctx, idx.cfg.ModelID, []string{req.Query})
+	if err != nil {
+		return nil, 0, err
+	}
+	query := vectors[0]
+
+	if idx.vectorBackend(ctx) {
+		return idx.repo.SearchSimilar(ctx, req, idx.cfg.ModelID, query, idx.cfg.MinScore)
+	}
+
+	// 未启用 pgvector 时在进程内计算相似度，只检索最近的消息，避免加载租户的全部向量
+	limit := idx.cfg.MaxCandidates
+	if limit <= 0 {
+		limit = defaultMaxCandidates
+	}
+	candidates, err := idx.repo.ListCandidates(ctx, req, idx.cfg.ModelID, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	return rankCandidates(candidates, query, req, idx.cfg.MinScore)
+}
+
+// vectorBackend 判断是否使用 pgvector 检索，auto 模式下首次调用时检测数据库
+func (idx *EmbeddingIndexer) vectorBackend(ctx context.Context) bool {
+	idx.backendOnce.Do(func() {
+		switch idx.cfg.Backend {
+		case EmbeddingBackendPGVector:
+			idx.useVector = true
+		case EmbeddingBackendMemory:
+			idx.useVector = false
+		default:
+			supported, err := idx.repo.VectorSupported(ctx)
+			if err != nil {
+				idx.logger.Warn("检测 pgvector 失败，使用进程内检索", err)
+			}
+			idx.useVector = supported
+		}
+	})
+	return idx.useVector
+}
+
+// Sweep 定期补全缺失或由旧模型生成的向量，直到 ctx 取消；未启用向量化时直接返回
+func (idx *EmbeddingIndexer) Sweep(ctx context.Context) {
+	if idx.embedder == nil {
+		return
+	}
+
+	interval := time.Duration(idx.cfg.SweepInterval) * time.Second
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// 后台任务跨租户处理消息，向量的租户取自消息本身
+	ctx = db.WithSystem(ctx)
+	for {
+		idx.backfill(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// backfill 分批向量化没有当前模型向量的消息，直到没有待处理消息或出错
+func (idx *EmbeddingIndexer) backfill(ctx context.Context) {
+	batchSize := idx.cfg.BatchSize
+	if batchSize <= 0 {
+		batchSize = 64
+	}
+
+	for {
+		pending, err := idx.repo.ListPending(ctx, idx.cfg.ModelID, batchSize)
+		if err != nil {
+			idx.logger.Error("获取待向量化消息失败", err)
+			return
+		}
+		if len(pending) == 0 {
+			return
+		}
+		if err := idx.embed(ctx, pending); err != nil {
+			idx.logger.Error("批量向量化消息失败", err)
+			return
+		}
+		if len(pending) < batchSize {
+			return
+		}
+	}
+}
+
+// embed 向量化消息并保存
+func (idx *EmbeddingIndexer) embed(ctx context.Context, messages []*chat.Message) error {
+	texts := make([]string, len(messages))
+	for i, message := range messages {
+		texts[i] = truncateRunes(message.Content, maxEmbeddingInputRunes)
+	}
+
+	vectors, err := idx.embedder.Embed(ctx, idx.cfg.ModelID, texts)
+	if err != nil {
+		return err
+	}
+
+	embeddings := make([]*chat.MessageEmbedding, len(messages))
+	for i, message := range messages {
+		embeddings[i] = &chat.MessageEmbedding{
+			MessageID: message.ID,
+			TenantID:  message.TenantID,
+			CanvasID:  message.CanvasID,
+			Model:     idx.cfg.ModelID,
+			Embedding: vectors[i],
+		}
+	}
+	return idx.repo.Upsert(ctx, embeddings)
+}
+
+// embeddable 判断消息是否需要向量化，只处理有内容的用户和助手消息
+func embeddable(message *chat.Message) bool {
+	if message.Content == "" {
+		return false
+	}
+	return message.Role == chat.MessageRoleUser || message.Role == chat.MessageRoleAssistant
+}
+
+// rankCandidates 在进程内计算候选消息与查询向量的余弦相似度，返回排序后的一页结果
+// 画布结果取画布内最相似的消息，与 pgvector 检索的结果一致
+func rankCandidates(candidates []*chat.EmbeddingCandidate, query []float32, req *chat.SearchRequest, minScore float64) ([]*chat.SearchResult, int, error) {
+	var messages []*chat.SearchResult
+	best := make(map[string]*chat.SearchResult)
+	for _, candidate := range candidates {
+		if len(candidate.Embedding) != len(query) {
+			continue
+		}
+		score := cosineSimilarity(candidate.Embedding, query)
+		if score < minScore {
+			continue
+		}
+
+		result := candidate.SearchResult
+		result.Kind = chat.SearchKindMessage
+		result.Rank = score
+		messages = append(messages, &result)
+
+		if current, ok := best[result.CanvasID]; !ok || score > current.Rank {
+			canvasResult := result
+			canvasResult.Kind = chat.SearchKindCanvas
+			best[result.CanvasID] = &canvasResult
+		}
+	}
+
+	var results []*chat.SearchResult
+	if req.Kind == nil || *req.Kind == chat.SearchKindCanvas {
+		for _, result := range best {
+			results = append(results, result)
+		}
+	}
+	if req.Kind == nil || *req.Kind == chat.SearchKindMessage {
+		results = append(results, messages...)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Rank != results[j].Rank {
+			return results[i].Rank > results[j].Rank
+		}
+		return results[i].CreatedAt.After(results[j].CreatedAt)
+	})
+
+	// 分页
+	total := len(results)
+	start := req.Offset
+	if start > total {
+		start = total
+	}
+	end := start + req.Limit
+	if end > total {
+		end = total
+	}
+
+	return results[start:end], total, nil
+}
+
+// cosineSimilarity 计算两个等长向量的余弦相似度
+func cosineSimilarity(a, b []float32) float64 {
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
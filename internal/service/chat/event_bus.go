@@ -0,0 +1,174 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/chat"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/db"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/logger"
+)
+
+const (
+	// presenceTTL 是在线状态的有效期，订阅者需在此之前刷新
+	presenceTTL = 60 * time.Second
+	// presenceRefresh 是订阅者刷新在线状态的间隔
+	presenceRefresh = 20 * time.Second
+)
+
+// EventBus 表示基于 Redis 发布订阅的画布事件总线
+// 任意实例发布的事件都会推送给所有实例上订阅该画布的客户端
+type EventBus struct {
+	redis  *db.Redis
+	logger *logger.Logger
+}
+
+// NewEventBus 创建一个新的画布事件总线
+func NewEventBus(redis *db.Redis, logger *logger.Logger) *EventBus {
+	return &EventBus{
+		redis:  redis,
+		logger: logger,
+	}
+}
+
+// Publish 发布画布事件，失败时只记录日志，不影响业务流程
+func (b *EventBus) Publish(ctx context.Context, event *chat.CanvasEvent) {
+	if b.redis == nil {
+		return
+	}
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		b.logger.Error("序列化画布事件失败", err)
+		return
+	}
+	if err := b.redis.Client.Publish(ctx, canvasChannel(event.CanvasID), payload).Err(); err != nil {
+		b.logger.Error("发布画布事件失败", err)
+	}
+}
+
+// Subscribe 订阅画布事件，ctx 取消时关闭返回的通道
+func (b *EventBus) Subscribe(ctx context.Context, canvasID string) <-chan *chat.CanvasEvent {
+	out := make(chan *chat.CanvasEvent)
+	if b.redis == nil {
+		close(out)
+		return out
+	}
+
+	pubsub := b.redis.Client.Subscribe(ctx, canvasChannel(canvasID))
+	go func() {
+		defer close(out)
+		defer pubsub.Close()
+
+		messages := pubsub.Channel()
+		for {
+			select {
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+				var event chat.CanvasEvent
+				if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+					b.logger.Error("解析画布事件失败", err)
+					continue
+				}
+				select {
+				case out <- &event:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+// Join 登记订阅者在线，返回订阅者会话 ID
+// 同一用户可以从多个连接同时在线，在线用户列表按用户去重
+func (b *EventBus) Join(ctx context.Context, canvasID, userID string) string {
+	session := userID + ":" + uuid.New().String()
+	b.touchPresence(ctx, canvasID, session)
+	return session
+}
+
+// Refresh 刷新订阅者在线状态
+func (b *EventBus) Refresh(ctx context.Context, canvasID, session string) {
+	b.touchPresence(ctx, canvasID, session)
+}
+
+// Leave 移除订阅者在线状态
+func (b *EventBus) Leave(ctx context.Context, canvasID, session string) {
+	if b.redis == nil {
+		return
+	}
+	if err := b.redis.Client.ZRem(ctx, presenceKey(canvasID), session).Err(); err != nil {
+		b.logger.Error("移除在线状态失败", err)
+	}
+}
+
+// OnlineUsers 返回画布当前在线的用户
+func (b *EventBus) OnlineUsers(ctx context.Context, canvasID string) []string {
+	if b.redis == nil {
+		return nil
+	}
+
+	key := presenceKey(canvasID)
+	now := time.Now()
+
+	// 清理过期的会话
+	b.redis.Client.ZRemRangeByScore(ctx, key, "-inf", strconv.FormatInt(now.Add(-presenceTTL).Unix(), 10))
+
+	sessions, err := b.redis.Client.ZRange(ctx, key, 0, -1).Result()
+	if err != nil {
+		b.logger.Error("获取在线用户失败", err)
+		return nil
+	}
+
+	seen := make(map[string]bool, len(sessions))
+	users := make([]string, 0, len(sessions))
+	for _, session := range sessions {
+		userID, _, _ := strings.Cut(session, ":")
+		if !seen[userID] {
+			seen[userID] = true
+			users = append(users, userID)
+		}
+	}
+	return users
+}
+
+// touchPresence 以当前时间刷新会话的在线状态
+func (b *EventBus) touchPresence(ctx context.Context, canvasID, session string) {
+	if b.redis == nil {
+		return
+	}
+
+	key := presenceKey(canvasID)
+	pipe := b.redis.Client.TxPipeline()
+	pipe.ZAdd(ctx, key, &redis.Z{Score: float64(time.Now().Unix()), Member: session})
+	pipe.Expire(ctx, key, presenceTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		b.logger.Error("刷新在线状态失败", err)
+	}
+}
+
+// canvasChannel 返回画布事件频道
+func canvasChannel(canvasID string) string {
+	return fmt.Sprintf("chat:canvas:%s:events", canvasID)
+}
+
+// presenceKey 返回画布在线状态的键
+func presenceKey(canvasID string) string {
+	return fmt.Sprintf("chat:canvas:%s:presence", canvasID)
+}
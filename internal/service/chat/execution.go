@@ -0,0 +1,147 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/chat"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/sandbox"
+)
+
+// 代码执行相关错误
+var (
+	ErrExecutionDisabled    = errors.New("代码执行未启用")
+	ErrExecutionUnsupported = errors.New("只能执行代码画布中助手回复的 Python、JavaScript 或 Go 代码块")
+	ErrCodeBlockNotFound    = errors.New("代码块不存在")
+)
+
+const (
+	// maxExecutionsPerMessage 为单条消息保留的最近执行记录数
+	maxExecutionsPerMessage = 10
+	// executionEventBuffer 为执行事件通道的缓冲大小
+	executionEventBuffer = 64
+)
+
+// ExecuteCode 在沙箱中执行助手回复中的代码块，以事件流返回输出，执行结果保存到消息元数据
+// 请求上下文取消时终止执行，已产生的输出仍会保存
+func (s *Service) ExecuteCode(ctx context.Context, userID, canvasID, messageID string, req *chat.ExecuteCodeRequest) (<-chan GenerationEvent, error) {
+	canvas, err := s.getWritableCanvas(ctx, canvasID)
+	if err != nil {
+		return nil, err
+	}
+	if canvas.Type != chat.CanvasTypeCode {
+		return nil, ErrExecutionUnsupported
+	}
+
+	// 全局沙箱和租户开关都开启时才允许执行
+	settings, err := s.settings.GetSettings(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !s.sandbox.Enabled() || !settings.CodeExecutionEnabled {
+		return nil, ErrExecutionDisabled
+	}
+
+	message, err := s.getCanvasMessage(ctx, canvasID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if message.Role != chat.MessageRoleAssistant {
+		return nil, ErrExecutionUnsupported
+	}
+
+	blocks := fencedBlocks(message.Content)
+	if req.Block < 0 || req.Block >= len(blocks) {
+		return nil, ErrCodeBlockNotFound
+	}
+	block := blocks[req.Block]
+	language := sandbox.NormalizeLanguage(block.language)
+	if block.patch || language == "" {
+		return nil, ErrExecutionUnsupported
+	}
+
+	executionID := uuid.New().String()
+	events := make(chan GenerationEvent, executionEventBuffer)
+	var seq int64
+	emit := func(event *chat.ExecutionEvent) {
+		event.ExecutionID = executionID
+		event.Language = language
+		data, err := json.Marshal(event)
+		if err != nil {
+			return
+		}
+		seq++
+		events <- GenerationEvent{GenerationID: executionID, ID: seq, Data: data}
+	}
+
+	go func() {
+		defer close(events)
+
+		emit(&chat.ExecutionEvent{Type: chat.ExecutionEventStart})
+		output, err := s.sandbox.Run(ctx, language, block.content, func(stream, data string) {
+			eventType := chat.ExecutionEventStdout
+			if stream == sandbox.StreamStderr {
+				eventType = chat.ExecutionEventStderr
+			}
+			emit(&chat.ExecutionEvent{Type: eventType, Data: data})
+		})
+		if err != nil {
+			s.logger.Error("执行代码失败", err)
+			message := "执行代码失败"
+			if errors.Is(err, sandbox.ErrIsolationFailed) {
+				message = "代码执行环境不可用"
+			}
+			emit(&chat.ExecutionEvent{Type: chat.ExecutionEventError, Error: message})
+			return
+		}
+
+		result := &chat.ExecutionResult{
+			ID:         executionID,
+			Block:      req.Block,
+			Language:   language,
+			ExitCode:   output.ExitCode,
+			Stdout:     output.Stdout,
+			Stderr:     output.Stderr,
+			TimedOut:   output.TimedOut,
+			Truncated:  output.Truncated,
+			DurationMs: output.Duration.Milliseconds(),
+			CreatedBy:  userID,
+			CreatedAt:  time.Now(),
+		}
+
+		// 客户端断开后仍保存执行结果
+		saveCtx := context.WithoutCancel(ctx)
+		if err := s.saveExecution(saveCtx, canvasID, userID, messageID, result); err != nil {
+			s.logger.Error("保存执行结果失败", err)
+			emit(&chat.ExecutionEvent{Type: chat.ExecutionEventError, Result: result, Error: "保存执行结果失败"})
+			return
+		}
+		emit(&chat.ExecutionEvent{Type: chat.ExecutionEventDone, Result: result})
+	}()
+
+	return events, nil
+}
+
+// saveExecution 将执行结果追加到消息元数据并广播消息的变更
+func (s *Service) saveExecution(ctx context.Context, canvasID, userID, messageID string, result *chat.ExecutionResult) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	updated, err := s.messageRepo.AppendExecution(ctx, messageID, data, maxExecutionsPerMessage)
+	if err != nil {
+		return err
+	}
+
+	s.bus.Publish(ctx, &chat.CanvasEvent{
+		Type:     chat.CanvasEventMessageUpdated,
+		CanvasID: canvasID,
+		UserID:   userID,
+		Message:  updated,
+	})
+	return nil
+}
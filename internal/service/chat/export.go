@@ -0,0 +1,570 @@
+package chat
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/chat"
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/model"
+	"github.com/zhuiye8/Lyss-chat-server/internal/repository/postgres"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/db"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/logger"
+)
+
+const (
+	// exportPageSize 是导出时每批读取的消息数
+	exportPageSize = 200
+	// exportURLExpiry 是附件和工作区导出文件预签名链接的有效期
+	exportURLExpiry = 24 * time.Hour
+	// inlineAttachmentMaxBytes 是可内嵌到导出文件中的单个附件的最大字节数，超过时以链接导出
+	inlineAttachmentMaxBytes = 5 << 20
+	// exportTimeLayout 是导出文件中时间的显示格式
+	exportTimeLayout = "2006-01-02 15:04:05"
+)
+
+// ErrInvalidExportOptions 表示导出格式或附件导出方式无效
+var ErrInvalidExportOptions = errors.New("无效的导出选项")
+
+// ExportService 表示对话导出服务
+// 单个画布直接流式写出；整个工作区在后台打包为 zip 上传到 MinIO，通过后台任务查询下载链接
+type ExportService struct {
+	canvasRepo     chat.CanvasRepository
+	messageRepo    chat.MessageRepository
+	modelRepo      model.ModelRepository
+	attachmentRepo chat.AttachmentRepository
+	minio          *db.MinIO
+	jobs           *JobTracker
+	logger         *logger.Logger
+}
+
+// NewExportService 创建一个新的对话导出服务
+func NewExportService(database *db.Postgres, redis *db.Redis, minio *db.MinIO, logger *logger.Logger) *ExportService {
+	return &ExportService{
+		canvasRepo:     postgres.NewCanvasRepository(database),
+		messageRepo:    postgres.NewMessageRepository(database),
+		modelRepo:      postgres.NewModelRepository(database),
+		attachmentRepo: postgres.NewAttachmentRepository(database),
+		minio:          minio,
+		jobs:           NewJobTracker(redis, logger),
+		logger:         logger,
+	}
+}
+
+// GetCanvas 获取要导出的画布
+func (s *ExportService) GetCanvas(ctx context.Context, id string) (*chat.Canvas, error) {
+	return s.canvasRepo.GetByID(ctx, id)
+}
+
+// Export 将画布导出到 w
+// Markdown 和 HTML 导出画布当前分支，JSON 导出包含所有分支的完整消息树
+// 消息分批读取并逐条写出，w 实现 Flush 时每批写完后刷新
+func (s *ExportService) Export(ctx context.Context, canvas *chat.Canvas, opts chat.ExportOptions, w io.Writer) error {
+	if err := NormalizeExportOptions(&opts); err != nil {
+		return err
+	}
+
+	e := &canvasExporter{
+		service: s,
+		ctx:     ctx,
+		canvas:  canvas,
+		opts:    opts,
+		w:       w,
+		models:  make(map[string]string),
+	}
+	switch opts.Format {
+	case chat.ExportFormatMarkdown:
+		return e.markdown()
+	case chat.ExportFormatHTML:
+		return e.html()
+	default:
+		return e.json()
+	}
+}
+
+// StartWorkspaceExport 创建导出整个工作区的后台任务
+func (s *ExportService) StartWorkspaceExport(ctx context.Context, userID, workspaceID string, req *chat.WorkspaceExportRequest) (*chat.Job, error) {
+	opts := chat.ExportOptions{Format: req.Format, Attachments: req.Attachments}
+	if err := NormalizeExportOptions(&opts); err != nil {
+		return nil, err
+	}
+
+	job, err := s.jobs.Create(ctx, chat.JobTypeWorkspaceExport, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 任务不随请求结束而取消，但保留请求上下文中的租户信息
+	go s.exportWorkspace(context.WithoutCancel(ctx), job, workspaceID, opts)
+
+	return job, nil
+}
+
+// exportWorkspace 将工作区的所有画布打包为 zip 并上传到 MinIO
+func (s *ExportService) exportWorkspace(ctx context.Context, job *chat.Job, workspaceID string, opts chat.ExportOptions) {
+	canvases, err := s.listWorkspaceCanvases(ctx, workspaceID)
+	if err != nil {
+		s.jobs.Fail(ctx, job, err)
+		return
+	}
+	s.jobs.Progress(ctx, job, 0, len(canvases))
+
+	// 边打包边上传，不在内存或磁盘中保留完整的 zip
+	reader, writer := io.Pipe()
+	go func() {
+		zw := zip.NewWriter(writer)
+		for i, canvas := range canvases {
+			entry, err := zw.Create(ExportFileName(canvas, opts.Format))
+			if err == nil {
+				err = s.Export(ctx, canvas, opts, entry)
+			}
+			if err != nil {
+				writer.CloseWithError(fmt.Errorf("导出画布 %s 失败: %w", canvas.ID, err))
+				return
+			}
+			s.jobs.Progress(ctx, job, i+1, len(canvases))
+		}
+		writer.CloseWithError(zw.Close())
+	}()
+
+	objectKey := fmt.Sprintf("exports/%s/%s/%s.zip", job.TenantID, workspaceID, job.ID)
+	_, err = s.minio.Client.PutObject(ctx, s.minio.Bucket, objectKey, reader, -1, minio.PutObjectOptions{
+		ContentType: "application/zip",
+	})
+	if err != nil {
+		reader.CloseWithError(err)
+		s.logger.Error("上传工作区导出文件失败", err)
+		s.jobs.Fail(ctx, job, err)
+		return
+	}
+
+	downloadURL, err := s.minio.Client.PresignedGetObject(ctx, s.minio.Bucket, objectKey, exportURLExpiry, url.Values{})
+	if err != nil {
+		s.jobs.Fail(ctx, job, err)
+		return
+	}
+
+	s.jobs.Complete(ctx, job, &chat.WorkspaceExportResult{
+		ObjectKey: objectKey,
+		URL:       downloadURL.String(),
+		ExpiresAt: time.Now().Add(exportURLExpiry),
+	})
+}
+
+// listWorkspaceCanvases 分页读取工作区的所有画布
+func (s *ExportService) listWorkspaceCanvases(ctx context.Context, workspaceID string) ([]*chat.Canvas, error) {
+	var canvases []*chat.Canvas
+	for offset := 0; ; offset += exportPageSize {
+		page, total, err := s.canvasRepo.List(ctx, &chat.CanvasListRequest{
+			WorkspaceID: workspaceID,
+			Offset:      offset,
+			Limit:       exportPageSize,
+		})
+		if err != nil {
+			return nil, err
+		}
+		canvases = append(canvases, page...)
+		if len(page) == 0 || len(canvases) >= total {
+			return canvases, nil
+		}
+	}
+}
+
+// exportAttachment 转换导出的附件
+// 存储在 MinIO 中的附件使用预签名链接；要求内嵌且不超过大小限制时读取内容
+func (s *ExportService) exportAttachment(ctx context.Context, attachment *chat.Attachment, inline bool) *chat.ExportedAttachment {
+	exported := &chat.ExportedAttachment{
+		Name:     attachment.Name,
+		MimeType: attachment.MimeType,
+		Size:     attachment.Size,
+	}
+
+	// 外部链接原样导出
+	if externalURL(attachment.URL) {
+		exported.URL = attachment.URL
+		return exported
+	}
+
+	if inline && attachment.Size <= inlineAttachmentMaxBytes {
+		if data, err := s.readObject(ctx, attachment.URL); err == nil {
+			exported.Data = base64.StdEncoding.EncodeToString(data)
+			return exported
+		} else {
+			s.logger.Warn("读取附件失败，改为以链接导出", err)
+		}
+	}
+
+	presigned, err := s.minio.Client.PresignedGetObject(ctx, s.minio.Bucket, attachment.URL, exportURLExpiry, url.Values{})
+	if err != nil {
+		s.logger.Error("生成附件下载链接失败", err)
+		return exported
+	}
+	exported.URL = presigned.String()
+	return exported
+}
+
+// readObject 读取 MinIO 对象的内容，超过内嵌大小限制时返回错误
+func (s *ExportService) readObject(ctx context.Context, objectKey string) ([]byte, error) {
+	object, err := s.minio.Client.GetObject(ctx, s.minio.Bucket, objectKey, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer object.Close()
+
+	data, err := io.ReadAll(io.LimitReader(object, inlineAttachmentMaxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > inlineAttachmentMaxBytes {
+		return nil, errors.New("附件超过内嵌大小限制")
+	}
+	return data, nil
+}
+
+// modelName 返回模型的显示名称，查询失败时返回模型 ID
+func (s *ExportService) modelName(ctx context.Context, modelID string) string {
+	m, err := s.modelRepo.GetByID(ctx, modelID)
+	if err != nil {
+		return modelID
+	}
+	return m.Name
+}
+
+// ExportFileName 返回画布导出文件的文件名
+func ExportFileName(canvas *chat.Canvas, format string) string {
+	name := strings.Map(func(r rune) rune {
+		if r < 0x20 || strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(canvas.Title))
+	name = truncateRunes(name, 50)
+	if name == "" {
+		name = "canvas"
+	}
+
+	id := canvas.ID
+	if len(id) > 8 {
+		id = id[:8]
+	}
+	return fmt.Sprintf("%s-%s.%s", name, id, format)
+}
+
+// NormalizeExportOptions 校验导出选项并填充默认值，格式默认为 Markdown，附件默认以链接导出
+func NormalizeExportOptions(opts *chat.ExportOptions) error {
+	switch opts.Format {
+	case "":
+		opts.Format = chat.ExportFormatMarkdown
+	case chat.ExportFormatMarkdown, chat.ExportFormatJSON, chat.ExportFormatHTML:
+	default:
+		return ErrInvalidExportOptions
+	}
+
+	switch opts.Attachments {
+	case "":
+		opts.Attachments = chat.ExportAttachmentsLink
+	case chat.ExportAttachmentsLink, chat.ExportAttachmentsInline:
+	default:
+		return ErrInvalidExportOptions
+	}
+	return nil
+}
+
+// canvasExporter 表示一次画布导出
+type canvasExporter struct {
+	service *ExportService
+	ctx     context.Context
+	canvas  *chat.Canvas
+	opts    chat.ExportOptions
+	w       io.Writer
+	models  map[string]string
+}
+
+// forEachPage 分批读取要导出的消息，转换后交给 fn
+// fullTree 为 true 时读取画布的全部消息，否则只读取当前分支
+func (e *canvasExporter) forEachPage(fullTree bool, fn func([]*chat.ExportedMessage) error) error {
+	if fullTree {
+		for offset := 0; ; offset += exportPageSize {
+			page, total, err := e.service.messageRepo.GetByCanvasID(e.ctx, e.canvas.ID, offset, exportPageSize)
+			if err != nil {
+				return err
+			}
+			if err := e.emitPage(page, fn); err != nil {
+				return err
+			}
+			if len(page) == 0 || offset+len(page) >= total {
+				return nil
+			}
+		}
+	}
+
+	if e.canvas.ActiveLeafID == nil {
+		return nil
+	}
+	branch, err := e.service.messageRepo.GetBranch(e.ctx, *e.canvas.ActiveLeafID)
+	if err != nil {
+		return err
+	}
+	for start := 0; start < len(branch); start += exportPageSize {
+		end := start + exportPageSize
+		if end > len(branch) {
+			end = len(branch)
+		}
+		if err := e.emitPage(branch[start:end], fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// emitPage 为一批消息加载附件和模型名称，交给 fn 后刷新输出
+func (e *canvasExporter) emitPage(messages []*chat.Message, fn func([]*chat.ExportedMessage) error) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(messages))
+	for i, message := range messages {
+		ids[i] = message.ID
+	}
+	attachments, err := e.service.attachmentRepo.ListByMessageIDs(e.ctx, ids)
+	if err != nil {
+		return err
+	}
+	byMessage := make(map[string][]*chat.ExportedAttachment)
+	inline := e.opts.Attachments == chat.ExportAttachmentsInline
+	for _, attachment := range attachments {
+		byMessage[attachment.MessageID] = append(byMessage[attachment.MessageID], e.service.exportAttachment(e.ctx, attachment, inline))
+	}
+
+	page := make([]*chat.ExportedMessage, len(messages))
+	for i, message := range messages {
+		exported := &chat.ExportedMessage{
+			ID:          message.ID,
+			ParentID:    message.ParentID,
+			Role:        message.Role,
+			Content:     message.Content,
+			Attachments: byMessage[message.ID],
+			CreatedAt:   message.CreatedAt,
+		}
+		if message.Role == chat.MessageRoleAssistant {
+			exported.ModelID, exported.ModelName = e.messageModel(message)
+		}
+		page[i] = exported
+	}
+
+	if err := fn(page); err != nil {
+		return err
+	}
+	if flusher, ok := e.w.(interface{ Flush() }); ok {
+		flusher.Flush()
+	}
+	return nil
+}
+
+// messageModel 返回生成助手消息的模型 ID 和名称
+// 早期消息未记录模型时使用画布的模型，导入的消息使用来源中记录的模型名称
+func (e *canvasExporter) messageModel(message *chat.Message) (string, string) {
+	var metadata struct {
+		chat.AssistantMetadata
+		chat.ImportMetadata
+	}
+	if len(message.Metadata) > 0 && json.Unmarshal(message.Metadata, &metadata) == nil {
+		if metadata.ModelID != "" {
+			return metadata.ModelID, e.modelName(metadata.ModelID)
+		}
+		if metadata.Source != "" {
+			return "", metadata.SourceModel
+		}
+	}
+	if e.canvas.ModelID != nil {
+		return *e.canvas.ModelID, e.modelName(*e.canvas.ModelID)
+	}
+	return "", ""
+}
+
+// modelName 返回模型名称，同一次导出内缓存查询结果
+func (e *canvasExporter) modelName(modelID string) string {
+	name, ok := e.models[modelID]
+	if !ok {
+		name = e.service.modelName(e.ctx, modelID)
+		e.models[modelID] = name
+	}
+	return name
+}
+
+// markdown 以 Markdown 导出当前分支
+func (e *canvasExporter) markdown() error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", e.canvas.Title)
+	if e.canvas.Description != nil && *e.canvas.Description != "" {
+		fmt.Fprintf(&b, "%s\n\n", *e.canvas.Description)
+	}
+	fmt.Fprintf(&b, "- 创建时间：%s\n- 导出时间：%s\n", e.canvas.CreatedAt.Format(exportTimeLayout), time.Now().Format(exportTimeLayout))
+	if _, err := io.WriteString(e.w, b.String()); err != nil {
+		return err
+	}
+
+	return e.forEachPage(false, func(page []*chat.ExportedMessage) error {
+		for _, message := range page {
+			b.Reset()
+			fmt.Fprintf(&b, "\n---\n\n### %s · %s\n\n%s\n", roleLabel(message), message.CreatedAt.Format(exportTimeLayout), message.Content)
+			if len(message.Attachments) > 0 {
+				b.WriteString("\n附件：\n\n")
+				for _, attachment := range message.Attachments {
+					switch {
+					case attachment.Data != "" && strings.HasPrefix(attachment.MimeType, "image/"):
+						fmt.Fprintf(&b, "- ![%s](%s)\n", attachment.Name, dataURI(attachment))
+					case attachment.Data != "":
+						fmt.Fprintf(&b, "- [%s](%s)\n", attachment.Name, dataURI(attachment))
+					default:
+						fmt.Fprintf(&b, "- [%s](%s)\n", attachment.Name, attachment.URL)
+					}
+				}
+			}
+			if _, err := io.WriteString(e.w, b.String()); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// exportHTMLStyle 是 HTML 导出使用的内联样式，导出文件不依赖外部资源
+const exportHTMLStyle = `body{max-width:860px;margin:2em auto;padding:0 1em;font-family:-apple-system,"PingFang SC","Microsoft YaHei",sans-serif;line-height:1.6;color:#222}
+.message{border-top:1px solid #ddd;padding:1em 0}
+.message header{color:#666;font-size:.9em;margin-bottom:.5em}
+.message.assistant{background:#fafafa}
+.content{white-space:pre-wrap;word-wrap:break-word}
+.attachments img{max-width:100%}`
+
+// html 以 HTML 导出当前分支
+func (e *canvasExporter) html() error {
+	var b strings.Builder
+	title := html.EscapeString(e.canvas.Title)
+	fmt.Fprintf(&b, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n<style>\n%s\n</style>\n</head>\n<body>\n<h1>%s</h1>\n", title, exportHTMLStyle, title)
+	if e.canvas.Description != nil && *e.canvas.Description != "" {
+		fmt.Fprintf(&b, "<p>%s</p>\n", html.EscapeString(*e.canvas.Description))
+	}
+	fmt.Fprintf(&b, "<p>创建时间：%s<br>导出时间：%s</p>\n", e.canvas.CreatedAt.Format(exportTimeLayout), time.Now().Format(exportTimeLayout))
+	if _, err := io.WriteString(e.w, b.String()); err != nil {
+		return err
+	}
+
+	err := e.forEachPage(false, func(page []*chat.ExportedMessage) error {
+		for _, message := range page {
+			b.Reset()
+			fmt.Fprintf(&b, "<section class=\"message %s\">\n<header><strong>%s</strong> · <time datetime=\"%s\">%s</time></header>\n<div class=\"content\">%s</div>\n",
+				html.EscapeString(message.Role),
+				html.EscapeString(roleLabel(message)),
+				message.CreatedAt.Format(time.RFC3339),
+				message.CreatedAt.Format(exportTimeLayout),
+				html.EscapeString(message.Content),
+			)
+			if len(message.Attachments) > 0 {
+				b.WriteString("<ul class=\"attachments\">\n")
+				for _, attachment := range message.Attachments {
+					name := html.EscapeString(attachment.Name)
+					switch {
+					case attachment.Data != "" && strings.HasPrefix(attachment.MimeType, "image/"):
+						fmt.Fprintf(&b, "<li><img src=\"%s\" alt=\"%s\"></li>\n", dataURI(attachment), name)
+					case attachment.Data != "":
+						fmt.Fprintf(&b, "<li><a download=\"%s\" href=\"%s\">%s</a></li>\n", name, dataURI(attachment), name)
+					default:
+						fmt.Fprintf(&b, "<li><a href=\"%s\">%s</a></li>\n", html.EscapeString(attachment.URL), name)
+					}
+				}
+				b.WriteString("</ul>\n")
+			}
+			b.WriteString("</section>\n")
+			if _, err := io.WriteString(e.w, b.String()); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(e.w, "</body>\n</html>\n")
+	return err
+}
+
+// json 以 JSON 导出完整的消息树，消息按创建时间排列，通过 parent_id 还原树结构
+func (e *canvasExporter) json() error {
+	canvas, err := json.Marshal(e.canvas)
+	if err != nil {
+		return err
+	}
+	exportedAt, _ := json.Marshal(time.Now())
+	if _, err := fmt.Fprintf(e.w, "{\"exported_at\":%s,\"canvas\":%s,\"messages\":[", exportedAt, canvas); err != nil {
+		return err
+	}
+
+	first := true
+	err = e.forEachPage(true, func(page []*chat.ExportedMessage) error {
+		for _, message := range page {
+			payload, err := json.Marshal(message)
+			if err != nil {
+				return err
+			}
+			if !first {
+				if _, err := io.WriteString(e.w, ","); err != nil {
+					return err
+				}
+			}
+			first = false
+			if _, err := e.w.Write(payload); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(e.w, "]}\n")
+	return err
+}
+
+// roleLabel 返回消息角色的显示名称，助手消息附带模型名称
+func roleLabel(message *chat.ExportedMessage) string {
+	switch message.Role {
+	case chat.MessageRoleUser:
+		return "用户"
+	case chat.MessageRoleAssistant:
+		if message.ModelName != "" {
+			return fmt.Sprintf("助手（%s）", message.ModelName)
+		}
+		return "助手"
+	case chat.MessageRoleSystem:
+		return "系统"
+	default:
+		return message.Role
+	}
+}
+
+// dataURI 返回内嵌附件的 data URI
+func dataURI(attachment *chat.ExportedAttachment) string {
+	mimeType := attachment.MimeType
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+	return fmt.Sprintf("data:%s;base64,%s", mimeType, attachment.Data)
+}
+
+// externalURL 判断附件地址是否为外部链接，否则为 MinIO 中的对象键
+func externalURL(location string) bool {
+	return strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://")
+}
@@ -0,0 +1,175 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/chat"
+)
+
+const (
+	// maxFeedbackCommentRunes 是反馈评论的最大字符数
+	maxFeedbackCommentRunes = 2000
+	// maxFeedbackStatsGroups 是反馈统计返回的最大分组数
+	maxFeedbackStatsGroups = 200
+)
+
+// 反馈错误
+var (
+	ErrInvalidFeedback  = errors.New("无效的反馈")
+	ErrFeedbackNotFound = errors.New("反馈不存在")
+)
+
+// feedbackReasons 是允许的反馈原因代码
+var feedbackReasons = map[string]bool{
+	chat.FeedbackReasonAccurate:      true,
+	chat.FeedbackReasonHelpful:       true,
+	chat.FeedbackReasonWellWritten:   true,
+	chat.FeedbackReasonInaccurate:    true,
+	chat.FeedbackReasonUnhelpful:     true,
+	chat.FeedbackReasonIncomplete:    true,
+	chat.FeedbackReasonOffTopic:      true,
+	chat.FeedbackReasonTooVerbose:    true,
+	chat.FeedbackReasonUnsafe:        true,
+	chat.FeedbackReasonBadFormatting: true,
+	chat.FeedbackReasonOther:         true,
+}
+
+// SubmitFeedback 提交或修改当前用户对助手消息的反馈
+func (s *Service) SubmitFeedback(ctx context.Context, userID, canvasID, messageID string, req *chat.FeedbackRequest) (*chat.Feedback, error) {
+	if req.Rating != chat.FeedbackRatingUp && req.Rating != chat.FeedbackRatingDown {
+		return nil, ErrInvalidFeedback
+	}
+
+	// 原因代码去重，保持提交顺序
+	reasons := pq.StringArray{}
+	seen := make(map[string]bool, len(req.Reasons))
+	for _, reason := range req.Reasons {
+		if !feedbackReasons[reason] {
+			return nil, ErrInvalidFeedback
+		}
+		if !seen[reason] {
+			seen[reason] = true
+			reasons = append(reasons, reason)
+		}
+	}
+
+	var comment *string
+	if req.Comment != nil {
+		trimmed := strings.TrimSpace(*req.Comment)
+		if len([]rune(trimmed)) > maxFeedbackCommentRunes {
+			return nil, ErrInvalidFeedback
+		}
+		if trimmed != "" {
+			comment = &trimmed
+		}
+	}
+
+	message, err := s.getCanvasMessage(ctx, canvasID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if message.Role != chat.MessageRoleAssistant {
+		return nil, ErrInvalidBranchTarget
+	}
+
+	feedback := &chat.Feedback{
+		MessageID: message.ID,
+		CanvasID:  canvasID,
+		UserID:    userID,
+		ModelID:   s.messageModelID(ctx, message),
+		Rating:    req.Rating,
+		Reasons:   reasons,
+		Comment:   comment,
+	}
+	if err := s.feedbackRepo.Upsert(ctx, feedback); err != nil {
+		return nil, err
+	}
+
+	return feedback, nil
+}
+
+// GetFeedback 获取当前用户对消息的反馈
+func (s *Service) GetFeedback(ctx context.Context, userID, canvasID, messageID string) (*chat.Feedback, error) {
+	if _, err := s.getCanvasMessage(ctx, canvasID, messageID); err != nil {
+		return nil, err
+	}
+
+	feedback, err := s.feedbackRepo.Get(ctx, messageID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if feedback == nil {
+		return nil, ErrFeedbackNotFound
+	}
+	return feedback, nil
+}
+
+// DeleteFeedback 撤销当前用户对消息的反馈
+func (s *Service) DeleteFeedback(ctx context.Context, userID, canvasID, messageID string) error {
+	if _, err := s.getCanvasMessage(ctx, canvasID, messageID); err != nil {
+		return err
+	}
+
+	deleted, err := s.feedbackRepo.Delete(ctx, messageID, userID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrFeedbackNotFound
+	}
+	return nil
+}
+
+// FeedbackStats 按模型、提供商、画布或时间段统计反馈，Satisfaction 为好评占比
+func (s *Service) FeedbackStats(ctx context.Context, req *chat.FeedbackStatsRequest) ([]*chat.FeedbackStat, error) {
+	switch req.GroupBy {
+	case "":
+		req.GroupBy = chat.FeedbackGroupByModel
+	case chat.FeedbackGroupByModel, chat.FeedbackGroupByProvider, chat.FeedbackGroupByCanvas,
+		chat.FeedbackGroupByDay, chat.FeedbackGroupByWeek, chat.FeedbackGroupByMonth:
+	default:
+		return nil, ErrInvalidFeedback
+	}
+	if req.From != nil && req.To != nil && !req.From.Before(*req.To) {
+		return nil, ErrInvalidFeedback
+	}
+	if req.Limit <= 0 || req.Limit > maxFeedbackStatsGroups {
+		req.Limit = maxFeedbackStatsGroups
+	}
+
+	stats, err := s.feedbackRepo.Stats(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, stat := range stats {
+		if stat.Total > 0 {
+			stat.Satisfaction = float64(stat.Up) / float64(stat.Total)
+		}
+	}
+	return stats, nil
+}
+
+// messageModelID 返回生成助手消息的模型，早期消息未记录模型时使用画布当前的模型
+func (s *Service) messageModelID(ctx context.Context, message *chat.Message) *string {
+	var metadata chat.AssistantMetadata
+	if len(message.Metadata) > 0 && json.Unmarshal(message.Metadata, &metadata) == nil && metadata.ModelID != "" {
+		return &metadata.ModelID
+	}
+
+	// 导入的消息不是由本系统的模型生成
+	var imported chat.ImportMetadata
+	if len(message.Metadata) > 0 && json.Unmarshal(message.Metadata, &imported) == nil && imported.Source != "" {
+		return nil
+	}
+
+	canvas, err := s.canvasRepo.GetByID(ctx, message.CanvasID)
+	if err != nil {
+		return nil
+	}
+	return canvas.ModelID
+}
@@ -0,0 +1,104 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/zhuiye8/Lyss-chat-server/pkg/db"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/logger"
+)
+
+// generationStopChannel 是跨实例广播停止生成请求的 Redis 频道
+const generationStopChannel = "chat:generations:stop"
+
+// 生成取消原因
+var (
+	ErrGenerationStopped   = errors.New("生成已被停止")
+	ErrGenerationAbandoned = errors.New("生成长时间无人订阅")
+)
+
+// GenerationRegistry 表示进行中生成的登记表
+// 停止请求可能落在任意实例上，因此通过 Redis 发布订阅广播，由持有该生成的实例取消
+type GenerationRegistry struct {
+	redis   *db.Redis
+	logger  *logger.Logger
+	mu      sync.Mutex
+	cancels map[string]context.CancelCauseFunc
+	once    sync.Once
+}
+
+// NewGenerationRegistry 创建一个新的生成登记表
+func NewGenerationRegistry(redis *db.Redis, logger *logger.Logger) *GenerationRegistry {
+	return &GenerationRegistry{
+		redis:   redis,
+		logger:  logger,
+		cancels: make(map[string]context.CancelCauseFunc),
+	}
+}
+
+// Register 登记一次生成，返回可被停止的上下文以及生成结束时需调用的释放函数
+func (r *GenerationRegistry) Register(ctx context.Context, canvasID, generationID string) (context.Context, func()) {
+	r.once.Do(r.subscribe)
+
+	genCtx, cancel := context.WithCancelCause(ctx)
+	key := generationKey(canvasID, generationID)
+
+	r.mu.Lock()
+	r.cancels[key] = cancel
+	r.mu.Unlock()
+
+	return genCtx, func() {
+		r.mu.Lock()
+		delete(r.cancels, key)
+		r.mu.Unlock()
+		cancel(nil)
+	}
+}
+
+// Stop 停止生成：先尝试取消本实例上的生成，否则广播给其他实例
+func (r *GenerationRegistry) Stop(ctx context.Context, canvasID, generationID string) error {
+	key := generationKey(canvasID, generationID)
+	if r.cancelLocal(key, ErrGenerationStopped) || r.redis == nil {
+		return nil
+	}
+	return r.redis.Client.Publish(ctx, generationStopChannel, key).Err()
+}
+
+// Abandon 取消本实例上无人订阅的生成
+func (r *GenerationRegistry) Abandon(canvasID, generationID string) {
+	r.cancelLocal(generationKey(canvasID, generationID), ErrGenerationAbandoned)
+}
+
+// cancelLocal 取消本实例上的生成，返回是否找到
+func (r *GenerationRegistry) cancelLocal(key string, cause error) bool {
+	r.mu.Lock()
+	cancel, ok := r.cancels[key]
+	r.mu.Unlock()
+
+	if ok {
+		cancel(cause)
+	}
+	return ok
+}
+
+// subscribe 订阅停止生成频道
+func (r *GenerationRegistry) subscribe() {
+	if r.redis == nil {
+		return
+	}
+
+	pubsub := r.redis.Client.Subscribe(context.Background(), generationStopChannel)
+	go func() {
+		for msg := range pubsub.Channel() {
+			r.cancelLocal(msg.Payload, ErrGenerationStopped)
+		}
+		r.logger.Info("停止生成订阅已关闭")
+	}()
+}
+
+// generationKey 返回生成在登记表中的键
+func generationKey(canvasID, generationID string) string {
+	return strings.Join([]string{canvasID, generationID}, ":")
+}
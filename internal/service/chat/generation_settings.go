@@ -0,0 +1,78 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/chat"
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/model"
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/user"
+)
+
+// ErrInvalidSettings 表示生成设置不被模型支持或超出范围
+var ErrInvalidSettings = errors.New("无效的生成设置")
+
+// validateCanvasSettings 校验画布叠加助手后的生成设置是否被画布使用的模型支持
+func (s *Service) validateCanvasSettings(ctx context.Context, canvas *chat.Canvas, settings *user.TenantSettings) error {
+	assistant, err := s.canvasAssistant(ctx, canvas)
+	if err != nil {
+		return err
+	}
+	modelID, err := s.resolveModelID(ctx, canvas, assistant, settings)
+	if err != nil {
+		return err
+	}
+	m, err := s.modelRepo.GetByID(ctx, modelID)
+	if err != nil {
+		return err
+	}
+	return validateSettings(m, assistantSettings(assistant).Merge(&canvas.Settings))
+}
+
+// validateSettings 校验生成设置是否在模型 Parameters 声明的范围内
+func validateSettings(m *model.Model, settings chat.GenerationSettings) error {
+	supported := m.SupportedParameters()
+
+	if settings.Temperature != nil {
+		if supported.Temperature == nil {
+			return fmt.Errorf("%w: 模型不支持 temperature", ErrInvalidSettings)
+		}
+		if !supported.Temperature.Contains(*settings.Temperature) {
+			return fmt.Errorf("%w: temperature 超出模型允许的范围", ErrInvalidSettings)
+		}
+	}
+
+	if settings.TopP != nil {
+		if supported.TopP == nil {
+			return fmt.Errorf("%w: 模型不支持 top_p", ErrInvalidSettings)
+		}
+		if !supported.TopP.Contains(*settings.TopP) {
+			return fmt.Errorf("%w: top_p 超出模型允许的范围", ErrInvalidSettings)
+		}
+	}
+
+	if settings.MaxOutputTokens != nil {
+		if *settings.MaxOutputTokens <= 0 {
+			return fmt.Errorf("%w: max_output_tokens 必须大于 0", ErrInvalidSettings)
+		}
+		if supported.MaxTokens > 0 && *settings.MaxOutputTokens > supported.MaxTokens {
+			return fmt.Errorf("%w: max_output_tokens 不能超过 %d", ErrInvalidSettings, supported.MaxTokens)
+		}
+	}
+
+	if len(settings.Stop) > 0 {
+		if supported.MaxStop == 0 {
+			return fmt.Errorf("%w: 模型不支持 stop", ErrInvalidSettings)
+		}
+		if len(settings.Stop) > supported.MaxStop {
+			return fmt.Errorf("%w: stop 最多 %d 个", ErrInvalidSettings, supported.MaxStop)
+		}
+	}
+
+	if settings.Seed != nil && !supported.Seed {
+		return fmt.Errorf("%w: 模型不支持 seed", ErrInvalidSettings)
+	}
+
+	return nil
+}
@@ -0,0 +1,212 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/chat"
+	"github.com/zhuiye8/Lyss-chat-server/internal/repository/postgres"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/db"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/logger"
+)
+
+const (
+	// MaxImportBytes 是导入文件的最大字节数
+	MaxImportBytes = 64 << 20
+	// importBatchSize 是每批写入的消息数
+	importBatchSize = 500
+	// maxImportErrors 是导入结果中保留的错误信息条数
+	maxImportErrors = 20
+	// importTitleRunes 是画布标题的最大字符数
+	importTitleRunes = 255
+)
+
+// ErrInvalidImport 表示导入文件无法识别或不包含可导入的对话
+var ErrInvalidImport = errors.New("无法识别的导入文件")
+
+// ImportService 表示对话导入服务
+// 文件在请求中解析，写入在后台任务中逐个对话进行，通过后台任务查询进度和结果
+type ImportService struct {
+	canvasRepo  chat.CanvasRepository
+	messageRepo chat.MessageRepository
+	importRepo  chat.ImportRepository
+	jobs        *JobTracker
+	logger      *logger.Logger
+}
+
+// NewImportService 创建一个新的对话导入服务
+func NewImportService(database *db.Postgres, redis *db.Redis, logger *logger.Logger) *ImportService {
+	return &ImportService{
+		canvasRepo:  postgres.NewCanvasRepository(database),
+		messageRepo: postgres.NewMessageRepository(database),
+		importRepo:  postgres.NewImportRepository(database),
+		jobs:        NewJobTracker(redis, logger),
+		logger:      logger,
+	}
+}
+
+// StartImport 解析导入文件并创建导入对话的后台任务
+// 每个对话导入为一个画布，已导入过的对话计为重复并跳过
+func (s *ImportService) StartImport(ctx context.Context, userID, workspaceID string, req *chat.ImportRequest) (*chat.Job, error) {
+	conversations, err := parseImport(req.Format, req.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	job, err := s.jobs.Create(ctx, chat.JobTypeImport, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 任务不随请求结束而取消，但保留请求上下文中的租户信息
+	go s.runImport(context.WithoutCancel(ctx), job, userID, workspaceID, conversations)
+
+	return job, nil
+}
+
+// runImport 逐个导入对话并更新任务进度
+func (s *ImportService) runImport(ctx context.Context, job *chat.Job, userID, workspaceID string, conversations []*importedConversation) {
+	result := &chat.ImportResult{CanvasIDs: []string{}}
+	s.jobs.Progress(ctx, job, 0, len(conversations))
+
+	for i, conversation := range conversations {
+		canvasID, err := s.importConversation(ctx, userID, workspaceID, conversation)
+		switch {
+		case errors.Is(err, errDuplicateImport):
+			result.Duplicates++
+		case err != nil:
+			s.logger.Error("导入对话失败", err)
+			result.Failed++
+			if len(result.Errors) < maxImportErrors {
+				result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", conversation.displayTitle(), err))
+			}
+		default:
+			result.Imported++
+			result.CanvasIDs = append(result.CanvasIDs, canvasID)
+		}
+		s.jobs.Progress(ctx, job, i+1, len(conversations))
+	}
+
+	s.jobs.Complete(ctx, job, result)
+}
+
+// errDuplicateImport 表示对话已导入过
+var errDuplicateImport = errors.New("对话已导入")
+
+// importConversation 将一个对话导入为画布，返回画布 ID
+// 画布和消息分别写入，任何一步失败时删除已创建的画布
+func (s *ImportService) importConversation(ctx context.Context, userID, workspaceID string, conversation *importedConversation) (string, error) {
+	exists, err := s.importRepo.Exists(ctx, workspaceID, conversation.source, conversation.externalID)
+	if err != nil {
+		return "", err
+	}
+	if exists {
+		return "", errDuplicateImport
+	}
+
+	canvas := &chat.Canvas{
+		ID:              uuid.New().String(),
+		WorkspaceID:     workspaceID,
+		Title:           truncateRunes(conversation.displayTitle(), importTitleRunes),
+		TitleCustomized: conversation.title != "",
+		Type:            chat.CanvasTypeChat,
+		Status:          chat.CanvasStatusActive,
+		TopicTags:       pq.StringArray{},
+		CreatedBy:       userID,
+		CreatedAt:       conversation.createdAt,
+		UpdatedAt:       conversation.updatedAt,
+	}
+	if err := s.canvasRepo.Create(ctx, canvas); err != nil {
+		return "", fmt.Errorf("创建画布失败: %w", err)
+	}
+
+	if err := s.importMessages(ctx, userID, canvas, conversation); err != nil {
+		if deleteErr := s.canvasRepo.Delete(ctx, canvas.ID); deleteErr != nil {
+			s.logger.Error("删除导入失败的画布失败", deleteErr)
+		}
+		return "", err
+	}
+
+	return canvas.ID, nil
+}
+
+// importMessages 分批写入对话的消息，设置当前分支并记录导入来源
+func (s *ImportService) importMessages(ctx context.Context, userID string, canvas *chat.Canvas, conversation *importedConversation) error {
+	// 来源消息 ID 到新消息 ID 的映射，消息按父消息在前的顺序排列
+	ids := make(map[string]string, len(conversation.messages))
+	messages := make([]*chat.Message, 0, len(conversation.messages))
+	for _, imported := range conversation.messages {
+		metadata, err := json.Marshal(&chat.ImportMetadata{
+			Source:      conversation.source,
+			SourceID:    imported.sourceID,
+			SourceModel: imported.model,
+		})
+		if err != nil {
+			return err
+		}
+
+		message := &chat.Message{
+			ID:        uuid.New().String(),
+			TenantID:  canvas.TenantID,
+			CanvasID:  canvas.ID,
+			Role:      imported.role,
+			Content:   imported.content,
+			Metadata:  metadata,
+			CreatedBy: userID,
+			CreatedAt: imported.createdAt,
+		}
+		if parentID, ok := ids[imported.parentID]; ok {
+			message.ParentID = &parentID
+		}
+		ids[imported.sourceID] = message.ID
+		messages = append(messages, message)
+	}
+
+	for start := 0; start < len(messages); start += importBatchSize {
+		end := start + importBatchSize
+		if end > len(messages) {
+			end = len(messages)
+		}
+		if err := s.messageRepo.CreateBatch(ctx, messages[start:end]); err != nil {
+			return fmt.Errorf("写入消息失败: %w", err)
+		}
+	}
+
+	if leafID, ok := ids[conversation.leafID]; ok {
+		if err := s.canvasRepo.SetActiveLeaf(ctx, canvas.ID, leafID); err != nil {
+			return fmt.Errorf("设置当前分支失败: %w", err)
+		}
+	}
+
+	// 并发导入同一对话时唯一索引冲突，计为重复
+	err := s.importRepo.Create(ctx, &chat.CanvasImport{
+		WorkspaceID: canvas.WorkspaceID,
+		CanvasID:    canvas.ID,
+		Source:      conversation.source,
+		ExternalID:  conversation.externalID,
+		CreatedBy:   userID,
+	})
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return errDuplicateImport
+	}
+	return err
+}
+
+// displayTitle 返回对话的标题，来源没有标题时使用第一条用户消息的开头
+func (c *importedConversation) displayTitle() string {
+	if c.title != "" {
+		return c.title
+	}
+	for _, message := range c.messages {
+		if message.role == chat.MessageRoleUser {
+			return truncateRunes(strings.Join(strings.Fields(message.content), " "), 50)
+		}
+	}
+	return "导入的对话"
+}
@@ -0,0 +1,466 @@
+package chat
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/chat"
+)
+
+// importedConversation 表示从导出文件中解析出的一个对话
+// messages 按父消息在前的顺序排列，parentID 和 leafID 均为来源中的消息 ID
+type importedConversation struct {
+	source     string
+	externalID string
+	title      string
+	createdAt  time.Time
+	updatedAt  time.Time
+	messages   []*importedMessage
+	leafID     string
+}
+
+// importedMessage 表示从导出文件中解析出的一条消息，sourceID 为来源树中的节点 ID
+type importedMessage struct {
+	sourceID  string
+	parentID  string
+	role      string
+	content   string
+	model     string
+	createdAt time.Time
+}
+
+// importNode 表示来源消息树中的一个节点，message 为 nil 时表示不导入该节点
+// 不导入的节点（工具调用、隐藏消息、空消息等）的子节点挂到最近的已导入祖先上
+type importNode struct {
+	id        string
+	parentID  string
+	createdAt time.Time
+	message   *importedMessage
+}
+
+// parseImport 解析导出文件，format 为空时根据内容识别格式
+func parseImport(format string, data []byte) ([]*importedConversation, error) {
+	data = bytes.TrimSpace(data)
+	if format == "" {
+		format = detectImportFormat(data)
+	}
+
+	var conversations []*importedConversation
+	var err error
+	switch format {
+	case chat.ImportSourceChatGPT:
+		conversations, err = parseChatGPTExport(data)
+	case chat.ImportSourceOpenAI:
+		conversations, err = parseOpenAIExport(data)
+	default:
+		return nil, ErrInvalidImport
+	}
+	if err != nil {
+		return nil, ErrInvalidImport
+	}
+
+	// 跳过没有可导入消息的对话
+	filtered := conversations[:0]
+	for _, conversation := range conversations {
+		if len(conversation.messages) > 0 {
+			filtered = append(filtered, conversation)
+		}
+	}
+	if len(filtered) == 0 {
+		return nil, ErrInvalidImport
+	}
+	return filtered, nil
+}
+
+// detectImportFormat 根据内容识别导出格式，ChatGPT 导出的对话包含 mapping 字段
+func detectImportFormat(data []byte) string {
+	var probe []struct {
+		Mapping json.RawMessage `json:"mapping"`
+	}
+	if json.Unmarshal(data, &probe) == nil && len(probe) > 0 && len(probe[0].Mapping) > 0 {
+		return chat.ImportSourceChatGPT
+	}
+
+	var single struct {
+		Mapping json.RawMessage `json:"mapping"`
+	}
+	if json.Unmarshal(data, &single) == nil && len(single.Mapping) > 0 {
+		return chat.ImportSourceChatGPT
+	}
+	return chat.ImportSourceOpenAI
+}
+
+// chatgptConversation 表示 ChatGPT conversations.json 中的一个对话
+type chatgptConversation struct {
+	ID             string                 `json:"id"`
+	ConversationID string                 `json:"conversation_id"`
+	Title          string                 `json:"title"`
+	CreateTime     *float64               `json:"create_time"`
+	UpdateTime     *float64               `json:"update_time"`
+	Mapping        map[string]chatgptNode `json:"mapping"`
+	CurrentNode    string                 `json:"current_node"`
+}
+
+// chatgptNode 表示 ChatGPT 对话树中的节点
+type chatgptNode struct {
+	ID      string          `json:"id"`
+	Message *chatgptMessage `json:"message"`
+	Parent  *string         `json:"parent"`
+}
+
+// chatgptMessage 表示 ChatGPT 对话树中的消息
+type chatgptMessage struct {
+	ID     string `json:"id"`
+	Author struct {
+		Role string `json:"role"`
+	} `json:"author"`
+	CreateTime *float64 `json:"create_time"`
+	Content    struct {
+		ContentType string            `json:"content_type"`
+		Parts       []json.RawMessage `json:"parts"`
+		Text        string            `json:"text"`
+	} `json:"content"`
+	Metadata struct {
+		ModelSlug      string `json:"model_slug"`
+		VisuallyHidden bool   `json:"is_visually_hidden_from_conversation"`
+	} `json:"metadata"`
+}
+
+// parseChatGPTExport 解析 ChatGPT 导出的 conversations.json，也接受单个对话
+func parseChatGPTExport(data []byte) ([]*importedConversation, error) {
+	var raw []*chatgptConversation
+	if len(data) > 0 && data[0] == '{' {
+		var single chatgptConversation
+		if err := json.Unmarshal(data, &single); err != nil {
+			return nil, err
+		}
+		raw = append(raw, &single)
+	} else if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	conversations := make([]*importedConversation, 0, len(raw))
+	for _, c := range raw {
+		if c == nil {
+			continue
+		}
+		conversation := &importedConversation{
+			source:     chat.ImportSourceChatGPT,
+			externalID: c.ID,
+			title:      strings.TrimSpace(c.Title),
+			createdAt:  unixTime(c.CreateTime),
+			updatedAt:  unixTime(c.UpdateTime),
+		}
+		if conversation.externalID == "" {
+			conversation.externalID = c.ConversationID
+		}
+
+		// 按创建时间排列节点，兄弟消息的顺序与原对话一致
+		nodes := make([]importNode, 0, len(c.Mapping))
+		for id, node := range c.Mapping {
+			n := importNode{id: id, message: node.Message.imported()}
+			if node.Message != nil {
+				n.createdAt = unixTime(node.Message.CreateTime)
+			}
+			if node.Parent != nil {
+				n.parentID = *node.Parent
+			}
+			nodes = append(nodes, n)
+		}
+		sort.Slice(nodes, func(i, j int) bool {
+			if !nodes[i].createdAt.Equal(nodes[j].createdAt) {
+				return nodes[i].createdAt.Before(nodes[j].createdAt)
+			}
+			return nodes[i].id < nodes[j].id
+		})
+
+		conversation.messages, conversation.leafID = flattenImportTree(nodes, c.CurrentNode, conversation.createdAt)
+		if conversation.externalID == "" {
+			conversation.externalID = contentHash(conversation.messages)
+		}
+		conversations = append(conversations, conversation)
+	}
+	return conversations, nil
+}
+
+// imported 转换 ChatGPT 消息，不需要导入时返回 nil
+func (m *chatgptMessage) imported() *importedMessage {
+	if m == nil || m.Metadata.VisuallyHidden {
+		return nil
+	}
+	role := m.Author.Role
+	if role != chat.MessageRoleUser && role != chat.MessageRoleAssistant && role != chat.MessageRoleSystem {
+		return nil
+	}
+
+	// 只导入文本内容，图片等非文本片段被忽略
+	var parts []string
+	for _, part := range m.Content.Parts {
+		var text string
+		if json.Unmarshal(part, &text) == nil && text != "" {
+			parts = append(parts, text)
+		}
+	}
+	content := strings.Join(parts, "\n")
+	if content == "" {
+		content = m.Content.Text
+	}
+	if strings.TrimSpace(content) == "" {
+		return nil
+	}
+
+	return &importedMessage{
+		role:      role,
+		content:   content,
+		model:     m.Metadata.ModelSlug,
+		createdAt: unixTime(m.CreateTime),
+	}
+}
+
+// openAIConversation 表示 OpenAI 风格的对话
+type openAIConversation struct {
+	ID        string           `json:"id"`
+	Title     string           `json:"title"`
+	Model     string           `json:"model"`
+	CreatedAt json.RawMessage  `json:"created_at"`
+	Messages  []*openAIMessage `json:"messages"`
+}
+
+// openAIMessage 表示 OpenAI 风格的消息
+// 提供 id 时按 parent_id 还原分支，否则按顺序组成单一分支
+type openAIMessage struct {
+	ID        string          `json:"id"`
+	ParentID  string          `json:"parent_id"`
+	Role      string          `json:"role"`
+	Content   json.RawMessage `json:"content"`
+	Model     string          `json:"model"`
+	CreatedAt json.RawMessage `json:"created_at"`
+}
+
+// parseOpenAIExport 解析 OpenAI 风格的 JSON
+// 支持单个对话 {"messages": [...]}、对话数组 [{"messages": [...]}] 以及消息数组 [{"role": ..., "content": ...}]
+func parseOpenAIExport(data []byte) ([]*importedConversation, error) {
+	var raw []*openAIConversation
+	if len(data) > 0 && data[0] == '{' {
+		var single openAIConversation
+		if err := json.Unmarshal(data, &single); err != nil {
+			return nil, err
+		}
+		raw = append(raw, &single)
+	} else {
+		var items []json.RawMessage
+		if err := json.Unmarshal(data, &items); err != nil {
+			return nil, err
+		}
+		var probe struct {
+			Messages json.RawMessage `json:"messages"`
+		}
+		if len(items) > 0 && json.Unmarshal(items[0], &probe) == nil && len(probe.Messages) == 0 {
+			// 消息数组
+			var messages []*openAIMessage
+			if err := json.Unmarshal(data, &messages); err != nil {
+				return nil, err
+			}
+			raw = append(raw, &openAIConversation{Messages: messages})
+		} else if err := json.Unmarshal(data, &raw); err != nil {
+			return nil, err
+		}
+	}
+
+	conversations := make([]*importedConversation, 0, len(raw))
+	for _, c := range raw {
+		if c == nil {
+			continue
+		}
+		conversation := &importedConversation{
+			source:     chat.ImportSourceOpenAI,
+			externalID: c.ID,
+			title:      strings.TrimSpace(c.Title),
+			createdAt:  parseImportTime(c.CreatedAt),
+		}
+
+		nodes := make([]importNode, 0, len(c.Messages))
+		previous := ""
+		for i, m := range c.Messages {
+			if m == nil {
+				continue
+			}
+			n := importNode{id: m.ID, parentID: m.ParentID}
+			if n.id == "" {
+				// 没有 ID 的消息接在上一条消息之后
+				n.id = strconv.Itoa(i)
+				n.parentID = previous
+			}
+			n.message = m.imported(c.Model)
+			nodes = append(nodes, n)
+			previous = n.id
+		}
+
+		conversation.messages, conversation.leafID = flattenImportTree(nodes, previous, conversation.createdAt)
+		conversation.updatedAt = conversation.createdAt
+		for _, message := range conversation.messages {
+			if message.createdAt.After(conversation.updatedAt) {
+				conversation.updatedAt = message.createdAt
+			}
+		}
+		if conversation.externalID == "" {
+			conversation.externalID = contentHash(conversation.messages)
+		}
+		conversations = append(conversations, conversation)
+	}
+	return conversations, nil
+}
+
+// imported 转换 OpenAI 风格的消息，不需要导入时返回 nil
+func (m *openAIMessage) imported(defaultModel string) *importedMessage {
+	role := m.Role
+	if role == "developer" {
+		role = chat.MessageRoleSystem
+	}
+	if role != chat.MessageRoleUser && role != chat.MessageRoleAssistant && role != chat.MessageRoleSystem {
+		return nil
+	}
+
+	// content 为字符串或内容片段数组，只导入文本片段
+	var content string
+	if json.Unmarshal(m.Content, &content) != nil {
+		var parts []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		}
+		if json.Unmarshal(m.Content, &parts) == nil {
+			var texts []string
+			for _, part := range parts {
+				if part.Text != "" && (part.Type == "text" || part.Type == "input_text" || part.Type == "output_text") {
+					texts = append(texts, part.Text)
+				}
+			}
+			content = strings.Join(texts, "\n")
+		}
+	}
+	if strings.TrimSpace(content) == "" {
+		return nil
+	}
+
+	model := m.Model
+	if model == "" && role == chat.MessageRoleAssistant {
+		model = defaultModel
+	}
+	return &importedMessage{
+		role:      role,
+		content:   content,
+		model:     model,
+		createdAt: parseImportTime(m.CreatedAt),
+	}
+}
+
+// flattenImportTree 将来源消息树转换为父消息在前的消息列表
+// 跳过不导入的节点，并返回 leafID 对应的最近的已导入消息作为当前分支的叶子
+// 缺少时间的消息排在父消息之后，根消息缺少时间时使用对话的时间，对话也没有时间时使用当前时间
+// 父子关系成环的节点无法从根节点到达，以环中最先出现的节点作为根消息导入，不丢弃
+func flattenImportTree(nodes []importNode, leafID string, fallback time.Time) ([]*importedMessage, string) {
+	if fallback.IsZero() {
+		fallback = time.Now()
+	}
+
+	index := make(map[string]*importNode, len(nodes))
+	children := make(map[string][]*importNode)
+	var roots []*importNode
+	for i := range nodes {
+		index[nodes[i].id] = &nodes[i]
+	}
+	for i := range nodes {
+		node := &nodes[i]
+		if _, ok := index[node.parentID]; ok && node.parentID != node.id {
+			children[node.parentID] = append(children[node.parentID], node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+
+	var messages []*importedMessage
+	var leaf string
+	visited := make(map[string]bool, len(nodes))
+	var walk func(node *importNode, parentID string, parentTime time.Time)
+	walk = func(node *importNode, parentID string, parentTime time.Time) {
+		if visited[node.id] {
+			return
+		}
+		visited[node.id] = true
+
+		if node.message != nil {
+			node.message.sourceID = node.id
+			node.message.parentID = parentID
+			if node.message.createdAt.IsZero() {
+				node.message.createdAt = parentTime.Add(time.Millisecond)
+			}
+			messages = append(messages, node.message)
+			parentID = node.message.sourceID
+			parentTime = node.message.createdAt
+		}
+		if node.id == leafID {
+			leaf = parentID
+		}
+		for _, child := range children[node.id] {
+			walk(child, parentID, parentTime)
+		}
+	}
+	for _, root := range roots {
+		walk(root, "", fallback)
+	}
+	for i := range nodes {
+		walk(&nodes[i], "", fallback)
+	}
+
+	// 未指定叶子或叶子不存在时使用最后一条消息
+	if leaf == "" && len(messages) > 0 {
+		leaf = messages[len(messages)-1].sourceID
+	}
+	return messages, leaf
+}
+
+// contentHash 返回对话内容的哈希，用于来源未提供对话 ID 时的重复检测
+func contentHash(messages []*importedMessage) string {
+	h := sha256.New()
+	for _, message := range messages {
+		h.Write([]byte(message.role))
+		h.Write([]byte{0})
+		h.Write([]byte(message.content))
+		h.Write([]byte{0})
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil))
+}
+
+// unixTime 将带小数的 Unix 秒数转换为时间
+func unixTime(seconds *float64) time.Time {
+	if seconds == nil || *seconds <= 0 {
+		return time.Time{}
+	}
+	whole, frac := math.Modf(*seconds)
+	return time.Unix(int64(whole), int64(frac*1e9))
+}
+
+// parseImportTime 解析 RFC 3339 字符串或 Unix 秒数表示的时间
+func parseImportTime(raw json.RawMessage) time.Time {
+	if len(raw) == 0 {
+		return time.Time{}
+	}
+	var seconds float64
+	if json.Unmarshal(raw, &seconds) == nil {
+		return unixTime(&seconds)
+	}
+	var text string
+	if json.Unmarshal(raw, &text) == nil {
+		if t, err := time.Parse(time.RFC3339, text); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,182 @@
+package chat
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/chat"
+)
+
+// summarizeImport 将导入的消息表示为 "来源ID<父ID:角色:内容" 的列表，便于比较
+func summarizeImport(messages []*importedMessage) []string {
+	items := make([]string, 0, len(messages))
+	for _, m := range messages {
+		items = append(items, m.sourceID+"<"+m.parentID+":"+m.role+":"+m.content)
+	}
+	return items
+}
+
+func TestParseChatGPTExport(t *testing.T) {
+	data := `[{
+		"id": "conv-1",
+		"title": " Trip plan ",
+		"create_time": 1700000000,
+		"update_time": 1700000100,
+		"current_node": "a2",
+		"mapping": {
+			"root": {"id": "root", "message": null, "parent": null},
+			"sys": {"id": "sys", "parent": "root", "message": {"author": {"role": "system"}, "create_time": 1700000001, "content": {"content_type": "text", "parts": ["hidden"]}, "metadata": {"is_visually_hidden_from_conversation": true}}},
+			"u1": {"id": "u1", "parent": "sys", "message": {"author": {"role": "user"}, "create_time": 1700000002, "content": {"content_type": "text", "parts": ["Plan a trip", {"asset": "image"}, "to Kyoto"]}}},
+			"tool": {"id": "tool", "parent": "u1", "message": {"author": {"role": "tool"}, "create_time": 1700000003, "content": {"content_type": "text", "parts": ["search results"]}}},
+			"a1": {"id": "a1", "parent": "tool", "message": {"author": {"role": "assistant"}, "create_time": 1700000004, "content": {"content_type": "text", "parts": ["Day 1"]}, "metadata": {"model_slug": "gpt-4o"}}},
+			"a2": {"id": "a2", "parent": "tool", "message": {"author": {"role": "assistant"}, "create_time": 1700000005, "content": {"content_type": "code", "text": "Day 1 revised"}}}
+		}
+	}]`
+
+	conversations, err := parseImport("", []byte(data))
+	if err != nil {
+		t.Fatalf("parseImport: %v", err)
+	}
+	if len(conversations) != 1 {
+		t.Fatalf("got %d conversations, want 1", len(conversations))
+	}
+	c := conversations[0]
+	if c.source != chat.ImportSourceChatGPT || c.externalID != "conv-1" || c.title != "Trip plan" {
+		t.Fatalf("conversation = %s/%s/%q", c.source, c.externalID, c.title)
+	}
+	if !c.createdAt.Equal(time.Unix(1700000000, 0)) {
+		t.Fatalf("createdAt = %v", c.createdAt)
+	}
+
+	// 隐藏的系统消息和工具消息不导入，子消息挂到最近的已导入祖先上
+	want := []string{
+		"u1<:user:Plan a trip\nto Kyoto",
+		"a1<u1:assistant:Day 1",
+		"a2<u1:assistant:Day 1 revised",
+	}
+	if got := summarizeImport(c.messages); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("messages = %q, want %q", got, want)
+	}
+	if c.leafID != "a2" {
+		t.Fatalf("leafID = %q, want a2", c.leafID)
+	}
+	if c.messages[1].model != "gpt-4o" {
+		t.Fatalf("model = %q, want gpt-4o", c.messages[1].model)
+	}
+}
+
+func TestParseOpenAIExport(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []string
+		leaf string
+	}{
+		{
+			name: "message array",
+			data: `[
+				{"role": "developer", "content": "Be brief"},
+				{"role": "user", "content": [{"type": "input_text", "text": "Hi"}, {"type": "image_url", "image_url": "x"}]},
+				{"role": "tool", "content": "ignored"},
+				{"role": "assistant", "content": "Hello"}
+			]`,
+			want: []string{"0<:system:Be brief", "1<0:user:Hi", "3<1:assistant:Hello"},
+			leaf: "3",
+		},
+		{
+			name: "conversation with branches",
+			data: `{"id": "c1", "title": "Branches", "model": "gpt-4", "messages": [
+				{"id": "u", "role": "user", "content": "Question"},
+				{"id": "a", "parent_id": "u", "role": "assistant", "content": "First"},
+				{"id": "b", "parent_id": "u", "role": "assistant", "content": "Second", "model": "gpt-4o"}
+			]}`,
+			want: []string{"u<:user:Question", "a<u:assistant:First", "b<u:assistant:Second"},
+			leaf: "b",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conversations, err := parseImport(chat.ImportSourceOpenAI, []byte(tt.data))
+			if err != nil {
+				t.Fatalf("parseImport: %v", err)
+			}
+			c := conversations[0]
+			if got := summarizeImport(c.messages); strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Fatalf("messages = %q, want %q", got, tt.want)
+			}
+			if c.leafID != tt.leaf {
+				t.Fatalf("leafID = %q, want %q", c.leafID, tt.leaf)
+			}
+			if c.externalID == "" {
+				t.Fatalf("externalID is empty")
+			}
+		})
+	}
+}
+
+func TestParseOpenAIExportDefaultModel(t *testing.T) {
+	data := `{"model": "gpt-4", "messages": [
+		{"role": "user", "content": "Question"},
+		{"role": "assistant", "content": "Answer"},
+		{"role": "assistant", "content": "Other", "model": "gpt-4o"}
+	]}`
+	conversations, err := parseImport("", []byte(data))
+	if err != nil {
+		t.Fatalf("parseImport: %v", err)
+	}
+	var models []string
+	for _, m := range conversations[0].messages {
+		models = append(models, m.model)
+	}
+	if got := strings.Join(models, ","); got != ",gpt-4,gpt-4o" {
+		t.Fatalf("models = %q, want %q", got, ",gpt-4,gpt-4o")
+	}
+}
+
+func TestParseImportInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		data   string
+	}{
+		{"not json", "", "nope"},
+		{"unknown format", "claude", `[]`},
+		{"no importable messages", "", `[{"role": "tool", "content": "x"}]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseImport(tt.format, []byte(tt.data)); !errors.Is(err, ErrInvalidImport) {
+				t.Fatalf("parseImport error = %v, want %v", err, ErrInvalidImport)
+			}
+		})
+	}
+}
+
+func TestFlattenImportTreeCycle(t *testing.T) {
+	message := func(content string) *importedMessage {
+		return &importedMessage{role: chat.MessageRoleUser, content: content}
+	}
+	// x 和 y 互为父节点，无法从根节点到达
+	nodes := []importNode{
+		{id: "r", message: message("root")},
+		{id: "c", parentID: "r", message: message("child")},
+		{id: "x", parentID: "y", message: message("x")},
+		{id: "y", parentID: "x", message: message("y")},
+	}
+
+	messages, leaf := flattenImportTree(nodes, "y", time.Unix(1700000000, 0))
+	want := []string{"r<:user:root", "c<r:user:child", "x<:user:x", "y<x:user:y"}
+	if got := summarizeImport(messages); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("messages = %q, want %q", got, want)
+	}
+	if leaf != "y" {
+		t.Fatalf("leaf = %q, want y", leaf)
+	}
+	for _, m := range messages {
+		if m.createdAt.IsZero() {
+			t.Fatalf("message %s has no creation time", m.sourceID)
+		}
+	}
+}
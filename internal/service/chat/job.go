@@ -0,0 +1,125 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/chat"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/db"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/logger"
+)
+
+// jobTTL 是后台任务状态在 Redis 中的保留时间
+const jobTTL = 24 * time.Hour
+
+// ErrJobNotFound 表示后台任务不存在、已过期或不属于当前用户
+var ErrJobNotFound = errors.New("任务不存在或已过期")
+
+// JobTracker 表示后台任务状态记录
+// 任务可能在任意实例上执行，状态保存在 Redis 中，按租户隔离
+type JobTracker struct {
+	redis  *db.Redis
+	logger *logger.Logger
+}
+
+// NewJobTracker 创建一个新的后台任务状态记录
+func NewJobTracker(redis *db.Redis, logger *logger.Logger) *JobTracker {
+	return &JobTracker{
+		redis:  redis,
+		logger: logger,
+	}
+}
+
+// Create 创建一个等待执行的任务
+func (t *JobTracker) Create(ctx context.Context, jobType, userID string) (*chat.Job, error) {
+	tenantID, _ := db.TenantFromContext(ctx)
+	now := time.Now()
+	job := &chat.Job{
+		ID:        uuid.New().String(),
+		TenantID:  tenantID,
+		Type:      jobType,
+		Status:    chat.JobStatusPending,
+		CreatedBy: userID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := t.save(ctx, job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// Get 获取任务，只有任务的创建者可以查看
+func (t *JobTracker) Get(ctx context.Context, userID, jobID string) (*chat.Job, error) {
+	tenantID, _ := db.TenantFromContext(ctx)
+	payload, err := t.redis.Client.Get(ctx, jobKey(tenantID, jobID)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrJobNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var job chat.Job
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return nil, err
+	}
+	if job.CreatedBy != userID {
+		return nil, ErrJobNotFound
+	}
+	return &job, nil
+}
+
+// Progress 更新任务进度并将其标记为执行中
+func (t *JobTracker) Progress(ctx context.Context, job *chat.Job, progress, total int) {
+	job.Status = chat.JobStatusRunning
+	job.Progress = progress
+	job.Total = total
+	t.update(ctx, job)
+}
+
+// Complete 将任务标记为完成并记录结果
+func (t *JobTracker) Complete(ctx context.Context, job *chat.Job, result interface{}) {
+	payload, err := json.Marshal(result)
+	if err != nil {
+		t.Fail(ctx, job, err)
+		return
+	}
+	job.Status = chat.JobStatusCompleted
+	job.Result = payload
+	t.update(ctx, job)
+}
+
+// Fail 将任务标记为失败
+func (t *JobTracker) Fail(ctx context.Context, job *chat.Job, cause error) {
+	job.Status = chat.JobStatusFailed
+	job.Error = cause.Error()
+	t.update(ctx, job)
+}
+
+// update 保存任务状态，失败时只记录日志，不影响任务执行
+func (t *JobTracker) update(ctx context.Context, job *chat.Job) {
+	job.UpdatedAt = time.Now()
+	if err := t.save(ctx, job); err != nil {
+		t.logger.Error("保存任务状态失败", err)
+	}
+}
+
+// save 将任务状态写入 Redis
+func (t *JobTracker) save(ctx context.Context, job *chat.Job) error {
+	payload, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	return t.redis.Client.Set(ctx, jobKey(job.TenantID, job.ID), payload, jobTTL).Err()
+}
+
+// jobKey 返回任务状态的 Redis 键
+func jobKey(tenantID, jobID string) string {
+	return fmt.Sprintf("chat:jobs:%s:%s", tenantID, jobID)
+}
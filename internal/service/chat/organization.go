@@ -0,0 +1,444 @@
+package chat
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/chat"
+)
+
+const (
+	// maxFolderNameRunes 是文件夹名称的最大字符数
+	maxFolderNameRunes = 255
+	// maxTagNameRunes 是标签名称的最大字符数
+	maxTagNameRunes = 50
+	// maxTagColorRunes 是标签颜色的最大字符数
+	maxTagColorRunes = 20
+	// maxCanvasTags 是每个画布的最大标签数
+	maxCanvasTags = 20
+)
+
+// 画布整理错误
+var (
+	ErrFolderNotFound    = errors.New("文件夹不存在")
+	ErrInvalidFolder     = errors.New("无效的文件夹")
+	ErrTagNotFound       = errors.New("标签不存在")
+	ErrInvalidTag        = errors.New("无效的标签")
+	ErrTagExists         = errors.New("标签已存在")
+	ErrInvalidCanvasList = errors.New("无效的画布列表条件")
+)
+
+// canvasSorts 是允许的画布列表排序字段
+var canvasSorts = map[string]bool{
+	chat.CanvasSortCreatedAt:     true,
+	chat.CanvasSortUpdatedAt:     true,
+	chat.CanvasSortLastMessageAt: true,
+	chat.CanvasSortTitle:         true,
+	chat.CanvasSortPinned:        true,
+	chat.CanvasSortFavorite:      true,
+}
+
+// GetCanvas 获取画布，附带标签和当前用户的收藏标记
+func (s *Service) GetCanvas(ctx context.Context, userID, id string) (*chat.Canvas, error) {
+	canvas, err := s.canvasRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	canvas.Favorite, err = s.canvasRepo.IsFavorite(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.attachTags(ctx, []*chat.Canvas{canvas}); err != nil {
+		return nil, err
+	}
+
+	return canvas, nil
+}
+
+// ListCanvases 按过滤和排序条件列出画布，附带标签和当前用户的收藏标记
+func (s *Service) ListCanvases(ctx context.Context, req *chat.CanvasListRequest, page, pageSize int) ([]*chat.Canvas, int, error) {
+	if req.Sort == "" {
+		req.Sort = chat.CanvasSortCreatedAt
+	}
+	if req.Order == "" {
+		req.Order = chat.SortOrderDesc
+	}
+	if !canvasSorts[req.Sort] || (req.Order != chat.SortOrderAsc && req.Order != chat.SortOrderDesc) {
+		return nil, 0, ErrInvalidCanvasList
+	}
+	if req.Status != nil && *req.Status != chat.CanvasStatusActive && *req.Status != chat.CanvasStatusArchived {
+		return nil, 0, ErrInvalidCanvasList
+	}
+	req.TagIDs = uniqueStrings(req.TagIDs)
+
+	req.Offset = (page - 1) * pageSize
+	req.Limit = pageSize
+
+	canvases, total, err := s.canvasRepo.List(ctx, req)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := s.attachTags(ctx, canvases); err != nil {
+		return nil, 0, err
+	}
+
+	return canvases, total, nil
+}
+
+// MoveCanvas 将画布移动到同一工作区的文件夹，FolderID 为空表示移动到顶层
+func (s *Service) MoveCanvas(ctx context.Context, id string, req *chat.MoveCanvasRequest) (*chat.Canvas, error) {
+	canvas, err := s.canvasRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	folderID := req.FolderID
+	if folderID != nil && *folderID == "" {
+		folderID = nil
+	}
+	if folderID != nil {
+		if err := s.checkFolder(ctx, canvas.WorkspaceID, *folderID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.canvasRepo.SetFolder(ctx, id, folderID); err != nil {
+		return nil, err
+	}
+	canvas.FolderID = folderID
+
+	s.publishCanvas(ctx, canvas)
+
+	return canvas, nil
+}
+
+// SetCanvasTags 替换画布的标签，标签须属于画布所在的工作区
+func (s *Service) SetCanvasTags(ctx context.Context, id string, req *chat.SetCanvasTagsRequest) (*chat.Canvas, error) {
+	canvas, err := s.canvasRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	tagIDs := uniqueStrings(req.TagIDs)
+	if len(tagIDs) > maxCanvasTags {
+		return nil, ErrInvalidTag
+	}
+	if len(tagIDs) > 0 {
+		count, err := s.tagRepo.CountInWorkspace(ctx, canvas.WorkspaceID, tagIDs)
+		if err != nil {
+			return nil, err
+		}
+		if count != len(tagIDs) {
+			return nil, ErrTagNotFound
+		}
+	}
+
+	if err := s.tagRepo.SetCanvasTags(ctx, id, tagIDs); err != nil {
+		return nil, err
+	}
+	if err := s.attachTags(ctx, []*chat.Canvas{canvas}); err != nil {
+		return nil, err
+	}
+
+	s.publishCanvas(ctx, canvas)
+
+	return canvas, nil
+}
+
+// SetCanvasPinned 置顶或取消置顶画布，置顶对工作区内所有成员生效
+func (s *Service) SetCanvasPinned(ctx context.Context, id string, pinned bool) (*chat.Canvas, error) {
+	if _, err := s.canvasRepo.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+
+	if err := s.canvasRepo.SetPinned(ctx, id, pinned); err != nil {
+		return nil, err
+	}
+
+	canvas, err := s.canvasRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.publishCanvas(ctx, canvas)
+
+	return canvas, nil
+}
+
+// SetCanvasFavorite 收藏或取消收藏画布，收藏只对当前用户生效
+func (s *Service) SetCanvasFavorite(ctx context.Context, userID, id string, favorite bool) error {
+	if _, err := s.canvasRepo.GetByID(ctx, id); err != nil {
+		return err
+	}
+	return s.canvasRepo.SetFavorite(ctx, id, userID, favorite)
+}
+
+// ListFolders 列出工作区的所有文件夹
+func (s *Service) ListFolders(ctx context.Context, workspaceID string) ([]*chat.Folder, error) {
+	folders, err := s.folderRepo.ListByWorkspace(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if folders == nil {
+		folders = []*chat.Folder{}
+	}
+	return folders, nil
+}
+
+// CreateFolder 在工作区中创建文件夹
+func (s *Service) CreateFolder(ctx context.Context, userID, workspaceID string, req *chat.CreateFolderRequest) (*chat.Folder, error) {
+	name, ok := normalizeName(req.Name, maxFolderNameRunes)
+	if !ok {
+		return nil, ErrInvalidFolder
+	}
+	parentID := req.ParentID
+	if parentID != nil && *parentID == "" {
+		parentID = nil
+	}
+	if parentID != nil {
+		if err := s.checkFolder(ctx, workspaceID, *parentID); err != nil {
+			return nil, err
+		}
+	}
+
+	folder := &chat.Folder{
+		WorkspaceID: workspaceID,
+		ParentID:    parentID,
+		Name:        name,
+		CreatedBy:   userID,
+	}
+	if err := s.folderRepo.Create(ctx, folder); err != nil {
+		return nil, err
+	}
+
+	return folder, nil
+}
+
+// UpdateFolder 重命名或移动文件夹，不能移动到自身或子孙文件夹下
+func (s *Service) UpdateFolder(ctx context.Context, id string, req *chat.UpdateFolderRequest) (*chat.Folder, error) {
+	folder, err := s.getFolder(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		name, ok := normalizeName(*req.Name, maxFolderNameRunes)
+		if !ok {
+			return nil, ErrInvalidFolder
+		}
+		folder.Name = name
+	}
+	if req.ParentID != nil {
+		if *req.ParentID == "" {
+			folder.ParentID = nil
+		} else {
+			if err := s.checkFolder(ctx, folder.WorkspaceID, *req.ParentID); err != nil {
+				return nil, err
+			}
+			descendant, err := s.folderRepo.IsDescendant(ctx, *req.ParentID, id)
+			if err != nil {
+				return nil, err
+			}
+			if descendant {
+				return nil, ErrInvalidFolder
+			}
+			folder.ParentID = req.ParentID
+		}
+	}
+
+	if err := s.folderRepo.Update(ctx, folder); err != nil {
+		return nil, err
+	}
+
+	return folder, nil
+}
+
+// DeleteFolder 删除文件夹，其中的子文件夹和画布移动到上一级
+func (s *Service) DeleteFolder(ctx context.Context, id string) error {
+	if _, err := s.getFolder(ctx, id); err != nil {
+		return err
+	}
+	return s.folderRepo.Delete(ctx, id)
+}
+
+// ListTags 列出工作区的所有标签
+func (s *Service) ListTags(ctx context.Context, workspaceID string) ([]*chat.Tag, error) {
+	tags, err := s.tagRepo.ListByWorkspace(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if tags == nil {
+		tags = []*chat.Tag{}
+	}
+	return tags, nil
+}
+
+// CreateTag 在工作区中创建标签，名称不区分大小写唯一
+func (s *Service) CreateTag(ctx context.Context, userID, workspaceID string, req *chat.CreateTagRequest) (*chat.Tag, error) {
+	name, ok := normalizeName(req.Name, maxTagNameRunes)
+	if !ok {
+		return nil, ErrInvalidTag
+	}
+	color, ok := normalizeColor(req.Color)
+	if !ok {
+		return nil, ErrInvalidTag
+	}
+
+	tag := &chat.Tag{
+		WorkspaceID: workspaceID,
+		Name:        name,
+		Color:       color,
+		CreatedBy:   userID,
+	}
+	if err := tagWriteError(s.tagRepo.Create(ctx, tag)); err != nil {
+		return nil, err
+	}
+
+	return tag, nil
+}
+
+// UpdateTag 修改标签的名称或颜色
+func (s *Service) UpdateTag(ctx context.Context, id string, req *chat.UpdateTagRequest) (*chat.Tag, error) {
+	tag, err := s.tagRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrTagNotFound
+		}
+		return nil, err
+	}
+
+	if req.Name != nil {
+		name, ok := normalizeName(*req.Name, maxTagNameRunes)
+		if !ok {
+			return nil, ErrInvalidTag
+		}
+		tag.Name = name
+	}
+	if req.Color != nil {
+		color, ok := normalizeColor(req.Color)
+		if !ok {
+			return nil, ErrInvalidTag
+		}
+		tag.Color = color
+	}
+
+	if err := tagWriteError(s.tagRepo.Update(ctx, tag)); err != nil {
+		return nil, err
+	}
+
+	return tag, nil
+}
+
+// DeleteTag 删除标签，画布上的该标签随之移除
+func (s *Service) DeleteTag(ctx context.Context, id string) error {
+	if _, err := s.tagRepo.GetByID(ctx, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrTagNotFound
+		}
+		return err
+	}
+	return s.tagRepo.Delete(ctx, id)
+}
+
+// attachTags 为画布填充标签
+func (s *Service) attachTags(ctx context.Context, canvases []*chat.Canvas) error {
+	if len(canvases) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(canvases))
+	byID := make(map[string]*chat.Canvas, len(canvases))
+	for i, canvas := range canvases {
+		ids[i] = canvas.ID
+		byID[canvas.ID] = canvas
+		canvas.Tags = []*chat.Tag{}
+	}
+
+	tags, err := s.tagRepo.ListByCanvasIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		if canvas, ok := byID[tag.CanvasID]; ok {
+			t := tag.Tag
+			canvas.Tags = append(canvas.Tags, &t)
+		}
+	}
+
+	return nil
+}
+
+// getFolder 获取文件夹，不存在时返回 ErrFolderNotFound
+func (s *Service) getFolder(ctx context.Context, id string) (*chat.Folder, error) {
+	folder, err := s.folderRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrFolderNotFound
+		}
+		return nil, err
+	}
+	return folder, nil
+}
+
+// checkFolder 校验文件夹存在且属于指定工作区
+func (s *Service) checkFolder(ctx context.Context, workspaceID, folderID string) error {
+	folder, err := s.getFolder(ctx, folderID)
+	if err != nil {
+		return err
+	}
+	if folder.WorkspaceID != workspaceID {
+		return ErrFolderNotFound
+	}
+	return nil
+}
+
+// normalizeName 去除名称首尾空白并校验长度
+func normalizeName(name string, maxRunes int) (string, bool) {
+	name = strings.TrimSpace(name)
+	if name == "" || len([]rune(name)) > maxRunes {
+		return "", false
+	}
+	return name, true
+}
+
+// normalizeColor 校验标签颜色，空字符串表示不设置颜色
+func normalizeColor(color *string) (*string, bool) {
+	if color == nil {
+		return nil, true
+	}
+	value := strings.TrimSpace(*color)
+	if value == "" {
+		return nil, true
+	}
+	if len([]rune(value)) > maxTagColorRunes {
+		return nil, false
+	}
+	return &value, true
+}
+
+// tagWriteError 将标签名称的唯一约束冲突转换为 ErrTagExists
+func tagWriteError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return ErrTagExists
+	}
+	return err
+}
+
+// uniqueStrings 去除空值和重复值，保持原有顺序
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	unique := make([]string, 0, len(values))
+	for _, value := range values {
+		if value == "" || seen[value] {
+			continue
+		}
+		seen[value] = true
+		unique = append(unique, value)
+	}
+	return unique
+}
@@ -0,0 +1,487 @@
+package chat
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/cloudwego/eino/components/prompt"
+	"github.com/cloudwego/eino/schema"
+	"github.com/lib/pq"
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/chat"
+)
+
+const (
+	// maxPromptTitleRunes 是提示词模板标题的最大字符数
+	maxPromptTitleRunes = 255
+	// maxPromptContentRunes 是提示词模板内容的最大字符数
+	maxPromptContentRunes = 20000
+	// maxPromptVariables 是每个模板的最大变量数
+	maxPromptVariables = 50
+	// maxPromptShares 是每个模板的最大分享用户数
+	maxPromptShares = 100
+)
+
+// 提示词库错误
+var (
+	ErrPromptNotFound        = errors.New("提示词模板不存在")
+	ErrPromptForbidden       = errors.New("只有模板创建者可以修改模板")
+	ErrInvalidPrompt         = errors.New("无效的提示词模板")
+	ErrPromptVariableMissing = errors.New("缺少模板变量")
+)
+
+var (
+	// promptVariablePattern 匹配模板中引用的变量，如 {{ language }}、{{ language|upper }}
+	promptVariablePattern = regexp.MustCompile(`\{\{-?\s*([A-Za-z_][A-Za-z0-9_]*)`)
+	// promptBoundPattern 匹配模板内 for 和 set 语句绑定的局部变量
+	promptBoundPattern = regexp.MustCompile(`\{%-?\s*(?:for\s+([A-Za-z0-9_,\s]+?)\s+in\b|set\s+([A-Za-z_][A-Za-z0-9_]*))`)
+	// promptVariableName 校验变量名
+	promptVariableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// promptReservedWords 是 Jinja2 表达式中的字面量和内置变量，不视为模板变量
+var promptReservedWords = map[string]bool{
+	"true": true, "false": true, "none": true, "True": true, "False": true, "None": true,
+	"loop": true,
+}
+
+// promptSorts 是允许的模板列表排序字段
+var promptSorts = map[string]bool{
+	chat.PromptSortUpdatedAt: true,
+	chat.PromptSortUsage:     true,
+	chat.PromptSortTitle:     true,
+}
+
+// ListPrompts 列出当前用户可见的提示词模板
+func (s *Service) ListPrompts(ctx context.Context, req *chat.PromptListRequest, page, pageSize int) ([]*chat.PromptTemplate, int, error) {
+	if req.Sort == "" {
+		req.Sort = chat.PromptSortUpdatedAt
+	}
+	if !promptSorts[req.Sort] || (req.Scope != nil && !validPromptScope(*req.Scope)) {
+		return nil, 0, fmt.Errorf("%w: 排序字段只能为 updated_at、usage 或 title，范围只能为 user、workspace 或 tenant", ErrInvalidPrompt)
+	}
+	req.Query = strings.TrimSpace(req.Query)
+	req.Offset = (page - 1) * pageSize
+	req.Limit = pageSize
+
+	templates, total, err := s.promptRepo.List(ctx, req)
+	if err != nil {
+		return nil, 0, err
+	}
+	if templates == nil {
+		templates = []*chat.PromptTemplate{}
+	}
+
+	return templates, total, nil
+}
+
+// GetPrompt 获取当前用户可见的模板，创建者可同时看到分享对象
+func (s *Service) GetPrompt(ctx context.Context, userID, id string) (*chat.PromptTemplate, error) {
+	template, err := s.getVisiblePrompt(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if template.CreatedBy == userID {
+		template.SharedWith, err = s.promptRepo.ListShares(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return template, nil
+}
+
+// CreatePrompt 创建提示词模板，模板内容中引用但未声明的变量自动补充为必填变量
+func (s *Service) CreatePrompt(ctx context.Context, userID string, req *chat.CreatePromptRequest) (*chat.PromptTemplate, error) {
+	template := &chat.PromptTemplate{
+		Scope:       req.Scope,
+		WorkspaceID: req.WorkspaceID,
+		Title:       req.Title,
+		Description: req.Description,
+		Content:     req.Content,
+		Role:        req.Role,
+		Variables:   req.Variables,
+		CreatedBy:   userID,
+	}
+	if template.Role == "" {
+		template.Role = chat.PromptRoleUser
+	}
+
+	if err := s.normalizePrompt(ctx, template); err != nil {
+		return nil, err
+	}
+
+	if err := s.promptRepo.Create(ctx, template); err != nil {
+		return nil, err
+	}
+
+	return template, nil
+}
+
+// UpdatePrompt 修改提示词模板，内容或变量变化时生成新版本
+func (s *Service) UpdatePrompt(ctx context.Context, userID, id string, req *chat.UpdatePromptRequest) (*chat.PromptTemplate, error) {
+	template, err := s.getOwnPrompt(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	content, variables := template.Content, template.Variables
+	if req.Scope != nil {
+		template.Scope = *req.Scope
+	}
+	if req.WorkspaceID != nil {
+		template.WorkspaceID = emptyToNil(req.WorkspaceID)
+	}
+	if req.Title != nil {
+		template.Title = *req.Title
+	}
+	if req.Description != nil {
+		template.Description = emptyToNil(req.Description)
+	}
+	if req.Content != nil {
+		template.Content = *req.Content
+	}
+	if req.Role != nil {
+		template.Role = *req.Role
+	}
+	if req.Variables != nil {
+		template.Variables = *req.Variables
+	}
+
+	if err := s.normalizePrompt(ctx, template); err != nil {
+		return nil, err
+	}
+
+	newVersion := template.Content != content || !samePromptVariables(template.Variables, variables)
+	if err := s.promptRepo.Update(ctx, template, newVersion); err != nil {
+		return nil, err
+	}
+
+	return template, nil
+}
+
+// DeletePrompt 删除提示词模板及其全部版本
+func (s *Service) DeletePrompt(ctx context.Context, userID, id string) error {
+	if _, err := s.getOwnPrompt(ctx, userID, id); err != nil {
+		return err
+	}
+	return s.promptRepo.Delete(ctx, id)
+}
+
+// ListPromptVersions 列出模板的版本历史
+func (s *Service) ListPromptVersions(ctx context.Context, userID, id string) ([]*chat.PromptTemplateVersion, error) {
+	if _, err := s.getVisiblePrompt(ctx, userID, id); err != nil {
+		return nil, err
+	}
+
+	versions, err := s.promptRepo.ListVersions(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if versions == nil {
+		versions = []*chat.PromptTemplateVersion{}
+	}
+
+	return versions, nil
+}
+
+// SharePrompt 替换模板的分享对象，被分享的用户可以查看和使用个人模板
+func (s *Service) SharePrompt(ctx context.Context, userID, id string, req *chat.SharePromptRequest) ([]string, error) {
+	if _, err := s.getOwnPrompt(ctx, userID, id); err != nil {
+		return nil, err
+	}
+
+	userIDs := make([]string, 0, len(req.UserIDs))
+	for _, sharedID := range uniqueStrings(req.UserIDs) {
+		if sharedID != userID {
+			userIDs = append(userIDs, sharedID)
+		}
+	}
+	if len(userIDs) > maxPromptShares {
+		return nil, fmt.Errorf("%w: 每个模板最多分享给 %d 个用户", ErrInvalidPrompt, maxPromptShares)
+	}
+
+	if err := s.promptRepo.SetShares(ctx, id, userID, userIDs); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && (pqErr.Code == "23503" || pqErr.Code == "22P02") {
+			return nil, fmt.Errorf("%w: 分享的用户不存在", ErrInvalidPrompt)
+		}
+		return nil, err
+	}
+
+	return userIDs, nil
+}
+
+// RenderPrompt 以给定变量渲染模板，用于预览，不计入使用次数
+func (s *Service) RenderPrompt(ctx context.Context, userID, id string, req *chat.RenderPromptRequest) (*chat.RenderPromptResponse, error) {
+	template, err := s.getVisiblePrompt(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.renderPromptVersion(ctx, template, req)
+}
+
+// StartCanvasFromPrompt 以模板创建画布并计入使用次数
+// 系统提示词模板渲染后作为画布的系统提示词；用户消息模板渲染后作为第一条消息发送
+func (s *Service) StartCanvasFromPrompt(ctx context.Context, userID, id string, req *chat.StartCanvasFromPromptRequest) (*chat.StartCanvasFromPromptResponse, error) {
+	template, err := s.getVisiblePrompt(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	rendered, err := s.renderPromptVersion(ctx, template, &req.RenderPromptRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	createReq := &chat.CreateCanvasRequest{
+		Title:       template.Title,
+		WorkspaceID: req.WorkspaceID,
+		FolderID:    req.FolderID,
+		Type:        req.Type,
+		ModelID:     req.ModelID,
+		Settings:    req.Settings,
+	}
+	if req.Title != nil && strings.TrimSpace(*req.Title) != "" {
+		createReq.Title = *req.Title
+	}
+	if createReq.Type == "" {
+		createReq.Type = chat.CanvasTypeChat
+	}
+	if rendered.Role == chat.PromptRoleSystem {
+		settings := chat.GenerationSettings{}
+		if req.Settings != nil {
+			settings = *req.Settings
+		}
+		settings.SystemPrompt = &rendered.Content
+		createReq.Settings = &settings
+	}
+
+	canvas, err := s.CreateCanvas(ctx, userID, createReq)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.promptRepo.IncrementUsage(ctx, id); err != nil {
+		s.logger.Error("累加模板使用次数失败", err)
+	}
+
+	response := &chat.StartCanvasFromPromptResponse{Canvas: canvas}
+	if rendered.Role != chat.PromptRoleUser {
+		return response, nil
+	}
+
+	// 在消息元数据中记录来源模板
+	metadata, _ := json.Marshal(map[string]any{
+		"prompt_template_id": template.ID,
+		"prompt_version":     rendered.Version,
+	})
+	response.Message, err = s.SendMessage(ctx, userID, canvas.ID, &chat.SendMessageRequest{
+		Content:  rendered.Content,
+		Metadata: metadata,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if response.Canvas, err = s.canvasRepo.GetByID(ctx, canvas.ID); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// renderPromptVersion 渲染模板的指定版本，未指定时使用当前版本
+func (s *Service) renderPromptVersion(ctx context.Context, template *chat.PromptTemplate, req *chat.RenderPromptRequest) (*chat.RenderPromptResponse, error) {
+	content, variables, version := template.Content, template.Variables, template.Version
+	if req.Version != nil && *req.Version != template.Version {
+		v, err := s.promptRepo.GetVersion(ctx, template.ID, *req.Version)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, ErrPromptNotFound
+			}
+			return nil, err
+		}
+		content, variables, version = v.Content, v.Variables, v.Version
+	}
+
+	values, err := promptValues(variables, req.Variables)
+	if err != nil {
+		return nil, err
+	}
+	rendered, err := renderPromptContent(ctx, content, values)
+	if err != nil {
+		return nil, err
+	}
+
+	return &chat.RenderPromptResponse{Version: version, Role: template.Role, Content: rendered}, nil
+}
+
+// normalizePrompt 校验模板字段，合并声明的变量与内容中引用的变量，并试渲染以检查模板语法
+func (s *Service) normalizePrompt(ctx context.Context, template *chat.PromptTemplate) error {
+	title, ok := normalizeName(template.Title, maxPromptTitleRunes)
+	if !ok {
+		return fmt.Errorf("%w: 标题不能为空且不能超过 %d 个字符", ErrInvalidPrompt, maxPromptTitleRunes)
+	}
+	template.Title = title
+
+	if strings.TrimSpace(template.Content) == "" || len([]rune(template.Content)) > maxPromptContentRunes {
+		return fmt.Errorf("%w: 内容不能为空且不能超过 %d 个字符", ErrInvalidPrompt, maxPromptContentRunes)
+	}
+	if !validPromptScope(template.Scope) {
+		return fmt.Errorf("%w: 范围只能为 user、workspace 或 tenant", ErrInvalidPrompt)
+	}
+	if template.Scope == chat.PromptScopeWorkspace && template.WorkspaceID == nil {
+		return fmt.Errorf("%w: 工作区模板必须指定工作区", ErrInvalidPrompt)
+	}
+	if template.Scope != chat.PromptScopeWorkspace {
+		template.WorkspaceID = nil
+	}
+	if template.Role != chat.PromptRoleSystem && template.Role != chat.PromptRoleUser {
+		return fmt.Errorf("%w: 角色只能为 system 或 user", ErrInvalidPrompt)
+	}
+
+	variables, err := mergePromptVariables(template.Content, template.Variables)
+	if err != nil {
+		return err
+	}
+	template.Variables = variables
+
+	// 所有变量取占位值试渲染，提前暴露语法错误
+	values := make(map[string]any, len(variables))
+	for _, v := range variables {
+		values[v.Name] = ""
+	}
+	if _, err := renderPromptContent(ctx, template.Content, values); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// getVisiblePrompt 获取当前用户可见的模板，个人模板仅创建者和被分享的用户可见
+func (s *Service) getVisiblePrompt(ctx context.Context, userID, id string) (*chat.PromptTemplate, error) {
+	template, err := s.promptRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrPromptNotFound
+		}
+		return nil, err
+	}
+	if template.Scope != chat.PromptScopeUser || template.CreatedBy == userID {
+		return template, nil
+	}
+
+	shared, err := s.promptRepo.IsSharedWith(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !shared {
+		return nil, ErrPromptNotFound
+	}
+
+	return template, nil
+}
+
+// getOwnPrompt 获取当前用户创建的模板，其他可见用户只能使用不能修改
+func (s *Service) getOwnPrompt(ctx context.Context, userID, id string) (*chat.PromptTemplate, error) {
+	template, err := s.getVisiblePrompt(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if template.CreatedBy != userID {
+		return nil, ErrPromptForbidden
+	}
+	return template, nil
+}
+
+// renderPromptContent 使用 eino 的 Jinja2 模板渲染内容，与聊天图形使用同一套提示词模板组件
+func renderPromptContent(ctx context.Context, content string, values map[string]any) (string, error) {
+	messages, err := prompt.FromMessages(schema.Jinja2, schema.UserMessage(content)).Format(ctx, values)
+	if err != nil {
+		return "", fmt.Errorf("%w: 模板语法错误: %v", ErrInvalidPrompt, err)
+	}
+	if len(messages) == 0 {
+		return "", nil
+	}
+	return messages[0].Content, nil
+}
+
+// promptValues 按模板变量整理渲染取值，未传入的变量使用默认值，必填变量缺失时返回错误
+func promptValues(variables chat.PromptVariables, input map[string]any) (map[string]any, error) {
+	values := make(map[string]any, len(variables))
+	var missing []string
+	for _, v := range variables {
+		if value, ok := input[v.Name]; ok && value != nil && value != "" {
+			values[v.Name] = value
+			continue
+		}
+		switch {
+		case v.Default != nil:
+			values[v.Name] = *v.Default
+		case v.Required:
+			missing = append(missing, v.Name)
+		default:
+			values[v.Name] = ""
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrPromptVariableMissing, strings.Join(missing, ", "))
+	}
+	return values, nil
+}
+
+// mergePromptVariables 校验声明的变量，并补充内容中引用但未声明的变量
+// 模板内 for 和 set 语句绑定的局部变量不视为模板变量
+func mergePromptVariables(content string, declared chat.PromptVariables) (chat.PromptVariables, error) {
+	variables := make(chat.PromptVariables, 0, len(declared))
+	seen := make(map[string]bool, len(declared))
+	for _, v := range declared {
+		v.Name = strings.TrimSpace(v.Name)
+		if !promptVariableName.MatchString(v.Name) {
+			return nil, fmt.Errorf("%w: 变量名只能包含字母、数字和下划线，且不能以数字开头", ErrInvalidPrompt)
+		}
+		if seen[v.Name] {
+			return nil, fmt.Errorf("%w: 变量 %s 重复声明", ErrInvalidPrompt, v.Name)
+		}
+		seen[v.Name] = true
+		variables = append(variables, v)
+	}
+
+	bound := make(map[string]bool)
+	for _, match := range promptBoundPattern.FindAllStringSubmatch(content, -1) {
+		for _, name := range strings.Split(match[1]+","+match[2], ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				bound[name] = true
+			}
+		}
+	}
+	for _, match := range promptVariablePattern.FindAllStringSubmatch(content, -1) {
+		name := match[1]
+		if seen[name] || bound[name] || promptReservedWords[name] {
+			continue
+		}
+		seen[name] = true
+		variables = append(variables, chat.PromptVariable{Name: name, Required: true})
+	}
+
+	if len(variables) > maxPromptVariables {
+		return nil, fmt.Errorf("%w: 每个模板最多 %d 个变量", ErrInvalidPrompt, maxPromptVariables)
+	}
+	return variables, nil
+}
+
+// samePromptVariables 判断两组变量是否相同
+func samePromptVariables(a, b chat.PromptVariables) bool {
+	ja, _ := json.Marshal(a)
+	jb, _ := json.Marshal(b)
+	return string(ja) == string(jb)
+}
+
+// validPromptScope 判断模板范围是否有效
+func validPromptScope(scope string) bool {
+	return scope == chat.PromptScopeUser || scope == chat.PromptScopeWorkspace || scope == chat.PromptScopeTenant
+}
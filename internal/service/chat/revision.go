@@ -0,0 +1,149 @@
+package chat
+
+import (
+	"context"
+	"errors"
+
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/chat"
+)
+
+// ErrMessageUnchanged 表示编辑后的内容与当前内容相同
+var ErrMessageUnchanged = errors.New("消息内容未改变")
+
+// UpdateMessage 原地编辑用户或系统消息，编辑前的内容保存为修订版本
+// 与 EditMessage 不同，原地编辑不创建新分支，也不重新生成回复
+func (s *Service) UpdateMessage(ctx context.Context, userID, canvasID, messageID string, req *chat.UpdateMessageRequest) (*chat.Message, error) {
+	if _, err := s.getWritableCanvas(ctx, canvasID); err != nil {
+		return nil, err
+	}
+
+	message, err := s.getCanvasMessage(ctx, canvasID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if message.Role != chat.MessageRoleUser && message.Role != chat.MessageRoleSystem {
+		return nil, ErrInvalidBranchTarget
+	}
+	if req.Content == message.Content && req.Metadata == nil {
+		return nil, ErrMessageUnchanged
+	}
+
+	updated, err := s.messageRepo.UpdateContent(ctx, messageID, req.Content, req.Metadata, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.bus.Publish(ctx, &chat.CanvasEvent{
+		Type:     chat.CanvasEventMessageUpdated,
+		CanvasID: canvasID,
+		UserID:   userID,
+		Message:  updated,
+	})
+	s.embeddings.Index(ctx, updated)
+
+	return updated, nil
+}
+
+// DeleteMessage 软删除消息，返回被删除的消息 ID
+// prune 为 true 时一并删除整个子树，否则子消息在消息树中显示在被删除消息的父消息下
+// 画布当前分支的叶子被删除时，切换到离被删除消息最近的分支
+func (s *Service) DeleteMessage(ctx context.Context, userID, canvasID, messageID string, prune bool) ([]string, error) {
+	canvas, err := s.getWritableCanvas(ctx, canvasID)
+	if err != nil {
+		return nil, err
+	}
+
+	message, err := s.getCanvasMessage(ctx, canvasID, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	deleted, err := s.messageRepo.SoftDelete(ctx, messageID, userID, prune)
+	if err != nil {
+		return nil, err
+	}
+
+	s.bus.Publish(ctx, &chat.CanvasEvent{
+		Type:       chat.CanvasEventMessageDeleted,
+		CanvasID:   canvasID,
+		UserID:     userID,
+		MessageIDs: deleted,
+	})
+
+	if canvas.ActiveLeafID != nil && containsString(deleted, *canvas.ActiveLeafID) {
+		if err := s.resetActiveLeaf(ctx, canvas, message.ParentID); err != nil {
+			return nil, err
+		}
+	}
+
+	return deleted, nil
+}
+
+// ListMessageRevisions 获取消息的当前内容及历史版本，已删除的消息仍可查看
+func (s *Service) ListMessageRevisions(ctx context.Context, canvasID, messageID string) (*chat.MessageRevisions, error) {
+	message, err := s.messageRepo.GetByID(ctx, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if message.CanvasID != canvasID {
+		return nil, ErrMessageNotInCanvas
+	}
+
+	revisions, err := s.messageRepo.ListRevisions(ctx, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if revisions == nil {
+		revisions = []*chat.MessageRevision{}
+	}
+
+	return &chat.MessageRevisions{Message: message, Revisions: revisions}, nil
+}
+
+// resetActiveLeaf 将画布当前分支切换到 anchorID 所在分支的最新叶子
+// anchorID 为空或已不存在时切换到最新的根分支，画布没有消息时清除当前分支
+func (s *Service) resetActiveLeaf(ctx context.Context, canvas *chat.Canvas, anchorID *string) error {
+	messages, err := s.messageRepo.ListByCanvasID(ctx, canvas.ID)
+	if err != nil {
+		return err
+	}
+
+	tree, _ := buildMessageTree(canvas, messages)
+	nodes := make(map[string]*chat.MessageNode, len(messages))
+	indexNodes(tree.Roots, nodes)
+
+	// anchorID 指向已删除的消息时，改用其最近的未删除祖先
+	byID := make(map[string]*chat.Message, len(messages))
+	for _, msg := range messages {
+		byID[msg.ID] = msg
+	}
+
+	leafID := ""
+	if anchor, ok := nodes[liveAncestorID(byID, anchorID)]; ok {
+		leafID = latestLeaf(anchor).ID
+	} else if len(tree.Roots) > 0 {
+		leafID = latestLeaf(tree.Roots[len(tree.Roots)-1]).ID
+	}
+
+	if err := s.canvasRepo.SetActiveLeaf(ctx, canvas.ID, leafID); err != nil {
+		return err
+	}
+	if leafID == "" {
+		canvas.ActiveLeafID = nil
+	} else {
+		canvas.ActiveLeafID = &leafID
+	}
+
+	s.publishCanvas(ctx, canvas)
+	return nil
+}
+
+// containsString 判断 values 是否包含 value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,198 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"html"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/chat"
+)
+
+const (
+	// maxSearchQueryRunes 是搜索词的最大字符数
+	maxSearchQueryRunes = 200
+	// snippetRunes 是高亮片段的最大字符数
+	snippetRunes = 160
+	// snippetLead 是片段中首个命中位置之前保留的字符数
+	snippetLead = 40
+)
+
+// 高亮标记，片段中的其余内容均已做 HTML 转义
+const (
+	highlightOpen  = "<mark>"
+	highlightClose = "</mark>"
+)
+
+// ErrInvalidSearch 表示搜索条件无效
+var ErrInvalidSearch = errors.New("无效的搜索条件")
+
+// Search 搜索当前租户下的画布标题和消息内容，结果按相关度排序并附带高亮片段
+// 语义搜索按消息向量与搜索词的相似度排序，画布结果指向画布内最相似的消息
+func (s *Service) Search(ctx context.Context, req *chat.SearchRequest, page, pageSize int) ([]*chat.SearchResult, int, error) {
+	req.Query = strings.TrimSpace(req.Query)
+	if req.Query == "" || len([]rune(req.Query)) > maxSearchQueryRunes {
+		return nil, 0, ErrInvalidSearch
+	}
+	if req.Kind != nil && *req.Kind != chat.SearchKindCanvas && *req.Kind != chat.SearchKindMessage {
+		return nil, 0, ErrInvalidSearch
+	}
+	if req.Mode == "" {
+		req.Mode = chat.SearchModeKeyword
+	}
+
+	req.Offset = (page - 1) * pageSize
+	req.Limit = pageSize
+
+	var results []*chat.SearchResult
+	var total int
+	var err error
+	switch req.Mode {
+	case chat.SearchModeKeyword:
+		results, total, err = s.searchRepo.Search(ctx, req)
+	case chat.SearchModeSemantic:
+		results, total, err = s.embeddings.Search(ctx, req)
+	default:
+		return nil, 0, ErrInvalidSearch
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	terms := highlightTerms(req.Query)
+	for _, result := range results {
+		result.Snippet = highlightSnippet(result.Content, terms)
+	}
+
+	return results, total, nil
+}
+
+// highlightTerms 返回需要高亮的词，按长度从长到短排列
+// 数据库按二元组匹配 CJK 文本，命中的内容不一定连续出现完整的搜索词，
+// 因此除完整的词外也高亮其中的 CJK 二元组
+func highlightTerms(query string) [][]rune {
+	seen := make(map[string]bool)
+	var terms [][]rune
+	add := func(term []rune) {
+		if len(term) == 0 || seen[string(term)] {
+			return
+		}
+		seen[string(term)] = true
+		terms = append(terms, term)
+	}
+
+	for _, field := range strings.Fields(query) {
+		term := lowerRunes([]rune(field))
+		add(term)
+		for i := 0; i+1 < len(term); i++ {
+			if isCJK(term[i]) && isCJK(term[i+1]) {
+				add(term[i : i+2])
+			}
+		}
+	}
+
+	sort.SliceStable(terms, func(i, j int) bool {
+		return len(terms[i]) > len(terms[j])
+	})
+	return terms
+}
+
+// highlightSnippet 截取首个命中位置附近的内容，并用 <mark> 标记命中的词
+func highlightSnippet(content string, terms [][]rune) string {
+	runes := []rune(content)
+	lowered := lowerRunes(runes)
+
+	// 从左到右匹配，同一位置优先匹配较长的词
+	type span struct{ start, end int }
+	var spans []span
+	for i := 0; i < len(lowered); {
+		matched := 0
+		for _, term := range terms {
+			if hasPrefixRunes(lowered[i:], term) {
+				matched = len(term)
+				break
+			}
+		}
+		if matched == 0 {
+			i++
+			continue
+		}
+		spans = append(spans, span{i, i + matched})
+		i += matched
+	}
+
+	// 确定片段范围
+	start := 0
+	if len(spans) > 0 && spans[0].start > snippetLead {
+		start = spans[0].start - snippetLead
+	}
+	end := start + snippetRunes
+	if end > len(runes) {
+		end = len(runes)
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	pos := start
+	for _, sp := range spans {
+		if sp.end <= start {
+			continue
+		}
+		if sp.start >= end {
+			break
+		}
+		s, e := sp.start, sp.end
+		if s < start {
+			s = start
+		}
+		if e > end {
+			e = end
+		}
+		b.WriteString(html.EscapeString(string(runes[pos:s])))
+		b.WriteString(highlightOpen)
+		b.WriteString(html.EscapeString(string(runes[s:e])))
+		b.WriteString(highlightClose)
+		pos = e
+	}
+	b.WriteString(html.EscapeString(string(runes[pos:end])))
+	if end < len(runes) {
+		b.WriteString("…")
+	}
+
+	return b.String()
+}
+
+// lowerRunes 逐字符转换为小写，保持与原文的位置一一对应
+func lowerRunes(runes []rune) []rune {
+	lowered := make([]rune, len(runes))
+	for i, r := range runes {
+		lowered[i] = unicode.ToLower(r)
+	}
+	return lowered
+}
+
+// hasPrefixRunes 判断 s 是否以 prefix 开头
+func hasPrefixRunes(s, prefix []rune) bool {
+	if len(s) < len(prefix) {
+		return false
+	}
+	for i, r := range prefix {
+		if s[i] != r {
+			return false
+		}
+	}
+	return true
+}
+
+// isCJK 判断字符是否属于按二元组切分的 CJK 范围，与 search_tokens 函数保持一致
+func isCJK(r rune) bool {
+	return (r >= 0x3040 && r <= 0x30FF) ||
+		(r >= 0x3400 && r <= 0x4DBF) ||
+		(r >= 0x4E00 && r <= 0x9FFF) ||
+		(r >= 0xAC00 && r <= 0xD7AF) ||
+		(r >= 0xF900 && r <= 0xFAFF)
+}
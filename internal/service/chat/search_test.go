@@ -0,0 +1,72 @@
+package chat
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHighlightTerms(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"Go", []string{"go"}},
+		{"Go 数据库", []string{"数据库", "go", "数据", "据库"}},
+		{"go GO", []string{"go"}},
+		{"数据 数据", []string{"数据"}},
+		{"a数据b", []string{"a数据b", "数据"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			var got []string
+			for _, term := range highlightTerms(tt.query) {
+				got = append(got, string(term))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("highlightTerms(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHighlightSnippet(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		content string
+		want    string
+	}{
+		{"case insensitive", "world", "Hello World", "Hello <mark>World</mark>"},
+		{"html escaped", "go", "<b>go</b> & Go", "&lt;b&gt;<mark>go</mark>&lt;/b&gt; &amp; <mark>Go</mark>"},
+		{"longest term first", "数据库", "数据库设计", "<mark>数据库</mark>设计"},
+		{"cjk bigrams matched separately", "数据库", "数据和据库", "<mark>数据</mark>和<mark>据库</mark>"},
+		{"no match", "go", "rust", "rust"},
+		{
+			name:    "window around first match",
+			query:   "go",
+			content: strings.Repeat("a", 100) + "go" + strings.Repeat("b", 300),
+			want:    "…" + strings.Repeat("a", snippetLead) + "<mark>go</mark>" + strings.Repeat("b", snippetRunes-snippetLead-2) + "…",
+		},
+		{
+			name:    "no match truncated from start",
+			query:   "go",
+			content: strings.Repeat("x", 200),
+			want:    strings.Repeat("x", snippetRunes) + "…",
+		},
+		{
+			name:    "match cut at window end",
+			query:   "go",
+			content: "go" + strings.Repeat("x", snippetRunes-3) + "go",
+			want:    "<mark>go</mark>" + strings.Repeat("x", snippetRunes-3) + "<mark>g</mark>…",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := highlightSnippet(tt.content, highlightTerms(tt.query))
+			if got != tt.want {
+				t.Fatalf("highlightSnippet = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
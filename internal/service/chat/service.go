@@ -2,33 +2,67 @@ package chat
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/cloudwego/eino/schema"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"github.com/zhuiye8/Lyss-chat-server/internal/ai/graphs"
+	"github.com/zhuiye8/Lyss-chat-server/internal/ai/providers"
+	"github.com/zhuiye8/Lyss-chat-server/internal/ai/tokenizer"
 	"github.com/zhuiye8/Lyss-chat-server/internal/domain/chat"
 	"github.com/zhuiye8/Lyss-chat-server/internal/domain/model"
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/user"
 	"github.com/zhuiye8/Lyss-chat-server/internal/repository/postgres"
+	"github.com/zhuiye8/Lyss-chat-server/internal/service/tenant"
 	"github.com/zhuiye8/Lyss-chat-server/pkg/config"
 	"github.com/zhuiye8/Lyss-chat-server/pkg/db"
 	"github.com/zhuiye8/Lyss-chat-server/pkg/logger"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/sandbox"
+)
+
+// 聊天服务错误
+var (
+	ErrProviderNotAllowed = errors.New("租户不允许使用该模型提供商")
+	ErrNoModelConfigured  = errors.New("未配置可用的模型")
+	ErrChatUnavailable    = errors.New("未配置聊天图形")
 )
 
 // Service 表示聊天服务
 type Service struct {
-	canvasRepo  chat.CanvasRepository
-	messageRepo chat.MessageRepository
-	modelRepo   model.ModelRepository
-	aiGraphs    *graphs.ChatGraphs
-	logger      *logger.Logger
+	canvasRepo     chat.CanvasRepository
+	messageRepo    chat.MessageRepository
+	modelRepo      model.ModelRepository
+	providerRepo   model.ProviderRepository
+	searchRepo     chat.SearchRepository
+	feedbackRepo   chat.FeedbackRepository
+	folderRepo     chat.FolderRepository
+	tagRepo        chat.TagRepository
+	promptRepo     chat.PromptRepository
+	assistantRepo  chat.AssistantRepository
+	artifactRepo   chat.ArtifactRepository
+	settings       *tenant.SettingsService
+	contextBuilder *ContextBuilder
+	summarizer     *Summarizer
+	titler         *Titler
+	embeddings     *EmbeddingIndexer
+	generations    *GenerationRegistry
+	buffer         *StreamBuffer
+	bus            *EventBus
+	sandbox        *sandbox.Runner
+	aiGraphs       *graphs.ChatGraphs
+	logger         *logger.Logger
 }
 
 // NewService 创建一个新的聊天服�?
 func NewService(
 	database *db.Postgres,
+	redis *db.Redis,
 	aiGraphs *graphs.ChatGraphs,
 	cfg *config.Config,
 	logger *logger.Logger,
@@ -36,18 +70,68 @@ func NewService(
 	canvasRepo := postgres.NewCanvasRepository(database)
 	messageRepo := postgres.NewMessageRepository(database)
 	modelRepo := postgres.NewModelRepository(database)
+	providerRepo := postgres.NewProviderRepository(database)
 
 	return &Service{
-		canvasRepo:  canvasRepo,
-		messageRepo: messageRepo,
-		modelRepo:   modelRepo,
-		aiGraphs:    aiGraphs,
-		logger:      logger,
+		canvasRepo:     canvasRepo,
+		messageRepo:    messageRepo,
+		modelRepo:      modelRepo,
+		providerRepo:   providerRepo,
+		searchRepo:     postgres.NewSearchRepository(database),
+		feedbackRepo:   postgres.NewFeedbackRepository(database),
+		folderRepo:     postgres.NewFolderRepository(database),
+		tagRepo:        postgres.NewTagRepository(database),
+		promptRepo:     postgres.NewPromptRepository(database),
+		assistantRepo:  postgres.NewAssistantRepository(database),
+		artifactRepo:   postgres.NewArtifactRepository(database),
+		settings:       tenant.NewSettingsService(database, logger),
+		contextBuilder: NewContextBuilder(cfg.Chat),
+		summarizer:     NewSummarizer(postgres.NewSummaryRepository(database), aiGraphs, cfg.Chat, logger),
+		titler:         NewTitler(canvasRepo, aiGraphs, cfg.Chat, logger),
+		embeddings:     NewEmbeddingIndexer(postgres.NewEmbeddingRepository(database), cfg.Embedding, logger),
+		generations:    NewGenerationRegistry(redis, logger),
+		buffer:         NewStreamBuffer(redis, logger),
+		bus:            NewEventBus(redis, logger),
+		sandbox:        sandbox.NewRunner(cfg.Sandbox),
+		aiGraphs:       aiGraphs,
+		logger:         logger,
 	}
 }
 
+// Embeddings 返回消息向量索引器，其后台补全任务由调用方随服务生命周期启动
+func (s *Service) Embeddings() *EmbeddingIndexer {
+	return s.embeddings
+}
+
 // CreateCanvas 创建一个新的画�?
-func (s *Service) CreateCanvas(userID string, req *chat.CreateCanvasRequest) (*chat.Canvas, error) {
+func (s *Service) CreateCanvas(ctx context.Context, userID string, req *chat.CreateCanvasRequest) (*chat.Canvas, error) {
+	// 获取租户设置
+	settings, err := s.settings.GetSettings(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// 校验文件夹属于同一工作区
+	if req.FolderID != nil {
+		if err := s.checkFolder(ctx, req.WorkspaceID, *req.FolderID); err != nil {
+			return nil, err
+		}
+	}
+
+	// 校验助手对当前用户可见
+	if req.AssistantID != nil {
+		if _, err := s.getVisibleAssistant(ctx, userID, *req.AssistantID); err != nil {
+			return nil, err
+		}
+	}
+
+	// 校验指定模型的提供商
+	if req.ModelID != nil {
+		if err := s.checkModelAllowed(ctx, settings, *req.ModelID); err != nil {
+			return nil, err
+		}
+	}
+
 	// 设置默认状�?
 	status := chat.CanvasStatusActive
 
@@ -55,34 +139,50 @@ func (s *Service) CreateCanvas(userID string, req *chat.CreateCanvasRequest) (*c
 	canvas := &chat.Canvas{
 		ID:          uuid.New().String(),
 		WorkspaceID: req.WorkspaceID,
+		FolderID:    req.FolderID,
 		Title:       req.Title,
 		Description: req.Description,
 		Type:        req.Type,
 		Status:      status,
 		ModelID:     req.ModelID,
+		AssistantID: req.AssistantID,
+		TopicTags:   pq.StringArray{},
 		CreatedBy:   userID,
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
 	}
 
+	// 校验生成设置
+	if req.Settings != nil {
+		canvas.Settings = *req.Settings
+		if err := s.validateCanvasSettings(ctx, canvas, settings); err != nil {
+			return nil, err
+		}
+	}
+
 	// 保存画布
-	err := s.canvasRepo.Create(canvas)
+	err = s.canvasRepo.Create(ctx, canvas)
 	if err != nil {
 		return nil, fmt.Errorf("创建画布失败: %w", err)
 	}
 
+	// 租户关闭欢迎语时不创建系统消息
+	if settings.CanvasGreeting == nil {
+		return canvas, nil
+	}
+
 	// 创建系统消息
 	systemMessage := &chat.Message{
 		ID:        uuid.New().String(),
 		CanvasID:  canvas.ID,
 		Role:      chat.MessageRoleSystem,
-		Content:   "欢迎使用 Lyss Chat！我是您�?AI 助手，有什么可以帮您的吗？",
+		Content:   *settings.CanvasGreeting,
 		CreatedBy: userID,
 		CreatedAt: time.Now(),
 	}
 
 	// 保存系统消息
-	err = s.messageRepo.Create(systemMessage)
+	err = s.messageRepo.Create(ctx, systemMessage)
 	if err != nil {
 		s.logger.Error("创建系统消息失败", err)
 		// 继续处理，不要因为系统消息创建失败而阻止画布创�?
@@ -91,22 +191,24 @@ func (s *Service) CreateCanvas(userID string, req *chat.CreateCanvasRequest) (*c
 	return canvas, nil
 }
 
-// GetCanvas 获取画布
-func (s *Service) GetCanvas(id string) (*chat.Canvas, error) {
-	return s.canvasRepo.GetByID(id)
-}
 
 // UpdateCanvas 更新画布
-func (s *Service) UpdateCanvas(id string, req *chat.UpdateCanvasRequest) (*chat.Canvas, error) {
+func (s *Service) UpdateCanvas(ctx context.Context, userID, id string, req *chat.UpdateCanvasRequest) (*chat.Canvas, error) {
 	// 获取画布
-	canvas, err := s.canvasRepo.GetByID(id)
+	canvas, err := s.canvasRepo.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
+	// 归档的画布只能在取消归档的同时修改
+	if canvas.Status == chat.CanvasStatusArchived && (req.Status == nil || *req.Status == chat.CanvasStatusArchived) {
+		return nil, ErrCanvasArchived
+	}
+
 	// 更新字段
 	if req.Title != nil {
 		canvas.Title = *req.Title
+		canvas.TitleCustomized = true
 	}
 	if req.Description != nil {
 		canvas.Description = req.Description
@@ -117,244 +219,583 @@ func (s *Service) UpdateCanvas(id string, req *chat.UpdateCanvasRequest) (*chat.
 	if req.ModelID != nil {
 		canvas.ModelID = req.ModelID
 	}
+	if req.Settings != nil {
+		canvas.Settings = *req.Settings
+	}
+	if req.AssistantID != nil {
+		canvas.AssistantID = emptyToNil(req.AssistantID)
+		if canvas.AssistantID != nil {
+			if _, err := s.getVisibleAssistant(ctx, userID, *canvas.AssistantID); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// 更换模型、助手或设置时重新校验生成设置
+	if req.ModelID != nil || req.AssistantID != nil || req.Settings != nil {
+		settings, err := s.settings.GetSettings(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if req.ModelID != nil {
+			if err := s.checkModelAllowed(ctx, settings, *req.ModelID); err != nil {
+				return nil, err
+			}
+		}
+		if err := s.validateCanvasSettings(ctx, canvas, settings); err != nil {
+			return nil, err
+		}
+	}
 
 	// 保存画布
-	err = s.canvasRepo.Update(canvas)
+	err = s.canvasRepo.Update(ctx, canvas)
 	if err != nil {
 		return nil, fmt.Errorf("更新画布失败: %w", err)
 	}
 
+	s.publishCanvas(ctx, canvas)
+
 	return canvas, nil
 }
 
-// DeleteCanvas 删除画布
-func (s *Service) DeleteCanvas(id string) error {
-	return s.canvasRepo.Delete(id)
+// SendMessage 发送消息
+func (s *Service) SendMessage(ctx context.Context, userID, canvasID string, req *chat.SendMessageRequest) (*chat.Message, error) {
+	turn, err := s.prepareTurn(ctx, canvasID, req.Settings)
+	if err != nil {
+		return nil, err
+	}
+
+	// 创建用户消息
+	userMessage, err := s.createUserMessage(ctx, userID, turn.canvas, s.defaultParentID(turn.canvas, req.ParentID), req.Content, req.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.reply(ctx, userID, turn, userMessage)
 }
 
-// ListCanvases 列出画布
-func (s *Service) ListCanvases(workspaceID string, canvasType *string, page, pageSize int) ([]*chat.Canvas, int, error) {
+// GetMessages 获取消息
+func (s *Service) GetMessages(ctx context.Context, canvasID string, page, pageSize int) ([]*chat.Message, int, error) {
 	offset := (page - 1) * pageSize
-	return s.canvasRepo.List(workspaceID, canvasType, offset, pageSize)
+	return s.messageRepo.GetByCanvasID(ctx, canvasID, offset, pageSize)
 }
 
-// SendMessage 发送消�?
-func (s *Service) SendMessage(userID, canvasID string, req *chat.SendMessageRequest) (*chat.Message, error) {
-	// 获取画布
-	canvas, err := s.canvasRepo.GetByID(canvasID)
+// StreamMessage 流式发送消息
+func (s *Service) StreamMessage(ctx context.Context, userID, canvasID string, req *chat.SendMessageRequest) (<-chan GenerationEvent, error) {
+	turn, err := s.prepareTurn(ctx, canvasID, req.Settings)
 	if err != nil {
 		return nil, err
 	}
 
 	// 创建用户消息
+	userMessage, err := s.createUserMessage(ctx, userID, turn.canvas, s.defaultParentID(turn.canvas, req.ParentID), req.Content, req.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.streamReply(ctx, userID, turn, userMessage), nil
+}
+
+// chatTurn 表示一次待生成回复的对话轮次
+type chatTurn struct {
+	canvas     *chat.Canvas
+	assistant  *chat.Assistant
+	settings   *user.TenantSettings
+	model      *model.Model
+	generation chat.GenerationSettings
+	// compareID 非空时为对比模式的一路生成，回复作为候选保存，选定后才继续分支
+	compareID string
+}
+
+// prepareTurn 获取画布、助手、租户设置并确定本轮使用的模型，归档的画布不能继续对话
+func (s *Service) prepareTurn(ctx context.Context, canvasID string, override *chat.GenerationSettings) (*chatTurn, error) {
+	// 获取画布
+	canvas, err := s.getWritableCanvas(ctx, canvasID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 获取租户设置并确定模型
+	settings, err := s.settings.GetSettings(ctx)
+	if err != nil {
+		return nil, err
+	}
+	assistant, err := s.canvasAssistant(ctx, canvas)
+	if err != nil {
+		return nil, err
+	}
+	modelID, err := s.resolveModelID(ctx, canvas, assistant, settings)
+	if err != nil {
+		return nil, err
+	}
+	m, err := s.modelRepo.GetByID(ctx, modelID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 助手设置依次叠加画布设置和本条消息的覆盖设置，并按当前模型校验
+	generation := assistantSettings(assistant).Merge(&canvas.Settings).Merge(override)
+	if err := validateSettings(m, generation); err != nil {
+		return nil, err
+	}
+
+	return &chatTurn{canvas: canvas, assistant: assistant, settings: settings, model: m, generation: generation}, nil
+}
+
+// defaultParentID 未指定父消息时，默认接在画布当前分支之后
+func (s *Service) defaultParentID(canvas *chat.Canvas, parentID *string) *string {
+	if parentID != nil {
+		return parentID
+	}
+	return canvas.ActiveLeafID
+}
+
+// createUserMessage 保存用户消息并将其设为画布当前分支
+func (s *Service) createUserMessage(ctx context.Context, userID string, canvas *chat.Canvas, parentID *string, content string, metadata json.RawMessage) (*chat.Message, error) {
 	userMessage := &chat.Message{
 		ID:        uuid.New().String(),
-		CanvasID:  canvasID,
-		ParentID:  req.ParentID,
+		CanvasID:  canvas.ID,
+		ParentID:  parentID,
 		Role:      chat.MessageRoleUser,
-		Content:   req.Content,
-		Metadata:  req.Metadata,
+		Content:   content,
+		Metadata:  metadata,
 		CreatedBy: userID,
 		CreatedAt: time.Now(),
 	}
 
 	// 保存用户消息
-	err = s.messageRepo.Create(userMessage)
+	err := s.messageRepo.Create(ctx, userMessage)
 	if err != nil {
 		return nil, fmt.Errorf("保存用户消息失败: %w", err)
 	}
 
-	// 获取对话历史
+	s.setActiveLeaf(ctx, canvas.ID, userMessage.ID)
+	s.publishMessage(ctx, userMessage)
+	s.embeddings.Index(ctx, userMessage)
+
+	return userMessage, nil
+}
+
+// buildGraphInput 沿分支回溯至根消息，按模型的上下文预算构建聊天图形的输入
+// 同时返回完整的分支历史，供生成回复后刷新摘要
+func (s *Service) buildGraphInput(ctx context.Context, turn *chatTurn, userMessage *chat.Message) (map[string]any, []*chat.Message) {
+	// 系统提示词优先使用画布设置，其次为租户默认值
+	systemPrompt := graphs.DefaultSystemPrompt
+	if turn.generation.SystemPrompt != nil {
+		systemPrompt = *turn.generation.SystemPrompt
+	} else if turn.settings.DefaultSystemPrompt != nil {
+		systemPrompt = *turn.settings.DefaultSystemPrompt
+	}
+
+	// 代码画布附加文件的当前内容，使模型以差异修改文件而不是重新输出
+	systemPrompt += s.artifactContext(ctx, turn.canvas)
+
+	// 获取分支历史
 	var history []*chat.Message
-	if req.ParentID != nil {
-		history, err = s.messageRepo.GetConversation(*req.ParentID, 10)
+	if userMessage.ParentID != nil {
+		branch, err := s.messageRepo.GetBranch(ctx, *userMessage.ParentID)
 		if err != nil {
 			s.logger.Error("获取对话历史失败", err)
 			// 继续处理，使用空历史
-			history = []*chat.Message{}
+		} else {
+			history = branch
 		}
 	}
 
 	// 添加当前用户消息
 	history = append(history, userMessage)
 
-	// 转换�?Eino 消息格式
-	einoMessages := make([]*schema.Message, 0, len(history))
-	for _, msg := range history {
-		einoMessages = append(einoMessages, &schema.Message{
-			Role:    msg.Role,
-			Content: msg.Content,
-		})
+	// 已被摘要的消息以摘要代替
+	var summaryContent string
+	summary, covered := s.summarizer.Latest(ctx, history)
+	if summary != nil {
+		summaryContent = summary.Content
 	}
 
-	// 调用 AI 模型
-	ctx := context.Background()
-	var modelID string
-	if canvas.ModelID != nil {
-		modelID = *canvas.ModelID
-	} else {
-		// 使用默认模型
-		modelID = "default"
+	// 裁剪到模型的上下文预算内
+	messages := s.contextBuilder.Build(
+		tokenizer.ForModel(turn.model.ModelID),
+		graphs.RenderSystemMessage(systemPrompt, turn.settings.DefaultLanguage),
+		summaryContent,
+		history[covered:],
+		s.contextBuilder.Budget(turn.model, turn.generation.MaxOutputTokens),
+	)
+
+	return map[string]any{
+		"messages":      messages,
+		"model_id":      turn.model.ID,
+		"params":        turn.generation.Params(),
+		"system_prompt": systemPrompt,
+		"language":      turn.settings.DefaultLanguage,
+	}, history
+}
+
+// refreshSummary 在后台刷新分支摘要，不阻塞回复
+func (s *Service) refreshSummary(ctx context.Context, turn *chatTurn, history []*chat.Message, aiMessage *chat.Message) {
+	branch := append(history[:len(history):len(history)], aiMessage)
+	go s.summarizer.Refresh(context.WithoutCancel(ctx), branch, turn.model.ID, turn.settings.DefaultLanguage)
+}
+
+// autoTitle 在后台为尚未自动命名的画布生成标题和标签，完成后广播画布变更
+func (s *Service) autoTitle(ctx context.Context, turn *chatTurn, history []*chat.Message, aiMessage *chat.Message) {
+	if turn.canvas.AutoTitledAt != nil {
+		return
 	}
 
-	// 准备输入
-	input := map[string]any{
-		"messages": einoMessages,
-		"model_id": modelID,
+	exchange := append(history[:len(history):len(history)], aiMessage)
+	go func() {
+		ctx := context.WithoutCancel(ctx)
+		if canvas := s.titler.Generate(ctx, turn.canvas, exchange, turn.model.ID, turn.settings.DefaultLanguage); canvas != nil {
+			s.publishCanvas(ctx, canvas)
+		}
+	}()
+}
+
+// completeReply 在助手回复保存后广播并索引消息，并沿该回复继续分支
+// 对比模式的候选回复不切换当前分支，选定后再继续
+func (s *Service) completeReply(ctx context.Context, userID string, turn *chatTurn, history []*chat.Message, aiMessage *chat.Message) {
+	if turn.compareID == "" {
+		s.setActiveLeaf(ctx, aiMessage.CanvasID, aiMessage.ID)
 	}
+	s.publishMessage(ctx, aiMessage)
+	s.embeddings.Index(ctx, aiMessage)
+	if turn.compareID != "" {
+		return
+	}
+	s.continueBranch(ctx, userID, turn, history, aiMessage)
+}
+
+// continueBranch 以助手回复作为分支的最新一轮：刷新摘要、自动命名画布并提取产物
+func (s *Service) continueBranch(ctx context.Context, userID string, turn *chatTurn, history []*chat.Message, aiMessage *chat.Message) {
+	s.refreshSummary(ctx, turn, history, aiMessage)
+	s.autoTitle(ctx, turn, history, aiMessage)
+	s.extractArtifacts(ctx, userID, turn, aiMessage)
+}
+
+// reply 为用户消息生成 AI 回复
+func (s *Service) reply(ctx context.Context, userID string, turn *chatTurn, userMessage *chat.Message) (*chat.Message, error) {
+	// 准备输入
+	input, history := s.buildGraphInput(ctx, turn, userMessage)
 
 	// 调用 AI 图形
-	aiResponse, err := s.aiGraphs.Chat.Invoke(ctx, input)
+	graph, err := s.chatGraph(ctx, turn)
+	if err != nil {
+		s.logger.Error("构建 AI 图形失败", err)
+		return nil, fmt.Errorf("构建 AI 图形失败: %w", err)
+	}
+	aiResponse, err := graph.Invoke(ctx, input)
 	if err != nil {
 		s.logger.Error("调用 AI 模型失败", err)
 		return nil, fmt.Errorf("调用 AI 模型失败: %w", err)
 	}
 
+	var usage *chat.TokenUsage
+	if aiResponse.ResponseMeta != nil {
+		usage = toTokenUsage(aiResponse.ResponseMeta.Usage)
+	}
+
 	// 创建 AI 响应消息
 	aiMessage := &chat.Message{
 		ID:        uuid.New().String(),
-		CanvasID:  canvasID,
+		CanvasID:  userMessage.CanvasID,
 		ParentID:  &userMessage.ID,
 		Role:      chat.MessageRoleAssistant,
 		Content:   aiResponse.Content,
+		Metadata:  assistantMetadata(turn, chat.FinishReasonStop, usage),
 		CreatedBy: userID,
 		CreatedAt: time.Now(),
 	}
 
 	// 保存 AI 响应消息
-	err = s.messageRepo.Create(aiMessage)
+	err = s.messageRepo.Create(ctx, aiMessage)
 	if err != nil {
 		s.logger.Error("保存 AI 响应消息失败", err)
 		return nil, fmt.Errorf("保存 AI 响应消息失败: %w", err)
 	}
 
-	return aiMessage, nil
-}
+	s.completeReply(ctx, userID, turn, history, aiMessage)
 
-// GetMessages 获取消息
-func (s *Service) GetMessages(canvasID string, page, pageSize int) ([]*chat.Message, int, error) {
-	offset := (page - 1) * pageSize
-	return s.messageRepo.GetByCanvasID(canvasID, offset, pageSize)
+	return aiMessage, nil
 }
 
-// StreamMessage 流式发送消�?
-func (s *Service) StreamMessage(userID, canvasID string, req *chat.SendMessageRequest) (<-chan *chat.Message, error) {
-	// 获取画布
-	canvas, err := s.canvasRepo.GetByID(canvasID)
-	if err != nil {
-		return nil, err
-	}
+// streamReply 为用户消息流式生成 AI 回复，返回当前请求订阅的生成事件
+// 事件依次为 start、若干 delta/tool_call/usage、done，出错时发送 error
+// 生成与请求连接解耦：事件写入 Redis 缓冲，客户端断开后可重新订阅；
+// 生成被主动停止或长时间无人订阅时取消，已生成的部分以 cancelled 结束原因保存
+// 生成 ID 与助手消息 ID 相同
+func (s *Service) streamReply(ctx context.Context, userID string, turn *chatTurn, userMessage *chat.Message) <-chan GenerationEvent {
+	// 准备输入
+	input, history := s.buildGraphInput(ctx, turn, userMessage)
 
-	// 创建用户消息
-	userMessage := &chat.Message{
+	// 创建 AI 响应消息
+	aiMessage := &chat.Message{
 		ID:        uuid.New().String(),
-		CanvasID:  canvasID,
-		ParentID:  req.ParentID,
-		Role:      chat.MessageRoleUser,
-		Content:   req.Content,
-		Metadata:  req.Metadata,
+		CanvasID:  userMessage.CanvasID,
+		ParentID:  &userMessage.ID,
+		Role:      chat.MessageRoleAssistant,
+		Content:   "",
 		CreatedBy: userID,
 		CreatedAt: time.Now(),
 	}
 
-	// 保存用户消息
-	err = s.messageRepo.Create(userMessage)
-	if err != nil {
-		return nil, fmt.Errorf("保存用户消息失败: %w", err)
-	}
-
-	// 获取对话历史
-	var history []*chat.Message
-	if req.ParentID != nil {
-		history, err = s.messageRepo.GetConversation(*req.ParentID, 10)
-		if err != nil {
-			s.logger.Error("获取对话历史失败", err)
-			// 继续处理，使用空历史
-			history = []*chat.Message{}
-		}
-	}
-
-	// 添加当前用户消息
-	history = append(history, userMessage)
-
-	// 转换�?Eino 消息格式
-	einoMessages := make([]*schema.Message, 0, len(history))
-	for _, msg := range history {
-		einoMessages = append(einoMessages, &schema.Message{
-			Role:    msg.Role,
-			Content: msg.Content,
+	// 生成不随请求断开而取消，但保留请求上下文中的租户信息
+	saveCtx := context.WithoutCancel(ctx)
+
+	// 登记生成，使其可以被停止
+	genCtx, release := s.generations.Register(saveCtx, aiMessage.CanvasID, aiMessage.ID)
+	writer := s.buffer.open(saveCtx, aiMessage.CanvasID, aiMessage.ID)
+
+	// emit 写入生成事件，并作为 message.delta 广播给画布的其他订阅者
+	emit := func(event *chat.StreamEvent, final bool) {
+		eventID := writer.Append(saveCtx, event, final)
+		s.bus.Publish(saveCtx, &chat.CanvasEvent{
+			Type:         chat.CanvasEventMessageDelta,
+			CanvasID:     aiMessage.CanvasID,
+			UserID:       userID,
+			GenerationID: aiMessage.ID,
+			EventID:      eventID,
+			Delta:        event,
 		})
 	}
 
-	// 调用 AI 模型
-	ctx := context.Background()
-	var modelID string
-	if canvas.ModelID != nil {
-		modelID = *canvas.ModelID
-	} else {
-		// 使用默认模型
-		modelID = "default"
-	}
-
-	// 准备输入
-	input := map[string]any{
-		"messages": einoMessages,
-		"model_id": modelID,
-	}
+	// 当前请求从缓冲中订阅事件，与重新连接走同一路径
+	events := s.buffer.Read(ctx, aiMessage.CanvasID, aiMessage.ID, 0)
 
-	// 创建结果通道
-	resultChan := make(chan *chat.Message)
+	// 先发送开始事件，客户端据此获得生成 ID
+	emit(&chat.StreamEvent{
+		Type:      chat.StreamEventStart,
+		MessageID: aiMessage.ID,
+		ParentID:  aiMessage.ParentID,
+		ModelID:   turn.model.ID,
+	}, false)
 
 	// 启动 goroutine 处理流式响应
 	go func() {
-		defer close(resultChan)
+		defer release()
 
 		// 调用 AI 图形流式接口
-		aiResponseChan, err := s.aiGraphs.Chat.Stream(ctx, input)
+		var aiResponseChan <-chan *schema.Message
+		graph, err := s.chatGraph(genCtx, turn)
+		if err == nil {
+			aiResponseChan, err = graph.Stream(genCtx, input)
+		}
 		if err != nil {
 			s.logger.Error("调用 AI 模型流式接口失败", err)
+			emit(&chat.StreamEvent{
+				Type:  chat.StreamEventError,
+				Error: "调用 AI 模型失败",
+			}, true)
 			return
 		}
 
-		// 创建 AI 响应消息
-		aiMessage := &chat.Message{
-			ID:        uuid.New().String(),
-			CanvasID:  canvasID,
-			ParentID:  &userMessage.ID,
-			Role:      chat.MessageRoleAssistant,
-			Content:   "",
-			CreatedBy: userID,
-			CreatedAt: time.Now(),
-		}
-
-		// 处理流式响应
-		var fullContent string
-		for chunk := range aiResponseChan {
-			fullContent += chunk.Content
-			
-			// 更新消息内容
-			aiMessage.Content = fullContent
-			
-			// 发送到结果通道
-			resultChan <- &chat.Message{
-				ID:        aiMessage.ID,
-				CanvasID:  aiMessage.CanvasID,
-				ParentID:  aiMessage.ParentID,
-				Role:      aiMessage.Role,
-				Content:   aiMessage.Content,
-				CreatedBy: aiMessage.CreatedBy,
-				CreatedAt: aiMessage.CreatedAt,
+		// 定期检查是否仍有客户端订阅
+		watchdog := time.NewTicker(attachTTL / 2)
+		defer watchdog.Stop()
+
+		// 处理流式响应，分片只包含新增内容
+		var fullContent strings.Builder
+		var usage *chat.TokenUsage
+		finishReason := chat.FinishReasonStop
+	loop:
+		for {
+			select {
+			case chunk, ok := <-aiResponseChan:
+				if !ok {
+					break loop
+				}
+				if err := providers.ChunkError(chunk); err != nil {
+					s.logger.Error("AI 模型流式响应失败", err)
+					finishReason = chat.FinishReasonError
+					emit(&chat.StreamEvent{
+						Type:  chat.StreamEventError,
+						Error: "AI 模型响应中断",
+					}, false)
+					break loop
+				}
+
+				for _, event := range s.chunkEvents(chunk) {
+					emit(event, false)
+				}
+				fullContent.WriteString(chunk.Content)
+				if chunk.ResponseMeta != nil {
+					if chunk.ResponseMeta.Usage != nil {
+						usage = toTokenUsage(chunk.ResponseMeta.Usage)
+					}
+					if chunk.ResponseMeta.FinishReason != "" {
+						finishReason = chunk.ResponseMeta.FinishReason
+					}
+				}
+			case <-watchdog.C:
+				if !s.buffer.Attached(saveCtx, aiMessage.CanvasID, aiMessage.ID) {
+					s.generations.Abandon(aiMessage.CanvasID, aiMessage.ID)
+				}
+			case <-genCtx.Done():
+				break loop
 			}
 		}
 
-		// 保存完整�?AI 响应消息
-		aiMessage.Content = fullContent
-		err = s.messageRepo.Create(aiMessage)
+		// 保存完整（或被取消、出错时已生成部分）的 AI 响应消息
+		if genCtx.Err() != nil {
+			finishReason = chat.FinishReasonCancelled
+		}
+		aiMessage.Content = fullContent.String()
+		aiMessage.Metadata = assistantMetadata(turn, finishReason, usage)
+		err = s.messageRepo.Create(saveCtx, aiMessage)
 		if err != nil {
 			s.logger.Error("保存 AI 响应消息失败", err)
+			emit(&chat.StreamEvent{
+				Type:  chat.StreamEventError,
+				Error: "保存 AI 响应消息失败",
+			}, true)
+			return
 		}
+
+		s.completeReply(saveCtx, userID, turn, history, aiMessage)
+
+		emit(&chat.StreamEvent{
+			Type:         chat.StreamEventDone,
+			MessageID:    aiMessage.ID,
+			FinishReason: finishReason,
+		}, true)
 	}()
 
-	return resultChan, nil
+	return events
 }
 
+// chunkEvents 将模型输出的分片转换为流式事件
+func (s *Service) chunkEvents(chunk *schema.Message) []*chat.StreamEvent {
+	var events []*chat.StreamEvent
+	if chunk.Content != "" {
+		events = append(events, &chat.StreamEvent{
+			Type:  chat.StreamEventDelta,
+			Delta: chunk.Content,
+		})
+	}
+	for _, tc := range chunk.ToolCalls {
+		delta := &chat.ToolCallDelta{
+			ID:        tc.ID,
+			Name:      tc.Function.Name,
+			Arguments: tc.Function.Arguments,
+		}
+		if tc.Index != nil {
+			delta.Index = *tc.Index
+		}
+		events = append(events, &chat.StreamEvent{
+			Type:     chat.StreamEventToolCall,
+			ToolCall: delta,
+		})
+	}
+	if chunk.ResponseMeta != nil && chunk.ResponseMeta.Usage != nil {
+		events = append(events, &chat.StreamEvent{
+			Type:  chat.StreamEventUsage,
+			Usage: toTokenUsage(chunk.ResponseMeta.Usage),
+		})
+	}
+	return events
+}
+
+// AttachGeneration 重新订阅正在进行或刚结束的生成，从 lastEventID 之后的事件开始重放
+func (s *Service) AttachGeneration(ctx context.Context, canvasID, generationID string, lastEventID int64) (<-chan GenerationEvent, error) {
+	// 校验画布可访问
+	if _, err := s.canvasRepo.GetByID(ctx, canvasID); err != nil {
+		return nil, err
+	}
+
+	exists, err := s.buffer.Exists(ctx, canvasID, generationID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrGenerationNotFound
+	}
+
+	return s.buffer.Read(ctx, canvasID, generationID, lastEventID), nil
+}
+
+// StopGeneration 停止画布上正在进行的流式生成，可在任意实例上调用
+func (s *Service) StopGeneration(ctx context.Context, canvasID, generationID string) error {
+	// 校验画布可访问
+	if _, err := s.canvasRepo.GetByID(ctx, canvasID); err != nil {
+		return err
+	}
+
+	return s.generations.Stop(ctx, canvasID, generationID)
+}
+
+// assistantMetadata 构建助手消息的元数据
+func assistantMetadata(turn *chatTurn, finishReason string, usage *chat.TokenUsage) json.RawMessage {
+	metadata, _ := json.Marshal(chat.AssistantMetadata{
+		ModelID:      turn.model.ID,
+		FinishReason: finishReason,
+		Usage:        usage,
+		CompareID:    turn.compareID,
+	})
+	return metadata
+}
+
+// toTokenUsage 转换模型返回的 token 用量
+func toTokenUsage(usage *schema.TokenUsage) *chat.TokenUsage {
+	if usage == nil {
+		return nil
+	}
+	return &chat.TokenUsage{
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		TotalTokens:      usage.TotalTokens,
+	}
+}
+
+// setActiveLeaf 更新画布当前分支，失败时仅记录日志
+func (s *Service) setActiveLeaf(ctx context.Context, canvasID, messageID string) {
+	if err := s.canvasRepo.SetActiveLeaf(ctx, canvasID, messageID); err != nil {
+		s.logger.Error("更新画布当前分支失败", err)
+	}
+}
+
+// resolveModelID 确定画布使用的模型：画布模型优先，其次为助手的默认模型，最后为租户默认模型
+func (s *Service) resolveModelID(ctx context.Context, canvas *chat.Canvas, assistant *chat.Assistant, settings *user.TenantSettings) (string, error) {
+	var modelID string
+	switch {
+	case canvas.ModelID != nil:
+		modelID = *canvas.ModelID
+	case assistant != nil && assistant.ModelID != nil:
+		modelID = *assistant.ModelID
+	case settings.DefaultModelID != nil:
+		modelID = *settings.DefaultModelID
+	default:
+		return "", ErrNoModelConfigured
+	}
+
+	if err := s.checkModelAllowed(ctx, settings, modelID); err != nil {
+		return "", err
+	}
+
+	return modelID, nil
+}
+
+// checkModelAllowed 校验模型所属提供商是否在租户允许列表中
+func (s *Service) checkModelAllowed(ctx context.Context, settings *user.TenantSettings, modelID string) error {
+	if len(settings.AllowedProviders) == 0 {
+		return nil
+	}
+
+	// 模型已被删除时按未配置模型处理，避免与画布不存在混淆
+	m, err := s.modelRepo.GetByID(ctx, modelID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNoModelConfigured
+		}
+		return err
+	}
+
+	provider, err := s.providerRepo.GetByID(ctx, m.ProviderID)
+	if err != nil {
+		return err
+	}
+
+	if !settings.IsProviderAllowed(provider.Code) {
+		return ErrProviderNotAllowed
+	}
+
+	return nil
+}
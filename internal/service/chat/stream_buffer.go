@@ -0,0 +1,195 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/chat"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/db"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/logger"
+)
+
+const (
+	// streamBufferTTL 是生成事件在 Redis 中的保留时间，每次写入时刷新
+	streamBufferTTL = 10 * time.Minute
+	// streamReadBlock 是读取事件时单次阻塞等待的时长
+	streamReadBlock = 5 * time.Second
+	// attachTTL 是订阅者心跳的有效期，超过该时间无人订阅的生成视为被放弃
+	attachTTL = 30 * time.Second
+)
+
+// ErrGenerationNotFound 表示生成不存在或事件已过期
+var ErrGenerationNotFound = errors.New("生成不存在或已过期")
+
+// GenerationEvent 表示一条带序号的生成事件，序号在同一生成内从 1 开始递增
+type GenerationEvent struct {
+	GenerationID string
+	ID           int64
+	Data         json.RawMessage
+}
+
+// StreamBuffer 表示基于 Redis Streams 的生成事件缓冲
+// 生成过程中的每条事件都写入 Redis，客户端可以从任意实例按事件序号重新订阅
+type StreamBuffer struct {
+	redis  *db.Redis
+	logger *logger.Logger
+}
+
+// NewStreamBuffer 创建一个新的生成事件缓冲
+func NewStreamBuffer(redis *db.Redis, logger *logger.Logger) *StreamBuffer {
+	return &StreamBuffer{
+		redis:  redis,
+		logger: logger,
+	}
+}
+
+// generationWriter 表示一次生成的事件写入器
+type generationWriter struct {
+	buffer *StreamBuffer
+	key    string
+	seq    int64
+}
+
+// open 打开一次生成的事件写入器，并登记初始订阅者
+func (b *StreamBuffer) open(ctx context.Context, canvasID, generationID string) *generationWriter {
+	b.touchAttached(ctx, canvasID, generationID)
+	return &generationWriter{
+		buffer: b,
+		key:    eventsKey(canvasID, generationID),
+	}
+}
+
+// Append 追加一条事件并返回其序号，final 表示这是生成的最后一条事件
+func (w *generationWriter) Append(ctx context.Context, event *chat.StreamEvent, final bool) int64 {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		w.buffer.logger.Error("序列化生成事件失败", err)
+		return 0
+	}
+
+	w.seq++
+	values := map[string]interface{}{"data": payload}
+	if final {
+		values["final"] = "1"
+	}
+
+	// 使用 0-<序号> 作为条目 ID，使 Redis 条目 ID 与 SSE 事件 ID 一一对应
+	pipe := w.buffer.redis.Client.TxPipeline()
+	pipe.XAdd(ctx, &redis.XAddArgs{
+		Stream: w.key,
+		ID:     fmt.Sprintf("0-%d", w.seq),
+		Values: values,
+	})
+	pipe.Expire(ctx, w.key, streamBufferTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		w.buffer.logger.Error("写入生成事件失败", err)
+	}
+	return w.seq
+}
+
+// Attached 判断是否仍有客户端订阅该生成
+func (b *StreamBuffer) Attached(ctx context.Context, canvasID, generationID string) bool {
+	n, err := b.redis.Client.Exists(ctx, attachedKey(canvasID, generationID)).Result()
+	if err != nil {
+		// Redis 异常时不中断生成
+		return true
+	}
+	return n > 0
+}
+
+// Exists 判断生成的事件是否仍在缓冲中
+func (b *StreamBuffer) Exists(ctx context.Context, canvasID, generationID string) (bool, error) {
+	n, err := b.redis.Client.Exists(ctx, eventsKey(canvasID, generationID)).Result()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// Read 读取序号大于 afterID 的事件，直到读到最后一条事件、缓冲过期或 ctx 取消
+// 读取期间持续刷新订阅者心跳
+func (b *StreamBuffer) Read(ctx context.Context, canvasID, generationID string, afterID int64) <-chan GenerationEvent {
+	key := eventsKey(canvasID, generationID)
+	out := make(chan GenerationEvent)
+
+	go func() {
+		defer close(out)
+
+		lastID := fmt.Sprintf("0-%d", afterID)
+		for {
+			b.touchAttached(ctx, canvasID, generationID)
+
+			streams, err := b.redis.Client.XRead(ctx, &redis.XReadArgs{
+				Streams: []string{key, lastID},
+				Count:   100,
+				Block:   streamReadBlock,
+			}).Result()
+			if errors.Is(err, redis.Nil) {
+				// 等待超时：缓冲已过期说明生成方异常退出
+				if exists, err := b.Exists(ctx, canvasID, generationID); err == nil && !exists {
+					return
+				}
+				continue
+			}
+			if err != nil {
+				if ctx.Err() == nil {
+					b.logger.Error("读取生成事件失败", err)
+				}
+				return
+			}
+
+			for _, stream := range streams {
+				for _, msg := range stream.Messages {
+					lastID = msg.ID
+
+					event := GenerationEvent{GenerationID: generationID, ID: parseEventSeq(msg.ID)}
+					if data, ok := msg.Values["data"].(string); ok {
+						event.Data = json.RawMessage(data)
+					}
+
+					select {
+					case out <- event:
+					case <-ctx.Done():
+						return
+					}
+
+					if msg.Values["final"] == "1" {
+						return
+					}
+				}
+			}
+		}
+	}()
+
+	return out
+}
+
+// touchAttached 刷新订阅者心跳
+func (b *StreamBuffer) touchAttached(ctx context.Context, canvasID, generationID string) {
+	if err := b.redis.Client.Set(ctx, attachedKey(canvasID, generationID), 1, attachTTL).Err(); err != nil && ctx.Err() == nil {
+		b.logger.Error("刷新生成订阅心跳失败", err)
+	}
+}
+
+// eventsKey 返回生成事件流的键
+func eventsKey(canvasID, generationID string) string {
+	return fmt.Sprintf("chat:generations:%s:%s:events", canvasID, generationID)
+}
+
+// attachedKey 返回生成订阅者心跳的键
+func attachedKey(canvasID, generationID string) string {
+	return fmt.Sprintf("chat:generations:%s:%s:attached", canvasID, generationID)
+}
+
+// parseEventSeq 从 Redis 条目 ID（0-<序号>）中解析事件序号
+func parseEventSeq(id string) int64 {
+	_, seq, _ := strings.Cut(id, "-")
+	n, _ := strconv.ParseInt(seq, 10, 64)
+	return n
+}
@@ -0,0 +1,126 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/chat"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/db"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/logger"
+)
+
+// newTestStreamBuffer 连接 TEST_REDIS_ADDR 指定的 Redis，未设置时跳过测试
+func newTestStreamBuffer(t *testing.T) *StreamBuffer {
+	t.Helper()
+	addr := os.Getenv("TEST_REDIS_ADDR")
+	if addr == "" {
+		t.Skip("未设置 TEST_REDIS_ADDR")
+	}
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		t.Fatalf("连接 Redis 失败: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return NewStreamBuffer(&db.Redis{Client: client}, logger.New("error"))
+}
+
+// collectEvents 读取全部事件，返回事件序号和增量内容
+func collectEvents(t *testing.T, events <-chan GenerationEvent) ([]int64, []string) {
+	t.Helper()
+	var ids []int64
+	var deltas []string
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return ids, deltas
+			}
+			var decoded chat.StreamEvent
+			if err := json.Unmarshal(event.Data, &decoded); err != nil {
+				t.Fatalf("解析事件 %d 失败: %v", event.ID, err)
+			}
+			ids = append(ids, event.ID)
+			deltas = append(deltas, decoded.Delta)
+		case <-timeout:
+			t.Fatalf("读取事件超时，已读取 %v", ids)
+		}
+	}
+}
+
+func TestStreamBufferResume(t *testing.T) {
+	buffer := newTestStreamBuffer(t)
+	ctx := context.Background()
+	canvasID := "test-canvas"
+	generationID := fmt.Sprintf("test-%d", time.Now().UnixNano())
+	t.Cleanup(func() {
+		buffer.redis.Client.Del(ctx, eventsKey(canvasID, generationID), attachedKey(canvasID, generationID))
+	})
+
+	if exists, err := buffer.Exists(ctx, canvasID, generationID); err != nil || exists {
+		t.Fatalf("Exists before open = %v, %v, want false", exists, err)
+	}
+
+	// 先订阅再写入，订阅者应按顺序收到实时事件
+	live := buffer.Read(ctx, canvasID, generationID, 0)
+	writer := buffer.open(ctx, canvasID, generationID)
+	for i, delta := range []string{"a", "b", "c"} {
+		seq := writer.Append(ctx, &chat.StreamEvent{Type: "delta", Delta: delta}, i == 2)
+		if seq != int64(i+1) {
+			t.Fatalf("Append seq = %d, want %d", seq, i+1)
+		}
+	}
+	ids, deltas := collectEvents(t, live)
+	if !reflect.DeepEqual(ids, []int64{1, 2, 3}) || !reflect.DeepEqual(deltas, []string{"a", "b", "c"}) {
+		t.Fatalf("live events = %v %q", ids, deltas)
+	}
+
+	// 按 Last-Event-ID 重新订阅时只重放之后的事件
+	tests := []struct {
+		afterID    int64
+		wantIDs    []int64
+		wantDeltas []string
+	}{
+		{0, []int64{1, 2, 3}, []string{"a", "b", "c"}},
+		{1, []int64{2, 3}, []string{"b", "c"}},
+		{2, []int64{3}, []string{"c"}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("after %d", tt.afterID), func(t *testing.T) {
+			ids, deltas := collectEvents(t, buffer.Read(ctx, canvasID, generationID, tt.afterID))
+			if !reflect.DeepEqual(ids, tt.wantIDs) || !reflect.DeepEqual(deltas, tt.wantDeltas) {
+				t.Fatalf("events = %v %q, want %v %q", ids, deltas, tt.wantIDs, tt.wantDeltas)
+			}
+		})
+	}
+
+	if !buffer.Attached(ctx, canvasID, generationID) {
+		t.Fatalf("Attached = false after reading")
+	}
+	if exists, err := buffer.Exists(ctx, canvasID, generationID); err != nil || !exists {
+		t.Fatalf("Exists = %v, %v, want true", exists, err)
+	}
+}
+
+func TestParseEventSeq(t *testing.T) {
+	tests := []struct {
+		id   string
+		want int64
+	}{
+		{"0-1", 1},
+		{"0-42", 42},
+		{"1700000000000-3", 3},
+		{"invalid", 0},
+	}
+	for _, tt := range tests {
+		if got := parseEventSeq(tt.id); got != tt.want {
+			t.Errorf("parseEventSeq(%q) = %d, want %d", tt.id, got, tt.want)
+		}
+	}
+}
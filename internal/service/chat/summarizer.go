@@ -0,0 +1,157 @@
+package chat
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/zhuiye8/Lyss-chat-server/internal/ai/graphs"
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/chat"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/config"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/logger"
+)
+
+// summarySystemPrompt 是生成对话摘要时使用的系统提示词
+const summarySystemPrompt = `你是一个对话摘要助手。请将给定的对话压缩为简洁的摘要，保留用户的目标、关键事实、已做出的决定和尚未解决的问题。
+如果提供了已有摘要，请将其与新的对话合并为一份完整的摘要。只输出摘要内容，不要添加额外说明。`
+
+// Summarizer 表示对话摘要器，将分支中较早的消息压缩为摘要
+type Summarizer struct {
+	summaryRepo chat.SummaryRepository
+	aiGraphs    *graphs.ChatGraphs
+	modelID     string
+	threshold   int
+	keepRecent  int
+	logger      *logger.Logger
+}
+
+// NewSummarizer 创建一个新的对话摘要器
+func NewSummarizer(summaryRepo chat.SummaryRepository, aiGraphs *graphs.ChatGraphs, cfg config.ChatConfig, logger *logger.Logger) *Summarizer {
+	return &Summarizer{
+		summaryRepo: summaryRepo,
+		aiGraphs:    aiGraphs,
+		modelID:     cfg.SummaryModelID,
+		threshold:   cfg.SummaryThreshold,
+		keepRecent:  cfg.SummaryKeepRecent,
+		logger:      logger,
+	}
+}
+
+// Latest 返回覆盖 branch 前缀且内容未被修改的最新摘要，以及其覆盖的消息数
+// branch 按时间正序排列，从根消息开始
+func (s *Summarizer) Latest(ctx context.Context, branch []*chat.Message) (*chat.ConversationSummary, int) {
+	if s.threshold <= 0 || len(branch) == 0 {
+		return nil, 0
+	}
+
+	summaries, err := s.summaryRepo.ListByCoveredMessageIDs(ctx, branch[0].CanvasID, messageIDs(branch))
+	if err != nil {
+		s.logger.Error("获取对话摘要失败", err)
+		return nil, 0
+	}
+
+	// 摘要按覆盖消息数从多到少排列，取第一份仍然有效的
+	for _, summary := range summaries {
+		covered := summary.MessageCount
+		if covered <= 0 || covered > len(branch) || branch[covered-1].ID != summary.CoveredMessageID {
+			continue
+		}
+		if summary.Checksum != branchChecksum(branch[:covered]) {
+			continue
+		}
+		return summary, covered
+	}
+
+	return nil, 0
+}
+
+// Refresh 在分支中未被摘要的消息超过阈值时生成新的摘要
+// 已有摘要失效（分支被编辑）时会从根消息重新生成，未配置聊天图形时跳过
+func (s *Summarizer) Refresh(ctx context.Context, branch []*chat.Message, fallbackModelID, language string) {
+	if s.threshold <= 0 {
+		return
+	}
+	if s.aiGraphs == nil {
+		s.logger.Warn("未配置聊天图形，跳过生成对话摘要")
+		return
+	}
+
+	previous, covered := s.Latest(ctx, branch)
+	if len(branch)-covered <= s.threshold {
+		return
+	}
+
+	// 保留最近的消息不摘要
+	end := len(branch) - s.keepRecent
+	if end <= covered {
+		return
+	}
+
+	modelID := s.modelID
+	if modelID == "" {
+		modelID = fallbackModelID
+	}
+
+	// 构建摘要请求
+	var transcript strings.Builder
+	if previous != nil {
+		transcript.WriteString("已有摘要：\n")
+		transcript.WriteString(previous.Content)
+		transcript.WriteString("\n\n新的对话：\n")
+	}
+	for _, msg := range branch[covered:end] {
+		fmt.Fprintf(&transcript, "%s: %s\n", msg.Role, msg.Content)
+	}
+
+	input := map[string]any{
+		"messages":      []*schema.Message{schema.UserMessage(transcript.String())},
+		"model_id":      modelID,
+		"system_prompt": summarySystemPrompt,
+		"language":      language,
+	}
+
+	// 通过聊天图形调用摘要模型
+	response, err := s.aiGraphs.Chat.Invoke(ctx, input)
+	if err != nil {
+		s.logger.Error("生成对话摘要失败", err)
+		return
+	}
+
+	summary := &chat.ConversationSummary{
+		CanvasID:         branch[0].CanvasID,
+		CoveredMessageID: branch[end-1].ID,
+		MessageCount:     end,
+		Content:          response.Content,
+		Checksum:         branchChecksum(branch[:end]),
+		ModelID:          &modelID,
+	}
+	if err := s.summaryRepo.Upsert(ctx, summary); err != nil {
+		s.logger.Error("保存对话摘要失败", err)
+	}
+}
+
+// branchChecksum 计算分支前缀的校验和，任一消息被修改时校验和随之改变
+func branchChecksum(messages []*chat.Message) string {
+	h := sha256.New()
+	for _, msg := range messages {
+		h.Write([]byte(msg.ID))
+		h.Write([]byte{0})
+		h.Write([]byte(msg.Role))
+		h.Write([]byte{0})
+		h.Write([]byte(msg.Content))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// messageIDs 返回消息 ID 列表
+func messageIDs(messages []*chat.Message) []string {
+	ids := make([]string, 0, len(messages))
+	for _, msg := range messages {
+		ids = append(ids, msg.ID)
+	}
+	return ids
+}
@@ -0,0 +1,163 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/zhuiye8/Lyss-chat-server/internal/ai/graphs"
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/chat"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/config"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/logger"
+)
+
+const (
+	// titleSystemPrompt 是生成画布标题和标签时使用的系统提示词
+	titleSystemPrompt = `你是一个对话标题助手。请根据给定的对话生成一个不超过 20 个字的简短标题，以及不超过 %d 个主题标签。
+只输出 JSON，格式为 {"title": "标题", "tags": ["标签"]}，不要添加额外说明。`
+
+	// titleMaxRunes 是标题的最大字符数
+	titleMaxRunes = 50
+	// titleMaxTagRunes 是单个标签的最大字符数
+	titleMaxTagRunes = 20
+	// titleExcerptRunes 是每条消息送入标题模型的最大字符数
+	titleExcerptRunes = 2000
+)
+
+// Titler 表示画布标题生成器，在首轮对话后生成标题和主题标签
+// 未配置聊天图形时跳过生成
+type Titler struct {
+	canvasRepo chat.CanvasRepository
+	aiGraphs   *graphs.ChatGraphs
+	enabled    bool
+	modelID    string
+	maxTags    int
+	logger     *logger.Logger
+}
+
+// NewTitler 创建一个新的画布标题生成器
+func NewTitler(canvasRepo chat.CanvasRepository, aiGraphs *graphs.ChatGraphs, cfg config.ChatConfig, logger *logger.Logger) *Titler {
+	return &Titler{
+		canvasRepo: canvasRepo,
+		aiGraphs:   aiGraphs,
+		enabled:    cfg.AutoTitle,
+		modelID:    cfg.TitleModelID,
+		maxTags:    cfg.TitleMaxTags,
+		logger:     logger,
+	}
+}
+
+// Generate 根据对话为画布生成标题和标签并保存，返回更新后的画布
+// 画布已自动命名过、生成失败或并发任务已先完成时返回 nil
+// 用户已修改过标题时只保存标签
+func (t *Titler) Generate(ctx context.Context, canvas *chat.Canvas, exchange []*chat.Message, fallbackModelID, language string) *chat.Canvas {
+	if !t.enabled || canvas.AutoTitledAt != nil {
+		return nil
+	}
+	if t.aiGraphs == nil {
+		t.logger.Warn("未配置聊天图形，跳过生成画布标题", canvas.ID)
+		return nil
+	}
+
+	modelID := t.modelID
+	if modelID == "" {
+		modelID = fallbackModelID
+	}
+
+	// 构建标题请求，只使用用户和助手消息
+	var transcript strings.Builder
+	for _, msg := range exchange {
+		if msg.Role != chat.MessageRoleUser && msg.Role != chat.MessageRoleAssistant {
+			continue
+		}
+		fmt.Fprintf(&transcript, "%s: %s\n", msg.Role, truncateRunes(msg.Content, titleExcerptRunes))
+	}
+
+	input := map[string]any{
+		"messages":      []*schema.Message{schema.UserMessage(transcript.String())},
+		"model_id":      modelID,
+		"system_prompt": fmt.Sprintf(titleSystemPrompt, t.maxTags),
+		"language":      language,
+	}
+
+	// 通过聊天图形调用标题模型
+	response, err := t.aiGraphs.Chat.Invoke(ctx, input)
+	if err != nil {
+		t.logger.Error("生成画布标题失败", err)
+		return nil
+	}
+
+	title, tags, err := parseTitle(response.Content, t.maxTags)
+	if err != nil {
+		t.logger.Warn("解析画布标题失败", err)
+		return nil
+	}
+
+	applied, err := t.canvasRepo.ApplyAutoTitle(ctx, canvas.ID, title, tags)
+	if err != nil {
+		t.logger.Error("保存画布标题失败", err)
+		return nil
+	}
+	if !applied {
+		return nil
+	}
+
+	updated, err := t.canvasRepo.GetByID(ctx, canvas.ID)
+	if err != nil {
+		t.logger.Error("获取画布失败", err)
+		return nil
+	}
+	return updated
+}
+
+// parseTitle 解析模型输出的标题 JSON
+// 模型可能在 JSON 外包裹代码块或说明文字，因此只取第一个 { 到最后一个 } 之间的内容
+func parseTitle(content string, maxTags int) (string, []string, error) {
+	start := strings.Index(content, "{")
+	end := strings.LastIndex(content, "}")
+	if start < 0 || end <= start {
+		return "", nil, errors.New("模型输出中没有 JSON")
+	}
+
+	var result struct {
+		Title string   `json:"title"`
+		Tags  []string `json:"tags"`
+	}
+	if err := json.Unmarshal([]byte(content[start:end+1]), &result); err != nil {
+		return "", nil, err
+	}
+
+	title := truncateRunes(strings.Trim(strings.TrimSpace(result.Title), `"'《》「」`), titleMaxRunes)
+	if title == "" {
+		return "", nil, errors.New("模型输出的标题为空")
+	}
+
+	// 去除空白和重复的标签
+	tags := make([]string, 0, maxTags)
+	seen := make(map[string]bool)
+	for _, tag := range result.Tags {
+		tag = truncateRunes(strings.TrimSpace(strings.TrimPrefix(tag, "#")), titleMaxTagRunes)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if len(tags) >= maxTags {
+			break
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+
+	return title, tags, nil
+}
+
+// truncateRunes 将字符串截断到最多 n 个字符
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
@@ -0,0 +1,84 @@
+package chat
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTitle(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		maxTags   int
+		wantTitle string
+		wantTags  []string
+		wantErr   bool
+	}{
+		{
+			name:      "plain json",
+			content:   `{"title": "京都旅行计划", "tags": ["旅行", "日本"]}`,
+			maxTags:   3,
+			wantTitle: "京都旅行计划",
+			wantTags:  []string{"旅行", "日本"},
+		},
+		{
+			name:      "wrapped in code block and prose",
+			content:   "好的，标题如下：\n```json\n{\"title\": \"Go 并发\", \"tags\": [\"go\"]}\n```\n希望有帮助",
+			maxTags:   3,
+			wantTitle: "Go 并发",
+			wantTags:  []string{"go"},
+		},
+		{
+			name:      "quotes and brackets trimmed",
+			content:   `{"title": " 《数据库索引》 ", "tags": []}`,
+			maxTags:   3,
+			wantTitle: "数据库索引",
+			wantTags:  []string{},
+		},
+		{
+			name:      "tags cleaned, deduplicated and limited",
+			content:   `{"title": "t", "tags": ["#a", " a ", "", "b", "c", "d"]}`,
+			maxTags:   3,
+			wantTitle: "t",
+			wantTags:  []string{"a", "b", "c"},
+		},
+		{
+			name:      "no tags allowed",
+			content:   `{"title": "t", "tags": ["a"]}`,
+			maxTags:   0,
+			wantTitle: "t",
+			wantTags:  []string{},
+		},
+		{
+			name:      "long title and tag truncated",
+			content:   `{"title": "` + strings.Repeat("标", 60) + `", "tags": ["` + strings.Repeat("x", 30) + `"]}`,
+			maxTags:   3,
+			wantTitle: strings.Repeat("标", titleMaxRunes),
+			wantTags:  []string{strings.Repeat("x", titleMaxTagRunes)},
+		},
+		{name: "no json", content: "无法生成标题", maxTags: 3, wantErr: true},
+		{name: "invalid json", content: `{"title": }`, maxTags: 3, wantErr: true},
+		{name: "empty title", content: `{"title": " 「」 ", "tags": ["a"]}`, maxTags: 3, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			title, tags, err := parseTitle(tt.content, tt.maxTags)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseTitle = %q, %q, want error", title, tags)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseTitle: %v", err)
+			}
+			if title != tt.wantTitle {
+				t.Fatalf("title = %q, want %q", title, tt.wantTitle)
+			}
+			if !reflect.DeepEqual(tags, tt.wantTags) {
+				t.Fatalf("tags = %q, want %q", tags, tt.wantTags)
+			}
+		})
+	}
+}
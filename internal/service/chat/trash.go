@@ -0,0 +1,168 @@
+package chat
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/chat"
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/user"
+	"github.com/zhuiye8/Lyss-chat-server/internal/repository/postgres"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/config"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/db"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/logger"
+)
+
+// trashPurgeBatchSize 是每批清理的过期画布数
+const trashPurgeBatchSize = 100
+
+// ErrCanvasArchived 表示画布已归档，不能修改
+var ErrCanvasArchived = errors.New("画布已归档，只读")
+
+// DeleteCanvas 将画布移入回收站，画布及其消息在保留期内可以恢复
+func (s *Service) DeleteCanvas(ctx context.Context, userID, id string) error {
+	trashed, err := s.canvasRepo.Trash(ctx, id, userID)
+	if err != nil {
+		return err
+	}
+	if !trashed {
+		return fmt.Errorf("画布不存在: %w", sql.ErrNoRows)
+	}
+
+	s.bus.Publish(ctx, &chat.CanvasEvent{
+		Type:     chat.CanvasEventCanvasDeleted,
+		CanvasID: id,
+		UserID:   userID,
+	})
+
+	return nil
+}
+
+// RestoreCanvas 将画布移出回收站
+func (s *Service) RestoreCanvas(ctx context.Context, id string) (*chat.Canvas, error) {
+	canvas, err := s.canvasRepo.Restore(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.publishCanvas(ctx, canvas)
+
+	return canvas, nil
+}
+
+// ListTrash 列出工作区回收站中的画布
+func (s *Service) ListTrash(ctx context.Context, workspaceID string, page, pageSize int) ([]*chat.Canvas, int, error) {
+	offset := (page - 1) * pageSize
+	return s.canvasRepo.ListTrash(ctx, workspaceID, offset, pageSize)
+}
+
+// getWritableCanvas 获取画布并校验其可以修改，归档的画布只读
+func (s *Service) getWritableCanvas(ctx context.Context, id string) (*chat.Canvas, error) {
+	canvas, err := s.canvasRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if canvas.Status == chat.CanvasStatusArchived {
+		return nil, ErrCanvasArchived
+	}
+	return canvas, nil
+}
+
+// TrashPurger 表示回收站清理服务
+// 彻底删除画布时先删除其在 MinIO 中的附件对象，再删除画布，消息和附件记录随画布级联删除
+type TrashPurger struct {
+	canvasRepo     chat.CanvasRepository
+	attachmentRepo chat.AttachmentRepository
+	minio          *db.MinIO
+	cfg            config.ChatConfig
+	logger         *logger.Logger
+}
+
+// NewTrashPurger 创建一个新的回收站清理服务，定期清理由 Run 执行
+func NewTrashPurger(database *db.Postgres, minio *db.MinIO, cfg config.ChatConfig, logger *logger.Logger) *TrashPurger {
+	return &TrashPurger{
+		canvasRepo:     postgres.NewCanvasRepository(database),
+		attachmentRepo: postgres.NewAttachmentRepository(database),
+		minio:          minio,
+		cfg:            cfg,
+		logger:         logger,
+	}
+}
+
+// PurgeCanvas 彻底删除回收站中的画布
+func (p *TrashPurger) PurgeCanvas(ctx context.Context, id string) error {
+	canvas, err := p.canvasRepo.GetTrashedByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	return p.purge(ctx, canvas)
+}
+
+// Run 定期清理超过保留期的回收站画布，直到 ctx 取消
+func (p *TrashPurger) Run(ctx context.Context) {
+	interval := time.Duration(p.cfg.TrashPurgeInterval) * time.Second
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// 后台任务跨租户清理画布，保留天数取自各租户的设置
+	ctx = db.WithSystem(ctx)
+	for {
+		p.purgeExpired(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// purgeExpired 分批清理过期画布，直到没有过期画布或出错
+// 单个画布清理失败时跳过，下次清理时重试
+func (p *TrashPurger) purgeExpired(ctx context.Context) {
+	for {
+		expired, err := p.canvasRepo.ListExpiredTrash(ctx, user.DefaultTrashRetentionDays, trashPurgeBatchSize)
+		if err != nil {
+			p.logger.Error("获取过期的回收站画布失败", err)
+			return
+		}
+
+		purged := 0
+		for _, canvas := range expired {
+			if err := p.purge(ctx, canvas); err != nil {
+				p.logger.Error("清理回收站画布失败", err)
+				continue
+			}
+			purged++
+		}
+		if len(expired) < trashPurgeBatchSize || purged == 0 {
+			return
+		}
+	}
+}
+
+// purge 删除画布的附件对象和画布本身
+// 附件对象删除失败时保留画布，避免留下无法追踪的对象
+func (p *TrashPurger) purge(ctx context.Context, canvas *chat.Canvas) error {
+	attachments, err := p.attachmentRepo.ListByCanvasID(ctx, canvas.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, attachment := range attachments {
+		if externalURL(attachment.URL) {
+			continue
+		}
+		err := p.minio.Client.RemoveObject(ctx, p.minio.Bucket, attachment.URL, minio.RemoveObjectOptions{})
+		if err != nil {
+			return fmt.Errorf("删除附件对象失败: %w", err)
+		}
+	}
+
+	return p.canvasRepo.Delete(ctx, canvas.ID)
+}
@@ -345,7 +345,7 @@ func (s *ModelService) ListModels(ctx context.Context, providerID *string, statu
 }
 
 // CallModel 调用模型
-func (s *ModelService) CallModel(ctx context.Context, modelID string, messages []*schema.Message) (*schema.Message, error) {
+func (s *ModelService) CallModel(ctx context.Context, modelID string, messages []*schema.Message, params map[string]interface{}) (*schema.Message, error) {
 	// 获取模型
 	model, err := s.modelRepo.GetByID(modelID)
 	if err != nil {
@@ -365,11 +365,11 @@ func (s *ModelService) CallModel(ctx context.Context, modelID string, messages [
 	}
 
 	// 调用模型
-	return provider.Call(ctx, modelID, messages, nil)
+	return provider.Call(ctx, modelID, messages, params)
 }
 
 // StreamModel 流式调用模型
-func (s *ModelService) StreamModel(ctx context.Context, modelID string, messages []*schema.Message) (<-chan *schema.Message, error) {
+func (s *ModelService) StreamModel(ctx context.Context, modelID string, messages []*schema.Message, params map[string]interface{}) (<-chan *schema.Message, error) {
 	// 获取模型
 	model, err := s.modelRepo.GetByID(modelID)
 	if err != nil {
@@ -389,6 +389,6 @@ func (s *ModelService) StreamModel(ctx context.Context, modelID string, messages
 	}
 
 	// 流式调用模型
-	return provider.Stream(ctx, modelID, messages, nil)
+	return provider.Stream(ctx, modelID, messages, params)
 }
 
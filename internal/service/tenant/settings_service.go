@@ -0,0 +1,172 @@
+package tenant
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/model"
+	"github.com/zhuiye8/Lyss-chat-server/internal/domain/user"
+	"github.com/zhuiye8/Lyss-chat-server/internal/repository/postgres"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/db"
+	"github.com/zhuiye8/Lyss-chat-server/pkg/logger"
+)
+
+// 租户设置错误
+var (
+	ErrInvalidTrashRetention = errors.New("回收站保留天数无效")
+	ErrSettingsForbidden     = errors.New("只有租户管理员可以修改租户设置")
+	ErrInvalidDefaultModel   = errors.New("默认模型不存在")
+	ErrDefaultModelForbidden = errors.New("默认模型的提供商不在允许列表中")
+)
+
+// SettingsService 表示租户设置服务
+type SettingsService struct {
+	settingsRepo user.TenantSettingsRepository
+	userRepo     user.Repository
+	modelRepo    model.ModelRepository
+	providerRepo model.ProviderRepository
+	logger       *logger.Logger
+}
+
+// NewSettingsService 创建一个新的租户设置服务
+func NewSettingsService(database *db.Postgres, logger *logger.Logger) *SettingsService {
+	return &SettingsService{
+		settingsRepo: postgres.NewTenantSettingsRepository(database),
+		userRepo:     postgres.NewUserRepository(database),
+		modelRepo:    postgres.NewModelRepository(database),
+		providerRepo: postgres.NewProviderRepository(database),
+		logger:       logger,
+	}
+}
+
+// GetSettings 获取当前租户的设置，未配置时返回默认设置
+func (s *SettingsService) GetSettings(ctx context.Context) (*user.TenantSettings, error) {
+	tenantID, ok := db.TenantFromContext(ctx)
+	if !ok {
+		return nil, db.ErrMissingTenant
+	}
+
+	settings, err := s.settingsRepo.GetByTenantID(ctx, tenantID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return user.DefaultTenantSettings(tenantID), nil
+		}
+		return nil, fmt.Errorf("获取租户设置失败: %w", err)
+	}
+
+	return settings, nil
+}
+
+// UpdateSettings 更新当前租户的设置，用户需拥有修改租户设置的权限
+func (s *SettingsService) UpdateSettings(ctx context.Context, userID string, req *user.UpdateTenantSettingsRequest) (*user.TenantSettings, error) {
+	allowed, err := s.userRepo.HasPermission(ctx, userID, user.PermissionUpdateTenantSettings)
+	if err != nil {
+		return nil, fmt.Errorf("校验用户权限失败: %w", err)
+	}
+	if !allowed {
+		return nil, ErrSettingsForbidden
+	}
+
+	// 获取现有设置
+	settings, err := s.GetSettings(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// 更新字段，空字符串表示清除
+	if req.DefaultModelID != nil {
+		settings.DefaultModelID = nilIfEmpty(*req.DefaultModelID)
+	}
+	if req.DefaultSystemPrompt != nil {
+		settings.DefaultSystemPrompt = nilIfEmpty(*req.DefaultSystemPrompt)
+	}
+	if req.CanvasGreeting != nil {
+		settings.CanvasGreeting = nilIfEmpty(*req.CanvasGreeting)
+	}
+	if req.AllowedProviders != nil {
+		providers := pq.StringArray{}
+		for _, code := range *req.AllowedProviders {
+			if code = strings.TrimSpace(code); code != "" {
+				providers = append(providers, code)
+			}
+		}
+		settings.AllowedProviders = providers
+	}
+	if req.DefaultLanguage != nil {
+		if *req.DefaultLanguage == "" {
+			settings.DefaultLanguage = user.DefaultLanguage
+		} else {
+			settings.DefaultLanguage = *req.DefaultLanguage
+		}
+	}
+
+	if req.TrashRetentionDays != nil {
+		if *req.TrashRetentionDays < 1 || *req.TrashRetentionDays > user.MaxTrashRetentionDays {
+			return nil, ErrInvalidTrashRetention
+		}
+		settings.TrashRetentionDays = *req.TrashRetentionDays
+	}
+	if req.CodeExecutionEnabled != nil {
+		settings.CodeExecutionEnabled = *req.CodeExecutionEnabled
+	}
+
+	// 默认模型或允许的提供商变化时，确认默认模型仍然可用
+	if req.DefaultModelID != nil || req.AllowedProviders != nil {
+		if err := s.checkDefaultModel(ctx, settings); err != nil {
+			return nil, err
+		}
+	}
+
+	// 保存设置
+	err = s.settingsRepo.Upsert(ctx, settings)
+	if err != nil {
+		return nil, fmt.Errorf("更新租户设置失败: %w", err)
+	}
+
+	return settings, nil
+}
+
+// checkDefaultModel 检查默认模型存在且其提供商在允许列表中
+func (s *SettingsService) checkDefaultModel(ctx context.Context, settings *user.TenantSettings) error {
+	if settings.DefaultModelID == nil {
+		return nil
+	}
+	if _, err := uuid.Parse(*settings.DefaultModelID); err != nil {
+		return ErrInvalidDefaultModel
+	}
+
+	m, err := s.modelRepo.GetByID(ctx, *settings.DefaultModelID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalidDefaultModel
+		}
+		return fmt.Errorf("获取默认模型失败: %w", err)
+	}
+
+	provider, err := s.providerRepo.GetByID(ctx, m.ProviderID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalidDefaultModel
+		}
+		return fmt.Errorf("获取模型提供商失败: %w", err)
+	}
+
+	if !settings.IsProviderAllowed(provider.Code) {
+		return ErrDefaultModelForbidden
+	}
+
+	return nil
+}
+
+// nilIfEmpty 将空字符串转换为 nil
+func nilIfEmpty(value string) *string {
+	if strings.TrimSpace(value) == "" {
+		return nil
+	}
+	return &value
+}
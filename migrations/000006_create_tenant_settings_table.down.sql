@@ -0,0 +1,2 @@
+-- 删除表
+DROP TABLE IF EXISTS tenant_settings;
@@ -0,0 +1,11 @@
+-- 创建 tenant_settings 表
+CREATE TABLE IF NOT EXISTS tenant_settings (
+    tenant_id UUID PRIMARY KEY REFERENCES tenants(id) ON DELETE CASCADE,
+    default_model_id UUID REFERENCES models(id) ON DELETE SET NULL,
+    default_system_prompt TEXT,
+    canvas_greeting TEXT,
+    allowed_providers TEXT[] NOT NULL DEFAULT '{}',
+    default_language VARCHAR(20) NOT NULL DEFAULT 'zh-CN',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
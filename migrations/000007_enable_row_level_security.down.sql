@@ -0,0 +1,51 @@
+-- 删除行级安全策略
+DROP POLICY IF EXISTS tenant_isolation ON attachments;
+DROP POLICY IF EXISTS tenant_isolation ON messages;
+DROP POLICY IF EXISTS tenant_isolation ON canvases;
+DROP POLICY IF EXISTS tenant_isolation ON tenant_settings;
+DROP POLICY IF EXISTS tenant_isolation ON user_models;
+DROP POLICY IF EXISTS tenant_isolation ON api_keys;
+DROP POLICY IF EXISTS tenant_isolation ON models;
+DROP POLICY IF EXISTS tenant_isolation ON providers;
+DROP POLICY IF EXISTS tenant_isolation ON user_roles;
+DROP POLICY IF EXISTS tenant_isolation ON roles;
+DROP POLICY IF EXISTS tenant_isolation ON users;
+DROP POLICY IF EXISTS tenant_isolation ON tenants;
+
+-- 禁用行级安全
+ALTER TABLE attachments NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE attachments DISABLE ROW LEVEL SECURITY;
+ALTER TABLE messages NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE messages DISABLE ROW LEVEL SECURITY;
+ALTER TABLE canvases NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE canvases DISABLE ROW LEVEL SECURITY;
+ALTER TABLE tenant_settings NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE tenant_settings DISABLE ROW LEVEL SECURITY;
+ALTER TABLE user_models NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE user_models DISABLE ROW LEVEL SECURITY;
+ALTER TABLE api_keys NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE api_keys DISABLE ROW LEVEL SECURITY;
+ALTER TABLE models NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE models DISABLE ROW LEVEL SECURITY;
+ALTER TABLE providers NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE providers DISABLE ROW LEVEL SECURITY;
+ALTER TABLE user_roles NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE user_roles DISABLE ROW LEVEL SECURITY;
+ALTER TABLE roles NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE roles DISABLE ROW LEVEL SECURITY;
+ALTER TABLE users NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE users DISABLE ROW LEVEL SECURITY;
+ALTER TABLE tenants NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE tenants DISABLE ROW LEVEL SECURITY;
+
+-- 删除 tenant_id 列
+DROP INDEX IF EXISTS idx_attachments_tenant_id;
+DROP INDEX IF EXISTS idx_messages_tenant_id;
+DROP INDEX IF EXISTS idx_canvases_tenant_id;
+ALTER TABLE attachments DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE messages DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE canvases DROP COLUMN IF EXISTS tenant_id;
+
+-- 删除辅助函数
+DROP FUNCTION IF EXISTS app_current_tenant();
+DROP FUNCTION IF EXISTS app_rls_bypass();
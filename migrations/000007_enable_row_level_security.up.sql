@@ -0,0 +1,99 @@
+-- 行级安全辅助函数：是否为系统上下文（后台任务等），由应用通过 set_config('app.bypass_rls', 'on', true) 设置
+CREATE OR REPLACE FUNCTION app_rls_bypass() RETURNS BOOLEAN AS $$
+    SELECT COALESCE(current_setting('app.bypass_rls', true), '') = 'on';
+$$ LANGUAGE SQL STABLE;
+
+-- 行级安全辅助函数：当前事务的租户 ID，由应用通过 set_config('app.tenant_id', $1, true) 设置
+CREATE OR REPLACE FUNCTION app_current_tenant() RETURNS UUID AS $$
+    SELECT NULLIF(current_setting('app.tenant_id', true), '')::UUID;
+$$ LANGUAGE SQL STABLE;
+
+-- 为聊天相关表添加 tenant_id 列并回填
+ALTER TABLE canvases ADD COLUMN IF NOT EXISTS tenant_id UUID REFERENCES tenants(id);
+UPDATE canvases c SET tenant_id = u.tenant_id FROM users u WHERE c.created_by = u.id AND c.tenant_id IS NULL;
+ALTER TABLE canvases ALTER COLUMN tenant_id SET NOT NULL;
+
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS tenant_id UUID REFERENCES tenants(id);
+UPDATE messages m SET tenant_id = c.tenant_id FROM canvases c WHERE m.canvas_id = c.id AND m.tenant_id IS NULL;
+ALTER TABLE messages ALTER COLUMN tenant_id SET NOT NULL;
+
+ALTER TABLE attachments ADD COLUMN IF NOT EXISTS tenant_id UUID REFERENCES tenants(id);
+UPDATE attachments a SET tenant_id = m.tenant_id FROM messages m WHERE a.message_id = m.id AND a.tenant_id IS NULL;
+ALTER TABLE attachments ALTER COLUMN tenant_id SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_canvases_tenant_id ON canvases(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_messages_tenant_id ON messages(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_attachments_tenant_id ON attachments(tenant_id);
+
+-- 启用行级安全（FORCE 使表所有者同样受策略约束）
+ALTER TABLE tenants ENABLE ROW LEVEL SECURITY;
+ALTER TABLE tenants FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON tenants
+    USING (app_rls_bypass() OR id = app_current_tenant())
+    WITH CHECK (app_rls_bypass() OR id = app_current_tenant());
+
+ALTER TABLE users ENABLE ROW LEVEL SECURITY;
+ALTER TABLE users FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON users
+    USING (app_rls_bypass() OR tenant_id = app_current_tenant())
+    WITH CHECK (app_rls_bypass() OR tenant_id = app_current_tenant());
+
+ALTER TABLE roles ENABLE ROW LEVEL SECURITY;
+ALTER TABLE roles FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON roles
+    USING (app_rls_bypass() OR tenant_id = app_current_tenant())
+    WITH CHECK (app_rls_bypass() OR tenant_id = app_current_tenant());
+
+ALTER TABLE user_roles ENABLE ROW LEVEL SECURITY;
+ALTER TABLE user_roles FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON user_roles
+    USING (app_rls_bypass() OR EXISTS (SELECT 1 FROM users u WHERE u.id = user_roles.user_id))
+    WITH CHECK (app_rls_bypass() OR EXISTS (SELECT 1 FROM users u WHERE u.id = user_roles.user_id));
+
+ALTER TABLE providers ENABLE ROW LEVEL SECURITY;
+ALTER TABLE providers FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON providers
+    USING (app_rls_bypass() OR tenant_id = app_current_tenant())
+    WITH CHECK (app_rls_bypass() OR tenant_id = app_current_tenant());
+
+ALTER TABLE models ENABLE ROW LEVEL SECURITY;
+ALTER TABLE models FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON models
+    USING (app_rls_bypass() OR EXISTS (SELECT 1 FROM providers p WHERE p.id = models.provider_id))
+    WITH CHECK (app_rls_bypass() OR EXISTS (SELECT 1 FROM providers p WHERE p.id = models.provider_id));
+
+ALTER TABLE api_keys ENABLE ROW LEVEL SECURITY;
+ALTER TABLE api_keys FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON api_keys
+    USING (app_rls_bypass() OR tenant_id = app_current_tenant())
+    WITH CHECK (app_rls_bypass() OR tenant_id = app_current_tenant());
+
+ALTER TABLE user_models ENABLE ROW LEVEL SECURITY;
+ALTER TABLE user_models FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON user_models
+    USING (app_rls_bypass() OR EXISTS (SELECT 1 FROM users u WHERE u.id = user_models.user_id))
+    WITH CHECK (app_rls_bypass() OR EXISTS (SELECT 1 FROM users u WHERE u.id = user_models.user_id));
+
+ALTER TABLE tenant_settings ENABLE ROW LEVEL SECURITY;
+ALTER TABLE tenant_settings FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON tenant_settings
+    USING (app_rls_bypass() OR tenant_id = app_current_tenant())
+    WITH CHECK (app_rls_bypass() OR tenant_id = app_current_tenant());
+
+ALTER TABLE canvases ENABLE ROW LEVEL SECURITY;
+ALTER TABLE canvases FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON canvases
+    USING (app_rls_bypass() OR tenant_id = app_current_tenant())
+    WITH CHECK (app_rls_bypass() OR tenant_id = app_current_tenant());
+
+ALTER TABLE messages ENABLE ROW LEVEL SECURITY;
+ALTER TABLE messages FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON messages
+    USING (app_rls_bypass() OR tenant_id = app_current_tenant())
+    WITH CHECK (app_rls_bypass() OR tenant_id = app_current_tenant());
+
+ALTER TABLE attachments ENABLE ROW LEVEL SECURITY;
+ALTER TABLE attachments FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON attachments
+    USING (app_rls_bypass() OR tenant_id = app_current_tenant())
+    WITH CHECK (app_rls_bypass() OR tenant_id = app_current_tenant());
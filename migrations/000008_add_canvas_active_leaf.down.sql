@@ -0,0 +1,5 @@
+-- 删除索引
+DROP INDEX IF EXISTS idx_messages_canvas_parent;
+
+-- 删除 active_leaf_id 列
+ALTER TABLE canvases DROP COLUMN IF EXISTS active_leaf_id;
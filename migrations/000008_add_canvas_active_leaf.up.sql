@@ -0,0 +1,5 @@
+-- 为 canvases 表添加当前分支叶子消息指针
+ALTER TABLE canvases ADD COLUMN IF NOT EXISTS active_leaf_id UUID REFERENCES messages(id) ON DELETE SET NULL;
+
+-- 创建索引
+CREATE INDEX IF NOT EXISTS idx_messages_canvas_parent ON messages(canvas_id, parent_id);
@@ -0,0 +1,25 @@
+-- 创建 conversation_summaries 表
+-- 摘要覆盖从根消息到 covered_message_id 的分支前缀，checksum 用于检测该前缀是否被修改
+CREATE TABLE IF NOT EXISTS conversation_summaries (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id),
+    canvas_id UUID NOT NULL REFERENCES canvases(id) ON DELETE CASCADE,
+    covered_message_id UUID NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+    message_count INTEGER NOT NULL,
+    content TEXT NOT NULL,
+    checksum VARCHAR(64) NOT NULL,
+    model_id UUID REFERENCES models(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE(covered_message_id)
+);
+
+-- 创建索引
+CREATE INDEX idx_conversation_summaries_canvas_id ON conversation_summaries(canvas_id);
+
+-- 启用行级安全
+ALTER TABLE conversation_summaries ENABLE ROW LEVEL SECURITY;
+ALTER TABLE conversation_summaries FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON conversation_summaries
+    USING (app_rls_bypass() OR tenant_id = app_current_tenant())
+    WITH CHECK (app_rls_bypass() OR tenant_id = app_current_tenant());
@@ -0,0 +1,2 @@
+-- 删除 settings 列
+ALTER TABLE canvases DROP COLUMN IF EXISTS settings;
@@ -0,0 +1,2 @@
+-- 为 canvases 表添加生成设置（系统提示词、temperature、top_p、最大输出 token、停止序列、seed）
+ALTER TABLE canvases ADD COLUMN IF NOT EXISTS settings JSONB NOT NULL DEFAULT '{}';
@@ -0,0 +1,4 @@
+-- 删除自动标题相关列
+ALTER TABLE canvases DROP COLUMN IF EXISTS auto_titled_at;
+ALTER TABLE canvases DROP COLUMN IF EXISTS topic_tags;
+ALTER TABLE canvases DROP COLUMN IF EXISTS title_customized;
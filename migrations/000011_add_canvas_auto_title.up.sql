@@ -0,0 +1,6 @@
+-- 为 canvases 表添加自动标题相关列
+-- title_customized 表示用户是否手动修改过标题，修改过的标题不会被自动标题覆盖
+-- topic_tags 为自动生成的主题标签，auto_titled_at 为自动标题任务完成的时间
+ALTER TABLE canvases ADD COLUMN IF NOT EXISTS title_customized BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE canvases ADD COLUMN IF NOT EXISTS topic_tags TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE canvases ADD COLUMN IF NOT EXISTS auto_titled_at TIMESTAMP;
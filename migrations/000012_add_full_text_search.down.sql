@@ -0,0 +1,11 @@
+-- 删除索引
+DROP INDEX IF EXISTS idx_messages_search_vector;
+DROP INDEX IF EXISTS idx_canvases_search_vector;
+
+-- 删除检索向量列
+ALTER TABLE messages DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE canvases DROP COLUMN IF EXISTS search_vector;
+
+-- 删除分词函数
+DROP FUNCTION IF EXISTS search_query(TEXT);
+DROP FUNCTION IF EXISTS search_tokens(TEXT);
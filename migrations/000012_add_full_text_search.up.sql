@@ -0,0 +1,76 @@
+-- 全文检索的分词函数
+-- 内容以中文为主，'simple' 配置无法切分 CJK 文本，因此 CJK 连续片段按重叠二元组切分，
+-- 并保留片段的最后一个字，使任意单字都是某个词元的前缀；其余部分按单词切分
+-- CJK 范围包括日文假名、中日韩统一表意文字（含扩展 A 与兼容表意文字）和韩文音节
+CREATE OR REPLACE FUNCTION search_tokens(input TEXT) RETURNS TEXT[] AS $$
+    WITH normalized AS (
+        SELECT lower(coalesce(input, '')) AS t
+    ),
+    runs AS (
+        SELECT m.n, m.match[1] AS run
+        FROM normalized,
+            regexp_matches(t, '[\u3040-\u30ff\u3400-\u4dbf\u4e00-\u9fff\uac00-\ud7af\uf900-\ufaff]+', 'g')
+                WITH ORDINALITY AS m(match, n)
+    ),
+    chars AS (
+        SELECT runs.n, c.ch, c.i
+        FROM runs, regexp_split_to_table(runs.run, '') WITH ORDINALITY AS c(ch, i)
+    )
+    SELECT array(
+        SELECT DISTINCT token FROM (
+            SELECT ch || coalesce(lead(ch) OVER (PARTITION BY n ORDER BY i), '') AS token
+            FROM chars
+            UNION ALL
+            SELECT w[1]
+            FROM normalized,
+                regexp_matches(
+                    regexp_replace(t, '[\u3040-\u30ff\u3400-\u4dbf\u4e00-\u9fff\uac00-\ud7af\uf900-\ufaff]+', ' ', 'g'),
+                    '[[:alnum:]_]+', 'g'
+                ) AS w
+            WHERE char_length(w[1]) <= 100
+        ) tokens
+    )
+$$ LANGUAGE sql IMMUTABLE PARALLEL SAFE;
+
+-- 将搜索词转换为 tsquery，与 search_tokens 使用相同的切分方式
+-- 多字 CJK 片段只使用二元组，单字与单词使用前缀匹配，各词元之间为 AND 关系
+CREATE OR REPLACE FUNCTION search_query(input TEXT) RETURNS tsquery AS $$
+    WITH normalized AS (
+        SELECT lower(coalesce(input, '')) AS t
+    ),
+    runs AS (
+        SELECT m.n, m.match[1] AS run
+        FROM normalized,
+            regexp_matches(t, '[\u3040-\u30ff\u3400-\u4dbf\u4e00-\u9fff\uac00-\ud7af\uf900-\ufaff]+', 'g')
+                WITH ORDINALITY AS m(match, n)
+    ),
+    chars AS (
+        SELECT runs.n, c.ch, c.i, char_length(runs.run) AS run_length
+        FROM runs, regexp_split_to_table(runs.run, '') WITH ORDINALITY AS c(ch, i)
+    ),
+    tokens AS (
+        SELECT ch || coalesce(lead(ch) OVER (PARTITION BY n ORDER BY i), '') AS token, run_length
+        FROM chars
+    )
+    SELECT string_agg(quote_literal(token) || ':*', ' & ')::tsquery FROM (
+        SELECT token FROM tokens WHERE char_length(token) = 2 OR run_length = 1
+        UNION
+        SELECT w[1]
+        FROM normalized,
+            regexp_matches(
+                regexp_replace(t, '[\u3040-\u30ff\u3400-\u4dbf\u4e00-\u9fff\uac00-\ud7af\uf900-\ufaff]+', ' ', 'g'),
+                '[[:alnum:]_]+', 'g'
+            ) AS w
+        WHERE char_length(w[1]) <= 100
+    ) terms
+$$ LANGUAGE sql IMMUTABLE PARALLEL SAFE;
+
+-- 为 canvases 和 messages 表添加检索向量
+ALTER TABLE canvases ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (array_to_tsvector(search_tokens(title))) STORED;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (array_to_tsvector(search_tokens(content))) STORED;
+
+-- 创建索引
+CREATE INDEX IF NOT EXISTS idx_canvases_search_vector ON canvases USING GIN(search_vector);
+CREATE INDEX IF NOT EXISTS idx_messages_search_vector ON messages USING GIN(search_vector);
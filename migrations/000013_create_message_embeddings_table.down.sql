@@ -0,0 +1,3 @@
+-- 删除 message_embeddings 表
+-- pgvector 扩展可能被其他对象使用，不在此删除
+DROP TABLE IF EXISTS message_embeddings;
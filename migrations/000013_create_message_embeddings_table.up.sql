@@ -0,0 +1,32 @@
+-- 尝试启用 pgvector 扩展，未安装时由应用在进程内计算相似度
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM pg_available_extensions WHERE name = 'vector') THEN
+        CREATE EXTENSION IF NOT EXISTS vector;
+    END IF;
+END
+$$;
+
+-- 创建 message_embeddings 表
+-- 每条消息只保留当前嵌入模型生成的向量，model 与配置不一致的向量会被重新生成
+-- 向量以 REAL[] 存储，启用 pgvector 时查询中转换为 vector 类型计算距离
+CREATE TABLE IF NOT EXISTS message_embeddings (
+    message_id UUID PRIMARY KEY REFERENCES messages(id) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL REFERENCES tenants(id),
+    canvas_id UUID NOT NULL REFERENCES canvases(id) ON DELETE CASCADE,
+    model VARCHAR(255) NOT NULL,
+    dimensions INTEGER NOT NULL,
+    embedding REAL[] NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- 创建索引
+CREATE INDEX idx_message_embeddings_canvas_model ON message_embeddings(canvas_id, model);
+CREATE INDEX idx_message_embeddings_tenant_model ON message_embeddings(tenant_id, model);
+
+-- 启用行级安全
+ALTER TABLE message_embeddings ENABLE ROW LEVEL SECURITY;
+ALTER TABLE message_embeddings FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON message_embeddings
+    USING (app_rls_bypass() OR tenant_id = app_current_tenant())
+    WITH CHECK (app_rls_bypass() OR tenant_id = app_current_tenant());
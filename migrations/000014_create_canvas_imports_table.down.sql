@@ -0,0 +1,2 @@
+-- 删除 canvas_imports 表
+DROP TABLE IF EXISTS canvas_imports;
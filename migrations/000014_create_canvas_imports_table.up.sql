@@ -0,0 +1,24 @@
+-- 创建 canvas_imports 表
+-- 记录导入的对话来源，同一工作区内来源相同的对话只导入一次
+-- external_id 为来源中的对话 ID，来源未提供 ID 时为对话内容的哈希
+CREATE TABLE IF NOT EXISTS canvas_imports (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id),
+    workspace_id UUID NOT NULL,
+    canvas_id UUID NOT NULL REFERENCES canvases(id) ON DELETE CASCADE,
+    source VARCHAR(50) NOT NULL,
+    external_id VARCHAR(255) NOT NULL,
+    created_by UUID NOT NULL REFERENCES users(id),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- 创建索引
+CREATE UNIQUE INDEX idx_canvas_imports_source ON canvas_imports(tenant_id, workspace_id, source, external_id);
+CREATE INDEX idx_canvas_imports_canvas_id ON canvas_imports(canvas_id);
+
+-- 启用行级安全
+ALTER TABLE canvas_imports ENABLE ROW LEVEL SECURITY;
+ALTER TABLE canvas_imports FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON canvas_imports
+    USING (app_rls_bypass() OR tenant_id = app_current_tenant())
+    WITH CHECK (app_rls_bypass() OR tenant_id = app_current_tenant());
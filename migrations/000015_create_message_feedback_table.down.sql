@@ -0,0 +1,2 @@
+-- 删除 message_feedback 表
+DROP TABLE IF EXISTS message_feedback;
@@ -0,0 +1,29 @@
+-- 创建 message_feedback 表
+-- 每个用户对每条助手消息只保留一条反馈，再次提交时覆盖
+-- model_id 为生成该消息的模型，冗余保存以便按模型统计
+CREATE TABLE IF NOT EXISTS message_feedback (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id),
+    message_id UUID NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+    canvas_id UUID NOT NULL REFERENCES canvases(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id),
+    model_id UUID,
+    rating VARCHAR(10) NOT NULL CHECK (rating IN ('up', 'down')),
+    reasons TEXT[] NOT NULL DEFAULT '{}',
+    comment TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- 创建索引
+CREATE UNIQUE INDEX idx_message_feedback_message_user ON message_feedback(message_id, user_id);
+CREATE INDEX idx_message_feedback_canvas_id ON message_feedback(canvas_id);
+CREATE INDEX idx_message_feedback_tenant_created ON message_feedback(tenant_id, created_at);
+CREATE INDEX idx_message_feedback_model_id ON message_feedback(model_id);
+
+-- 启用行级安全
+ALTER TABLE message_feedback ENABLE ROW LEVEL SECURITY;
+ALTER TABLE message_feedback FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON message_feedback
+    USING (app_rls_bypass() OR tenant_id = app_current_tenant())
+    WITH CHECK (app_rls_bypass() OR tenant_id = app_current_tenant());
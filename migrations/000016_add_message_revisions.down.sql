@@ -0,0 +1,9 @@
+-- 删除 message_revisions 表
+DROP TABLE IF EXISTS message_revisions;
+
+-- 删除编辑和软删除标记
+DROP INDEX IF EXISTS idx_messages_canvas_visible;
+ALTER TABLE messages DROP COLUMN IF EXISTS deleted_by;
+ALTER TABLE messages DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE messages DROP COLUMN IF EXISTS edited_by;
+ALTER TABLE messages DROP COLUMN IF EXISTS edited_at;
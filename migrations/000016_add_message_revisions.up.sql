@@ -0,0 +1,32 @@
+-- 为 messages 表添加编辑和软删除标记
+-- 软删除的消息不出现在列表、消息树和上下文中，仅保留用于审计
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS edited_at TIMESTAMP;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS edited_by UUID REFERENCES users(id);
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS deleted_by UUID REFERENCES users(id);
+
+-- 创建索引
+CREATE INDEX IF NOT EXISTS idx_messages_canvas_visible ON messages(canvas_id, created_at) WHERE deleted_at IS NULL;
+
+-- 创建 message_revisions 表
+-- 每次原地编辑前保存消息的上一版本，created_by 与 created_at 为该版本的作者和写入时间
+CREATE TABLE IF NOT EXISTS message_revisions (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id),
+    message_id UUID NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+    revision INTEGER NOT NULL,
+    content TEXT NOT NULL,
+    metadata JSONB,
+    created_by UUID NOT NULL REFERENCES users(id),
+    created_at TIMESTAMP NOT NULL
+);
+
+-- 创建索引
+CREATE UNIQUE INDEX idx_message_revisions_message_revision ON message_revisions(message_id, revision);
+
+-- 启用行级安全
+ALTER TABLE message_revisions ENABLE ROW LEVEL SECURITY;
+ALTER TABLE message_revisions FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON message_revisions
+    USING (app_rls_bypass() OR tenant_id = app_current_tenant())
+    WITH CHECK (app_rls_bypass() OR tenant_id = app_current_tenant());
@@ -0,0 +1,7 @@
+-- 删除回收站保留天数
+ALTER TABLE tenant_settings DROP COLUMN IF EXISTS trash_retention_days;
+
+-- 删除回收站标记
+DROP INDEX IF EXISTS idx_canvases_deleted_at;
+ALTER TABLE canvases DROP COLUMN IF EXISTS deleted_by;
+ALTER TABLE canvases DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,10 @@
+-- 为 canvases 表添加回收站标记
+-- 删除的画布先移入回收站，超过租户设置的保留天数后由后台任务彻底删除
+ALTER TABLE canvases ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+ALTER TABLE canvases ADD COLUMN IF NOT EXISTS deleted_by UUID REFERENCES users(id);
+
+-- 创建索引
+CREATE INDEX IF NOT EXISTS idx_canvases_deleted_at ON canvases(deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- 为 tenant_settings 表添加回收站保留天数
+ALTER TABLE tenant_settings ADD COLUMN IF NOT EXISTS trash_retention_days INTEGER NOT NULL DEFAULT 30;
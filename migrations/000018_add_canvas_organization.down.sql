@@ -0,0 +1,12 @@
+-- 删除画布的文件夹、置顶和最后消息时间
+DROP INDEX IF EXISTS idx_canvases_workspace_last_message;
+DROP INDEX IF EXISTS idx_canvases_folder_id;
+ALTER TABLE canvases DROP COLUMN IF EXISTS last_message_at;
+ALTER TABLE canvases DROP COLUMN IF EXISTS pinned_at;
+ALTER TABLE canvases DROP COLUMN IF EXISTS folder_id;
+
+-- 删除收藏、标签和文件夹
+DROP TABLE IF EXISTS canvas_favorites;
+DROP TABLE IF EXISTS canvas_tag_links;
+DROP TABLE IF EXISTS canvas_tags;
+DROP TABLE IF EXISTS canvas_folders;
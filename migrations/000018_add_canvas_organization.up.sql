@@ -0,0 +1,101 @@
+-- 创建 canvas_folders 表
+-- 文件夹属于工作区，parent_id 为空表示顶层文件夹
+CREATE TABLE IF NOT EXISTS canvas_folders (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id),
+    workspace_id UUID NOT NULL,
+    parent_id UUID REFERENCES canvas_folders(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    created_by UUID NOT NULL REFERENCES users(id),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- 创建索引
+CREATE INDEX idx_canvas_folders_workspace_id ON canvas_folders(workspace_id);
+CREATE INDEX idx_canvas_folders_parent_id ON canvas_folders(parent_id);
+
+-- 创建 canvas_tags 表
+-- 用户定义的标签，同一工作区内名称不区分大小写唯一，与自动生成的 topic_tags 相互独立
+CREATE TABLE IF NOT EXISTS canvas_tags (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id),
+    workspace_id UUID NOT NULL,
+    name VARCHAR(50) NOT NULL,
+    color VARCHAR(20),
+    created_by UUID NOT NULL REFERENCES users(id),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- 创建索引
+CREATE UNIQUE INDEX idx_canvas_tags_workspace_name ON canvas_tags(workspace_id, LOWER(name));
+
+-- 创建 canvas_tag_links 表
+CREATE TABLE IF NOT EXISTS canvas_tag_links (
+    canvas_id UUID NOT NULL REFERENCES canvases(id) ON DELETE CASCADE,
+    tag_id UUID NOT NULL REFERENCES canvas_tags(id) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL REFERENCES tenants(id),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (canvas_id, tag_id)
+);
+
+-- 创建索引
+CREATE INDEX idx_canvas_tag_links_tag_id ON canvas_tag_links(tag_id);
+
+-- 创建 canvas_favorites 表
+-- 收藏是用户个人的标记，置顶对工作区内所有成员生效
+CREATE TABLE IF NOT EXISTS canvas_favorites (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    canvas_id UUID NOT NULL REFERENCES canvases(id) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL REFERENCES tenants(id),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, canvas_id)
+);
+
+-- 创建索引
+CREATE INDEX idx_canvas_favorites_canvas_id ON canvas_favorites(canvas_id);
+
+-- 为 canvases 表添加文件夹、置顶和最后消息时间
+ALTER TABLE canvases ADD COLUMN IF NOT EXISTS folder_id UUID REFERENCES canvas_folders(id) ON DELETE SET NULL;
+ALTER TABLE canvases ADD COLUMN IF NOT EXISTS pinned_at TIMESTAMP;
+ALTER TABLE canvases ADD COLUMN IF NOT EXISTS last_message_at TIMESTAMP;
+
+-- 回填最后消息时间
+UPDATE canvases c
+SET last_message_at = m.last_message_at
+FROM (
+    SELECT canvas_id, MAX(created_at) AS last_message_at
+    FROM messages
+    WHERE deleted_at IS NULL
+    GROUP BY canvas_id
+) m
+WHERE m.canvas_id = c.id;
+
+-- 创建索引
+CREATE INDEX IF NOT EXISTS idx_canvases_folder_id ON canvases(folder_id);
+CREATE INDEX IF NOT EXISTS idx_canvases_workspace_last_message ON canvases(workspace_id, last_message_at DESC);
+
+-- 启用行级安全
+ALTER TABLE canvas_folders ENABLE ROW LEVEL SECURITY;
+ALTER TABLE canvas_folders FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON canvas_folders
+    USING (app_rls_bypass() OR tenant_id = app_current_tenant())
+    WITH CHECK (app_rls_bypass() OR tenant_id = app_current_tenant());
+
+ALTER TABLE canvas_tags ENABLE ROW LEVEL SECURITY;
+ALTER TABLE canvas_tags FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON canvas_tags
+    USING (app_rls_bypass() OR tenant_id = app_current_tenant())
+    WITH CHECK (app_rls_bypass() OR tenant_id = app_current_tenant());
+
+ALTER TABLE canvas_tag_links ENABLE ROW LEVEL SECURITY;
+ALTER TABLE canvas_tag_links FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON canvas_tag_links
+    USING (app_rls_bypass() OR tenant_id = app_current_tenant())
+    WITH CHECK (app_rls_bypass() OR tenant_id = app_current_tenant());
+
+ALTER TABLE canvas_favorites ENABLE ROW LEVEL SECURITY;
+ALTER TABLE canvas_favorites FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON canvas_favorites
+    USING (app_rls_bypass() OR tenant_id = app_current_tenant())
+    WITH CHECK (app_rls_bypass() OR tenant_id = app_current_tenant());
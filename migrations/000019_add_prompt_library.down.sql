@@ -0,0 +1,4 @@
+-- 删除提示词库
+DROP TABLE IF EXISTS prompt_template_shares;
+DROP TABLE IF EXISTS prompt_template_versions;
+DROP TABLE IF EXISTS prompt_templates;
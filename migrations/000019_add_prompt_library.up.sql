@@ -0,0 +1,71 @@
+-- 创建 prompt_templates 表
+-- scope 决定可见范围：user 仅创建者及被分享的用户可见，workspace 对工作区可见，tenant 对整个租户可见
+CREATE TABLE IF NOT EXISTS prompt_templates (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id),
+    scope VARCHAR(20) NOT NULL DEFAULT 'user',
+    workspace_id UUID,
+    title VARCHAR(255) NOT NULL,
+    description TEXT,
+    content TEXT NOT NULL,
+    role VARCHAR(20) NOT NULL DEFAULT 'user',
+    variables JSONB NOT NULL DEFAULT '[]',
+    version INTEGER NOT NULL DEFAULT 1,
+    usage_count INTEGER NOT NULL DEFAULT 0,
+    created_by UUID NOT NULL REFERENCES users(id),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT chk_prompt_templates_scope CHECK (scope IN ('user', 'workspace', 'tenant')),
+    CONSTRAINT chk_prompt_templates_workspace CHECK (scope <> 'workspace' OR workspace_id IS NOT NULL)
+);
+
+-- 创建索引
+CREATE INDEX idx_prompt_templates_created_by ON prompt_templates(created_by);
+CREATE INDEX idx_prompt_templates_workspace_id ON prompt_templates(workspace_id);
+CREATE INDEX idx_prompt_templates_scope ON prompt_templates(tenant_id, scope);
+
+-- 创建 prompt_template_versions 表
+-- 每次修改内容或变量都会保存一个新版本，包括当前版本
+CREATE TABLE IF NOT EXISTS prompt_template_versions (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id),
+    template_id UUID NOT NULL REFERENCES prompt_templates(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    content TEXT NOT NULL,
+    variables JSONB NOT NULL DEFAULT '[]',
+    created_by UUID NOT NULL REFERENCES users(id),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (template_id, version)
+);
+
+-- 创建 prompt_template_shares 表
+CREATE TABLE IF NOT EXISTS prompt_template_shares (
+    template_id UUID NOT NULL REFERENCES prompt_templates(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL REFERENCES tenants(id),
+    created_by UUID NOT NULL REFERENCES users(id),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (template_id, user_id)
+);
+
+-- 创建索引
+CREATE INDEX idx_prompt_template_shares_user_id ON prompt_template_shares(user_id);
+
+-- 启用行级安全
+ALTER TABLE prompt_templates ENABLE ROW LEVEL SECURITY;
+ALTER TABLE prompt_templates FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON prompt_templates
+    USING (app_rls_bypass() OR tenant_id = app_current_tenant())
+    WITH CHECK (app_rls_bypass() OR tenant_id = app_current_tenant());
+
+ALTER TABLE prompt_template_versions ENABLE ROW LEVEL SECURITY;
+ALTER TABLE prompt_template_versions FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON prompt_template_versions
+    USING (app_rls_bypass() OR tenant_id = app_current_tenant())
+    WITH CHECK (app_rls_bypass() OR tenant_id = app_current_tenant());
+
+ALTER TABLE prompt_template_shares ENABLE ROW LEVEL SECURITY;
+ALTER TABLE prompt_template_shares FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON prompt_template_shares
+    USING (app_rls_bypass() OR tenant_id = app_current_tenant())
+    WITH CHECK (app_rls_bypass() OR tenant_id = app_current_tenant());
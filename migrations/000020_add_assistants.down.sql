@@ -0,0 +1,6 @@
+-- 删除画布的助手
+DROP INDEX IF EXISTS idx_canvases_assistant_id;
+ALTER TABLE canvases DROP COLUMN IF EXISTS assistant_id;
+
+-- 删除助手
+DROP TABLE IF EXISTS assistants;
@@ -0,0 +1,38 @@
+-- 创建 assistants 表
+-- 助手打包系统提示词、默认模型与参数、启用的工具和关联的知识库
+-- scope 决定可见范围：user 仅创建者可见，发布到 workspace 或 tenant 后对工作区或整个租户可见
+CREATE TABLE IF NOT EXISTS assistants (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id),
+    scope VARCHAR(20) NOT NULL DEFAULT 'user',
+    workspace_id UUID,
+    name VARCHAR(100) NOT NULL,
+    description TEXT,
+    avatar VARCHAR(500),
+    system_prompt TEXT,
+    model_id UUID REFERENCES models(id) ON DELETE SET NULL,
+    settings JSONB NOT NULL DEFAULT '{}',
+    tools JSONB NOT NULL DEFAULT '[]',
+    knowledge_base_ids UUID[] NOT NULL DEFAULT '{}',
+    created_by UUID NOT NULL REFERENCES users(id),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT chk_assistants_scope CHECK (scope IN ('user', 'workspace', 'tenant')),
+    CONSTRAINT chk_assistants_workspace CHECK (scope <> 'workspace' OR workspace_id IS NOT NULL)
+);
+
+-- 创建索引
+CREATE INDEX idx_assistants_created_by ON assistants(created_by);
+CREATE INDEX idx_assistants_workspace_id ON assistants(workspace_id);
+CREATE INDEX idx_assistants_scope ON assistants(tenant_id, scope);
+
+-- 为 canvases 表添加助手，删除助手后画布回退为普通画布
+ALTER TABLE canvases ADD COLUMN IF NOT EXISTS assistant_id UUID REFERENCES assistants(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_canvases_assistant_id ON canvases(assistant_id);
+
+-- 启用行级安全
+ALTER TABLE assistants ENABLE ROW LEVEL SECURITY;
+ALTER TABLE assistants FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON assistants
+    USING (app_rls_bypass() OR tenant_id = app_current_tenant())
+    WITH CHECK (app_rls_bypass() OR tenant_id = app_current_tenant());
@@ -0,0 +1,5 @@
+-- 删除 canvas_artifact_versions 表
+DROP TABLE IF EXISTS canvas_artifact_versions;
+
+-- 删除 canvas_artifacts 表
+DROP TABLE IF EXISTS canvas_artifacts;
@@ -0,0 +1,46 @@
+-- 创建 canvas_artifacts 表
+-- 代码画布中助手回复的围栏代码块按名称提取为产物（通常为文件），同一画布内名称唯一
+-- current_version 为最新版本号，内容保存在 canvas_artifact_versions 中
+CREATE TABLE IF NOT EXISTS canvas_artifacts (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id),
+    canvas_id UUID NOT NULL REFERENCES canvases(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    language VARCHAR(50),
+    current_version INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- 创建 canvas_artifact_versions 表
+-- message_id 为产生该版本的助手消息，回退产生的版本 reverted_from 为回退到的版本号
+CREATE TABLE IF NOT EXISTS canvas_artifact_versions (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id),
+    artifact_id UUID NOT NULL REFERENCES canvas_artifacts(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    language VARCHAR(50),
+    content TEXT NOT NULL,
+    message_id UUID REFERENCES messages(id) ON DELETE SET NULL,
+    reverted_from INTEGER,
+    created_by UUID NOT NULL REFERENCES users(id),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- 创建索引
+CREATE UNIQUE INDEX idx_canvas_artifacts_canvas_name ON canvas_artifacts(canvas_id, name);
+CREATE UNIQUE INDEX idx_canvas_artifact_versions_artifact_version ON canvas_artifact_versions(artifact_id, version);
+CREATE INDEX idx_canvas_artifact_versions_message_id ON canvas_artifact_versions(message_id);
+
+-- 启用行级安全
+ALTER TABLE canvas_artifacts ENABLE ROW LEVEL SECURITY;
+ALTER TABLE canvas_artifacts FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON canvas_artifacts
+    USING (app_rls_bypass() OR tenant_id = app_current_tenant())
+    WITH CHECK (app_rls_bypass() OR tenant_id = app_current_tenant());
+
+ALTER TABLE canvas_artifact_versions ENABLE ROW LEVEL SECURITY;
+ALTER TABLE canvas_artifact_versions FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON canvas_artifact_versions
+    USING (app_rls_bypass() OR tenant_id = app_current_tenant())
+    WITH CHECK (app_rls_bypass() OR tenant_id = app_current_tenant());
@@ -0,0 +1,2 @@
+-- 删除代码执行开关
+ALTER TABLE tenant_settings DROP COLUMN IF EXISTS code_execution_enabled;
@@ -0,0 +1,4 @@
+-- 为 tenant_settings 表添加代码执行开关
+-- 开启后租户可以在沙箱中执行代码画布中助手回复的代码块，默认关闭
+-- 执行结果保存在消息元数据的 executions 中
+ALTER TABLE tenant_settings ADD COLUMN IF NOT EXISTS code_execution_enabled BOOLEAN NOT NULL DEFAULT FALSE;
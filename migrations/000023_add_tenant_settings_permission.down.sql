@@ -0,0 +1,2 @@
+-- 删除修改租户设置的权限，角色授权随之级联删除
+DELETE FROM permissions WHERE code = 'tenant_settings:update';
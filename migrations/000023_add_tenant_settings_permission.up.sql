@@ -0,0 +1,19 @@
+-- 迁移需要为所有租户的管理员角色授权，在本事务内绕过行级安全
+SELECT set_config('app.bypass_rls', 'on', true);
+
+-- 插入修改租户设置的权限
+INSERT INTO permissions (id, code, name, description, resource, action, created_at, updated_at)
+VALUES
+    ('20000000-0000-0000-0000-000000000017', 'tenant_settings:update', '更新租户设置', '允许修改租户设置', 'tenant_settings', 'update', NOW(), NOW())
+ON CONFLICT DO NOTHING;
+
+-- 为各租户的系统管理员角色分配该权限
+INSERT INTO role_permissions (id, role_id, permission_id, created_at)
+SELECT
+    md5(random()::text || clock_timestamp()::text || r.id::text)::uuid,
+    r.id,
+    '20000000-0000-0000-0000-000000000017',
+    NOW()
+FROM roles r
+WHERE r.is_system AND r.name = 'Admin'
+ON CONFLICT DO NOTHING;
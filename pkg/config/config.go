@@ -10,12 +10,17 @@ import (
 
 // Config 表示应用程序配置
 type Config struct {
-	LogLevel string        `json:"log_level"`
-	Server   ServerConfig  `json:"server"`
-	Database DatabaseConfig `json:"database"`
-	Redis    RedisConfig   `json:"redis"`
-	MinIO    MinIOConfig   `json:"minio"`
-	JWT      JWTConfig     `json:"jwt"`
+	LogLevel  string          `json:"log_level"`
+	Server    ServerConfig    `json:"server"`
+	Database  DatabaseConfig  `json:"database"`
+	Redis     RedisConfig     `json:"redis"`
+	MinIO     MinIOConfig     `json:"minio"`
+	JWT       JWTConfig       `json:"jwt"`
+	Chat      ChatConfig      `json:"chat"`
+	WebSocket WebSocketConfig `json:"websocket"`
+	Models    ModelsConfig    `json:"models"`
+	Embedding EmbeddingConfig `json:"embedding"`
+	Sandbox   SandboxConfig   `json:"sandbox"`
 }
 
 // ServerConfig 表示服务器配置
@@ -60,6 +65,99 @@ type JWTConfig struct {
 	RefreshExpirationHours int `json:"refresh_expiration_hours"`
 }
 
+// ChatConfig 表示对话上下文配置
+type ChatConfig struct {
+	// ContextStrategy 上下文超出预算时的处理策略：drop_oldest 丢弃最早的消息，truncate_oldest 截断最早的消息
+	ContextStrategy      string `json:"context_strategy"`
+	DefaultContextWindow int    `json:"default_context_window"`
+	ReservedOutputTokens int    `json:"reserved_output_tokens"`
+	// SummaryModelID 生成对话摘要使用的模型，为空时使用当前对话的模型
+	SummaryModelID string `json:"summary_model_id"`
+	// SummaryThreshold 未被摘要的消息数超过该值时触发摘要，0 表示关闭
+	SummaryThreshold  int `json:"summary_threshold"`
+	SummaryKeepRecent int `json:"summary_keep_recent"`
+	// AutoTitle 是否在首轮对话后自动生成画布标题和主题标签
+	AutoTitle bool `json:"auto_title"`
+	// TitleModelID 生成标题使用的轻量模型，为空时使用当前对话的模型
+	TitleModelID string `json:"title_model_id"`
+	TitleMaxTags int    `json:"title_max_tags"`
+	// TrashPurgeInterval 后台清理回收站中过期画布的间隔（秒）
+	TrashPurgeInterval int `json:"trash_purge_interval"`
+}
+
+// WebSocketConfig 表示 WebSocket 连接配置
+type WebSocketConfig struct {
+	// MaxFrameBytes 客户端单帧的最大字节数
+	MaxFrameBytes int64 `json:"max_frame_bytes"`
+	// FramesPerSecond 和 FrameBurst 限制每个连接的客户端帧速率
+	FramesPerSecond float64 `json:"frames_per_second"`
+	FrameBurst      int     `json:"frame_burst"`
+	// MaxConcurrentGenerations 每个连接同时进行的生成数
+	MaxConcurrentGenerations int `json:"max_concurrent_generations"`
+	// PingInterval 服务器发送心跳的间隔（秒）
+	PingInterval int `json:"ping_interval"`
+	// AllowedOrigins 允许建立连接的来源，为空时只允许与服务同源的请求
+	AllowedOrigins []string `json:"allowed_origins"`
+}
+
+// ModelsConfig 表示模型调用配置
+type ModelsConfig struct {
+	// APIKeys 按提供商代码（如 openai）配置的 API 密钥，调用模型时按模型所属提供商的代码选用
+	APIKeys map[string]string `json:"api_keys"`
+}
+
+// EmbeddingConfig 表示消息向量化与语义搜索配置
+type EmbeddingConfig struct {
+	Enabled bool `json:"enabled"`
+	// Provider 和 APIKey 用于创建支持向量化的模型提供商
+	Provider string `json:"provider"`
+	APIKey   string `json:"api_key"`
+	// ModelID 嵌入模型，更换后已有消息会在后台重新向量化
+	ModelID string `json:"model_id"`
+	// Backend 向量检索方式：auto 在安装了 pgvector 时使用 pgvector，否则在进程内计算；pgvector；memory
+	Backend string `json:"backend"`
+	// BatchSize 每次向量化的消息数
+	BatchSize int `json:"batch_size"`
+	// SweepInterval 后台补全缺失向量的间隔（秒）
+	SweepInterval int `json:"sweep_interval"`
+	// MinScore 语义搜索结果的最低余弦相似度
+	MinScore float64 `json:"min_score"`
+	// MaxCandidates 未使用 pgvector 时，进程内检索只在最近的这些消息中计算相似度
+	MaxCandidates int `json:"max_candidates"`
+}
+
+// SandboxConfig 表示代码执行沙箱配置
+// 每次执行在新的挂载、PID、网络、IPC 和 UTS 命名空间中运行，根文件系统切换为只读的最小目录树，
+// 并以专用的无特权用户身份运行；服务需以 root 运行才能建立隔离，无法建立时拒绝执行
+// 租户还需在设置中开启代码执行
+type SandboxConfig struct {
+	Enabled bool `json:"enabled"`
+	// Python、Node 和 Go 为各语言解释器或工具链的路径，解析后须位于沙箱根文件系统中
+	Python string `json:"python"`
+	Node   string `json:"node"`
+	Go     string `json:"go"`
+	// WorkDir 每次执行创建临时目录的父目录，为空时使用系统临时目录
+	WorkDir string `json:"work_dir"`
+	// Mounts 除 /usr 等系统目录外，以只读方式挂载到沙箱中的宿主机路径，例如安装在 /opt 下的解释器
+	Mounts []string `json:"mounts"`
+	// UID 为沙箱进程的起始用户 ID，并发的执行分别使用 UID 到 UID+MaxConcurrent-1，GID 为沙箱进程的组 ID
+	// 这些 ID 应专用于沙箱，不能被宿主机上的其他进程使用
+	UID int `json:"uid"`
+	GID int `json:"gid"`
+	// Timeout 单次执行的最长时间（秒），CPUTime 为 CPU 时间上限（秒），多线程时按各线程合计
+	// Go 代码每次在空的构建缓存中编译，需要较长的时间
+	Timeout int `json:"timeout"`
+	CPUTime int `json:"cpu_time"`
+	// MemoryMB 进程数据段的上限（MB）
+	MemoryMB int `json:"memory_mb"`
+	// MaxProcesses 单次执行可同时存在的进程和线程数上限
+	MaxProcesses int `json:"max_processes"`
+	// MaxOutputBytes 标准输出和标准错误合计的最大字节数，超出时终止执行
+	MaxOutputBytes int `json:"max_output_bytes"`
+	// MaxConcurrent 同时进行的执行数
+	MaxConcurrent int `json:"max_concurrent"`
+}
+
 // Load 从配置文件加载配置
 func Load() (*Config, error) {
 	// 默认配置
@@ -97,6 +195,45 @@ func Load() (*Config, error) {
 			ExpirationHours:  24,
 			RefreshExpirationHours: 168,
 		},
+		Chat: ChatConfig{
+			ContextStrategy:      "drop_oldest",
+			DefaultContextWindow: 8192,
+			ReservedOutputTokens: 1024,
+			SummaryThreshold:     20,
+			SummaryKeepRecent:    8,
+			AutoTitle:            true,
+			TitleMaxTags:         3,
+			TrashPurgeInterval:   3600,
+		},
+		WebSocket: WebSocketConfig{
+			MaxFrameBytes:            64 * 1024,
+			FramesPerSecond:          5,
+			FrameBurst:               20,
+			MaxConcurrentGenerations: 4,
+			PingInterval:             30,
+		},
+		Embedding: EmbeddingConfig{
+			Provider:      "openai",
+			ModelID:       "text-embedding-3-small",
+			Backend:       "auto",
+			BatchSize:     64,
+			SweepInterval: 60,
+			MinScore:      0.3,
+			MaxCandidates: 5000,
+		},
+		Sandbox: SandboxConfig{
+			Python:         "python3",
+			Node:           "node",
+			Go:             "go",
+			UID:            60000,
+			GID:            60000,
+			Timeout:        30,
+			CPUTime:        60,
+			MemoryMB:       512,
+			MaxProcesses:   128,
+			MaxOutputBytes: 64 * 1024,
+			MaxConcurrent:  4,
+		},
 	}
 
 	// 尝试从配置文件加载
@@ -224,4 +361,83 @@ func loadFromEnv(config *Config) {
 			config.JWT.RefreshExpirationHours = e
 		}
 	}
+
+	// 对话配置
+	if strategy := os.Getenv("CHAT_CONTEXT_STRATEGY"); strategy != "" {
+		config.Chat.ContextStrategy = strategy
+	}
+	if window := os.Getenv("CHAT_DEFAULT_CONTEXT_WINDOW"); window != "" {
+		var w int
+		if _, err := fmt.Sscanf(window, "%d", &w); err == nil {
+			config.Chat.DefaultContextWindow = w
+		}
+	}
+	if reserved := os.Getenv("CHAT_RESERVED_OUTPUT_TOKENS"); reserved != "" {
+		var r int
+		if _, err := fmt.Sscanf(reserved, "%d", &r); err == nil {
+			config.Chat.ReservedOutputTokens = r
+		}
+	}
+	if summaryModel := os.Getenv("CHAT_SUMMARY_MODEL_ID"); summaryModel != "" {
+		config.Chat.SummaryModelID = summaryModel
+	}
+	if threshold := os.Getenv("CHAT_SUMMARY_THRESHOLD"); threshold != "" {
+		var t int
+		if _, err := fmt.Sscanf(threshold, "%d", &t); err == nil {
+			config.Chat.SummaryThreshold = t
+		}
+	}
+	if autoTitle := os.Getenv("CHAT_AUTO_TITLE"); autoTitle != "" {
+		config.Chat.AutoTitle = strings.ToLower(autoTitle) == "true"
+	}
+	if titleModel := os.Getenv("CHAT_TITLE_MODEL_ID"); titleModel != "" {
+		config.Chat.TitleModelID = titleModel
+	}
+
+	// WebSocket 配置
+	if rate := os.Getenv("WS_FRAMES_PER_SECOND"); rate != "" {
+		var r float64
+		if _, err := fmt.Sscanf(rate, "%g", &r); err == nil {
+			config.WebSocket.FramesPerSecond = r
+		}
+	}
+	if maxGenerations := os.Getenv("WS_MAX_CONCURRENT_GENERATIONS"); maxGenerations != "" {
+		var m int
+		if _, err := fmt.Sscanf(maxGenerations, "%d", &m); err == nil {
+			config.WebSocket.MaxConcurrentGenerations = m
+		}
+	}
+	if origins := os.Getenv("WS_ALLOWED_ORIGINS"); origins != "" {
+		config.WebSocket.AllowedOrigins = strings.Split(origins, ",")
+	}
+
+	// 模型调用配置
+	if apiKey := os.Getenv("OPENAI_API_KEY"); apiKey != "" {
+		if config.Models.APIKeys == nil {
+			config.Models.APIKeys = make(map[string]string)
+		}
+		config.Models.APIKeys["openai"] = apiKey
+	}
+
+	// 向量化配置
+	if enabled := os.Getenv("EMBEDDING_ENABLED"); enabled != "" {
+		config.Embedding.Enabled = strings.ToLower(enabled) == "true"
+	}
+	if apiKey := os.Getenv("EMBEDDING_API_KEY"); apiKey != "" {
+		config.Embedding.APIKey = apiKey
+	}
+	if modelID := os.Getenv("EMBEDDING_MODEL_ID"); modelID != "" {
+		config.Embedding.ModelID = modelID
+	}
+	if backend := os.Getenv("EMBEDDING_BACKEND"); backend != "" {
+		config.Embedding.Backend = backend
+	}
+
+	// 代码执行沙箱配置
+	if enabled := os.Getenv("SANDBOX_ENABLED"); enabled != "" {
+		config.Sandbox.Enabled = strings.ToLower(enabled) == "true"
+	}
+	if workDir := os.Getenv("SANDBOX_WORK_DIR"); workDir != "" {
+		config.Sandbox.WorkDir = workDir
+	}
 }
@@ -0,0 +1,70 @@
+package db
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// contextKey 是数据库上下文键类型
+type contextKey string
+
+const (
+	tenantContextKey contextKey = "db_tenant_id"
+	systemContextKey contextKey = "db_system"
+)
+
+// ErrMissingTenant 表示上下文中既没有租户也没有系统标记
+var ErrMissingTenant = errors.New("上下文中缺少租户信息")
+
+// WithTenant 返回携带租户 ID 的上下文，仓库据此启用行级安全策略
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantContextKey, tenantID)
+}
+
+// WithSystem 返回绕过行级安全策略的上下文，仅供后台任务等系统操作使用
+func WithSystem(ctx context.Context) context.Context {
+	return context.WithValue(ctx, systemContextKey, true)
+}
+
+// TenantFromContext 从上下文获取租户 ID
+func TenantFromContext(ctx context.Context) (string, bool) {
+	tenantID, ok := ctx.Value(tenantContextKey).(string)
+	return tenantID, ok && tenantID != ""
+}
+
+// isSystem 判断上下文是否为系统上下文
+func isSystem(ctx context.Context) bool {
+	system, _ := ctx.Value(systemContextKey).(bool)
+	return system
+}
+
+// InTx 在事务中执行 fn，并通过 SET LOCAL 语义设置当前租户
+// 设置仅在事务内生效，连接归还连接池后不会泄漏到其他请求
+func (p *Postgres) InTx(ctx context.Context, fn func(tx *sqlx.Tx) error) error {
+	tx, err := p.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if isSystem(ctx) {
+		_, err = tx.ExecContext(ctx, `SELECT set_config('app.bypass_rls', 'on', true)`)
+	} else {
+		tenantID, ok := TenantFromContext(ctx)
+		if !ok {
+			return ErrMissingTenant
+		}
+		_, err = tx.ExecContext(ctx, `SELECT set_config('app.tenant_id', $1, true)`, tenantID)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
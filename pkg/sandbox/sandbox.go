@@ -0,0 +1,411 @@
+// Package sandbox 在隔离的本地沙箱中执行代码片段
+// 每次执行启动新进程：无网络（新的网络命名空间），独立的 PID、IPC、UTS 和私有挂载命名空间，
+// 根文件系统通过 pivot_root 切换为只读挂载系统目录的最小目录树，并挂载新的 /proc，
+// 进程以专用的无特权用户身份运行，并限制 CPU 时间、内存、文件大小、进程数和运行时间
+// 隔离由服务自身的可执行文件以初始化模式完成，服务的 main 需首先调用 Init；无法建立隔离时拒绝执行
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/zhuiye8/Lyss-chat-server/pkg/config"
+)
+
+// 支持的语言
+const (
+	LanguagePython     = "python"
+	LanguageJavaScript = "javascript"
+	LanguageGo         = "go"
+)
+
+// 输出流名称
+const (
+	StreamStdout = "stdout"
+	StreamStderr = "stderr"
+)
+
+// 沙箱错误
+var (
+	ErrDisabled            = errors.New("代码执行未启用")
+	ErrUnsupportedLanguage = errors.New("不支持的语言")
+	ErrUnsupportedPlatform = errors.New("当前平台不支持沙箱执行")
+	ErrIsolationFailed     = errors.New("无法建立沙箱隔离")
+)
+
+const (
+	// readBufferSize 为读取输出的缓冲大小，每次读取的内容作为一个输出分片
+	readBufferSize = 4096
+	// maxFileSize 为进程可写入的单个文件大小上限（字节），需容纳 Go 编译产物
+	maxFileSize = 64 << 20
+	// maxOpenFiles 为进程可打开的文件数上限
+	maxOpenFiles = 256
+	// defaultTimeout 为未配置时单次执行的最长时间（秒）
+	defaultTimeout = 10
+	// defaultMemoryMB 为未配置时进程数据段的上限（MB）
+	defaultMemoryMB = 512
+	// defaultMaxProcesses 为未配置时单次执行的进程和线程数上限
+	defaultMaxProcesses = 128
+	// workDir 为沙箱中代码所在的可写目录
+	workDir = "/work"
+)
+
+// languageAliases 将代码块的语言标记映射为支持的语言
+var languageAliases = map[string]string{
+	"python":     LanguagePython,
+	"python3":    LanguagePython,
+	"py":         LanguagePython,
+	"javascript": LanguageJavaScript,
+	"js":         LanguageJavaScript,
+	"node":       LanguageJavaScript,
+	"go":         LanguageGo,
+	"golang":     LanguageGo,
+}
+
+// NormalizeLanguage 返回语言标记对应的支持语言，不支持时返回空字符串
+func NormalizeLanguage(language string) string {
+	return languageAliases[strings.ToLower(strings.TrimSpace(language))]
+}
+
+// Result 表示一次执行的结果
+type Result struct {
+	ExitCode  int
+	Stdout    string
+	Stderr    string
+	TimedOut  bool
+	Truncated bool
+	Duration  time.Duration
+}
+
+// initSpec 描述初始化进程建立的沙箱和要执行的目标程序，Root 和 Work 为宿主机上的路径
+type initSpec struct {
+	Root         string   `json:"root"`
+	Work         string   `json:"work"`
+	Mounts       []string `json:"mounts"`
+	UID          int      `json:"uid"`
+	GID          int      `json:"gid"`
+	CPUTime      int      `json:"cpu_time"`
+	MemoryBytes  uint64   `json:"memory_bytes"`
+	MaxProcesses int      `json:"max_processes"`
+	Argv         []string `json:"argv"`
+	Env          []string `json:"env"`
+}
+
+// systemMounts 为以只读方式挂载到沙箱中的系统目录，宿主机上为符号链接的目录在沙箱中重建为相同的链接
+var systemMounts = []string{"/usr", "/bin", "/sbin", "/lib", "/lib32", "/lib64", "/etc/ld.so.cache", "/etc/alternatives"}
+
+// OutputFunc 接收执行过程中的输出分片，stream 为 stdout 或 stderr
+type OutputFunc func(stream, data string)
+
+// Runner 表示沙箱执行器
+// 每个执行槽位对应一个专用的用户 ID，同时进行的执行互不共享用户，进程数上限按单次执行生效
+type Runner struct {
+	cfg   config.SandboxConfig
+	slots chan int
+}
+
+// NewRunner 创建一个新的沙箱执行器
+func NewRunner(cfg config.SandboxConfig) *Runner {
+	concurrent := cfg.MaxConcurrent
+	if concurrent < 1 {
+		concurrent = 1
+	}
+	if cfg.Timeout < 1 {
+		cfg.Timeout = defaultTimeout
+	}
+	if cfg.CPUTime < 1 {
+		cfg.CPUTime = cfg.Timeout
+	}
+	if cfg.MemoryMB < 1 {
+		cfg.MemoryMB = defaultMemoryMB
+	}
+	if cfg.MaxProcesses < 1 {
+		cfg.MaxProcesses = defaultMaxProcesses
+	}
+
+	slots := make(chan int, concurrent)
+	for i := 0; i < concurrent; i++ {
+		slots <- i
+	}
+	return &Runner{
+		cfg:   cfg,
+		slots: slots,
+	}
+}
+
+// Enabled 判断沙箱是否启用
+func (r *Runner) Enabled() bool {
+	return r.cfg.Enabled
+}
+
+// Run 在沙箱中执行代码，输出分片按产生顺序回调 onOutput
+// 超时、超出输出上限或 ctx 取消时终止进程，已产生的输出仍保留在结果中
+func (r *Runner) Run(ctx context.Context, language, code string, onOutput OutputFunc) (*Result, error) {
+	if !r.cfg.Enabled {
+		return nil, ErrDisabled
+	}
+	language = NormalizeLanguage(language)
+	if language == "" {
+		return nil, ErrUnsupportedLanguage
+	}
+
+	// 沙箱进程不能以 root 或服务自身的用户身份运行
+	if r.cfg.UID <= 0 || r.cfg.GID <= 0 {
+		return nil, fmt.Errorf("%w: 未配置沙箱用户", ErrIsolationFailed)
+	}
+
+	// 限制同时进行的执行数，槽位决定本次执行的用户 ID
+	var slot int
+	select {
+	case slot = <-r.slots:
+		defer func() { r.slots <- slot }()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	// 临时目录中 work 存放代码，挂载到沙箱的 /work；root 作为沙箱根文件系统的挂载点
+	dir, err := os.MkdirTemp(r.cfg.WorkDir, "sandbox-")
+	if err != nil {
+		return nil, fmt.Errorf("创建沙箱目录失败: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	work := filepath.Join(dir, "work")
+	root := filepath.Join(dir, "root")
+	for _, path := range []string{work, root} {
+		if err := os.Mkdir(path, 0o700); err != nil {
+			return nil, fmt.Errorf("创建沙箱目录失败: %w", err)
+		}
+	}
+
+	spec, err := r.command(language, work, code)
+	if err != nil {
+		return nil, err
+	}
+	spec.Root = root
+	spec.Work = work
+	spec.Mounts = r.cfg.Mounts
+	spec.UID = r.cfg.UID + slot
+	spec.GID = r.cfg.GID
+	spec.CPUTime = r.cfg.CPUTime
+	spec.MemoryBytes = uint64(r.cfg.MemoryMB) << 20
+	spec.MaxProcesses = r.cfg.MaxProcesses
+	if err := os.Chown(work, spec.UID, spec.GID); err != nil {
+		return nil, fmt.Errorf("%w: 设置沙箱目录权限失败: %v", ErrIsolationFailed, err)
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, time.Duration(r.cfg.Timeout)*time.Second)
+	defer cancel()
+
+	cmd, status, err := isolatedCommand(runCtx, spec)
+	if err != nil {
+		return nil, err
+	}
+	defer status.Close()
+	cmd.WaitDelay = time.Second
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	err = cmd.Start()
+	for _, file := range cmd.ExtraFiles {
+		file.Close()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: 启动沙箱进程失败: %v", ErrIsolationFailed, err)
+	}
+
+	// 初始化进程在建立隔离或启动目标程序失败时写入状态管道后退出，成功启动目标程序时管道随之关闭
+	failure, _ := io.ReadAll(status)
+	if len(failure) > 0 {
+		cmd.Wait()
+		return nil, fmt.Errorf("%w: %s", ErrIsolationFailed, failure)
+	}
+
+	collector := &outputCollector{limit: r.cfg.MaxOutputBytes, onOutput: onOutput, kill: cancel}
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go collector.read(&wg, StreamStdout, stdout)
+	go collector.read(&wg, StreamStderr, stderr)
+	wg.Wait()
+
+	waitErr := cmd.Wait()
+	result := &Result{
+		ExitCode:  -1,
+		Stdout:    collector.stdout.String(),
+		Stderr:    collector.stderr.String(),
+		TimedOut:  errors.Is(runCtx.Err(), context.DeadlineExceeded),
+		Truncated: collector.truncated,
+		Duration:  time.Since(start),
+	}
+	if cmd.ProcessState != nil {
+		result.ExitCode = cmd.ProcessState.ExitCode()
+	}
+
+	var exitErr *exec.ExitError
+	if waitErr != nil && !errors.As(waitErr, &exitErr) && !errors.Is(waitErr, exec.ErrWaitDelay) && runCtx.Err() == nil {
+		return nil, fmt.Errorf("等待沙箱进程失败: %w", waitErr)
+	}
+	return result, nil
+}
+
+// command 将代码写入沙箱的代码目录，返回执行命令和环境变量
+func (r *Runner) command(language, dir, code string) (*initSpec, error) {
+	env := []string{
+		"PATH=/usr/local/bin:/usr/bin:/bin",
+		"HOME=" + workDir,
+		"TMPDIR=" + workDir,
+		"LANG=C.UTF-8",
+	}
+
+	var file string
+	var argv []string
+	switch language {
+	case LanguagePython:
+		file = "main.py"
+		argv = []string{r.cfg.Python, "-I", file}
+	case LanguageJavaScript:
+		file = "main.js"
+		argv = []string{r.cfg.Node, fmt.Sprintf("--max-old-space-size=%d", max(r.cfg.MemoryMB/2, 16)), file}
+	case LanguageGo:
+		file = "main.go"
+		argv = []string{r.cfg.Go, "run", file}
+		env = append(env,
+			"GOCACHE="+workDir+"/.cache",
+			"GOPATH="+workDir+"/.go",
+			"GOPROXY=off",
+			"GOTOOLCHAIN=local",
+			"CGO_ENABLED=0",
+		)
+	}
+
+	// 解释器按服务进程的 PATH 解析为真实路径，并须位于沙箱根文件系统中
+	binary, err := exec.LookPath(argv[0])
+	if err == nil {
+		binary, err = filepath.EvalSymlinks(binary)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: 未找到 %s", ErrUnsupportedLanguage, argv[0])
+	}
+	if !r.mounted(binary) {
+		return nil, fmt.Errorf("%w: %s 不在沙箱根文件系统中", ErrUnsupportedLanguage, binary)
+	}
+	argv[0] = binary
+
+	if err := os.WriteFile(filepath.Join(dir, file), []byte(code), 0o644); err != nil {
+		return nil, fmt.Errorf("写入代码失败: %w", err)
+	}
+	return &initSpec{Argv: argv, Env: env}, nil
+}
+
+// mounted 判断宿主机路径是否位于沙箱根文件系统挂载的目录中
+func (r *Runner) mounted(path string) bool {
+	for _, mount := range append(systemMounts, r.cfg.Mounts...) {
+		if path == mount || strings.HasPrefix(path, strings.TrimSuffix(mount, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// outputCollector 收集标准输出和标准错误，合计超出上限时截断并终止进程
+type outputCollector struct {
+	mu        sync.Mutex
+	limit     int
+	written   int
+	truncated bool
+	stdout    strings.Builder
+	stderr    strings.Builder
+	onOutput  OutputFunc
+	kill      context.CancelFunc
+}
+
+// read 持续读取一路输出直到进程关闭该输出
+// 分片末尾不完整的 UTF-8 字符留到下一次读取后再写出，避免多字节字符被拆到两个分片中
+func (c *outputCollector) read(wg *sync.WaitGroup, stream string, reader io.Reader) {
+	defer wg.Done()
+	buf := make([]byte, readBufferSize)
+	pending := 0
+	for {
+		n, err := reader.Read(buf[pending:])
+		n += pending
+		if err != nil {
+			if n > 0 {
+				c.write(stream, buf[:n])
+			}
+			return
+		}
+		pending = incompleteSuffix(buf[:n])
+		if n > pending {
+			c.write(stream, buf[:n-pending])
+		}
+		copy(buf, buf[n-pending:n])
+	}
+}
+
+// incompleteSuffix 返回 data 末尾不完整的 UTF-8 字符的字节数
+func incompleteSuffix(data []byte) int {
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax+1; i-- {
+		if utf8.RuneStart(data[i]) {
+			if utf8.FullRune(data[i:]) {
+				return 0
+			}
+			return len(data) - i
+		}
+	}
+	return 0
+}
+
+// runeBoundary 返回不超过 n 且不拆分 UTF-8 字符的截断位置
+func runeBoundary(data []byte, n int) int {
+	for i := n; i >= 0 && i > n-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			return i
+		}
+	}
+	return n
+}
+
+// write 记录一个输出分片并回调，超出上限的部分丢弃，截断位置不拆分 UTF-8 字符
+func (c *outputCollector) write(stream string, data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.truncated {
+		return
+	}
+	if c.limit > 0 && c.written+len(data) > c.limit {
+		data = data[:runeBoundary(data, c.limit-c.written)]
+		c.truncated = true
+		c.kill()
+	}
+	if len(data) == 0 {
+		return
+	}
+	c.written += len(data)
+
+	if stream == StreamStdout {
+		c.stdout.Write(data)
+	} else {
+		c.stderr.Write(data)
+	}
+	if c.onOutput != nil {
+		c.onOutput(stream, string(data))
+	}
+}
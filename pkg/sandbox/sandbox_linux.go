@@ -0,0 +1,260 @@
+//go:build linux
+
+package sandbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// initCommand 为以初始化模式重新执行服务可执行文件时的 argv[0]
+const initCommand = "lyss-sandbox-init"
+
+// statusFD 为初始化进程中状态管道的文件描述符，紧随标准输入、输出和错误
+const statusFD = 3
+
+// namespaceFlags 为沙箱进程使用的新命名空间：网络命名空间中没有可用的网络接口
+const namespaceFlags = unix.CLONE_NEWNS | unix.CLONE_NEWPID | unix.CLONE_NEWNET | unix.CLONE_NEWIPC | unix.CLONE_NEWUTS
+
+// devices 为绑定挂载到沙箱 /dev 中的设备
+var devices = []string{"null", "zero", "random", "urandom"}
+
+// Init 在进程以沙箱初始化模式启动时建立隔离并执行目标程序，不再返回；其他情况下直接返回
+// 需在 main 的开头调用，早于读取配置和连接外部服务
+func Init() {
+	if len(os.Args) != 2 || os.Args[0] != initCommand {
+		return
+	}
+
+	// 禁止提权的标志只作用于当前线程，建立隔离和执行目标程序须在同一线程中完成
+	runtime.LockOSThread()
+
+	status := os.NewFile(statusFD, "status")
+	syscall.CloseOnExec(statusFD)
+
+	var spec initSpec
+	err := json.Unmarshal([]byte(os.Args[1]), &spec)
+	if err == nil {
+		err = spec.isolate()
+	}
+	if err == nil {
+		err = syscall.Exec(spec.Argv[0], spec.Argv, spec.Env)
+	}
+	fmt.Fprint(status, err)
+	os.Exit(1)
+}
+
+// isolatedCommand 返回以初始化模式启动服务可执行文件的命令，以及读取初始化状态的管道
+// 服务须以 root 运行才能创建命名空间、切换根文件系统和切换用户
+func isolatedCommand(ctx context.Context, spec *initSpec) (*exec.Cmd, *os.File, error) {
+	if os.Geteuid() != 0 {
+		return nil, nil, fmt.Errorf("%w: 服务需以 root 运行", ErrIsolationFailed)
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrIsolationFailed, err)
+	}
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return nil, nil, err
+	}
+	status, statusWriter, err := os.Pipe()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 沙箱进程是新 PID 命名空间中的 1 号进程，它退出或被终止时命名空间中的其他进程随之结束
+	cmd := exec.CommandContext(ctx, self)
+	cmd.Args = []string{initCommand, string(data)}
+	cmd.Env = []string{}
+	cmd.Dir = "/"
+	cmd.ExtraFiles = []*os.File{statusWriter}
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags: namespaceFlags,
+		Setpgid:    true,
+		Pdeathsig:  syscall.SIGKILL,
+	}
+	return cmd, status, nil
+}
+
+// isolate 在新的命名空间中切换根文件系统、设置资源限制并切换到沙箱用户
+func (spec *initSpec) isolate() error {
+	if len(spec.Argv) == 0 || spec.UID <= 0 || spec.GID <= 0 {
+		return errors.New("无效的沙箱参数")
+	}
+
+	// 挂载变更不能传播回宿主机
+	if err := unix.Mount("", "/", "", unix.MS_REC|unix.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("设置私有挂载失败: %w", err)
+	}
+	if err := spec.buildRoot(); err != nil {
+		return err
+	}
+
+	// 切换根文件系统并卸载原来的根，之后只读重新挂载新的根
+	if err := unix.Chdir(spec.Root); err != nil {
+		return err
+	}
+	if err := unix.PivotRoot(".", "."); err != nil {
+		return fmt.Errorf("切换根文件系统失败: %w", err)
+	}
+	if err := unix.Unmount(".", unix.MNT_DETACH); err != nil {
+		return fmt.Errorf("卸载原根文件系统失败: %w", err)
+	}
+	if err := unix.Chdir("/"); err != nil {
+		return err
+	}
+	if err := unix.Mount("", "/", "", unix.MS_BIND|unix.MS_REMOUNT|unix.MS_RDONLY|unix.MS_NOSUID|unix.MS_NODEV, ""); err != nil {
+		return fmt.Errorf("只读挂载根文件系统失败: %w", err)
+	}
+
+	// 新的 /proc 只包含沙箱 PID 命名空间中的进程
+	if err := unix.Mount("proc", "/proc", "proc", unix.MS_NOSUID|unix.MS_NODEV|unix.MS_NOEXEC, ""); err != nil {
+		return fmt.Errorf("挂载 /proc 失败: %w", err)
+	}
+	if err := unix.Sethostname([]byte("sandbox")); err != nil {
+		return err
+	}
+	if err := unix.Chdir(workDir); err != nil {
+		return err
+	}
+
+	if err := spec.setLimits(); err != nil {
+		return fmt.Errorf("设置资源限制失败: %w", err)
+	}
+
+	// 切换到沙箱用户，并禁止通过 setuid 程序重新获得特权
+	if err := syscall.Setgroups(nil); err != nil {
+		return fmt.Errorf("切换沙箱用户失败: %w", err)
+	}
+	if err := syscall.Setgid(spec.GID); err != nil {
+		return fmt.Errorf("切换沙箱用户失败: %w", err)
+	}
+	if err := syscall.Setuid(spec.UID); err != nil {
+		return fmt.Errorf("切换沙箱用户失败: %w", err)
+	}
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return fmt.Errorf("设置禁止提权失败: %w", err)
+	}
+
+	// 切换用户会清除父进程退出时的信号，需重新设置
+	return unix.Prctl(unix.PR_SET_PDEATHSIG, uintptr(unix.SIGKILL), 0, 0, 0)
+}
+
+// buildRoot 在 Root 上挂载 tmpfs，并在其中建立最小的目录树：只读的系统目录、设备、/proc 挂载点和可写的 /work
+func (spec *initSpec) buildRoot() error {
+	if err := unix.Mount("tmpfs", spec.Root, "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, "size=1m,mode=0755"); err != nil {
+		return fmt.Errorf("挂载沙箱根文件系统失败: %w", err)
+	}
+
+	for _, path := range append(systemMounts, spec.Mounts...) {
+		if err := spec.mountReadOnly(path); err != nil {
+			return fmt.Errorf("挂载 %s 失败: %w", path, err)
+		}
+	}
+
+	dev := filepath.Join(spec.Root, "dev")
+	if err := os.Mkdir(dev, 0o755); err != nil {
+		return err
+	}
+	if err := unix.Mount("tmpfs", dev, "tmpfs", unix.MS_NOSUID|unix.MS_NOEXEC, "size=64k,mode=0755"); err != nil {
+		return fmt.Errorf("挂载 /dev 失败: %w", err)
+	}
+	for _, device := range devices {
+		target := filepath.Join(dev, device)
+		if err := os.WriteFile(target, nil, 0o666); err != nil {
+			return err
+		}
+		if err := unix.Mount("/dev/"+device, target, "", unix.MS_BIND, ""); err != nil {
+			return fmt.Errorf("挂载 /dev/%s 失败: %w", device, err)
+		}
+	}
+	for name, target := range map[string]string{"fd": "/proc/self/fd", "stdin": "/proc/self/fd/0", "stdout": "/proc/self/fd/1", "stderr": "/proc/self/fd/2"} {
+		if err := os.Symlink(target, filepath.Join(dev, name)); err != nil {
+			return err
+		}
+	}
+
+	if err := os.Mkdir(filepath.Join(spec.Root, "proc"), 0o555); err != nil {
+		return err
+	}
+
+	work := filepath.Join(spec.Root, workDir)
+	if err := os.Mkdir(work, 0o755); err != nil {
+		return err
+	}
+	if err := unix.Mount(spec.Work, work, "", unix.MS_BIND, ""); err != nil {
+		return fmt.Errorf("挂载代码目录失败: %w", err)
+	}
+	if err := unix.Mount("", work, "", unix.MS_BIND|unix.MS_REMOUNT|unix.MS_NOSUID|unix.MS_NODEV, ""); err != nil {
+		return fmt.Errorf("挂载代码目录失败: %w", err)
+	}
+	return nil
+}
+
+// mountReadOnly 将宿主机路径以只读方式挂载到沙箱根文件系统的相同位置，符号链接重建为相同的链接，不存在的路径跳过
+func (spec *initSpec) mountReadOnly(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	target := filepath.Join(spec.Root, path)
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		link, err := os.Readlink(path)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(link, target)
+	case info.IsDir():
+		err = os.Mkdir(target, 0o755)
+	default:
+		err = os.WriteFile(target, nil, 0o444)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := unix.Mount(path, target, "", unix.MS_BIND|unix.MS_REC, ""); err != nil {
+		return err
+	}
+	return unix.Mount("", target, "", unix.MS_BIND|unix.MS_REMOUNT|unix.MS_RDONLY|unix.MS_NOSUID|unix.MS_NODEV, "")
+}
+
+// setLimits 设置 CPU 时间、数据段、文件大小、打开文件数和进程数的上限，并禁止核心转储
+// 进程数上限按用户计算，每次执行使用专用的用户 ID，上限只作用于本次执行
+func (spec *initSpec) setLimits() error {
+	limits := []struct {
+		resource int
+		value    uint64
+	}{
+		{unix.RLIMIT_CPU, uint64(spec.CPUTime)},
+		{unix.RLIMIT_DATA, spec.MemoryBytes},
+		{unix.RLIMIT_FSIZE, maxFileSize},
+		{unix.RLIMIT_NOFILE, maxOpenFiles},
+		{unix.RLIMIT_CORE, 0},
+		{unix.RLIMIT_NPROC, uint64(spec.MaxProcesses)},
+	}
+	for _, limit := range limits {
+		if err := unix.Setrlimit(limit.resource, &unix.Rlimit{Cur: limit.value, Max: limit.value}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+//go:build linux
+
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zhuiye8/Lyss-chat-server/pkg/config"
+)
+
+func TestMain(m *testing.M) {
+	Init()
+	os.Exit(m.Run())
+}
+
+func newTestRunner(t *testing.T) *Runner {
+	t.Helper()
+	if os.Geteuid() != 0 {
+		t.Skip("沙箱隔离需要 root")
+	}
+	// 解释器须位于沙箱挂载的系统目录中
+	if _, err := os.Stat("/usr/bin/python3"); err != nil {
+		t.Skip("未安装 /usr/bin/python3")
+	}
+	return NewRunner(config.SandboxConfig{
+		Enabled:        true,
+		Python:         "/usr/bin/python3",
+		UID:            60000,
+		GID:            60000,
+		Timeout:        20,
+		MaxProcesses:   16,
+		MaxOutputBytes: 64 * 1024,
+		MaxConcurrent:  2,
+	})
+}
+
+func runPython(t *testing.T, runner *Runner, code string) *Result {
+	t.Helper()
+	result, err := runner.Run(context.Background(), LanguagePython, code, nil)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	return result
+}
+
+func TestRunIsolation(t *testing.T) {
+	runner := newTestRunner(t)
+
+	secret := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(secret, []byte("secret"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		code string
+		want string
+	}{
+		{"output", `print("hello")`, "hello"},
+		{"unprivileged user", `import os; print(os.getuid() in (60000, 60001), os.getgid())`, "True 60000"},
+		{"host files hidden", `import os; print(os.path.exists(` + "\"" + secret + "\"" + `), os.path.exists("/etc/passwd"))`, "False False"},
+		{"fresh proc", `import os; print(sorted(p for p in os.listdir("/proc") if p.isdigit()))`, "['1']"},
+		{"read-only root", "try:\n    open('/usr/x', 'w')\nexcept OSError:\n    print('denied')", "denied"},
+		{"no network", "import socket\ntry:\n    socket.create_connection(('1.1.1.1', 53), timeout=1)\nexcept OSError:\n    print('offline')", "offline"},
+		{"process limit", "import os\nn = 0\ntry:\n    while n < 100:\n        if os.fork() == 0:\n            import time; time.sleep(5); os._exit(0)\n        n += 1\nexcept OSError:\n    pass\nprint('limited' if n < 100 else 'unbounded')", "limited"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := runPython(t, runner, tt.code)
+			if got := strings.TrimSpace(result.Stdout); got != tt.want {
+				t.Fatalf("stdout = %q, want %q (stderr %q)", got, tt.want, result.Stderr)
+			}
+		})
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	runner := newTestRunner(t)
+	runner.cfg.Timeout = 1
+
+	result := runPython(t, runner, "while True:\n    pass")
+	if !result.TimedOut {
+		t.Fatalf("TimedOut = false, want true")
+	}
+}
+
+func TestRunRefusesWithoutUser(t *testing.T) {
+	runner := NewRunner(config.SandboxConfig{Enabled: true, Python: "python3"})
+
+	_, err := runner.Run(context.Background(), LanguagePython, `print("hello")`, nil)
+	if !errors.Is(err, ErrIsolationFailed) {
+		t.Fatalf("Run error = %v, want %v", err, ErrIsolationFailed)
+	}
+}
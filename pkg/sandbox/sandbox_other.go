@@ -0,0 +1,17 @@
+//go:build !linux
+
+package sandbox
+
+import (
+	"context"
+	"os"
+	"os/exec"
+)
+
+// Init 在非 Linux 平台上不做任何事
+func Init() {}
+
+// isolatedCommand 在非 Linux 平台上不可用，沙箱依赖 Linux 命名空间
+func isolatedCommand(ctx context.Context, spec *initSpec) (*exec.Cmd, *os.File, error) {
+	return nil, nil, ErrUnsupportedPlatform
+}
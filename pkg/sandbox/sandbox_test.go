@@ -0,0 +1,71 @@
+package sandbox
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"testing/iotest"
+	"unicode/utf8"
+)
+
+// collect 以逐字节读取的方式收集 output，返回回调收到的分片
+func collect(t *testing.T, limit int, output string) (*outputCollector, []string) {
+	t.Helper()
+	var chunks []string
+	collector := &outputCollector{
+		limit:    limit,
+		onOutput: func(stream, data string) { chunks = append(chunks, data) },
+		kill:     func() {},
+	}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	collector.read(&wg, StreamStdout, iotest.OneByteReader(strings.NewReader(output)))
+	return collector, chunks
+}
+
+func TestOutputCollectorKeepsRunes(t *testing.T) {
+	output := "你好，世界 😀 hello"
+	collector, chunks := collect(t, 0, output)
+
+	for _, chunk := range chunks {
+		if !utf8.ValidString(chunk) {
+			t.Fatalf("chunk %q is not valid UTF-8", chunk)
+		}
+	}
+	if got := strings.Join(chunks, ""); got != output {
+		t.Fatalf("chunks = %q, want %q", got, output)
+	}
+	if got := collector.stdout.String(); got != output {
+		t.Fatalf("stdout = %q, want %q", got, output)
+	}
+}
+
+func TestOutputCollectorIncompleteAtEOF(t *testing.T) {
+	// 输出以不完整的字符结束时原样保留
+	output := "ab\xe4\xbd"
+	collector, _ := collect(t, 0, output)
+	if got := collector.stdout.String(); got != output {
+		t.Fatalf("stdout = %q, want %q", got, output)
+	}
+}
+
+func TestOutputCollectorTruncatesOnRuneBoundary(t *testing.T) {
+	tests := []struct {
+		limit int
+		want  string
+	}{
+		{1, "a"},
+		{2, "a"},
+		{3, "a"},
+		{4, "a你"},
+		{5, "a你"},
+		{8, "a你😀"},
+	}
+	for _, tt := range tests {
+		collector := &outputCollector{limit: tt.limit, kill: func() {}}
+		collector.write(StreamStdout, []byte("a你😀b"))
+		if got := collector.stdout.String(); got != tt.want || !collector.truncated {
+			t.Errorf("limit %d: stdout = %q, truncated = %v, want %q", tt.limit, got, collector.truncated, tt.want)
+		}
+	}
+}